@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"joao/wasm-manipulator/internal/wconfigs"
+	"joao/wasm-manipulator/pkg/wfile"
+)
+
+// batchCommand transforms the modules of the arguments, i.e., files, directories or glob patterns, with the input transformation.
+// the transformation is read once and the modules are transformed concurrently by worker processes, so a failure does not stop the others.
+// exits with an error status when some module fails.
+func batchCommand(args []string) {
+	configs := wconfigs.Get()
+	if len(args) == 0 {
+		logrus.Fatalln("no modules defined: the batch command expects files, directories or glob patterns")
+	}
+	jobs, err := batchJobs(args, filePath(configs.OutputDir), configs.OutputFlat)
+	if err != nil {
+		logrus.Fatalln(err)
+	}
+
+	transformation, issues, err := readTransformation(filePath(configs.InputTransformation))
+	if err != nil {
+		logrus.Fatalln(err)
+	}
+	if hasErrors(issues) {
+		logrus.Fatalln("invalid transformations: fix the errors listed by the wmr check command")
+	}
+
+	workers := poolSize()
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+	logrus.WithFields(logrus.Fields{"modules": len(jobs), "workers": workers}).Infoln("Transforming modules")
+	results := runWorkers(&workerSetup{Config: configs, Transformation: transformation}, jobs, workers)
+
+	switch configs.Format {
+	case "json":
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			logrus.Fatalf("encoding batch results: %v", err)
+		}
+		fmt.Println(string(data))
+	case "text", "":
+		printBatchResults(os.Stdout, results)
+	default:
+		logrus.Fatalf("unknown output format %q", configs.Format)
+	}
+	for _, res := range results {
+		if !res.Success {
+			os.Exit(1)
+		}
+	}
+}
+
+// batchJobs returns the jobs for the modules of some inputs.
+// the directories are searched recursively for wasm and wat files, keeping their relative path on the output directory.
+func batchJobs(inputs []string, outDir string, flat bool) ([]*batchJob, error) {
+	ext := ".wasm"
+	if flat {
+		ext = ".wat"
+	}
+	var jobs []*batchJob
+	inputsMap := make(map[string]struct{})
+	outputsMap := make(map[string]string)
+	addJob := func(input, rel string) error {
+		if _, ok := inputsMap[input]; ok {
+			return nil
+		}
+		inputsMap[input] = struct{}{}
+		output := filepath.Join(outDir, wfile.ReplaceExt(rel, ext))
+		if other, ok := outputsMap[output]; ok {
+			return fmt.Errorf("modules %q and %q have the same output file %q", other, input, output)
+		}
+		outputsMap[output] = input
+		jobs = append(jobs, &batchJob{Input: input, OutputModule: output, OutputJS: wfile.ReplaceExt(output, ".js")})
+		return nil
+	}
+
+	for _, input := range inputs {
+		matches, err := filepath.Glob(filePath(input))
+		if err != nil {
+			return nil, fmt.Errorf("invalid input pattern %q: %w", input, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no modules found for %q", input)
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				if err := addJob(match, filepath.Base(match)); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			err = filepath.Walk(match, func(path string, info os.FileInfo, err error) error {
+				if err != nil || info.IsDir() || !isModuleFile(path) {
+					return err
+				}
+				rel, err := filepath.Rel(match, path)
+				if err != nil {
+					return err
+				}
+				return addJob(path, rel)
+			})
+			if err != nil {
+				return nil, fmt.Errorf("searching modules on %q: %w", match, err)
+			}
+		}
+	}
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("no modules found for %s", strings.Join(inputs, " "))
+	}
+	return jobs, nil
+}
+
+// isModuleFile returns if some file is a module, i.e., a wasm or wat file.
+func isModuleFile(filename string) bool {
+	ext := filepath.Ext(filename)
+	return ext == ".wasm" || ext == ".wat"
+}
+
+// printBatchResults prints the results of the batch command in a human readable format.
+func printBatchResults(w io.Writer, results []*batchResult) {
+	var failed int
+	for _, res := range results {
+		if !res.Success {
+			failed++
+			fmt.Fprintf(w, "fail %s: %s\n", res.Input, res.Error)
+			continue
+		}
+		fmt.Fprintf(w, "ok   %s -> %s (%d join-points)\n", res.Input, res.Output, res.JoinPoints)
+	}
+	fmt.Fprintf(w, "%d modules: %d transformed, %d failed\n", len(results), len(results)-failed, failed)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBatchJobs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.wasm", "b.wat", "notes.txt", filepath.Join("sub", "c.wasm"), filepath.Join("other", "a.wasm")} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	jobs, err := batchJobs([]string{filepath.Join(dir, "sub"), filepath.Join(dir, "*.wa*"), filepath.Join(dir, "a.wasm")}, "out", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, job := range jobs {
+		rel, _ := filepath.Rel(dir, job.Input)
+		got = append(got, rel+" "+job.OutputModule+" "+job.OutputJS)
+	}
+	expected := []string{"sub/c.wasm out/c.wasm out/c.js", "a.wasm out/a.wasm out/a.js", "b.wat out/b.wasm out/b.js"}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected jobs:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	if _, err := batchJobs([]string{filepath.Join(dir, "a.wasm"), filepath.Join(dir, "other", "a.wasm")}, "out", false); err == nil || !strings.Contains(err.Error(), "same output file") {
+		t.Errorf("expected output conflict error, got %v", err)
+	}
+	if _, err := batchJobs([]string{filepath.Join(dir, "*.none")}, "out", false); err == nil {
+		t.Error("expected no modules error")
+	}
+}
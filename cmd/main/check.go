@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	"joao/wasm-manipulator/internal/waspect"
+	"joao/wasm-manipulator/internal/wconfigs"
+	"joao/wasm-manipulator/internal/wyaml"
+	"joao/wasm-manipulator/pkg/wfile"
+)
+
+// checkCommand validates a transformation file, printing all the issues found.
+// the file is the first argument or, when not defined, the input transformation.
+// exits with an error status when some error is found.
+func checkCommand(args []string) {
+	configs := wconfigs.Get()
+	filename := configs.InputTransformation
+	if len(args) > 0 {
+		filename = args[0]
+	}
+	_, issues, err := readTransformation(filePath(filename))
+	if err != nil {
+		logrus.Fatalln(err)
+	}
+
+	switch configs.Format {
+	case "json":
+		if issues == nil {
+			issues = []*wyaml.Issue{}
+		}
+		data, err := json.MarshalIndent(issues, "", "  ")
+		if err != nil {
+			logrus.Fatalf("encoding issues: %v", err)
+		}
+		fmt.Println(string(data))
+	case "text", "":
+		for _, issue := range issues {
+			fmt.Printf("%s:%s\n", filename, issue)
+		}
+	default:
+		logrus.Fatalf("unknown output format %q", configs.Format)
+	}
+	if hasErrors(issues) {
+		os.Exit(1)
+	}
+}
+
+// readTransformation reads a transformation file and validates it.
+// returns the transformation with all the issues found.
+func readTransformation(filename string) (*wyaml.BaseYAML, []*wyaml.Issue, error) {
+	content, err := wfile.ReadFile(filename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading yaml input content: %w", err)
+	}
+	return parseTransformation(filename, content)
+}
+
+// parseTransformation parses the content of a transformation file and validates it, as readTransformation.
+// the filename is only used to choose the format.
+func parseTransformation(filename, content string) (*wyaml.BaseYAML, []*wyaml.Issue, error) {
+	transformation, source, issues, err := wyaml.ParseSource(filename, content)
+	if err != nil {
+		return nil, nil, err
+	}
+	if transformation != nil {
+		issues = append(issues, waspect.Check(transformation, source)...)
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Line != issues[j].Line {
+			return issues[i].Line < issues[j].Line
+		}
+		return issues[i].Column < issues[j].Column
+	})
+	return transformation, issues, nil
+}
+
+// hasErrors returns if some of the issues is an error.
+func hasErrors(issues []*wyaml.Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == wyaml.SeverityError {
+			return true
+		}
+	}
+	return false
+}
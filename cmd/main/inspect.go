@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"joao/wasm-manipulator/internal/wcode"
+	"joao/wasm-manipulator/internal/wconfigs"
+)
+
+// inspectCommand prints the functions, globals and types of some module.
+// the module is the first argument or, when not defined, the input module.
+func inspectCommand(args []string) {
+	configs := wconfigs.Get()
+	filename := configs.InputModule
+	if len(args) > 0 {
+		filename = args[0]
+	}
+	code, err := readModule(filename)
+	if err != nil {
+		logrus.Fatalln(err)
+	}
+	info := wcode.NewModuleContext(wcode.NewCodeParser(code).Parse()).Inspect()
+
+	switch configs.Format {
+	case "json":
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			logrus.Fatalf("encoding module info: %v", err)
+		}
+		fmt.Println(string(data))
+	case "text", "":
+		printModuleInfo(os.Stdout, info)
+	default:
+		logrus.Fatalf("unknown output format %q", configs.Format)
+	}
+}
+
+// printModuleInfo prints the module info in a human readable format.
+func printModuleInfo(w io.Writer, info *wcode.ModuleInfo) {
+	fmt.Fprintf(w, "functions (%d):\n", len(info.Functions))
+	for _, fn := range info.Functions {
+		var flags []string
+		if fn.IsImported {
+			flags = append(flags, "imported")
+		}
+		if fn.IsExported {
+			flags = append(flags, "exported")
+		}
+		if fn.IsStart {
+			flags = append(flags, "start")
+		}
+		fmt.Fprintf(w, "  %d %s %s", fn.Order, fn.Index, fn.Name)
+		if len(flags) > 0 {
+			fmt.Fprintf(w, " [%s]", strings.Join(flags, ","))
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "    params: (%s) locals: (%s) results: (%s)\n",
+			strings.Join(fn.ParamTypes, " "), strings.Join(fn.LocalTypes, " "), strings.Join(fn.ResultTypes, " "))
+		if len(fn.Calls) > 0 {
+			fmt.Fprintf(w, "    calls: %s\n", strings.Join(fn.Calls, " "))
+		}
+	}
+
+	fmt.Fprintf(w, "globals (%d):\n", len(info.Globals))
+	for _, global := range info.Globals {
+		mutability := "const"
+		if global.Mutable {
+			mutability = "mut"
+		}
+		fmt.Fprintf(w, "  %d %s %s %s %s\n", global.Order, global.Index, global.Name, mutability, global.Type)
+	}
+
+	fmt.Fprintf(w, "types (%d):\n", len(info.Types))
+	for _, typ := range info.Types {
+		fmt.Fprintf(w, "  %d %s (%s) -> (%s)\n", typ.Order, typ.Index, strings.Join(typ.Params, " "), strings.Join(typ.Results, " "))
+	}
+
+	if info.Start != "" {
+		fmt.Fprintf(w, "start: %s\n", info.Start)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+
+	"github.com/sirupsen/logrus"
+
+	"joao/wasm-manipulator/internal/wcode"
+	"joao/wasm-manipulator/internal/wconfigs"
+	"joao/wasm-manipulator/internal/wlsp"
+)
+
+// lspCommand runs the language server for the transformation files over the standard input and output.
+// the input module, when it exists, is used to complete the function names on the pointcuts.
+func lspCommand(_ []string) {
+	// The standard output is reserved to the protocol messages.
+	logrus.SetOutput(os.Stderr)
+
+	var module *wcode.ModuleInfo
+	configs := wconfigs.Get()
+	if _, err := os.Stat(filePath(configs.InputModule)); err == nil {
+		code, err := readModule(configs.InputModule)
+		if err != nil {
+			logrus.Warnf("module completion disabled: %v", err)
+		} else {
+			module = wcode.NewModuleContext(wcode.NewCodeParser(code).Parse()).Inspect()
+		}
+	}
+
+	if err := wlsp.NewServer(os.Stdin, os.Stdout, module).Run(); err != nil {
+		logrus.Fatalln(err)
+	}
+}
@@ -6,36 +6,61 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
 
 	"joao/wasm-manipulator/internal/waspect"
+	"joao/wasm-manipulator/internal/wcode"
 	"joao/wasm-manipulator/internal/wconfigs"
 	"joao/wasm-manipulator/internal/wgenerator"
 	"joao/wasm-manipulator/internal/wyaml"
 	"joao/wasm-manipulator/pkg/wfile"
 )
 
+// commands contains the tool commands by name.
+var commands = map[string]func(args []string){
+	"inspect": inspectCommand,
+	"check":   checkCommand,
+	"schema":  schemaCommand,
+	"lsp":     lspCommand,
+	"repl":    replCommand,
+	"batch":   batchCommand,
+	"serve":   serveCommand,
+	"worker":  workerCommand,
+}
+
 // main is the entry function for the execution of this command line tool,
 func main() {
 	var code string
 	var err error
 
 	configs := wconfigs.Get()
-	ext := strings.Trim(filepath.Ext(configs.InputModule), ".")
 
-	logrus.Infof("Reading module (%s file)", ext)
-	switch ext {
-	case "wasm":
-		code, err = wfile.ReadWasmFile(filePath(configs.InputModule))
-	case "wat":
-		code, err = wfile.ReadWatFile(filePath(configs.InputModule))
-	default:
-		err = fmt.Errorf("unknown input file extension %q", ext)
+	// Execute the command, when defined.
+	if name := pflag.Arg(0); name != "" {
+		command, ok := commands[name]
+		if !ok {
+			logrus.Fatalf("unknown command %q", name)
+		}
+		command(pflag.Args()[1:])
+		return
 	}
+
+	// Transform the module on each change, when watching the input files.
+	if configs.Watch {
+		newWatcher(configs).watch()
+		return
+	}
+
+	ext := strings.Trim(filepath.Ext(configs.InputModule), ".")
+	start := time.Now()
+	code, err = readModule(configs.InputModule)
 	if err != nil {
 		logrus.Fatalln(err)
 	}
+	readPhases := []*waspect.PhaseReport{waspect.NewPhaseReport("read module", start)}
 
 	if ext != "wasm" && configs.OutputOriginal != "" {
 		logrus.Infoln("Printing untouched wasm file")
@@ -46,14 +71,32 @@ func main() {
 	}
 
 	logrus.Infoln("Reading transformations (yaml file)")
-	transformation, err := wyaml.Read(filePath(configs.InputTransformation))
+	start = time.Now()
+	transformation, issues, err := readTransformation(filePath(configs.InputTransformation))
 	if err != nil {
 		logrus.Fatalln(err)
 	}
+	readPhases = append(readPhases, waspect.NewPhaseReport("read transformation", start))
+	for _, issue := range issues {
+		if issue.Severity == wyaml.SeverityError {
+			logrus.Errorf("%s:%s", configs.InputTransformation, issue)
+		} else {
+			logrus.Warnf("%s:%s", configs.InputTransformation, issue)
+		}
+	}
+	if hasErrors(issues) {
+		logrus.Fatalln("invalid transformations: fix the errors above (the wmr check command lists them)")
+	}
 
 	// Debug purpose. TODO: delete
 	logrus.Debugf(fmt.Sprintf("Input Length: %d\n", len(code)))
 
+	// Keep the original module to compare with the transformed one.
+	var original *wcode.ModuleContext
+	if configs.OutputDiff != "" {
+		original = wcode.NewModuleContext(wcode.NewCodeParser(code).Parse())
+	}
+
 	// Execute transformations on module
 	output, ok := waspect.Run(code, transformation)
 	if !ok {
@@ -61,6 +104,17 @@ func main() {
 		return
 	}
 
+	if original != nil {
+		logrus.Infoln("Printing module diff")
+		err = wfile.WriteFile(filePath(configs.OutputDiff), output.Diff(original).String())
+		if err != nil {
+			logrus.Errorf("could not print the module diff: %v", err)
+		}
+	}
+
+	report := output.Report
+	report.Phases = append(readPhases, report.Phases...)
+
 	outputWg := new(sync.WaitGroup)
 
 	outputWg.Add(1)
@@ -70,8 +124,10 @@ func main() {
 		outputWg.Done()
 	}()
 
+	var jsPhase *waspect.PhaseReport
 	outputWg.Add(1)
 	go func() {
+		start := time.Now()
 		jsCode, err := output.GenerateJsData()
 		if err != nil && err != wgenerator.ErrorUnnecessary {
 			logrus.Infoln("Printing javascript transformations")
@@ -84,19 +140,53 @@ func main() {
 				logrus.Fatalln(err)
 			}
 		}
+		jsPhase = waspect.NewPhaseReport("print javascript", start)
 		outputWg.Done()
 	}()
 
 	logrus.Infoln("Printing web assembly transformations")
-	err = wfile.PrintWasmCode(output.String(), filePath(configs.OutputModule))
+	start = time.Now()
+	if configs.OutputFlat {
+		err = wfile.PrintFlatWatCode(output.String(), filePath(configs.OutputModule))
+	} else {
+		err = wfile.PrintWasmCode(output.String(), filePath(configs.OutputModule))
+	}
 	if err != nil {
 		logrus.Fatalln(err)
 	}
+	report.Phases = append(report.Phases, waspect.NewPhaseReport("print module", start))
 	outputWg.Wait()
 
+	if configs.OutputReport != "" {
+		logrus.Infoln("Printing transformation report")
+		report.Phases = append(report.Phases, jsPhase)
+		if err := writeReport(report, configs); err != nil {
+			logrus.Errorf("could not print the transformation report: %v", err)
+		}
+	}
+
 	logrus.Infoln("Finishing execution")
 }
 
+// readModule reads the module code from a wasm or wat file.
+func readModule(filename string) (string, error) {
+	return readModuleFile(filePath(filename))
+}
+
+// readModuleFile reads the module code from a wasm or wat file, without using the data directory.
+func readModuleFile(filename string) (string, error) {
+	ext := strings.Trim(filepath.Ext(filename), ".")
+	logrus.Infof("Reading module (%s file)", ext)
+	switch ext {
+	case "wasm":
+		return wfile.ReadWasmFile(filename)
+	case "wat":
+		return wfile.ReadWatFile(filename)
+	default:
+		return "", fmt.Errorf("unknown input file extension %q", ext)
+	}
+}
+
 // init initiates the tool configurations.
 func init() {
 	wconfigs.SetupViperConfigs()
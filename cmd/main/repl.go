@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"joao/wasm-manipulator/internal/waspect"
+	"joao/wasm-manipulator/internal/wconfigs"
+	"joao/wasm-manipulator/internal/wyaml"
+)
+
+// replHelp is the help message of the repl command.
+const replHelp = `commands:
+  :advice <name>    select the join-points of some advice
+  :func <name>      select the join-point of some function (name or index, e.g. $f0)
+  :list             list the selected join-points
+  :select <n>       select the join-point used by the expressions
+  :help             print this message
+  :quit             exit
+expressions:
+  call.Args:map((a) => a.Instr):join(" ")    evaluated method by method, printing the type of each step
+  size: %func.TotalParams%                   evaluated as the advice code`
+
+// replCommand runs an interactive session that evaluates static expressions against the join-points of the input module.
+// the advice selected initially is the first argument, when defined.
+func replCommand(args []string) {
+	configs := wconfigs.Get()
+	code, err := readModule(configs.InputModule)
+	if err != nil {
+		logrus.Fatalln(err)
+	}
+	input := new(wyaml.BaseYAML)
+	if _, err := os.Stat(filePath(configs.InputTransformation)); err == nil {
+		transformation, issues, err := readTransformation(filePath(configs.InputTransformation))
+		if err != nil {
+			logrus.Fatalln(err)
+		}
+		if hasErrors(issues) {
+			logrus.Fatalln("invalid transformations: fix the errors listed by the wmr check command")
+		}
+		input = transformation
+	}
+
+	session := waspect.NewSession(code, input)
+	if len(args) > 0 {
+		replSelect(os.Stdout, session.SelectAdvice, args[0])
+	}
+
+	fmt.Println("Type :help for the available commands")
+	scanner := bufio.NewScanner(os.Stdin)
+	for fmt.Print("wmr> "); scanner.Scan(); fmt.Print("wmr> ") {
+		line := strings.TrimSpace(scanner.Text())
+		command, arg := line, ""
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			command, arg = line[:i], strings.TrimSpace(line[i+1:])
+		}
+		switch command {
+		case "":
+		case ":quit", ":exit":
+			return
+		case ":help":
+			io.WriteString(os.Stdout, replHelp+"\n")
+		case ":advice":
+			replSelect(os.Stdout, session.SelectAdvice, arg)
+		case ":func":
+			replSelect(os.Stdout, session.SelectFunction, arg)
+		case ":list":
+			for i, jp := range session.JoinPoints() {
+				marker := " "
+				if i == session.Selected() {
+					marker = "*"
+				}
+				fmt.Printf("%s %d %s\n", marker, i, jp)
+			}
+		case ":select":
+			i, err := strconv.Atoi(arg)
+			if err == nil {
+				err = session.Select(i)
+			}
+			if err != nil {
+				fmt.Printf("error: %v\n", err)
+			}
+		default:
+			replEvaluate(os.Stdout, session, line, configs.Format)
+		}
+	}
+	fmt.Println()
+}
+
+// replSelect selects the join-points with some select function of the session, printing the number found.
+func replSelect(w io.Writer, selectFn func(string) (int, error), name string) {
+	total, err := selectFn(name)
+	if err != nil {
+		fmt.Fprintf(w, "error: %v\n", err)
+		return
+	}
+	fmt.Fprintf(w, "%d join-points found\n", total)
+}
+
+// replEvaluate evaluates some expression on the session, printing the value and type of each step.
+func replEvaluate(w io.Writer, session *waspect.Session, expr, format string) {
+	steps, err := session.Evaluate(expr)
+	if err != nil {
+		fmt.Fprintf(w, "error: %v\n", err)
+		return
+	}
+	if format == "json" {
+		data, err := json.MarshalIndent(steps, "", "  ")
+		if err != nil {
+			logrus.Fatalf("encoding evaluation steps: %v", err)
+		}
+		fmt.Fprintln(w, string(data))
+		return
+	}
+	for _, step := range steps {
+		fmt.Fprintf(w, "%s\n  %s: %s\n", step.Expression, step.Type, step.Value)
+	}
+}
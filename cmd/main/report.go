@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"joao/wasm-manipulator/internal/waspect"
+	"joao/wasm-manipulator/internal/wconfigs"
+	"joao/wasm-manipulator/pkg/wfile"
+)
+
+// writeReport writes the json report of a transformation, with the sizes of the input and output module files.
+func writeReport(report *waspect.Report, configs wconfigs.ToolConfig) error {
+	input, err := os.Stat(filePath(configs.InputModule))
+	if err != nil {
+		return fmt.Errorf("reading input module size: %w", err)
+	}
+	output, err := os.Stat(filePath(configs.OutputModule))
+	if err != nil {
+		return fmt.Errorf("reading output module size: %w", err)
+	}
+	report.InputSize, report.OutputSize = input.Size(), output.Size()
+
+	// The pointcuts are kept readable, without escaping their operators.
+	var sb strings.Builder
+	encoder := json.NewEncoder(&sb)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("encoding report: %w", err)
+	}
+	return wfile.WriteFile(filePath(configs.OutputReport), sb.String())
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"joao/wasm-manipulator/internal/wyaml"
+	"joao/wasm-manipulator/pkg/wfile"
+)
+
+// schemaCommand prints the JSON Schema of the transformation file.
+// the schema is written to the file given as argument or, when not defined, to the standard output.
+func schemaCommand(args []string) {
+	data, err := wyaml.GenerateSchema().JSON()
+	if err != nil {
+		logrus.Fatalf("generating transformation schema: %v", err)
+	}
+	if len(args) == 0 {
+		fmt.Print(string(data))
+		return
+	}
+	if err := wfile.WriteFile(filePath(args[0]), string(data)); err != nil {
+		logrus.Fatalf("writing transformation schema: %v", err)
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"joao/wasm-manipulator/internal/wconfigs"
+	"joao/wasm-manipulator/internal/wyaml"
+)
+
+const (
+	// serveMaxRequestSize is the maximum size of the transform requests body.
+	serveMaxRequestSize = 64 << 20
+	// serveCacheSize is the maximum number of parsed transformations kept by the server.
+	serveCacheSize = 128
+)
+
+// transformRequest is the body of the transform requests with the json format.
+// the module and the transformation are encoded in base64, their names are only used to choose the formats.
+type transformRequest struct {
+	Module             []byte
+	ModuleName         string
+	Transformation     []byte
+	TransformationName string
+}
+
+// transformResponse is the body of the transform responses.
+// the module is encoded in base64, being nil when the transformation fails.
+type transformResponse struct {
+	Module []byte
+	JS     string
+	Report *transformReport
+}
+
+// transformReport describes the transformation of a module.
+type transformReport struct {
+	Success    bool
+	JoinPoints int
+	Advices    map[string]int
+	Issues     []*wyaml.Issue
+	Error      string
+}
+
+// cachedTransformation is a parsed transformation, with the issues found on its validation.
+type cachedTransformation struct {
+	transformation *wyaml.BaseYAML
+	issues         []*wyaml.Issue
+}
+
+// transformationCache keeps the parsed transformations by the hash of their content.
+// the oldest transformation is removed when the cache is full.
+type transformationCache struct {
+	mutex   *sync.Mutex
+	entries map[string]*cachedTransformation
+	keys    []string
+}
+
+// newTransformationCache is the constructor for transformationCache.
+func newTransformationCache() *transformationCache {
+	return &transformationCache{mutex: new(sync.Mutex), entries: make(map[string]*cachedTransformation)}
+}
+
+// get returns the parsed transformation of some file content, parsing it when it is not on the cache.
+func (c *transformationCache) get(filename string, content []byte) (*cachedTransformation, error) {
+	hash := sha256.Sum256(content)
+	key := fmt.Sprintf("%s:%s", filepath.Ext(filename), hex.EncodeToString(hash[:]))
+
+	c.mutex.Lock()
+	entry, ok := c.entries[key]
+	c.mutex.Unlock()
+	if ok {
+		return entry, nil
+	}
+
+	transformation, issues, err := parseTransformation(filename, string(content))
+	if err != nil {
+		return nil, err
+	}
+	entry = &cachedTransformation{transformation: transformation, issues: issues}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, ok := c.entries[key]; !ok {
+		if len(c.keys) >= serveCacheSize {
+			delete(c.entries, c.keys[0])
+			c.keys = c.keys[1:]
+		}
+		c.entries[key] = entry
+		c.keys = append(c.keys, key)
+	}
+	return entry, nil
+}
+
+// len returns the number of transformations on the cache.
+func (c *transformationCache) len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.entries)
+}
+
+// transformServer is the http server of the serve command.
+type transformServer struct {
+	pool  *workerPool
+	cache *transformationCache
+}
+
+// newTransformServer is the constructor for transformServer.
+func newTransformServer(pool *workerPool) *transformServer {
+	return &transformServer{pool: pool, cache: newTransformationCache()}
+}
+
+// serveCommand runs an http server that transforms the modules sent by the clients.
+// the modules are transformed by worker processes, which also limit the number of concurrent transformations.
+func serveCommand(_ []string) {
+	configs := wconfigs.Get()
+	pool := newWorkerPool(&workerSetup{Config: configs}, poolSize())
+	server := &http.Server{Addr: configs.Addr, Handler: newTransformServer(pool).handler()}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		logrus.Infoln("Stopping server")
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			logrus.Errorf("stopping server: %v", err)
+		}
+	}()
+
+	logrus.WithFields(logrus.Fields{"addr": configs.Addr, "workers": cap(pool.workers)}).Infoln("Listening for requests")
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logrus.Fatalln(err)
+	}
+	pool.close()
+	logrus.Infoln("Finishing execution")
+}
+
+// handler returns the http handler of the server.
+func (s *transformServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/transform", s.handleTransform)
+	return mux
+}
+
+// handleHealth answers with the state of the server.
+func (s *transformServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"Status":          "ok",
+		"Workers":         cap(s.pool.workers),
+		"Running":         cap(s.pool.workers) - len(s.pool.workers),
+		"Transformations": s.cache.len(),
+	})
+}
+
+// handleTransform transforms the module of some request, answering with the transformed module, the javascript code and the report.
+// the request is a multipart form with the module and transformation files, or a json transformRequest.
+func (s *transformServer) handleTransform(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, serveMaxRequestSize)
+	req, err := readTransformRequest(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, &transformResponse{Report: &transformReport{Error: err.Error()}})
+		return
+	}
+	moduleExt := filepath.Ext(req.ModuleName)
+	if !isModuleFile(req.ModuleName) {
+		err := fmt.Errorf("unknown module file extension %q", moduleExt)
+		writeJSON(w, http.StatusBadRequest, &transformResponse{Report: &transformReport{Error: err.Error()}})
+		return
+	}
+
+	entry, err := s.cache.get(req.TransformationName, req.Transformation)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, &transformResponse{Report: &transformReport{Error: err.Error()}})
+		return
+	}
+	report := &transformReport{Issues: entry.issues}
+	if hasErrors(entry.issues) {
+		report.Error = "invalid transformations: fix the errors listed on the issues"
+		writeJSON(w, http.StatusBadRequest, &transformResponse{Report: report})
+		return
+	}
+
+	dir, err := ioutil.TempDir("", "wmr-serve-")
+	if err != nil {
+		logrus.Errorf("creating temporary directory: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(dir)
+	job := &batchJob{
+		Input:          filepath.Join(dir, "input"+moduleExt),
+		OutputModule:   filepath.Join(dir, "output.wasm"),
+		OutputJS:       filepath.Join(dir, "output.js"),
+		Transformation: entry.transformation,
+	}
+	if wconfigs.Get().OutputFlat {
+		job.OutputModule = filepath.Join(dir, "output.wat")
+	}
+	if err := ioutil.WriteFile(job.Input, req.Module, 0644); err != nil {
+		logrus.Errorf("writing input module: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	res := s.pool.run(job)
+	report.Success, report.JoinPoints, report.Advices, report.Error = res.Success, res.JoinPoints, res.Advices, res.Error
+	if !res.Success {
+		writeJSON(w, http.StatusUnprocessableEntity, &transformResponse{Report: report})
+		return
+	}
+	resp := &transformResponse{Report: report}
+	if resp.Module, err = ioutil.ReadFile(res.Output); err == nil && res.JS != "" {
+		var js []byte
+		js, err = ioutil.ReadFile(res.JS)
+		resp.JS = string(js)
+	}
+	if err != nil {
+		logrus.Errorf("reading transformation output: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// readTransformRequest reads the module and the transformation of some request, with the multipart or json format.
+// the module is a wasm file and the transformation a yaml file, unless their names have other extensions.
+func readTransformRequest(r *http.Request) (*transformRequest, error) {
+	req := &transformRequest{ModuleName: "input.wasm", TransformationName: "input.yml"}
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid content type: %w", err)
+	}
+	switch mediaType {
+	case "application/json":
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			return nil, fmt.Errorf("decoding request: %w", err)
+		}
+		if req.ModuleName == "" {
+			req.ModuleName = "input.wasm"
+		}
+		if req.TransformationName == "" {
+			req.TransformationName = "input.yml"
+		}
+	case "multipart/form-data":
+		if err := r.ParseMultipartForm(serveMaxRequestSize); err != nil {
+			return nil, fmt.Errorf("decoding request: %w", err)
+		}
+		if req.Module, err = readFormFile(r, "module", &req.ModuleName); err != nil {
+			return nil, err
+		}
+		if req.Transformation, err = readFormFile(r, "transformation", &req.TransformationName); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported content type %q", mediaType)
+	}
+	if len(req.Module) == 0 {
+		return nil, errors.New("module not defined")
+	}
+	if len(req.Transformation) == 0 {
+		return nil, errors.New("transformation not defined")
+	}
+	return req, nil
+}
+
+// readFormFile reads some file of a multipart form, updating the name when the file has one.
+func readFormFile(r *http.Request, field string, name *string) ([]byte, error) {
+	file, header, err := r.FormFile(field)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s file: %w", field, err)
+	}
+	defer file.Close()
+	if header.Filename != "" {
+		*name = header.Filename
+	}
+	return ioutil.ReadAll(file)
+}
+
+// writeJSON writes some value as the json body of a response.
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		logrus.Errorf("writing response: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTransformServer(t *testing.T) {
+	server := httptest.NewServer(newTransformServer(newWorkerPool(&workerSetup{}, 2)).handler())
+	defer server.Close()
+
+	transformation := "aspects:\n  advices:\n    a1:\n      pointcut: () => nothere()\n      advice: nop\n"
+	for i := 0; i < 2; i++ {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		module, _ := form.CreateFormFile("module", "module.wat")
+		module.Write([]byte("(module)"))
+		file, _ := form.CreateFormFile("transformation", "transformation.yml")
+		file.Write([]byte(transformation))
+		form.Close()
+
+		resp, err := http.Post(server.URL+"/transform", form.FormDataContentType(), &body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var res transformResponse
+		err = json.NewDecoder(resp.Body).Decode(&res)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusBadRequest || len(res.Report.Issues) != 1 || !strings.Contains(res.Report.Issues[0].Message, `unknown pointcut "nothere"`) {
+			t.Errorf("expected the invalid transformation issue, got %d %+v", resp.StatusCode, res.Report)
+		}
+	}
+
+	resp, err := http.Post(server.URL+"/transform", "application/json", strings.NewReader(`{"Module": "KG1vZHVsZSk=", "ModuleName": "module.txt", "Transformation": "YTogMQ=="}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected bad request for the unknown module extension, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(server.URL + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var health map[string]interface{}
+	err = json.NewDecoder(resp.Body).Decode(&health)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if health["Status"] != "ok" || health["Workers"] != 2.0 || health["Transformations"] != 1.0 {
+		t.Errorf("unexpected health %v", health)
+	}
+}
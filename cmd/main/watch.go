@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"joao/wasm-manipulator/internal/waspect"
+	"joao/wasm-manipulator/internal/wcode"
+	"joao/wasm-manipulator/internal/wconfigs"
+	"joao/wasm-manipulator/internal/wgenerator"
+	"joao/wasm-manipulator/pkg/wfile"
+)
+
+// watchInterval is the interval between the checks for changes on the watched files.
+const watchInterval = 500 * time.Millisecond
+
+// fileStamp identifies a version of some file by its modification time and size.
+// the stamp of a file that does not exist is the zero value.
+type fileStamp struct {
+	modTime int64
+	size    int64
+}
+
+// statStamp returns the stamp of some file.
+func statStamp(filename string) fileStamp {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime().UnixNano(), size: info.Size()}
+}
+
+// watcher transforms the input module each time it or the transformation file changes.
+// the module is only read again when it changes, being reused by the transformations of the following versions of the transformation file.
+type watcher struct {
+	configs             wconfigs.ToolConfig
+	moduleStamp         fileStamp
+	transformationStamp fileStamp
+	code                string
+	original            *wcode.ModuleContext
+}
+
+// newWatcher is the constructor for watcher.
+func newWatcher(configs wconfigs.ToolConfig) *watcher {
+	return &watcher{configs: configs}
+}
+
+// watch checks the files for changes until the process is stopped, printing the result of each transformation.
+// the outputs are only replaced by successful transformations, so the last good outputs are kept on failure.
+func (w *watcher) watch() {
+	if !w.configs.Verbose {
+		logrus.SetLevel(logrus.WarnLevel)
+	}
+	fmt.Printf("Watching %s and %s\n", w.configs.InputModule, w.configs.InputTransformation)
+	for first := true; ; first = false {
+		moduleStamp := statStamp(filePath(w.configs.InputModule))
+		transformationStamp := statStamp(filePath(w.configs.InputTransformation))
+		moduleChanged := moduleStamp != w.moduleStamp
+		transformationChanged := transformationStamp != w.transformationStamp
+		if !first && !moduleChanged && !transformationChanged {
+			time.Sleep(watchInterval)
+			continue
+		}
+		w.moduleStamp, w.transformationStamp = moduleStamp, transformationStamp
+
+		var changed []string
+		if moduleChanged {
+			changed = append(changed, w.configs.InputModule)
+		}
+		if transformationChanged {
+			changed = append(changed, w.configs.InputTransformation)
+		}
+		fmt.Printf("[%s] transforming (changed: %s)\n", time.Now().Format("15:04:05"), strings.Join(changed, ", "))
+
+		start := time.Now()
+		var output *waspect.TransformationResult
+		var diff *wcode.ModuleDiff
+		var runErr error
+		if err := guardFatal(func() { output, diff, runErr = w.run(moduleChanged) }); err != nil {
+			runErr = err
+		}
+		if runErr == nil {
+			// The outputs are only written after the transformation finished without fatal errors.
+			runErr = w.writeOutputs(output, diff)
+		}
+		if runErr != nil {
+			fmt.Printf("error: %v (keeping the last output)\n", runErr)
+			continue
+		}
+		fmt.Printf("ok in %s: %s\n", time.Since(start).Round(time.Millisecond), diff.Summary())
+	}
+}
+
+// run transforms the module with the transformation file, returning the transformation and its changes.
+// the module is read again only when it changed or when the previous read failed.
+func (w *watcher) run(moduleChanged bool) (*waspect.TransformationResult, *wcode.ModuleDiff, error) {
+	if moduleChanged || w.original == nil {
+		w.original = nil
+		code, err := readModule(w.configs.InputModule)
+		if err != nil {
+			return nil, nil, err
+		}
+		w.code = code
+		w.original = wcode.NewModuleContext(wcode.NewCodeParser(code).Parse())
+	}
+
+	transformation, issues, err := readTransformation(filePath(w.configs.InputTransformation))
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, issue := range issues {
+		fmt.Printf("%s:%s\n", w.configs.InputTransformation, issue)
+	}
+	if hasErrors(issues) {
+		return nil, nil, errors.New("invalid transformations")
+	}
+
+	output, ok := waspect.Run(w.code, transformation)
+	if !ok {
+		return nil, nil, errors.New("no advices were defined (the allow_empty option applies the global context)")
+	}
+	return output, output.Diff(w.original), nil
+}
+
+// writeOutputs writes the output files of some transformation.
+// the files are written to temporary files first, replacing the previous outputs only when all of them are written.
+func (w *watcher) writeOutputs(output *waspect.TransformationResult, diff *wcode.ModuleDiff) error {
+	var files [][2]string
+	defer func() {
+		for _, file := range files {
+			os.Remove(file[0])
+		}
+	}()
+	tempPath := func(name string) string {
+		path := filePath(name)
+		tmp := filepath.Join(filepath.Dir(path), ".wmr-watch-"+filepath.Base(path))
+		files = append(files, [2]string{tmp, path})
+		return tmp
+	}
+
+	var err error
+	if w.configs.OutputFlat {
+		err = wfile.PrintFlatWatCode(output.String(), tempPath(w.configs.OutputModule))
+	} else {
+		err = wfile.PrintWasmCode(output.String(), tempPath(w.configs.OutputModule))
+	}
+	if err != nil {
+		return fmt.Errorf("printing web assembly code: %w", err)
+	}
+	jsCode, err := output.GenerateJsData()
+	if err != nil && err != wgenerator.ErrorUnnecessary {
+		return fmt.Errorf("generating javascript code: %w", err)
+	}
+	if err == nil || output.NeedJS || w.configs.PrintJS {
+		if err := wfile.PrintJsCode(jsCode, tempPath(w.configs.OutputJavascript)); err != nil {
+			return fmt.Errorf("printing javascript code: %w", err)
+		}
+	}
+	if w.configs.OutputDiff != "" {
+		if err := wfile.WriteFile(tempPath(w.configs.OutputDiff), diff.String()); err != nil {
+			return fmt.Errorf("printing module diff: %w", err)
+		}
+	}
+
+	for _, file := range files {
+		if err := os.Rename(file[0], file[1]); err != nil {
+			return fmt.Errorf("replacing output file: %w", err)
+		}
+	}
+	return nil
+}
+
+// errFatalAborted is the error returned when a guarded function logs a fatal error.
+var errFatalAborted = errors.New("aborted by the error above")
+
+// guardFatal executes some function, returning an error instead of exiting when it logs a fatal error.
+// the goroutine that logged the error is stopped, so the watcher can continue.
+// the function is always waited for, since it keeps running when the error is logged by some of its goroutines.
+func guardFatal(fn func()) error {
+	logger := logrus.StandardLogger()
+	exitFn := logger.ExitFunc
+	defer func() {
+		logger.ExitFunc = exitFn
+	}()
+	failed := make(chan struct{}, 1)
+	logger.ExitFunc = func(int) {
+		select {
+		case failed <- struct{}{}:
+		default:
+		}
+		runtime.Goexit()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	<-done
+	select {
+	case <-failed:
+		return errFatalAborted
+	default:
+		return nil
+	}
+}
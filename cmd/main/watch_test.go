@@ -0,0 +1,33 @@
+package main
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestGuardFatal(t *testing.T) {
+	if err := guardFatal(func() {}); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	// The fatal error logged by a goroutine stops it, but the guarded function keeps running until it returns.
+	var finished bool
+	err := guardFatal(func() {
+		wg := new(sync.WaitGroup)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logrus.Fatal("failed")
+		}()
+		wg.Wait()
+		finished = true
+	})
+	if err != errFatalAborted {
+		t.Errorf("expected %v, got %v", errFatalAborted, err)
+	}
+	if !finished {
+		t.Error("expected the guarded function to be waited for")
+	}
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"joao/wasm-manipulator/internal/waspect"
+	"joao/wasm-manipulator/internal/wconfigs"
+	"joao/wasm-manipulator/internal/wgenerator"
+	"joao/wasm-manipulator/internal/wyaml"
+	"joao/wasm-manipulator/pkg/wfile"
+)
+
+// workerSetup is the first message sent to a worker process.
+// it contains the configurations and the transformation shared by all its jobs.
+type workerSetup struct {
+	Config         wconfigs.ToolConfig
+	Transformation *wyaml.BaseYAML
+}
+
+// batchJob is the transformation of a module, executed by a worker process.
+// the transformation, when defined, replaces the one of the worker setup.
+type batchJob struct {
+	Input          string
+	OutputModule   string
+	OutputJS       string
+	Transformation *wyaml.BaseYAML
+}
+
+// batchResult is the result of a job, sent by the worker process when the job finishes.
+type batchResult struct {
+	Input      string
+	Output     string
+	JS         string
+	Success    bool
+	JoinPoints int
+	Advices    map[string]int
+	Error      string
+}
+
+// workerCommand executes the jobs sent by the main process through the standard input, one at a time.
+// the results are sent through the standard output, the other output of the tool is redirected to the standard error.
+// the fatal errors are sent as the result of the current job, before the process exits.
+func workerCommand([]string) {
+	out := os.Stdout
+	os.Stdout = os.Stderr
+
+	decoder := json.NewDecoder(os.Stdin)
+	var setup workerSetup
+	if err := decoder.Decode(&setup); err != nil {
+		logrus.Fatalf("reading worker setup: %v", err)
+	}
+	wconfigs.Set(setup.Config)
+	if setup.Config.Verbose {
+		logrus.SetLevel(logrus.TraceLevel)
+	} else {
+		logrus.SetLevel(logrus.WarnLevel)
+	}
+
+	hook := &workerFatalHook{mutex: new(sync.Mutex), encoder: json.NewEncoder(out)}
+	logrus.AddHook(hook)
+	for {
+		var job batchJob
+		if err := decoder.Decode(&job); err != nil {
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			logrus.Fatalf("reading worker job: %v", err)
+		}
+		transformation := setup.Transformation
+		if job.Transformation != nil {
+			transformation = job.Transformation
+		}
+		hook.start(&job)
+		res := runBatchJob(transformation, &job)
+		if err := hook.finish(res); err != nil {
+			logrus.Fatalf("writing worker result: %v", err)
+		}
+	}
+}
+
+// runBatchJob transforms the module of some job, writing the output files.
+func runBatchJob(transformation *wyaml.BaseYAML, job *batchJob) *batchResult {
+	res := &batchResult{Input: job.Input}
+	code, err := readModuleFile(job.Input)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	output, ok := waspect.Run(code, transformation)
+	if !ok {
+		res.Error = "no advices were defined (the allow_empty option applies the global context)"
+		return res
+	}
+	res.JoinPoints, res.Advices = output.TotalJoinPoints(), output.JoinPoints
+
+	if err := os.MkdirAll(filepath.Dir(job.OutputModule), 0755); err != nil {
+		res.Error = fmt.Sprintf("creating output directory: %v", err)
+		return res
+	}
+	jsCode, err := output.GenerateJsData()
+	if err != nil && err != wgenerator.ErrorUnnecessary {
+		res.Error = fmt.Sprintf("generating javascript code: %v", err)
+		return res
+	}
+	if err == nil || output.NeedJS || wconfigs.Get().PrintJS {
+		if err := wfile.PrintJsCode(jsCode, job.OutputJS); err != nil {
+			res.Error = fmt.Sprintf("printing javascript code: %v", err)
+			return res
+		}
+		res.JS = job.OutputJS
+	}
+	if wconfigs.Get().OutputFlat {
+		err = wfile.PrintFlatWatCode(output.String(), job.OutputModule)
+	} else {
+		err = wfile.PrintWasmCode(output.String(), job.OutputModule)
+	}
+	if err != nil {
+		res.Error = fmt.Sprintf("printing web assembly code: %v", err)
+		return res
+	}
+	res.Output, res.Success = job.OutputModule, true
+	return res
+}
+
+// workerFatalHook sends the fatal errors of a worker process as the result of its current job.
+type workerFatalHook struct {
+	mutex   *sync.Mutex
+	encoder *json.Encoder
+	job     *batchJob
+}
+
+// Levels returns the levels handled by the hook.
+func (h *workerFatalHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.PanicLevel, logrus.FatalLevel}
+}
+
+// Fire sends the failed result of the current job, if any.
+// only the first error is sent, since the process exits after it.
+func (h *workerFatalHook) Fire(entry *logrus.Entry) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.job == nil {
+		return nil
+	}
+	message := entry.Message
+	if len(entry.Data) > 0 {
+		keys := make([]string, 0, len(entry.Data))
+		for k := range entry.Data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		fields := make([]string, len(keys))
+		for i, k := range keys {
+			fields[i] = fmt.Sprintf("%s=%v", k, entry.Data[k])
+		}
+		message = fmt.Sprintf("%s (%s)", message, strings.Join(fields, ", "))
+	}
+	err := h.encoder.Encode(&batchResult{Input: h.job.Input, Error: message})
+	h.job = nil
+	return err
+}
+
+// start sets the current job.
+func (h *workerFatalHook) start(job *batchJob) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.job = job
+}
+
+// finish sends the result of the current job, unless it was already sent by a fatal error.
+func (h *workerFatalHook) finish(res *batchResult) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.job == nil {
+		return nil
+	}
+	h.job = nil
+	return h.encoder.Encode(res)
+}
+
+// worker is a worker process, i.e., this tool executed with the worker command.
+type worker struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	encoder *json.Encoder
+	decoder *json.Decoder
+}
+
+// newWorker is the constructor for worker.
+// the process is started and receives the setup message.
+func newWorker(setup *workerSetup) (*worker, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("finding the tool executable: %w", err)
+	}
+	cmd := exec.Command(executable, "worker")
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("creating worker input: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("creating worker output: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting worker process: %w", err)
+	}
+	w := &worker{cmd: cmd, stdin: stdin, encoder: json.NewEncoder(stdin), decoder: json.NewDecoder(stdout)}
+	if err := w.encoder.Encode(setup); err != nil {
+		w.close()
+		return nil, fmt.Errorf("sending worker setup: %w", err)
+	}
+	return w, nil
+}
+
+// run sends some job to the worker process and waits for its result.
+func (w *worker) run(job *batchJob) (*batchResult, error) {
+	if err := w.encoder.Encode(job); err != nil {
+		return nil, fmt.Errorf("sending worker job: %w", err)
+	}
+	var res batchResult
+	if err := w.decoder.Decode(&res); err != nil {
+		return nil, fmt.Errorf("reading worker result: %w", err)
+	}
+	return &res, nil
+}
+
+// close closes the worker input and waits for the process to exit.
+func (w *worker) close() error {
+	w.stdin.Close()
+	return w.cmd.Wait()
+}
+
+// workerPool is a pool of worker processes, executing a limited number of jobs concurrently.
+// the processes are started when needed and replaced after a failed job, since the failure may have stopped them.
+type workerPool struct {
+	setup   *workerSetup
+	workers chan *worker
+}
+
+// newWorkerPool is the constructor for workerPool.
+func newWorkerPool(setup *workerSetup, total int) *workerPool {
+	workers := make(chan *worker, total)
+	for i := 0; i < total; i++ {
+		workers <- nil
+	}
+	return &workerPool{setup: setup, workers: workers}
+}
+
+// run executes some job on a worker process, waiting for one to be available.
+func (p *workerPool) run(job *batchJob) *batchResult {
+	w := <-p.workers
+	defer func() {
+		p.workers <- w
+	}()
+	if w == nil {
+		started, err := newWorker(p.setup)
+		if err != nil {
+			return &batchResult{Input: job.Input, Error: err.Error()}
+		}
+		w = started
+	}
+	res, err := w.run(job)
+	if err != nil || !res.Success {
+		// The process exits after a fatal error, so it is waited for before being replaced.
+		if exitErr := w.close(); exitErr != nil && err != nil {
+			err = fmt.Errorf("worker process exited: %w", exitErr)
+		}
+		w = nil
+	}
+	if err != nil {
+		return &batchResult{Input: job.Input, Error: err.Error()}
+	}
+	return res
+}
+
+// close stops the worker processes, waiting for the running jobs to finish.
+func (p *workerPool) close() {
+	for i := 0; i < cap(p.workers); i++ {
+		if w := <-p.workers; w != nil {
+			if err := w.close(); err != nil {
+				logrus.Warnf("closing worker process: %v", err)
+			}
+		}
+	}
+}
+
+// poolSize returns the number of worker processes, defined by the configurations or the number of CPUs.
+func poolSize() int {
+	if workers := wconfigs.Get().Workers; workers > 0 {
+		return workers
+	}
+	return runtime.NumCPU()
+}
+
+// runWorkers executes some jobs on a number of worker processes, returning the results in the same order.
+func runWorkers(setup *workerSetup, jobs []*batchJob, total int) []*batchResult {
+	pool := newWorkerPool(setup, total)
+	defer pool.close()
+
+	results := make([]*batchResult, len(jobs))
+	wg := new(sync.WaitGroup)
+	for i, job := range jobs {
+		wg.Add(1)
+		go func(i int, job *batchJob) {
+			defer wg.Done()
+			results[i] = pool.run(job)
+		}(i, job)
+	}
+	wg.Wait()
+	return results
+}
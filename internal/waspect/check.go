@@ -0,0 +1,331 @@
+package waspect
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"joao/wasm-manipulator/internal/wcode"
+	"joao/wasm-manipulator/internal/wparser/lex"
+	"joao/wasm-manipulator/internal/wparser/pointcut"
+	"joao/wasm-manipulator/internal/wparser/template"
+	"joao/wasm-manipulator/internal/wparser/variable"
+	"joao/wasm-manipulator/internal/wyaml"
+)
+
+// keywordThis is the keyword with the join-point code on the advices.
+const keywordThis = "this"
+
+// checker is responsible to validate the transformation input before its execution.
+type checker struct {
+	input  *wyaml.BaseYAML
+	source *wyaml.Source
+	issues []*wyaml.Issue
+	global map[string]bool
+}
+
+// Check validates the transformation input without applying it to a module.
+// the pointcuts, templates, variable declarations and static expressions are parsed against the declared context.
+// returns all the issues found, sorted by their position on the yaml source.
+func Check(input *wyaml.BaseYAML, source *wyaml.Source) []*wyaml.Issue {
+	c := &checker{input: input, source: source, global: make(map[string]bool)}
+	c.checkTemplates()
+	c.checkPointcuts()
+	c.checkContext()
+	c.checkAdvices()
+	sort.SliceStable(c.issues, func(i, j int) bool {
+		if c.issues[i].Line != c.issues[j].Line {
+			return c.issues[i].Line < c.issues[j].Line
+		}
+		return c.issues[i].Column < c.issues[j].Column
+	})
+	return c.issues
+}
+
+// errorf adds an error issue to the value with some path.
+func (c *checker) errorf(path []string, format string, args ...interface{}) {
+	c.issues = append(c.issues, c.source.Issue(wyaml.SeverityError, fmt.Sprintf(format, args...), path...))
+}
+
+// warnf adds a warning issue to the value with some path.
+func (c *checker) warnf(path []string, format string, args ...interface{}) {
+	c.issues = append(c.issues, c.source.Issue(wyaml.SeverityWarning, fmt.Sprintf(format, args...), path...))
+}
+
+// checkTemplates parses the templates.
+func (c *checker) checkTemplates() {
+	for _, name := range sortedKeys(c.input.Templates) {
+		if _, err := template.Parse(name, c.input.Templates[name]); err != nil {
+			c.errorf([]string{"templates", name}, "invalid template %q: %v", name, err)
+		}
+	}
+}
+
+// checkPointcuts parses the global pointcuts and the pointcuts and templates they refer to.
+func (c *checker) checkPointcuts() {
+	for _, name := range sortedKeys(c.input.Pointcuts) {
+		path := []string{"pointcuts", name}
+		pc, err := pointcut.ParseWithoutContext(c.input.Pointcuts[name])
+		if err != nil {
+			c.errorf(path, "invalid pointcut %q: %v", name, err)
+			continue
+		}
+		params := make(map[string]bool, len(pc.Args))
+		for _, arg := range pc.Args {
+			params[arg.Name] = true
+		}
+		c.pointcutVariables(path, pc.Instrs, params, make(map[string]bool), map[string]bool{name: true})
+	}
+}
+
+// checkContext validates the global context declarations and the code of the added functions.
+func (c *checker) checkContext() {
+	context := c.input.Aspects.Context
+	path := []string{"aspects", "context"}
+
+	// Names available on the global zone.
+	for _, names := range [][]string{
+		sortedKeys(context.Memories), sortedKeys(context.Tables), sortedKeys(context.Variables),
+		sortedKeys(context.Functions), sortedKeys(context.Clones), sortedKeys(context.Imports),
+		sortedKeys(context.Data), sortedKeys(context.Elems),
+	} {
+		for _, name := range names {
+			c.global[name] = true
+		}
+	}
+
+	for _, name := range sortedKeys(context.Variables) {
+		c.checkVariable(childPath(path, "variables", name), context.Variables[name])
+	}
+	for _, name := range sortedKeys(context.Tables) {
+		if typ := context.Tables[name].Type; typ != "" && typ != "funcref" && typ != "externref" {
+			c.errorf(childPath(path, "tables", name, "type"), "invalid table type %q", typ)
+		}
+	}
+	for _, name := range sortedKeys(context.Functions) {
+		c.checkFunction(childPath(path, "functions", name), context.Functions[name])
+	}
+	if start := c.input.Aspects.Start; start != "" {
+		c.checkCode([]string{"aspects", "start"}, start, c.global)
+	}
+}
+
+// checkFunction validates the signature, variables and code of some context function.
+func (c *checker) checkFunction(path []string, function wyaml.FunctionYAML) {
+	scope := copyScope(c.global)
+	for i, arg := range function.Args {
+		argPath := childPath(path, "args", fmt.Sprint(i))
+		if !isValidType(arg.Type) {
+			c.errorf(childPath(argPath, "type"), "invalid argument type %q", arg.Type)
+		}
+		if arg.Name == "" {
+			c.errorf(argPath, "argument %d has no name", i)
+		}
+		scope[arg.Name] = true
+	}
+	if function.Result != "" && len(function.Results) > 0 {
+		c.errorf(childPath(path, "results"), "result and results cannot be both defined")
+	}
+	for i, result := range function.ResultTypes() {
+		if !isValidType(result) {
+			c.errorf(childPath(path, "results", fmt.Sprint(i)), "invalid result type %q", result)
+		}
+	}
+	for _, name := range sortedKeys(function.Variables) {
+		c.checkVariable(childPath(path, "variables", name), function.Variables[name])
+		scope[name] = true
+	}
+	if function.Imported == nil {
+		c.checkCode(childPath(path, "code"), function.Code, scope)
+	} else if function.Code != "" {
+		c.warnf(childPath(path, "code"), "code of imported function is ignored")
+	}
+}
+
+// checkAdvices validates the pointcut, variables and code of the advices.
+func (c *checker) checkAdvices() {
+	for _, name := range sortedKeys(c.input.Aspects.Advices) {
+		advice := c.input.Aspects.Advices[name]
+		path := []string{"aspects", "advices", name}
+		if advice.Pointcut == "" {
+			c.errorf(path, "advice %q has no pointcut", name)
+			continue
+		}
+		pc, err := pointcut.ParseWithContext(advice.Pointcut)
+		if err != nil {
+			c.errorf(childPath(path, "pointcut"), "invalid pointcut: %v", err)
+			continue
+		}
+
+		scope := copyScope(c.global)
+		scope[keywordThis] = true
+		params := make(map[string]bool, len(pc.Args))
+		for _, arg := range pc.Args {
+			params[arg.Name] = true
+			scope[arg.Name] = true
+		}
+		used := make(map[string]bool)
+		for v := range c.pointcutVariables(childPath(path, "pointcut"), pc.Instrs, params, used, make(map[string]bool)) {
+			scope[v] = true
+		}
+		for _, varName := range sortedKeys(advice.Variables) {
+			c.checkVariable(childPath(path, "variables", varName), advice.Variables[varName])
+			scope[varName] = true
+		}
+		for _, identifier := range c.checkCode(childPath(path, "advice"), advice.Advice, scope) {
+			used[identifier] = true
+		}
+		for _, arg := range pc.Args {
+			if !used[arg.Name] {
+				c.warnf(childPath(path, "pointcut"), "pointcut parameter %q is not used", arg.Name)
+			}
+		}
+	}
+}
+
+// pointcutVariables validates the pointcut instructions and returns the variables they add to the advice context.
+// the parameters used by the instructions are stored on the used map.
+// the visited global pointcuts are skipped, avoiding cycles.
+func (c *checker) pointcutVariables(path []string, instrs []pointcut.Instruction, params, used, visited map[string]bool) map[string]bool {
+	res := make(map[string]bool)
+	for _, instr := range instrs {
+		for v := range c.pointcutVariables(path, instr.GroupBlock, params, used, visited) {
+			res[v] = true
+		}
+		method := instr.Block
+		if method == nil {
+			continue
+		}
+		switch {
+		case method.Func != nil:
+			res[method.Func.Name] = true
+			for _, v := range method.Func.Input.Variables() {
+				res[v] = true
+			}
+		case method.Call != nil:
+			res[method.Call.Name] = true
+			for _, v := range method.Call.Input.Variables() {
+				res[v] = true
+			}
+		case method.Args != nil:
+			res[method.Args.Name] = true
+			for _, param := range method.Args.Input.Params {
+				if !params[param] {
+					c.errorf(path, "args refers to unknown pointcut parameter %q", param)
+				}
+				used[param] = true
+			}
+		case method.Returns != nil:
+			res[method.Returns.Name] = true
+		case method.Templ != nil:
+			name := method.Templ.Input.Template
+			if _, ok := c.input.Templates[name]; !ok {
+				c.errorf(path, "unknown template %q", name)
+			}
+			res[name] = true
+		case method.Other != nil:
+			name := method.Other.Name
+			for _, arg := range method.Other.Arguments {
+				if !params[arg] {
+					c.errorf(path, "pointcut %q refers to unknown pointcut parameter %q", name, arg)
+				}
+				used[arg] = true
+			}
+			value, ok := c.input.Pointcuts[name]
+			if !ok {
+				c.errorf(path, "unknown pointcut %q", name)
+				continue
+			}
+			pc, err := pointcut.ParseWithoutContext(value)
+			if err != nil {
+				continue
+			}
+			if len(pc.Args) != len(method.Other.Arguments) {
+				c.errorf(path, "pointcut %q expects %d arguments but got %d", name, len(pc.Args), len(method.Other.Arguments))
+			}
+			if visited[name] {
+				continue
+			}
+			visited[name] = true
+			otherParams := make(map[string]bool, len(pc.Args))
+			for _, arg := range pc.Args {
+				otherParams[arg.Name] = true
+			}
+			for v := range c.pointcutVariables([]string{"pointcuts", name}, pc.Instrs, otherParams, make(map[string]bool), visited) {
+				res[v] = true
+			}
+		}
+	}
+	return res
+}
+
+// checkVariable validates the declaration of some variable.
+func (c *checker) checkVariable(path []string, value string) {
+	expr, err := variable.Parse(value)
+	if err != nil {
+		c.errorf(path, "invalid variable declaration %q: %v", value, err)
+		return
+	}
+	if typ := expr.GetType(); !isValidType(typ) {
+		c.errorf(path, "invalid variable type %q", typ)
+	}
+}
+
+// checkCode validates the static expressions of some code against the variables in scope.
+// returns the variables referred by the code.
+func (c *checker) checkCode(path []string, code string, scope map[string]bool) []string {
+	identifiers, err := lex.Identifiers(code)
+	if err != nil {
+		c.errorf(path, "invalid static expression: %v", err)
+		return identifiers
+	}
+	reported := make(map[string]bool)
+	for _, identifier := range identifiers {
+		if scope[identifier] || reported[identifier] || isLiteral(identifier) {
+			continue
+		}
+		reported[identifier] = true
+		c.errorf(path, "static expression refers to unknown variable %q", identifier)
+	}
+	return identifiers
+}
+
+// isValidType returns if some type can be used by the variables and functions of the context.
+func isValidType(typ string) bool {
+	return wcode.IsVarTypeStrPrimitive(typ) || wcode.IsVarTypeStrValid(typ)
+}
+
+// isLiteral returns if some identifier is a literal value of the static expressions.
+func isLiteral(identifier string) bool {
+	switch identifier {
+	case lex.True, lex.False, lex.NaN:
+		return true
+	}
+	return false
+}
+
+// copyScope returns a copy of some variables scope.
+func copyScope(scope map[string]bool) map[string]bool {
+	res := make(map[string]bool, len(scope))
+	for k, v := range scope {
+		res[k] = v
+	}
+	return res
+}
+
+// sortedKeys returns the keys of some map with string keys, sorted.
+func sortedKeys(m interface{}) []string {
+	keys := reflect.ValueOf(m).MapKeys()
+	res := make([]string, len(keys))
+	for i, k := range keys {
+		res[i] = k.String()
+	}
+	sort.Strings(res)
+	return res
+}
+
+// childPath returns the path of some child value.
+func childPath(path []string, keys ...string) []string {
+	res := make([]string, 0, len(path)+len(keys))
+	return append(append(res, path...), keys...)
+}
@@ -0,0 +1,67 @@
+package waspect
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"joao/wasm-manipulator/internal/wyaml"
+)
+
+func TestCheck(t *testing.T) {
+	file, err := ioutil.TempFile("", "check-*.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(checkInput); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	input, source, issues, err := wyaml.ReadSource(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues = append(issues, Check(input, source)...)
+
+	var got []string
+	for _, issue := range issues {
+		got = append(got, issue.String())
+	}
+	expected := []string{
+		`9:9: error: static expression refers to unknown variable "counterr"`,
+		`12:7: error: unknown field "Pointcut" on "aspects.advices.a1", did you mean "pointcut"?`,
+		`14:7: error: unknown template "t2"`,
+		`14:7: warning: pointcut parameter "p" is not used`,
+		`15:14: error: "aspects.advices.a2.order" must be an integer`,
+		`16:7: error: invalid static expression: unknown method "joinx"`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(strings.Join(got, "\n"), e) {
+			t.Errorf("issue %q not found on:\n%s", e, strings.Join(got, "\n"))
+		}
+	}
+	if len(got) != len(expected)+1 {
+		t.Errorf("expected %d issues, got:\n%s", len(expected)+1, strings.Join(got, "\n"))
+	}
+}
+
+const checkInput = `templates:
+  t1: "(local.set %a% %b%)"
+aspects:
+  context:
+    variables:
+      counter: "i32 = 0"
+    functions:
+      log:
+        code: "(drop %counterr%)"
+  advices:
+    a1:
+      Pointcut: "func(*)"
+    a2:
+      pointcut: "(i32.param[0] p) => func(* %fname% (..)) && template(t2)"
+      order: "first"
+      advice: "%this% %fname% %counter% %func.Name:joinx(' ')%"
+`
@@ -5,6 +5,7 @@ import (
 	"runtime"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -119,6 +120,30 @@ func (tf *Transformation) applyGlobalContextTransformations() []string {
 		"total":     len(aspects.Context.Variables) + len(aspects.Context.Functions),
 	}).Infoln("Applying module modifications from global context")
 
+	// Handle memories and tables, sorted by name so the added indexes are the same on every run
+	for _, name := range sortedKeys(aspects.Context.Memories) {
+		memory := aspects.Context.Memories[name]
+		logFields := logrus.Fields{"name": name, "min": memory.Min}
+		logrus.WithFields(logFields).Traceln("Adding memory")
+
+		memDef, err := tf.context.AddMemory(&memory)
+		if err != nil {
+			logrus.WithFields(logFields).Fatalf("adding memory: %v", err)
+		}
+		zone.AddVariable(name, memDef.Name)
+	}
+	for _, name := range sortedKeys(aspects.Context.Tables) {
+		table := aspects.Context.Tables[name]
+		logFields := logrus.Fields{"name": name, "min": table.Min}
+		logrus.WithFields(logFields).Traceln("Adding table")
+
+		tableDef, err := tf.context.AddTable(&table)
+		if err != nil {
+			logrus.WithFields(logFields).Fatalf("adding table: %v", err)
+		}
+		zone.AddVariable(name, tableDef.Name)
+	}
+
 	// Handle global variables
 	for name, value := range aspects.Context.Variables {
 		logFields := logrus.Fields{"name": name, "value": value}
@@ -197,9 +222,164 @@ func (tf *Transformation) applyGlobalContextTransformations() []string {
 		// Save function on global zone.
 		zone.AddFunction(name, newFunctionZone(fnDef.Index(tf.context), fnDef.Name))
 	}
+
+	// Handle function clones
+	fns = tf.applyCloneDirectives(aspects.Context.Clones, zone, fns)
+
+	// Handle export and import directives
+	tf.applyExportDirectives(aspects.Context.Exports)
+	tf.applyImportDirectives(aspects.Context.Imports, zone)
+
+	// Handle data and element segments, sorted by name so the overlapping segments are applied in the same order
+	for _, name := range sortedKeys(aspects.Context.Data) {
+		data := aspects.Context.Data[name]
+		logFields := logrus.Fields{"name": name, "memory": data.Memory, "offset": data.Offset}
+		logrus.WithFields(logFields).Traceln("Adding data segment")
+
+		data.Memory = zoneValue(zone, data.Memory)
+		dataDef, err := tf.context.AddData(&data)
+		if err != nil {
+			logrus.WithFields(logFields).Fatalf("adding data segment: %v", err)
+		}
+		zone.AddVariable(name, dataDef.Name)
+	}
+	for _, name := range sortedKeys(aspects.Context.Elems) {
+		elem := aspects.Context.Elems[name]
+		logFields := logrus.Fields{"name": name, "table": elem.Table, "offset": elem.Offset}
+		logrus.WithFields(logFields).Traceln("Adding element segment")
+
+		elem.Table = zoneValue(zone, elem.Table)
+		functions := make([]string, len(elem.Functions))
+		for i, fnName := range elem.Functions {
+			functions[i] = zoneValue(zone, fnName)
+		}
+		elem.Functions = functions
+		elemDef, err := tf.context.AddElem(&elem)
+		if err != nil {
+			logrus.WithFields(logFields).Fatalf("adding element segment: %v", err)
+		}
+		zone.AddVariable(name, elemDef.Name)
+	}
 	return fns
 }
 
+// applyCloneDirectives adds the function clones to the module.
+// the clones are added to the functions list, so they are only changed by the advices with the all flag,
+// and the static expressions of the clones of context functions are also applied.
+// the clones are added sorted by name, so the output is the same on every run.
+func (tf *Transformation) applyCloneDirectives(clones map[string]wyaml.CloneYAML, zone *contextVariablesZone, fns []string) []string {
+	fnsMap := make(map[string]struct{}, len(fns))
+	for _, fn := range fns {
+		fnsMap[fn] = struct{}{}
+	}
+	for _, name := range sortedKeys(clones) {
+		clone := clones[name]
+		logFields := logrus.Fields{"name": name, "function": clone.Function, "args": clone.Args}
+		logrus.WithFields(logFields).Traceln("Cloning function")
+
+		fnDef, ok := tf.context.Function(zoneValue(zone, clone.Function))
+		if !ok {
+			fnDef, ok = tf.context.ExportFunction(clone.Function)
+		}
+		if !ok {
+			logrus.WithFields(logFields).Fatalf("cloning function: function %s not found", clone.Function)
+		}
+		cloneDef, err := tf.context.CloneFunction(fnDef, name, clone.Args)
+		if err != nil {
+			logrus.WithFields(logFields).Fatal(err)
+		}
+		if clone.Exported != nil {
+			cloneDef, err = tf.context.AddExportFunction(&wyaml.FunctionYAML{Exported: clone.Exported}, cloneDef)
+			if err != nil {
+				logrus.WithFields(logFields).Fatalf("adding an exported clone function: %v", err)
+			}
+		}
+		fns = append(fns, cloneDef.Name)
+		if _, ok := fnsMap[fnDef.Name]; ok {
+			if alias, ok := tf.context.FunctionAlias[fnDef.Name]; ok {
+				tf.functionsZone[name] = newContextVariablesZone(tf.functionsZone[alias])
+			}
+		}
+		tf.context.FunctionAlias[cloneDef.Name] = name
+
+		// Save clone on global zone.
+		zone.AddFunction(name, newFunctionZone(cloneDef.Index(tf.context), cloneDef.Name))
+	}
+	return fns
+}
+
+// applyExportDirectives applies the rewriting directives to the exported functions.
+// the aliases are added before the export is renamed or removed.
+// the exports are rewritten sorted by name, so the output is the same on every run.
+func (tf *Transformation) applyExportDirectives(exports map[string]wyaml.ExportYAML) {
+	for _, exportName := range sortedKeys(exports) {
+		export := exports[exportName]
+		logFields := logrus.Fields{"export": exportName, "rename": export.Rename, "remove": export.Remove, "alias": export.Alias}
+		logrus.WithFields(logFields).Traceln("Rewriting export")
+
+		for _, alias := range export.Alias {
+			if err := tf.context.AliasExport(exportName, alias); err != nil {
+				logrus.WithFields(logFields).Fatal(err)
+			}
+		}
+		switch {
+		case export.Remove && export.Rename != "":
+			logrus.WithFields(logFields).Fatalf("rewriting export: an export cannot be renamed and removed")
+		case export.Remove:
+			if err := tf.context.RemoveExport(exportName); err != nil {
+				logrus.WithFields(logFields).Fatal(err)
+			}
+		case export.Rename != "":
+			if err := tf.context.RenameExport(exportName, export.Rename); err != nil {
+				logrus.WithFields(logFields).Fatal(err)
+			}
+		}
+	}
+}
+
+// applyImportDirectives applies the rewriting directives to the imported functions.
+// the functions indexes on the zone are updated, since implementing an import changes them.
+// the imports are rewritten sorted by name, so the output is the same on every run.
+func (tf *Transformation) applyImportDirectives(imports map[string]wyaml.ImportYAML, zone *contextVariablesZone) {
+	for _, name := range sortedKeys(imports) {
+		imp := imports[name]
+		logFields := logrus.Fields{"name": name, "module": imp.Module, "field": imp.Field}
+		logrus.WithFields(logFields).Traceln("Rewriting import")
+
+		switch {
+		case imp.Redirect != nil && imp.Implement != "":
+			logrus.WithFields(logFields).Fatalf("rewriting import: an import cannot be redirected and implemented")
+		case imp.Redirect != nil:
+			if err := tf.context.RedirectImport(imp.Module, imp.Field, imp.Redirect.Module, imp.Redirect.Field); err != nil {
+				logrus.WithFields(logFields).Fatal(err)
+			}
+		case imp.Implement != "":
+			impl, ok := tf.context.Function(zoneValue(zone, imp.Implement))
+			if !ok {
+				logrus.WithFields(logFields).Fatalf("rewriting import: implementation function %s not found", imp.Implement)
+			}
+			fnDef, err := tf.context.ImplementImport(imp.Module, imp.Field, impl)
+			if err != nil {
+				logrus.WithFields(logFields).Fatal(err)
+			}
+			zone.AddFunction(name, newFunctionZone(fnDef.Index(tf.context), fnDef.Name))
+		}
+	}
+	for _, fnZone := range zone.FunctionsMap {
+		if fnDef, ok := tf.context.Function(fnZone.name); ok {
+			fnZone.index = fnDef.Index(tf.context)
+		}
+	}
+}
+
+// zoneValue returns the context value for some name, or the name itself when it is not on the zone.
+func zoneValue(zone *contextVariablesZone, name string) string {
+	if value, ok := zone.Value(name); ok {
+		return value
+	}
+	return name
+}
+
 // addStartFunctionCode adds the start function code.
 func (tf *Transformation) addStartFunctionCode(code string) string {
 	logrus.Infoln("Adding starting code")
@@ -228,7 +408,8 @@ func (tf *Transformation) addStartFunctionCode(code string) string {
 }
 
 // applyLocalContextTransformations applies the specific input context from an advice to the module.
-func (tf *Transformation) applyLocalContextTransformations(advice advice, fns []string) {
+// returns the join-points transformed.
+func (tf *Transformation) applyLocalContextTransformations(advice advice, fns []string) []*wpointcut.JoinPoint {
 	pointcut := advice.pointcut
 	if !pointcut.Initiated {
 		// It may be initialized when the "apply all" flag on the advice is set to false
@@ -242,14 +423,32 @@ func (tf *Transformation) applyLocalContextTransformations(advice advice, fns []
 		"total":  len(joinPoints),
 	}).Infof("Applying static transformations to join-points")
 
+	// The advices are filtered to remove function added (just a backup condition)
+	joinPoints = filterAddedFnsOnJoinPoints(advice.all, joinPoints, fns)
+
+	// Allocate the locals for the advice variables before changing the join-points.
+	locals, resets := tf.allocateAdviceLocals(advice, joinPoints)
+
+	// Analyse the stack types before changing the join-points, since the join-points of a function are changed concurrently.
+	var fnDefs []*wcode.FunctionDefinition
+	fnsMap := make(map[*wcode.FunctionDefinition]struct{})
+	for _, joinPoint := range joinPoints {
+		fnDef := joinPoint.FuncDefinition()
+		if _, ok := fnsMap[fnDef]; !ok {
+			fnsMap[fnDef] = struct{}{}
+			fnDefs = append(fnDefs, fnDef)
+		}
+	}
+	tf.context.KeepTypes(fnDefs)
+
 	wg := new(sync.WaitGroup)
 
 	// Execute each joinpoint. Each one is referred to a function.
-	// The advices are filtered to remove function added (just a backup condition)
-	for _, joinPoint := range filterAddedFnsOnJoinPoints(advice.all, joinPoints, fns) {
+	for _, joinPoint := range joinPoints {
 		wg.Add(1)
 
 		go func(joinPoint *wpointcut.JoinPoint) {
+			defer wg.Done()
 			var functionZone *contextVariablesZone
 			fnDef := joinPoint.FuncDefinition()
 			exportedName, ok := tf.context.AliasValue(fnDef.Name)
@@ -260,31 +459,85 @@ func (tf *Transformation) applyLocalContextTransformations(advice advice, fns []
 				functionZone = newContextVariablesZone(tf.globalZone)
 			}
 
-			// Add local variables to function
-			for name, value := range advice.input.Variables {
-				logFields := logrus.Fields{"advice": advice.name, "function": fnDef.Name, "name": name, "value": value}
-				logrus.WithFields(logFields).Traceln("Adding local variable")
-
-				// Adds local using the variable value.
-				localDef, err := tf.context.AddLocal(value, fnDef)
-				if err != nil {
-					logrus.WithFields(logFields).Fatalf("adding local to function %s: %v", name, err)
-				}
-				fnDef.Alias[localDef.Name] = name
+			// Add local variables to zone
+			for name, localDef := range locals[joinPoint] {
 				functionZone.AddVariable(name, localDef.Name)
 			}
 
+			params, err := newPointcutParameters(fnDef, advice.pointcut.Params)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{"advice": advice.name}).Fatal(err)
+			}
+
+			// The pooled locals reused by the join-point are reset before the advice code.
+			code := advice.input.Advice
+			if reset, ok := resets[joinPoint]; ok {
+				code = reset + "\n" + code
+			}
+
 			// Apply transformations on join-points
 			tf.applyJoinPointTransformations(joinPoint, parsedContext, fnDef,
-				advice.input.Advice, advice.name, advice.smart,
-				newPointcutParameters(fnDef, advice.pointcut.Params),
-				newContextVariables(functionZone))
-
-			wg.Done()
+				code, advice.name, advice.smart,
+				params, newContextVariables(functionZone))
 		}(joinPoint)
 	}
 
 	wg.Wait()
+	tf.context.ReleaseTypes(fnDefs)
+
+	// Set the advice of the new evaluations once per function, since the join-points of a function are changed concurrently.
+	for _, fnDef := range fnDefs {
+		tf.context.SetEvaluationsAdvice(fnDef, advice.name)
+	}
+	return joinPoints
+}
+
+// allocateAdviceLocals adds the locals for the advice variables to the join-points functions.
+// the locals are created per join-point, unless they are pooled or shared by the join-points of the same function.
+// returns the locals of each join-point and the code resetting the pooled locals reused by each join-point.
+func (tf *Transformation) allocateAdviceLocals(advice advice, joinPoints []*wpointcut.JoinPoint) (map[*wpointcut.JoinPoint]map[string]*wcode.FunctionLocalDefinition, map[*wpointcut.JoinPoint]string) {
+	res := make(map[*wpointcut.JoinPoint]map[string]*wcode.FunctionLocalDefinition, len(joinPoints))
+	resets := make(map[*wpointcut.JoinPoint]string)
+	if len(advice.input.Variables) == 0 {
+		return res, resets
+	}
+	names := make([]string, 0, len(advice.input.Variables))
+	for name := range advice.input.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pools := make(map[*wcode.FunctionDefinition]*wcode.LocalPool)
+	for _, joinPoint := range joinPoints {
+		fnDef := joinPoint.FuncDefinition()
+		pool, ok := pools[fnDef]
+		if !ok {
+			pool = tf.context.NewLocalPool(fnDef, advice.input.Shared, advice.input.Pooled)
+			pools[fnDef] = pool
+		}
+		res[joinPoint] = make(map[string]*wcode.FunctionLocalDefinition, len(names))
+		for _, name := range names {
+			value := advice.input.Variables[name]
+			logFields := logrus.Fields{"advice": advice.name, "function": fnDef.Name, "name": name, "value": value}
+			logrus.WithFields(logFields).Traceln("Adding local variable")
+
+			// Adds local using the variable value.
+			localDef, err := pool.Local(name, value, joinPoint.Blocks())
+			if err != nil {
+				logrus.WithFields(logFields).Fatalf("adding local to function %s: %v", name, err)
+			}
+			fnDef.Alias[localDef.Name] = name
+			res[joinPoint][name] = localDef
+		}
+		if reset := pool.ResetCode(joinPoint.Blocks()); reset != "" {
+			resets[joinPoint] = reset
+		}
+	}
+	for fnDef, pool := range pools {
+		logrus.WithFields(logrus.Fields{"advice": advice.name, "function": fnDef.Name, "locals": pool.Count()}).
+			Traceln("Allocated advice locals")
+	}
+	return res, resets
 }
 
 // applyJoinPointTransformations applies the join-point transformations to the module.
@@ -296,6 +549,7 @@ func (tf *Transformation) applyJoinPointTransformations(joinPoint *wpointcut.Joi
 		"index":      fnDef.Name,
 	}
 	logrus.WithFields(logFields).Traceln("Applying transformation to join-point")
+	tf.context.AddChangeOrigin(fnDef, adviceName, joinPoint.String(), joinPoint.Blocks())
 
 	keywords := wkeyword.NewStringValuesMap()
 	var staticMappers []wkeyword.KeywordsMap
@@ -306,6 +560,9 @@ func (tf *Transformation) applyJoinPointTransformations(joinPoint *wpointcut.Joi
 	for i, b := range blocks {
 		mappers := append(staticMappers, b)
 		keywords["this"] = joinPoint.InstrString(i)
+		if typ, ok := b.Type(); ok {
+			mappers = append(mappers, wkeyword.ValueTypesMap{"this": typ.String()})
+		}
 
 		if templatesMapper, ok := ctx.Templates(fnDef.Name, i, mappers...); !ok {
 			// Eager template filter fails
@@ -315,7 +572,7 @@ func (tf *Transformation) applyJoinPointTransformations(joinPoint *wpointcut.Joi
 			mappers = append(mappers, templatesMapper)
 		}
 
-		parsedOutput := lex.Parse(code, tf.context.OrderMap(), mappers...)
+		parsedOutput := lex.Parse(code, tf.context.OrderMap(), tf.context, mappers...)
 		err := b.Apply(parsedOutput.Output, smartAdvice)
 		if err != nil {
 			logrus.Fatalf("applying join-point code: %v", err)
@@ -341,13 +598,16 @@ func (tf *Transformation) applyTransformationsToAddedFunctions(fns []string) {
 	semaphore := make(chan struct{}, runtime.NumCPU())
 	for _, found := range jpSearch.Found() {
 		wg.Add(1)
+		semaphore <- struct{}{}
 		go func(found *wcode.JoinPointBlock) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
 			zone := globalZone
 			name := found.Instr().(*wcode.Instruction).Child(0).String()
 			if _, ok := fnsMap[name]; !ok {
 				// Function was not added be the user.
-				<-semaphore
-				wg.Done()
 				return
 			}
 			fnName, ok := tf.context.FunctionAlias[name]
@@ -360,15 +620,12 @@ func (tf *Transformation) applyTransformationsToAddedFunctions(fns []string) {
 			}
 			logrus.WithFields(logrus.Fields{"function": name, "alias": fnName}).Traceln("Executing static expressions on global function")
 			code := wcode.FuncInstrsString(found.Instr())
-			parsedOutput := lex.Parse(code, tf.context.OrderMap(), zone)
+			parsedOutput := lex.Parse(code, tf.context.OrderMap(), tf.context, zone)
 			if parsedOutput.Output != code {
 				logrus.WithFields(logrus.Fields{"function": name, "alias": fnName}).Traceln("Applying modifications to global function")
 				wcode.ReplaceBlocks([]*wcode.JoinPointBlock{found}, parsedOutput.Output)
 			}
-			<-semaphore
-			wg.Done()
 		}(found)
-		semaphore <- struct{}{}
 	}
 	wg.Wait()
 	close(semaphore)
@@ -423,12 +680,23 @@ func filterAddedFnsOnJoinPoints(all bool, jps []*wpointcut.JoinPoint, fns []stri
 // TransformationResult is responsible to manage the response data from the transformation.
 type TransformationResult struct {
 	*wcode.ModuleContext
-	tf *Transformation
+	tf         *Transformation
+	JoinPoints map[string]int
+	Report     *Report
 }
 
 // newTranformationResult is a constructor for TransformationResult.
-func newTranformationResult(tf *Transformation) *TransformationResult {
-	return &TransformationResult{tf.context, tf}
+func newTranformationResult(tf *Transformation, report *Report) *TransformationResult {
+	return &TransformationResult{tf.context, tf, make(map[string]int), report}
+}
+
+// TotalJoinPoints returns the number of join-points transformed by all the advices.
+func (tr *TransformationResult) TotalJoinPoints() int {
+	var total int
+	for _, count := range tr.JoinPoints {
+		total += count
+	}
+	return total
 }
 
 // GenerateJsData generates the javascript code.
@@ -454,43 +722,76 @@ func (tr *TransformationResult) GenerateJsData() (string, error) {
 		for _, arg := range fn.Parameters() {
 			fnArgs = append(fnArgs, wgenerator.NewJsArgumentDefinition(arg.TypeCodeOnFn(isExported), arg.IsPrimitive()))
 		}
-		returnsComposite := fn.Result != "" && !wcode.IsVarTypeStrPrimitive(fn.Result)
+		returnsComposite := fn.ReturnsComposite()
 		fns = append(fns, wgenerator.NewJsFunctionDefinition(opName, fnName, fnScope, fnArgs, returnsComposite))
 	}
-	return wgenerator.GetJsCode(fns)
+	var errorContexts []wgenerator.JsErrorContext
+	for _, errCtx := range tr.ErrorContexts() {
+		errorContexts = append(errorContexts, wgenerator.JsErrorContext(errCtx))
+	}
+	return wgenerator.GetJsCode(fns, errorContexts)
 }
 
 // Run executes the module transformation.
+// the time spent on each phase is added to the report of the result.
 func Run(code string, input *wyaml.BaseYAML) (*TransformationResult, bool) {
+	report := newReport()
+
 	// Creating transformation manager.
+	start := time.Now()
 	transformation := NewTransformation(code, input)
+	transformation.context.PureWasm = wconfigs.Get().PureWasm
+	original := transformation.context.Names()
+	report.AddPhase("parse module", start)
 
 	// Fill the join-points for each advice
+	start = time.Now()
 	advicesList := transformation.fillJoinPoints()
+	report.AddPhase("parse advices", start)
 
 	if len(advicesList) == 0 && !wconfigs.Get().AllowEmpty {
 		logrus.WithFields(logrus.Fields{"original": len(input.Aspects.Advices), "filtered": len(advicesList)}).
 			Infoln("Aborted transformations because no advices were defined")
-		return newTranformationResult(transformation), false
+		return newTranformationResult(transformation, report), false
 	}
 
 	// Modify module accordingly to global context
+	start = time.Now()
 	fns := transformation.applyGlobalContextTransformations()
 
 	// Modify start function accordingly to definition
 	if transformation.input.Aspects.Start != "" {
 		fns = append(fns, transformation.addStartFunctionCode(transformation.input.Aspects.Start))
 	}
+	report.AddPhase("global context", start)
 
 	// Apply transformations on each advice.
+	start = time.Now()
+	joinPoints := make(map[string]int, len(advicesList))
 	for _, v := range advicesList {
-		transformation.applyLocalContextTransformations(v, fns)
+		adviceJoinPoints := transformation.applyLocalContextTransformations(v, fns)
+		joinPoints[v.name] = len(adviceJoinPoints)
+		report.addAdvice(v, adviceJoinPoints)
 	}
+	report.AddPhase("advices", start)
 
 	// Resolve static expressions for the global functions.
+	start = time.Now()
 	transformation.applyTransformationsToAddedFunctions(fns)
+	report.AddPhase("added functions", start)
 	// Apply runtime transformations.
+	start = time.Now()
 	transformation.context.ApplyRuntimeTransformations()
+	report.AddPhase("runtime", start)
+	// Remove the unused code left behind by the transformations.
+	if wconfigs.Get().Optimize {
+		start = time.Now()
+		transformation.context.Optimize()
+		report.AddPhase("optimize", start)
+	}
+	report.finish(transformation.context, original)
 
-	return newTranformationResult(transformation), true
+	result := newTranformationResult(transformation, report)
+	result.JoinPoints = joinPoints
+	return result, true
 }
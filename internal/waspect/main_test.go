@@ -0,0 +1,209 @@
+package waspect
+
+import (
+	"strings"
+	"testing"
+
+	"joao/wasm-manipulator/internal/wyaml"
+)
+
+const localsCode = `(module
+	(type $t0 (func (param i32)))
+	(import "env" "log" (func $log (type $t0)))
+	(func $f (type $t0) (param $p i32)
+		(call $log (i32.const 0))
+		(call $log (i32.const 1)))
+	(func $g (type $t0) (param $p i32)
+		(call $log (local.get $p))))`
+
+func TestRun_AdviceLocals(t *testing.T) {
+	input := &wyaml.BaseYAML{Aspects: wyaml.AspectYAML{Advices: map[string]wyaml.AdviceYAML{
+		"a1": {
+			Pointcut:  "() => call(* $log (..))",
+			Variables: map[string]string{"v": "i32"},
+			Advice:    "(local.set %v% (i32.const 2))\n%this%",
+		},
+	}}}
+	output, ok := Run(localsCode, input)
+	if !ok {
+		t.Fatal("expected the transformation to run")
+	}
+
+	// Each join-point has its own local, used by all its blocks.
+	locals := output.Report.Added.Locals
+	if len(locals) != 2 || locals[0].Function != "$f" || locals[1].Function != "$g" {
+		t.Fatalf("expected a local for each join-point, got %+v", locals)
+	}
+	for _, local := range locals {
+		fnDef, _ := output.Function(local.Function)
+		if local.Alias != "v" || strings.Count(fnDef.Code(), "local.set "+local.Name) != strings.Count(fnDef.Code(), "call $log") {
+			t.Errorf("unexpected local %+v on function %s", local, fnDef.Code())
+		}
+	}
+}
+
+func TestRun_ExportDirectivesOrder(t *testing.T) {
+	input := &wyaml.BaseYAML{Aspects: wyaml.AspectYAML{
+		Context: wyaml.ContextYAML{Exports: map[string]wyaml.ExportYAML{
+			"g": {Alias: []string{"g2"}},
+			"f": {Alias: []string{"f2"}},
+			"h": {Alias: []string{"h2"}},
+		}},
+		Advices: map[string]wyaml.AdviceYAML{
+			"a1": {Pointcut: "() => call(* $log (..))", Advice: "%this%"},
+		},
+	}}
+
+	// The aliases are added sorted by export name on every run.
+	for i := 0; i < 5; i++ {
+		output, ok := Run(exportsCode, input)
+		if !ok {
+			t.Fatal("expected the transformation to run")
+		}
+		code := output.String()
+		f, g, h := strings.Index(code, `"f2"`), strings.Index(code, `"g2"`), strings.Index(code, `"h2"`)
+		if f == -1 || f > g || g > h {
+			t.Fatalf("expected the aliases sorted by export name:\n%s", code)
+		}
+	}
+}
+
+const exportsCode = `(module
+	(type $t0 (func))
+	(type $t1 (func (param i32)))
+	(import "env" "log" (func $log (type $t1)))
+	(func $f (type $t0)
+		(call $log (i32.const 0)))
+	(func $g (type $t0))
+	(func $h (type $t0))
+	(export "f" (func $f))
+	(export "g" (func $g))
+	(export "h" (func $h)))`
+
+func TestRun_CloneDirectivesOrder(t *testing.T) {
+	input := &wyaml.BaseYAML{Aspects: wyaml.AspectYAML{
+		Context: wyaml.ContextYAML{Clones: map[string]wyaml.CloneYAML{
+			"g1": {Function: "$g"},
+			"f1": {Function: "$f"},
+			"h1": {Function: "$h"},
+		}},
+		Advices: map[string]wyaml.AdviceYAML{
+			"a1": {Pointcut: "() => call(* $log (..))", Advice: "%this%"},
+		},
+	}}
+
+	// The clones are added sorted by name on every run.
+	for i := 0; i < 5; i++ {
+		output, ok := Run(exportsCode, input)
+		if !ok {
+			t.Fatal("expected the transformation to run")
+		}
+		var aliases []string
+		for _, fn := range output.Report.Added.Functions {
+			if fn.Alias != "" {
+				aliases = append(aliases, fn.Alias)
+			}
+		}
+		if strings.Join(aliases, " ") != "f1 g1 h1" {
+			t.Fatalf("expected the clones sorted by name, got %v", aliases)
+		}
+	}
+}
+
+func TestRun_AdviceData(t *testing.T) {
+	input := &wyaml.BaseYAML{Aspects: wyaml.AspectYAML{Advices: map[string]wyaml.AdviceYAML{
+		"a1": {
+			Pointcut: "() => call(* $log (..))",
+			Advice:   "(call $print %'entering':data()%)\n%this%",
+		},
+	}}}
+	output, ok := Run(dataCode, input)
+	if !ok {
+		t.Fatal("expected the transformation to run")
+	}
+
+	// The value is stored once after the initial memory, and each join-point gets its address and length.
+	code := output.String()
+	if strings.Count(code, "(i32.const 65536) (i32.const 8)") != 2 {
+		t.Errorf("expected the address and length of the value on each join-point:\n%s", code)
+	}
+	for _, expected := range []string{"(memory $m0 2)", `(data (i32.const 65536) "entering")`} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("module does not contain %q:\n%s", expected, code)
+		}
+	}
+}
+
+const dataCode = `(module
+	(type $t0 (func (param i32)))
+	(type $t1 (func (param i32 i32)))
+	(import "env" "log" (func $log (type $t0)))
+	(import "env" "print" (func $print (type $t1)))
+	(memory $m0 1)
+	(func $f (type $t0) (param $p i32)
+		(call $log (i32.const 0))
+		(call $log (local.get $p))))`
+
+func TestRun_SegmentDirectivesOrder(t *testing.T) {
+	input := &wyaml.BaseYAML{Aspects: wyaml.AspectYAML{
+		Context: wyaml.ContextYAML{
+			Tables: map[string]wyaml.TableYAML{
+				"b": {Min: 2, Type: "funcref"},
+				"a": {Min: 1, Type: "funcref"},
+				"c": {Min: 3, Type: "funcref"},
+			},
+			Data: map[string]wyaml.DataYAML{
+				"b": {Value: "bb"},
+				"a": {Value: "aa"},
+				"c": {Value: "cc"},
+			},
+		},
+		Advices: map[string]wyaml.AdviceYAML{
+			"a1": {Pointcut: "() => call(* $log (..))", Advice: "%this%"},
+		},
+	}}
+
+	// The tables and the overlapping data segments are added sorted by name on every run.
+	for i := 0; i < 5; i++ {
+		output, ok := Run(dataCode, input)
+		if !ok {
+			t.Fatal("expected the transformation to run")
+		}
+		code := output.String()
+		for _, expected := range []string{
+			"(table $wmr_t0 1 funcref) (table $wmr_t1 2 funcref) (table $wmr_t2 3 funcref)",
+			`(data $wmr_d0 (i32.const 0) "aa") (data $wmr_d1 (i32.const 0) "bb") (data $wmr_d2 (i32.const 0) "cc")`,
+		} {
+			if !strings.Contains(code, expected) {
+				t.Fatalf("module does not contain %q:\n%s", expected, code)
+			}
+		}
+	}
+}
+
+func TestRun_CloneDirectivesUntouched(t *testing.T) {
+	input := &wyaml.BaseYAML{Aspects: wyaml.AspectYAML{
+		Context: wyaml.ContextYAML{Clones: map[string]wyaml.CloneYAML{
+			"f_copy1": {Function: "$f"},
+			"f_copy2": {Function: "$f"},
+		}},
+		Advices: map[string]wyaml.AdviceYAML{
+			"a1": {Pointcut: "() => call(* $log (..))", Advice: "(nop)\n%this%"},
+		},
+	}}
+	output, ok := Run(exportsCode, input)
+	if !ok {
+		t.Fatal("expected the transformation to run")
+	}
+
+	// The clones of a module function are copies of the original code, only changed by the advices with the all flag.
+	for name, nops := range map[string]int{"$f": 1, "$f_copy1": 0, "$f_copy2": 0} {
+		fnDef, ok := output.Function(name)
+		if !ok {
+			t.Fatalf("function %s not found", name)
+		}
+		if count := strings.Count(fnDef.Code(), "(nop)"); count != nops {
+			t.Errorf("expected %d advice code(s) on function %s: %s", nops, name, fnDef.Code())
+		}
+	}
+}
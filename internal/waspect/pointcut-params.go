@@ -1,12 +1,11 @@
 package waspect
 
 import (
+	"fmt"
 	"joao/wasm-manipulator/internal/wcode"
 	"strconv"
 	"strings"
 
-	"github.com/sirupsen/logrus"
-
 	"joao/wasm-manipulator/internal/wkeyword"
 	"joao/wasm-manipulator/internal/wpointcut"
 )
@@ -18,23 +17,19 @@ type pointcutParameters struct {
 }
 
 // newPointcutParameters is a constructor for pointcutParameters.
-func newPointcutParameters(fnDef *wcode.FunctionDefinition, params map[string]wpointcut.ParsedParam) *pointcutParameters {
+// returns an error if some parameter is not found on the function.
+func newPointcutParameters(fnDef *wcode.FunctionDefinition, params map[string]wpointcut.ParsedParam) (*pointcutParameters, error) {
 	for _, val := range params {
-		var name string
-
-		// Find the immediate value.
-		if val.Variable == "param" {
-			name = parameterIndex(fnDef, val)
-		} else {
-			name = localIndex(fnDef, val)
+		name, err := parameterName(fnDef, val)
+		if err != nil {
+			return nil, err
 		}
-
 		fnDef.Alias[name] = val.Name
 	}
 	return &pointcutParameters{
 		fnDef:  fnDef,
 		params: params,
-	}
+	}, nil
 }
 
 // Is returns the type of keyword of some pointcut parameter.
@@ -46,72 +41,74 @@ func (er *pointcutParameters) Is(k string) wkeyword.KeywordType {
 }
 
 // Get returns the pointcut parameter, for a given key, as a keyword value.
+// the parameters not found on the function panic with the error, which is recovered by the expressions parser.
 func (er *pointcutParameters) Get(k string) (interface{}, wkeyword.KeywordType, bool) {
 	if val, ok := er.params[k]; ok {
-		var name string
-
-		// Find the immediate value.
-		if val.Variable == "param" {
-			name = parameterIndex(er.fnDef, val)
-		} else {
-			name = localIndex(er.fnDef, val)
+		name, err := parameterName(er.fnDef, val)
+		if err != nil {
+			panic(err)
 		}
-
 		return name, wkeyword.KeywordTypeString, true
 	}
 	return nil, wkeyword.KeywordTypeUnknown, false
 }
 
+// parameterName returns the immediate value of some pointcut parameter, i.e., the name of the parameter or local.
+func parameterName(fnDef *wcode.FunctionDefinition, val wpointcut.ParsedParam) (string, error) {
+	if val.Variable == "param" {
+		return parameterIndex(fnDef, val)
+	}
+	return localIndex(fnDef, val)
+}
+
 // parameterIndex returns the index value for some parameter.
-func parameterIndex(fnDef *wcode.FunctionDefinition, val wpointcut.ParsedParam) string {
+func parameterIndex(fnDef *wcode.FunctionDefinition, val wpointcut.ParsedParam) (string, error) {
 	if index, err := strconv.Atoi(val.Index); err == nil {
 		params := fnDef.Parameters()
 		if index >= len(params) {
-			logrus.Fatalf("finding parameter for function %s: parameter index out of range (index: %d, maximum: %d)",
+			return "", fmt.Errorf("finding parameter for function %s: parameter index out of range (index: %d, maximum: %d)",
 				fnDef.Name, index, len(params)-1)
 		}
-		return params[index].Name
+		return params[index].Name, nil
 	}
 	index := val.Index
 	if !strings.HasPrefix(index, "$") {
 		indexAux, ok := fnDef.AliasKey(index)
 		if !ok {
-			logrus.Fatalf("finding parameter for function %s: parameter named %s not found",
+			return "", fmt.Errorf("finding parameter for function %s: parameter named %s not found",
 				fnDef.Name, index)
 		}
 		index = indexAux
 	}
 	if param, ok := fnDef.Params[index]; ok {
-		return param.Name
+		return param.Name, nil
 	}
-	logrus.Fatalf("finding parameter for function %s: parameter index %s not found",
+	return "", fmt.Errorf("finding parameter for function %s: parameter index %s not found",
 		fnDef.Name, index)
-	return ""
 }
 
 // localIndex returns the index value for some local.
-func localIndex(fnDef *wcode.FunctionDefinition, val wpointcut.ParsedParam) string {
+func localIndex(fnDef *wcode.FunctionDefinition, val wpointcut.ParsedParam) (string, error) {
 	if index, err := strconv.Atoi(val.Index); err == nil {
 		locals := fnDef.LocalsArr()
 		if index >= len(locals) {
-			logrus.Fatalf("finding local for function %s: local index out of range (index: %d, maximum: %d)",
+			return "", fmt.Errorf("finding local for function %s: local index out of range (index: %d, maximum: %d)",
 				fnDef.Name, index, len(locals)-1)
 		}
-		return locals[index].Name
+		return locals[index].Name, nil
 	}
 	index := val.Index
 	if !strings.HasPrefix(index, "$") {
 		indexAux, ok := fnDef.AliasKey(index)
 		if !ok {
-			logrus.Fatalf("finding local for function %s: local named %s not found",
+			return "", fmt.Errorf("finding local for function %s: local named %s not found",
 				fnDef.Name, index)
 		}
 		index = indexAux
 	}
 	if local, ok := fnDef.Locals[index]; ok {
-		return local.Name
+		return local.Name, nil
 	}
-	logrus.Fatalf("finding local for function %s: local index %s not found",
+	return "", fmt.Errorf("finding local for function %s: local index %s not found",
 		fnDef.Name, index)
-	return ""
 }
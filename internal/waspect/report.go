@@ -0,0 +1,111 @@
+package waspect
+
+import (
+	"sort"
+	"time"
+
+	"joao/wasm-manipulator/internal/wcode"
+	"joao/wasm-manipulator/internal/wpointcut"
+)
+
+// Report describes a transformation, i.e., the join-points of each advice, the elements added to the module
+// and the reasons for requiring the javascript code.
+type Report struct {
+	Advices    []*AdviceReport
+	Added      *wcode.AddedElements
+	JS         *JSReport
+	InputSize  int64
+	OutputSize int64
+	Phases     []*PhaseReport
+}
+
+// AdviceReport describes the transformations of some advice.
+type AdviceReport struct {
+	Name       string
+	Pointcut   string
+	Parsed     *wpointcut.PointcutDescription
+	JoinPoints int
+	Functions  []*FunctionReport
+	fnDefs     []*wcode.FunctionDefinition
+}
+
+// FunctionReport identifies a function by its index and name, with the name given by the transformations, if any.
+type FunctionReport struct {
+	Index int
+	Name  string
+	Alias string
+}
+
+// JSReport describes if the javascript code is required and why, i.e., the glue functions imported by the module,
+// the composite values and the runtime expressions that use them.
+type JSReport struct {
+	Required    bool
+	Glue        []string
+	Composites  []wcode.RuntimeComposite
+	Expressions []wcode.RuntimeErrorContext
+}
+
+// PhaseReport contains the time spent on some phase of the transformation.
+type PhaseReport struct {
+	Name         string
+	Milliseconds float64
+}
+
+// newReport is the constructor for Report.
+func newReport() *Report {
+	return &Report{}
+}
+
+// NewPhaseReport is the constructor for PhaseReport.
+// the phase started at some time and finished now.
+func NewPhaseReport(name string, start time.Time) *PhaseReport {
+	return &PhaseReport{Name: name, Milliseconds: float64(time.Since(start).Microseconds()) / 1000}
+}
+
+// AddPhase adds a phase that started at some time and finished now.
+func (r *Report) AddPhase(name string, start time.Time) {
+	r.Phases = append(r.Phases, NewPhaseReport(name, start))
+}
+
+// addAdvice adds the report of an advice, with the functions of its join-points.
+// the functions are identified at the end of the transformation, since their indexes change when functions are imported.
+func (r *Report) addAdvice(advice advice, joinPoints []*wpointcut.JoinPoint) {
+	res := &AdviceReport{
+		Name:       advice.name,
+		Pointcut:   advice.input.Pointcut,
+		Parsed:     advice.pointcut.Describe(),
+		JoinPoints: len(joinPoints),
+	}
+	fnsMap := make(map[*wcode.FunctionDefinition]struct{})
+	for _, jp := range joinPoints {
+		fnDef := jp.FuncDefinition()
+		if _, ok := fnsMap[fnDef]; !ok {
+			fnsMap[fnDef] = struct{}{}
+			res.fnDefs = append(res.fnDefs, fnDef)
+		}
+	}
+	r.Advices = append(r.Advices, res)
+}
+
+// finish fills the data obtained from the transformed module.
+func (r *Report) finish(ctx *wcode.ModuleContext, original *wcode.ModuleNames) {
+	for _, advice := range r.Advices {
+		for _, fnDef := range advice.fnDefs {
+			advice.Functions = append(advice.Functions, &FunctionReport{
+				Index: fnDef.Index(ctx),
+				Name:  fnDef.Name,
+				Alias: ctx.FunctionAlias[fnDef.Name],
+			})
+		}
+		sort.Slice(advice.Functions, func(i, j int) bool {
+			return advice.Functions[i].Index < advice.Functions[j].Index
+		})
+	}
+	r.Added = ctx.Added(original)
+	r.JS = &JSReport{
+		Required:    ctx.NeedJS,
+		Glue:        ctx.GlueFunctions(),
+		Composites:  ctx.RuntimeComposites(),
+		Expressions: ctx.ErrorContexts(),
+	}
+}
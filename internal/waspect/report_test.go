@@ -0,0 +1,63 @@
+package waspect
+
+import (
+	"testing"
+
+	"joao/wasm-manipulator/internal/wyaml"
+)
+
+func TestRun_Report(t *testing.T) {
+	input := &wyaml.BaseYAML{
+		Pointcuts: map[string]string{"logCall": "() => call(* $log (..))"},
+		Aspects: wyaml.AspectYAML{
+			Context: wyaml.ContextYAML{Variables: map[string]string{"msg": `string = "hi"`}},
+			Advices: map[string]wyaml.AdviceYAML{
+				"a1": {Pointcut: "() => logCall() || func(* $f (..))", Variables: map[string]string{"tmp": "i32 = 0"}, Advice: "nop\n%this%"},
+			},
+		},
+	}
+	output, ok := Run(sessionCode, input)
+	if !ok {
+		t.Fatal("expected the transformation to run")
+	}
+	report := output.Report
+
+	if len(report.Advices) != 1 {
+		t.Fatalf("expected a single advice, got %d", len(report.Advices))
+	}
+	advice := report.Advices[0]
+	fnDef, _ := output.Function("$f")
+	if advice.JoinPoints != 1 || len(advice.Functions) != 1 || advice.Functions[0].Name != "$f" || advice.Functions[0].Index != fnDef.Index(output.ModuleContext) {
+		t.Errorf("unexpected join-points %d and functions %+v", advice.JoinPoints, advice.Functions)
+	}
+	expr := advice.Parsed.Expression
+	if expr.Type != "or" || expr.Children[0].Type != "pointcut" || expr.Children[0].Method != "logCall()" ||
+		expr.Children[0].Children[0].Method != "call(* $log (..))" || expr.Children[1].Method != "func(* $f (..))" {
+		t.Errorf("unexpected parsed pointcut %+v", expr)
+	}
+
+	if len(report.Added.Globals) != 1 || report.Added.Globals[0].Alias != "msg" || report.Added.Globals[0].Type != "string" {
+		t.Errorf("unexpected added globals %+v", report.Added.Globals)
+	}
+	if len(report.Added.Locals) != 1 || report.Added.Locals[0].Alias != "tmp" || report.Added.Locals[0].Function != "$f" {
+		t.Errorf("unexpected added locals %+v", report.Added.Locals)
+	}
+	if !report.JS.Required || len(report.JS.Composites) != 1 || report.JS.Composites[0].Value != "global msg" {
+		t.Errorf("unexpected javascript report %+v", report.JS)
+	}
+	var zone bool
+	for _, glue := range report.JS.Glue {
+		zone = zone || glue == "zone"
+	}
+	if !zone {
+		t.Errorf("expected the zone glue functions, got %v", report.JS.Glue)
+	}
+
+	var phases []string
+	for _, phase := range report.Phases {
+		phases = append(phases, phase.Name)
+	}
+	if len(phases) < 5 || phases[0] != "parse module" || phases[len(phases)-1] != "runtime" {
+		t.Errorf("unexpected phases %v", phases)
+	}
+}
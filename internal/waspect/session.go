@@ -0,0 +1,254 @@
+package waspect
+
+import (
+	"fmt"
+	"strings"
+
+	"joao/wasm-manipulator/internal/wkeyword"
+	"joao/wasm-manipulator/internal/wparser/lex"
+	"joao/wasm-manipulator/internal/wparser/pointcut"
+	"joao/wasm-manipulator/internal/wpointcut"
+	"joao/wasm-manipulator/internal/wyaml"
+)
+
+// EvaluationStep is the result of some step of a static expression, i.e., the expression until some method.
+type EvaluationStep struct {
+	Expression string
+	Type       string
+	Value      string
+}
+
+// Session evaluates static expressions against the join-points of a module, as the advices code.
+// the global context of the transformation is applied to the module, so its declarations can be used by the expressions.
+type Session struct {
+	tf         *Transformation
+	fns        []string
+	advice     *advice
+	context    *wpointcut.PointcutContext
+	joinPoints []*wpointcut.JoinPoint
+	locals     map[*wpointcut.JoinPoint]map[string]string
+	selected   int
+}
+
+// NewSession is the constructor for Session.
+func NewSession(code string, input *wyaml.BaseYAML) *Session {
+	tf := NewTransformation(code, input)
+	fns := tf.applyGlobalContextTransformations()
+	return &Session{tf: tf, fns: fns, selected: -1}
+}
+
+// SelectAdvice selects the join-points of some advice of the transformation.
+// returns the number of join-points found.
+func (s *Session) SelectAdvice(name string) (int, error) {
+	input, ok := s.tf.input.Aspects.Advices[name]
+	if !ok {
+		return 0, fmt.Errorf("advice %q not found", name)
+	}
+	return s.selectPointcut(name, input)
+}
+
+// SelectFunction selects the join-point of some function, by name or index (e.g. $f0).
+// returns the number of join-points found.
+func (s *Session) SelectFunction(name string) (int, error) {
+	return s.selectPointcut(name, wyaml.AdviceYAML{Pointcut: fmt.Sprintf("() => func(* %s (..))", name), All: true})
+}
+
+// selectPointcut selects the join-points of some advice input.
+func (s *Session) selectPointcut(name string, input wyaml.AdviceYAML) (total int, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("selecting join-points of %s: %v", name, v)
+		}
+	}()
+	pc, err := pointcut.ParseWithContext(input.Pointcut)
+	if err != nil {
+		return 0, fmt.Errorf("parsing pointcut expression %q: %w", input.Pointcut, err)
+	}
+	a := &advice{
+		name:     name,
+		input:    input,
+		pointcut: wpointcut.NewParsedPointcut(pc, s.tf.input).Init(s.tf.context, s.tf.input),
+		all:      input.All,
+	}
+	context := a.pointcut.Execute()
+	joinPoints := filterAddedFnsOnJoinPoints(a.all, context.All(), s.fns)
+
+	// The session does not change the join-points, so the pooled locals are not reset.
+	allocated, _ := s.tf.allocateAdviceLocals(*a, joinPoints)
+	locals := make(map[*wpointcut.JoinPoint]map[string]string, len(allocated))
+	for jp, defs := range allocated {
+		locals[jp] = make(map[string]string, len(defs))
+		for k, def := range defs {
+			locals[jp][k] = def.Name
+		}
+	}
+
+	s.advice, s.context, s.joinPoints, s.locals = a, context, joinPoints, locals
+	s.selected = -1
+	if len(joinPoints) > 0 {
+		s.selected = 0
+	}
+	return len(joinPoints), nil
+}
+
+// JoinPoints returns the description of the selected join-points.
+func (s *Session) JoinPoints() []string {
+	res := make([]string, len(s.joinPoints))
+	for i, jp := range s.joinPoints {
+		res[i] = fmt.Sprintf("%s %s", jp.FuncDefinition().Name, jp.InstrsString())
+	}
+	return res
+}
+
+// Select selects the join-point with some index, used by the evaluations.
+func (s *Session) Select(i int) error {
+	if i < 0 || i >= len(s.joinPoints) {
+		return fmt.Errorf("join-point %d not found (total: %d)", i, len(s.joinPoints))
+	}
+	s.selected = i
+	return nil
+}
+
+// Selected returns the index of the selected join-point, or -1 if none is selected.
+func (s *Session) Selected() int {
+	return s.selected
+}
+
+// Evaluate evaluates some static expression against the selected join-point.
+// the expressions without the delimiters (%) are evaluated method by method, returning the type and value of each step.
+// the code with delimiters is evaluated as the advice code, returning a single step with its output.
+// the errors found while evaluating the expression are returned, keeping the session usable.
+func (s *Session) Evaluate(input string) (res []*EvaluationStep, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			res, err = nil, fmt.Errorf("%v", v)
+		}
+	}()
+	if s.selected < 0 {
+		return nil, fmt.Errorf("no join-point selected")
+	}
+	mappers, err := s.mappers()
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.Contains(input, "%") {
+		if err := checkExpression(input, mappers); err != nil {
+			return nil, err
+		}
+		output, err := lex.Evaluate(input, s.tf.context.OrderMap(), s.tf.context, mappers...)
+		if err != nil {
+			return nil, err
+		}
+		return []*EvaluationStep{{Expression: input, Type: lex.String, Value: output}}, nil
+	}
+
+	if err := checkExpression("%"+input+"%", mappers); err != nil {
+		return nil, err
+	}
+	// The value types are ignored on the steps types, returning the static expression types (e.g. string instead of i32).
+	var typeMappers []wkeyword.KeywordsMap
+	for _, m := range mappers {
+		if _, ok := m.(wkeyword.ValueTypesMap); !ok {
+			typeMappers = append(typeMappers, m)
+		}
+	}
+	for _, step := range expressionSteps(input) {
+		typ, err := lex.Evaluate("%"+step+":type()%", s.tf.context.OrderMap(), s.tf.context, typeMappers...)
+		if err != nil {
+			return nil, err
+		}
+		value, err := lex.Evaluate("%"+step+"%", s.tf.context.OrderMap(), s.tf.context, mappers...)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &EvaluationStep{Expression: step, Type: typ, Value: value})
+	}
+	return res, nil
+}
+
+// mappers returns the keyword maps of the selected join-point, as on the advices code.
+func (s *Session) mappers() ([]wkeyword.KeywordsMap, error) {
+	jp := s.joinPoints[s.selected]
+	fnDef := jp.FuncDefinition()
+
+	functionZone := newContextVariablesZone(s.tf.globalZone)
+	if exportedName, ok := s.tf.context.AliasValue(fnDef.Name); ok && s.tf.functionsZone[exportedName] != nil {
+		functionZone = newContextVariablesZone(s.tf.functionsZone[exportedName])
+	}
+	for name, local := range s.locals[jp] {
+		functionZone.AddVariable(name, local)
+	}
+
+	params, err := newPointcutParameters(fnDef, s.advice.pointcut.Params)
+	if err != nil {
+		return nil, err
+	}
+	b := jp.Blocks()[0]
+	mappers := []wkeyword.KeywordsMap{
+		wkeyword.NewStringValuesMap([]string{keywordThis, jp.InstrString(0)}),
+		params,
+		newContextVariables(functionZone),
+		b,
+	}
+	if typ, ok := b.Type(); ok {
+		mappers = append(mappers, wkeyword.ValueTypesMap{keywordThis: typ.String()})
+	}
+	templatesMapper, ok := s.context.Templates(fnDef.Name, 0, mappers...)
+	if !ok {
+		return nil, fmt.Errorf("join-point does not match the templates after filtering with the context variables")
+	}
+	if templatesMapper != nil {
+		mappers = append(mappers, templatesMapper)
+	}
+	return mappers, nil
+}
+
+// checkExpression validates some code, returning an error for invalid expressions or unknown variables.
+func checkExpression(code string, mappers []wkeyword.KeywordsMap) error {
+	identifiers, err := lex.Identifiers(code)
+	if err != nil {
+		return fmt.Errorf("invalid static expression: %w", err)
+	}
+	for _, identifier := range identifiers {
+		if isLiteral(identifier) {
+			continue
+		}
+		found := false
+		for _, m := range mappers {
+			if m.Is(identifier) != wkeyword.KeywordTypeUnknown {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown variable %q", identifier)
+		}
+	}
+	return nil
+}
+
+// expressionSteps returns the steps of some expression, i.e., the expression until each of its methods.
+// ex. call.Args:map((a) => a.Instr):join(" ") has the steps call.Args, call.Args:map((a) => a.Instr) and the full expression.
+func expressionSteps(expr string) []string {
+	var res []string
+	var depth int
+	var quote rune
+	for i, c := range expr {
+		switch {
+		case quote != 0:
+			if c == quote && (i == 0 || expr[i-1] != '\\') {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		case c == ':' && depth == 0 && i > 0:
+			res = append(res, strings.TrimSpace(expr[:i]))
+		}
+	}
+	return append(res, strings.TrimSpace(expr))
+}
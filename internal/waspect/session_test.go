@@ -0,0 +1,77 @@
+package waspect
+
+import (
+	"strings"
+	"testing"
+
+	"joao/wasm-manipulator/internal/wyaml"
+)
+
+const sessionCode = `(module
+	(type $t0 (func (param i32)))
+	(import "env" "log" (func $log (type $t0)))
+	(func $f (type $t0) (param $p i32)
+		(call $log (i32.add (local.get $p) (i32.const 1))))
+	(export "f" (func $f)))`
+
+func TestSession(t *testing.T) {
+	input := &wyaml.BaseYAML{Aspects: wyaml.AspectYAML{Advices: map[string]wyaml.AdviceYAML{
+		"a1": {Pointcut: "() => call(* $log (..))", Advice: "nop"},
+	}}}
+	session := NewSession(sessionCode, input)
+
+	if _, err := session.SelectAdvice("a2"); err == nil {
+		t.Error("expected unknown advice error")
+	}
+	total, err := session.SelectAdvice("a1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || session.Selected() != 0 {
+		t.Fatalf("expected a single selected join-point, got %d (%v)", total, session.JoinPoints())
+	}
+
+	steps, err := session.Evaluate(`call.Args:map((a) => a.Instr):join(" ")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, step := range steps {
+		got = append(got, step.Expression+" "+step.Type)
+	}
+	expected := []string{"call.Args object", "call.Args:map((a) => a.Instr) object", `call.Args:map((a) => a.Instr):join(" ") string`}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected steps:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+	if value := steps[len(steps)-1].Value; !strings.Contains(value, "i32.add") {
+		t.Errorf("unexpected value %q", value)
+	}
+
+	steps, err = session.Evaluate("callee: %call.Callee.Index%")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps[0].Value != "callee: $log" {
+		t.Errorf("unexpected code output %q", steps[0].Value)
+	}
+	if _, err := session.Evaluate("%unknown%"); err == nil {
+		t.Error("expected unknown variable error")
+	}
+	if _, err := session.Evaluate("call.Args.Foo"); err == nil || !strings.Contains(err.Error(), "non-object") {
+		t.Errorf("expected property access error, got %v", err)
+	}
+	if _, err := session.Evaluate("%call.Args:map((a) => a.Instr.Foo)%"); err == nil {
+		t.Error("expected property access error on the lambda")
+	}
+
+	if _, err := session.SelectFunction("f"); err != nil {
+		t.Fatal(err)
+	}
+	steps, err = session.Evaluate("func.Name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps[0].Type != "object" || steps[0].Value != "f" {
+		t.Errorf("unexpected step %+v", steps[0])
+	}
+}
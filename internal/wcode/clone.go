@@ -0,0 +1,240 @@
+package wcode
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"joao/wasm-manipulator/internal/wlang"
+	"joao/wasm-manipulator/internal/wyaml"
+)
+
+// cloneNameRegex is the model for the clone names, with the characters allowed on the indexes.
+var cloneNameRegex = regexp.MustCompile(`^\$?[0-9A-Za-z!#$%&'*+\-./:<=>?@\\^_` + "`" + `|~]+$`)
+
+// CloneFunction adds a copy of some function to the module, using the clone name as index.
+// the parameters with a constant value are removed from the clone signature and
+// replaced by locals initialized with that value (specialization).
+func (ctx *ModuleContext) CloneFunction(fnDef *FunctionDefinition, name string, constants map[string]string) (*FunctionDefinition, error) {
+	if fnDef.Imported != nil {
+		return nil, fmt.Errorf("cloning function: imported function %s cannot be cloned", fnDef.Name)
+	}
+	index, err := cloneFunctionIndex(name)
+	if err != nil {
+		return nil, fmt.Errorf("cloning function: %w", err)
+	}
+	if _, ok := ctx.functions[index]; ok {
+		return nil, fmt.Errorf("cloning function: function %s already exists", index)
+	}
+
+	// Parses a copy of the function code.
+	codeEl := NewCodeParser(fnDef.instr.String()).parse()
+	if len(codeEl.blocks) == 0 {
+		return nil, errors.New("cloning function: error parsing function code")
+	}
+	instr, ok := codeEl.blocks[0].(*Instruction)
+	if !ok || len(instr.values) == 0 {
+		return nil, errors.New("cloning function: function code is not an instruction")
+	}
+	if len(constants) > 0 {
+		numericVisitor := newNumericLocalsVisitor()
+		instr.Traverse(numericVisitor)
+		if numericVisitor.found {
+			return nil, fmt.Errorf("cloning function: function %s uses locals by index and cannot be specialized", fnDef.Name)
+		}
+	}
+
+	// Removes the inline exports and the constant parameters.
+	fixed := make(map[string]*FunctionParamDefinition)
+	var params []wyaml.FunctionArgYAML
+	var results []string
+	values := []Block{newText(index)}
+	for _, child := range instr.values[1:] {
+		childInstr, ok := child.(*Instruction)
+		if !ok {
+			values = append(values, child)
+			continue
+		}
+		switch childInstr.name {
+		case instructionExport:
+			continue
+		case instructionParam:
+			if len(childInstr.values) != 2 {
+				if len(constants) > 0 {
+					return nil, fmt.Errorf("cloning function: function %s has unnamed parameters and cannot be specialized", fnDef.Name)
+				}
+				for _, v := range childInstr.values {
+					params = append(params, wyaml.FunctionArgYAML{Type: v.String()})
+				}
+				break
+			}
+			name, typ := childInstr.values[0].String(), childInstr.values[1].String()
+			if _, ok := constantValue(constants, name); ok {
+				fixed[name] = newFunctionParamDefinition(name, typ, len(fixed))
+				continue
+			}
+			params = append(params, wyaml.FunctionArgYAML{Name: name, Type: typ})
+		case instructionResult:
+			for _, v := range childInstr.values {
+				results = append(results, v.String())
+			}
+		}
+		values = append(values, child)
+	}
+	if len(fixed) != len(constants) {
+		return nil, fmt.Errorf("cloning function: some constant arguments are not parameters of function %s", fnDef.Name)
+	}
+	for _, param := range sortedParams(fixed) {
+		value, _ := constantValue(constants, param.Name)
+		if !isConstantLiteral(param.Type, value) {
+			return nil, fmt.Errorf("cloning function: value %q is not a constant of type %s for parameter %s", value, param.Type, param.Name)
+		}
+	}
+
+	// Replaces the function type when the signature changes.
+	if len(fixed) > 0 {
+		typeDef, err := ctx.resolveNewFunctionType(&wyaml.FunctionYAML{Args: params, Results: results})
+		if err != nil {
+			return nil, fmt.Errorf("cloning function: %w", err)
+		}
+		for _, child := range values {
+			if childInstr, ok := child.(*Instruction); ok && childInstr.name == instructionType && len(childInstr.values) > 0 {
+				childInstr.values[0] = newText(typeDef.Name)
+			}
+		}
+	}
+	instr.values = values
+
+	// Adds the clone to the module.
+	resDef, err := ctx.addFunction(index, instr.String())
+	if err != nil {
+		return nil, fmt.Errorf("cloning function: %w", err)
+	}
+	if resDef == nil {
+		return nil, fmt.Errorf("cloning function: function %s not added to the module", index)
+	}
+	for name, alias := range fnDef.Alias {
+		resDef.Alias[name] = alias
+	}
+
+	// Adds the constant parameters as locals.
+	for _, param := range sortedParams(fixed) {
+		value, _ := constantValue(constants, param.Name)
+		if _, err := ctx.addLocal(param.Name, param.Type, value, resDef); err != nil {
+			return nil, fmt.Errorf("cloning function: %w", err)
+		}
+	}
+	return resDef, nil
+}
+
+// cloneFunctionIndex returns the index for some clone, using its name.
+// the name must only have the characters allowed on the indexes, e.g. the clone f5 results in $f5.
+func cloneFunctionIndex(name string) (string, error) {
+	if !cloneNameRegex.MatchString(name) {
+		return "", fmt.Errorf("clone name %q is not a valid index", name)
+	}
+	return "$" + strings.TrimPrefix(name, "$"), nil
+}
+
+// isConstantLiteral returns if some value is a numeric literal of some type, as used by the const instructions.
+func isConstantLiteral(typ, value string) bool {
+	value = strings.ReplaceAll(value, "_", "")
+	switch wlang.CodeBlockType(typ) {
+	case wlang.I32:
+		if n, err := strconv.ParseInt(value, 0, 64); err == nil {
+			return n >= math.MinInt32 && n <= math.MaxUint32
+		}
+		return false
+	case wlang.I64:
+		if _, err := strconv.ParseInt(value, 0, 64); err == nil {
+			return true
+		}
+		_, err := strconv.ParseUint(value, 0, 64)
+		return err == nil
+	case wlang.F32, wlang.F64:
+		_, err := strconv.ParseFloat(value, 64)
+		return err == nil
+	}
+	return false
+}
+
+// constantValue returns the constant value for some parameter.
+// the parameter can be referred with or without the '$' prefix.
+func constantValue(constants map[string]string, name string) (string, bool) {
+	if value, ok := constants[name]; ok {
+		return value, true
+	}
+	value, ok := constants[strings.TrimPrefix(name, "$")]
+	return value, ok
+}
+
+// sortedParams returns the parameters sorted by their order.
+func sortedParams(params map[string]*FunctionParamDefinition) []*FunctionParamDefinition {
+	res := make([]*FunctionParamDefinition, 0, len(params))
+	for _, param := range params {
+		res = append(res, param)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].order < res[j].order
+	})
+	return res
+}
+
+// numericLocalsVisitor is responsible to find local instructions that refer to locals by index.
+type numericLocalsVisitor struct {
+	visitorAdapter
+	found bool
+}
+
+// newNumericLocalsVisitor is the constructor for numericLocalsVisitor.
+func newNumericLocalsVisitor() *numericLocalsVisitor {
+	return &numericLocalsVisitor{}
+}
+
+// VisitInstruction visits an instruction block.
+func (nv *numericLocalsVisitor) VisitInstruction(instr *Instruction) bool {
+	if isLocalInstruction(instr.name) && len(instr.values) > 0 && isNumericIndex(instr.values[0].String()) {
+		nv.found = true
+		return true
+	}
+	nv.visitFlat(instr.values)
+	return nv.found
+}
+
+// VisitElement visits an element block.
+func (nv *numericLocalsVisitor) VisitElement(el *element) bool {
+	nv.visitFlat(el.blocks)
+	return nv.found
+}
+
+// visitFlat looks for flat local instructions followed by an index.
+func (nv *numericLocalsVisitor) visitFlat(blocks []Block) {
+	for i := 0; i < len(blocks)-1; i++ {
+		if _, ok := blocks[i].(*text); !ok {
+			continue
+		}
+		if isLocalInstruction(blocks[i].String()) && isNumericIndex(blocks[i+1].String()) {
+			nv.found = true
+			return
+		}
+	}
+}
+
+// isLocalInstruction returns if some instruction name refers to a local.
+func isLocalInstruction(name string) bool {
+	switch name {
+	case instructionCodeGetLocal, instructionCodeSetLocal, instructionCodeTeeLocal:
+		return true
+	}
+	return false
+}
+
+// isNumericIndex returns if some value is a numeric index.
+func isNumericIndex(value string) bool {
+	_, err := strconv.Atoi(value)
+	return err == nil
+}
@@ -0,0 +1,66 @@
+package wcode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCloneFunction(t *testing.T) {
+	moduleCtx := NewModuleContext(NewCodeParser(cloneModuleCode).Parse())
+	fnDef, _ := moduleCtx.Function("$f")
+
+	cloneDef, err := moduleCtx.CloneFunction(fnDef, "f_clone", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cloneDef.Name != "$f_clone" || cloneDef.Exported != nil || len(cloneDef.Params) != 2 {
+		t.Errorf("invalid clone %+v", cloneDef)
+	}
+	if copyDef, err := moduleCtx.CloneFunction(fnDef, "f_copy", nil); err != nil || copyDef.Name != "$f_copy" {
+		t.Errorf("expected a second clone named $f_copy, got %v (%v)", copyDef, err)
+	}
+	specDef, err := moduleCtx.CloneFunction(fnDef, "f5", map[string]string{"n": "5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if specDef.Name != "$f5" || len(specDef.Params) != 1 || specDef.TypeName == fnDef.TypeName {
+		t.Errorf("invalid specialized function %+v", specDef)
+	}
+	for _, test := range []struct {
+		name      string
+		constants map[string]string
+	}{
+		{"f_clone", nil},
+		{"f x", nil},
+		{"fx", map[string]string{"x": "1"}},
+		{"fn", map[string]string{"n": "(call $g)"}},
+		{"fn", map[string]string{"n": "4294967296"}},
+	} {
+		if _, err := moduleCtx.CloneFunction(fnDef, test.name, test.constants); err == nil {
+			t.Errorf("expected error cloning %s with %v", test.name, test.constants)
+		}
+	}
+	indexed, _ := moduleCtx.Function("$g")
+	if _, err := moduleCtx.CloneFunction(indexed, "g1", map[string]string{"a": "1"}); err == nil {
+		t.Error("expected error specializing a function with locals by index")
+	}
+
+	code := moduleCtx.String()
+	for _, expected := range []string{`(local $n i32)`, `(local.set $n (i32.const 5))`, `(func $f (export "f")`} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("module does not contain %q:\n%s", expected, code)
+		}
+	}
+	if strings.Count(code, `(export "f"`) != 1 {
+		t.Errorf("clone must not keep the inline export:\n%s", code)
+	}
+}
+
+var cloneModuleCode = `
+(module
+	(type $t0 (func (param i32 i32) (result i32)))
+	(func $f (export "f") (type $t0) (param $n i32) (param $m i32) (result i32)
+		(i32.add (local.get $n) (local.get $m)))
+	(func $g (type $t0) (param $a i32) (param $b i32) (result i32)
+		(i32.add (local.get 0) (local.get 1))))
+`
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
 
 	"joao/wasm-manipulator/internal/wgenerator"
 	"joao/wasm-manipulator/internal/wparser/variable"
@@ -15,6 +17,7 @@ import (
 // ModuleContext contains the context data for some web assembly code module.
 type ModuleContext struct {
 	NeedJS          bool
+	PureWasm        bool
 	FunctionAlias   map[string]string
 	GlobalAlias     map[string]string
 	entryBlock      Block
@@ -26,9 +29,20 @@ type ModuleContext struct {
 	globals         map[string]*GlobalDefinition
 	exportGlobals   map[string]*GlobalDefinition
 	importGlobals   map[string]map[string]*GlobalDefinition
+	memories        map[string]*MemoryDefinition
+	tables          map[string]*TableDefinition
+	dataSegments    []*DataSegmentDefinition
+	elemSegments    []*ElementSegmentDefinition
 	runtimeChanges  map[string]*runtimeChanges
 	glueFunctions   *glueFunctionsState
 	evalsToRemove   []Block
+	usePureRuntime  bool
+	errorContexts   []*runtimeErrorContext
+	composites      []*runtimeComposite
+	glueGroups      []string
+	staticData      *staticData
+	changeOrigins   *changeOrigins
+	instrTypes      *instructionTypes
 }
 
 // NewModuleContext is the constructor for ModuleContext.
@@ -54,8 +68,13 @@ func newModuleContext(entryBlock Block) *ModuleContext {
 		globals:         make(map[string]*GlobalDefinition),
 		exportGlobals:   make(map[string]*GlobalDefinition),
 		importGlobals:   make(map[string]map[string]*GlobalDefinition),
+		memories:        make(map[string]*MemoryDefinition),
+		tables:          make(map[string]*TableDefinition),
 		runtimeChanges:  make(map[string]*runtimeChanges),
 		glueFunctions:   newGlueFunctionsState(),
+		staticData:      newStaticData(),
+		changeOrigins:   newChangeOrigins(),
+		instrTypes:      newInstructionTypes(),
 	}
 }
 
@@ -96,6 +115,18 @@ func (ctx *ModuleContext) FillContext(block Block) {
 	globalVisitor := newGlobalContextVisitor(ctx)
 	block.Traverse(globalVisitor)
 
+	// Fill memories and tables.
+	memoryVisitor := newMemoryContextVisitor(ctx)
+	block.Traverse(memoryVisitor)
+	tableVisitor := newTableContextVisitor(ctx)
+	block.Traverse(tableVisitor)
+
+	// Fill data and element segments.
+	dataVisitor := newDataContextVisitor(ctx)
+	block.Traverse(dataVisitor)
+	elemVisitor := newElemContextVisitor(ctx)
+	block.Traverse(elemVisitor)
+
 	// Fill exported info.
 	exportedVisitor := newExportedContextVisitor(ctx)
 	block.Traverse(exportedVisitor)
@@ -103,6 +134,10 @@ func (ctx *ModuleContext) FillContext(block Block) {
 	// Fill start function.
 	startFunctionVisitor := newStartFunctionContextVisitor(ctx)
 	block.Traverse(startFunctionVisitor)
+
+	// Fold the flat code of the functions.
+	foldVisitor := newFoldContextVisitor(ctx)
+	block.Traverse(foldVisitor)
 }
 
 // Function returns the function definition by its name.
@@ -146,6 +181,66 @@ func (ctx *ModuleContext) StartFunction() (*FunctionDefinition, bool) {
 	return ctx.startFunction, ctx.startFunction != nil
 }
 
+// Memory returns the memory definition by its name or index.
+func (ctx *ModuleContext) Memory(ref string) (*MemoryDefinition, bool) {
+	if res, ok := ctx.memories[ref]; ok {
+		return res, true
+	}
+	for _, mem := range ctx.memories {
+		if strconv.Itoa(mem.Index(ctx)) == ref {
+			return mem, true
+		}
+	}
+	return nil, false
+}
+
+// Memories returns the list of all memory definitions sorted by index.
+func (ctx *ModuleContext) Memories() []*MemoryDefinition {
+	var res []*MemoryDefinition
+	for _, mem := range ctx.memories {
+		res = append(res, mem)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Index(ctx) < res[j].Index(ctx)
+	})
+	return res
+}
+
+// Table returns the table definition by its name or index.
+func (ctx *ModuleContext) Table(ref string) (*TableDefinition, bool) {
+	if res, ok := ctx.tables[ref]; ok {
+		return res, true
+	}
+	for _, table := range ctx.tables {
+		if strconv.Itoa(table.Index(ctx)) == ref {
+			return table, true
+		}
+	}
+	return nil, false
+}
+
+// Tables returns the list of all table definitions sorted by index.
+func (ctx *ModuleContext) Tables() []*TableDefinition {
+	var res []*TableDefinition
+	for _, table := range ctx.tables {
+		res = append(res, table)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Index(ctx) < res[j].Index(ctx)
+	})
+	return res
+}
+
+// DataSegments returns the list of all data segment definitions.
+func (ctx *ModuleContext) DataSegments() []*DataSegmentDefinition {
+	return ctx.dataSegments
+}
+
+// ElementSegments returns the list of all element segment definitions.
+func (ctx *ModuleContext) ElementSegments() []*ElementSegmentDefinition {
+	return ctx.elemSegments
+}
+
 // AddGlobal adds a new global to the module.
 func (ctx *ModuleContext) AddGlobal(value string) (*GlobalDefinition, error) {
 	// Parses variable definition.
@@ -153,6 +248,9 @@ func (ctx *ModuleContext) AddGlobal(value string) (*GlobalDefinition, error) {
 	if err != nil {
 		return nil, fmt.Errorf("parsing global variable value %q: %v", value, err)
 	}
+	if err := validateOpaqueVariable(expr); err != nil {
+		return nil, fmt.Errorf("invalid global variable %q: %w", value, err)
+	}
 
 	// Transforms definition into code.
 	globalIndex := fmt.Sprintf("$%sg%d", wgenerator.CodeIndexPrefix, len(ctx.globals))
@@ -171,19 +269,32 @@ func (ctx *ModuleContext) AddGlobal(value string) (*GlobalDefinition, error) {
 
 // AddType adds a new type to the module.
 func (ctx *ModuleContext) AddType(function *wyaml.FunctionYAML) (*TypeDefinition, error) {
+	if err := checkFunctionResults(function); err != nil {
+		return nil, err
+	}
+
 	// Parse function data.
 	var params []string
 	for _, arg := range function.Args {
 		params = append(params, arg.Type)
 	}
-	result := function.Result
+	results := function.ResultTypes()
 
 	// Add type.
-	return ctx.addType(params, result)
+	return ctx.addType(params, results)
 }
 
 // AddFunction adds a new function to the module.
 func (ctx *ModuleContext) AddFunction(function *wyaml.FunctionYAML) (*FunctionDefinition, error) {
+	// Composite results are only supported on single result functions.
+	if results := function.ResultTypes(); len(results) > 1 {
+		for _, result := range results {
+			if !IsVarTypeStrPrimitive(result) {
+				return nil, fmt.Errorf("adding function: composite result %s is not supported on multi-value results", result)
+			}
+		}
+	}
+
 	// Prepare type data.
 	typeDef, err := ctx.resolveNewFunctionType(function)
 	if err != nil {
@@ -250,12 +361,111 @@ func (ctx *ModuleContext) AddLocal(value string, fnDef *FunctionDefinition) (*Fu
 	if err != nil {
 		return nil, fmt.Errorf("parsing local variable value %q: %v", value, err)
 	}
+	if err := validateOpaqueVariable(expr); err != nil {
+		return nil, fmt.Errorf("invalid local variable %q: %w", value, err)
+	}
 
 	// Generates an unique local index.
 	localIndex := fmt.Sprintf("$%sl%d", wgenerator.CodeIndexPrefix, len(fnDef.Locals))
 	return ctx.addLocal(localIndex, expr.GetType(), expr.GetValue(""), fnDef)
 }
 
+// AddMemory adds a new memory to the module.
+// returns an error if the module already has a memory, since the modules have a single memory.
+func (ctx *ModuleContext) AddMemory(memory *wyaml.MemoryYAML) (*MemoryDefinition, error) {
+	if err := validateLimits(memory.Min, memory.Max); err != nil {
+		return nil, fmt.Errorf("invalid memory limits: %w", err)
+	}
+	if len(ctx.memories) > 0 {
+		return nil, errors.New("the module already has a memory")
+	}
+
+	// Parses memory code.
+	memoryIndex := fmt.Sprintf("$%sm%d", wgenerator.CodeIndexPrefix, len(ctx.memories))
+	codeEl := NewCodeParser(wgenerator.MemoryToCode(memory, memoryIndex)).parse()
+	if len(codeEl.blocks) == 0 {
+		return nil, errors.New("error parsing memory code to add on module context")
+	}
+
+	// Adds memory element to module context.
+	ctx.addBlocks(codeEl)
+	return ctx.memories[memoryIndex], nil
+}
+
+// AddTable adds a new table to the module.
+func (ctx *ModuleContext) AddTable(table *wyaml.TableYAML) (*TableDefinition, error) {
+	if err := validateLimits(table.Min, table.Max); err != nil {
+		return nil, fmt.Errorf("invalid table limits: %w", err)
+	}
+
+	// Parses table code.
+	tableIndex := fmt.Sprintf("$%st%d", wgenerator.CodeIndexPrefix, len(ctx.tables))
+	codeEl := NewCodeParser(wgenerator.TableToCode(table, tableIndex)).parse()
+	if len(codeEl.blocks) == 0 {
+		return nil, errors.New("error parsing table code to add on module context")
+	}
+
+	// Adds table element to module context.
+	ctx.addBlocks(codeEl)
+	return ctx.tables[tableIndex], nil
+}
+
+// AddData adds a new active data segment to the module.
+// the segment targets the first memory of the module when no memory is provided.
+func (ctx *ModuleContext) AddData(data *wyaml.DataYAML) (*DataSegmentDefinition, error) {
+	if data.Memory != "" {
+		if _, ok := ctx.Memory(data.Memory); !ok {
+			return nil, fmt.Errorf("memory %s not found in module", data.Memory)
+		}
+	} else if len(ctx.memories) == 0 {
+		return nil, errors.New("module does not have a memory")
+	}
+	if data.Offset < 0 {
+		return nil, fmt.Errorf("invalid data offset %d", data.Offset)
+	}
+
+	// Parses data code.
+	dataIndex := fmt.Sprintf("$%sd%d", wgenerator.CodeIndexPrefix, len(ctx.dataSegments))
+	codeEl := NewCodeParser(wgenerator.DataToCode(data, dataIndex)).parse()
+	if len(codeEl.blocks) == 0 {
+		return nil, errors.New("error parsing data code to add on module context")
+	}
+
+	// Adds data element to module context.
+	ctx.addBlocks(codeEl)
+	return ctx.dataSegments[len(ctx.dataSegments)-1], nil
+}
+
+// AddElem adds a new active element segment to the module.
+// the segment targets the first table of the module when no table is provided.
+func (ctx *ModuleContext) AddElem(elem *wyaml.ElemYAML) (*ElementSegmentDefinition, error) {
+	if elem.Table != "" {
+		if _, ok := ctx.Table(elem.Table); !ok {
+			return nil, fmt.Errorf("table %s not found in module", elem.Table)
+		}
+	} else if len(ctx.tables) == 0 {
+		return nil, errors.New("module does not have a table")
+	}
+	if elem.Offset < 0 {
+		return nil, fmt.Errorf("invalid elem offset %d", elem.Offset)
+	}
+	for _, fnName := range elem.Functions {
+		if _, ok := ctx.functions[fnName]; !ok {
+			return nil, fmt.Errorf("function %s not found in module", fnName)
+		}
+	}
+
+	// Parses elem code.
+	codeEl := NewCodeParser(wgenerator.ElemToCode(elem)).parse()
+	if len(codeEl.blocks) == 0 {
+		return nil, errors.New("error parsing elem code to add on module context")
+	}
+
+	// Adds elem element to module context.
+	ctx.addBlocks(codeEl)
+	return ctx.elemSegments[len(ctx.elemSegments)-1], nil
+}
+
 // SetStartFunction sets a new start function for the module.
 func (ctx *ModuleContext) SetStartFunction(fn *FunctionDefinition) {
 	fn.IsStart = true
@@ -265,6 +475,7 @@ func (ctx *ModuleContext) SetStartFunction(fn *FunctionDefinition) {
 // ApplyRuntimeTransformations applies runtime transformations to module.
 func (ctx *ModuleContext) ApplyRuntimeTransformations() {
 	logrus.Infoln("Applying runtime modifications")
+	defer ctx.invalidateTypes(nil)
 
 	// Find start function.
 	startFnDef := ctx.startFunction
@@ -281,13 +492,22 @@ func (ctx *ModuleContext) ApplyRuntimeTransformations() {
 	}
 
 	// Add composite globals to start function.
-	logrus.Traceln("Handling runtime composite globals")
-	globalRuntimeVisitor := newGlobalRuntimeVisitor(ctx, startFnDef)
-	ctx.entryBlock.Traverse(globalRuntimeVisitor)
+	var globalsCount int
+	if ctx.PureWasm {
+		logrus.Traceln("Handling in-module composite globals")
+		pureGlobalVisitor := newPureGlobalCompositeVisitor(ctx, startFnDef)
+		ctx.entryBlock.Traverse(pureGlobalVisitor)
+		globalsCount = pureGlobalVisitor.foundCount
+	} else {
+		logrus.Traceln("Handling runtime composite globals")
+		globalRuntimeVisitor := newGlobalRuntimeVisitor(ctx, startFnDef)
+		ctx.entryBlock.Traverse(globalRuntimeVisitor)
+		globalsCount = globalRuntimeVisitor.foundCount
+	}
 
 	// Removes the start function if not needed.
 	if !hasStartFnDef {
-		if globalRuntimeVisitor.foundCount == 0 {
+		if globalsCount == 0 {
 			module := startFnDef.instr.getParent().(*Instruction)
 			err := module.removeChild(startFnDef.instr)
 			if err != nil {
@@ -306,20 +526,26 @@ func (ctx *ModuleContext) ApplyRuntimeTransformations() {
 		}
 	}
 
-	// Make sure every composite return is inside a return instruction.
-	logrus.Traceln("Handling runtime composite returns")
-	returnFuncVisitor := newCompositeReturnFuncVisitor(ctx)
-	ctx.entryBlock.Traverse(returnFuncVisitor)
-
-	// Make sure every instruction that is a call for a function that returns a composite value is suportted.
-	logrus.Traceln("Handling runtime calls to functions with composite returns")
-	callFuncReturnCompositeVisitor := newCallCompositeReturnFuncVisitor(returnFuncVisitor.fnDefs)
-	ctx.entryBlock.Traverse(callFuncReturnCompositeVisitor)
-
-	// Remove unknown web assembly types.
-	logrus.Traceln("Removing runtime composite types from module")
-	compositeVisitor := newCompositePropsVisitor()
-	ctx.entryBlock.Traverse(compositeVisitor)
+	if ctx.PureWasm {
+		// Replace composite values by handles of the in-module runtime.
+		logrus.Traceln("Handling in-module composite variables")
+		ctx.applyPureCompositeTransformations()
+	} else {
+		// Make sure every composite return is inside a return instruction.
+		logrus.Traceln("Handling runtime composite returns")
+		returnFuncVisitor := newCompositeReturnFuncVisitor(ctx)
+		ctx.entryBlock.Traverse(returnFuncVisitor)
+
+		// Make sure every instruction that is a call for a function that returns a composite value is suportted.
+		logrus.Traceln("Handling runtime calls to functions with composite returns")
+		callFuncReturnCompositeVisitor := newCallCompositeReturnFuncVisitor(returnFuncVisitor.fnDefs)
+		ctx.entryBlock.Traverse(callFuncReturnCompositeVisitor)
+
+		// Remove unknown web assembly types.
+		logrus.Traceln("Removing runtime composite types from module")
+		compositeVisitor := newCompositePropsVisitor()
+		ctx.entryBlock.Traverse(compositeVisitor)
+	}
 
 	// Apply runtime transformations.
 	logrus.Traceln("Executing runtime expressions/references")
@@ -327,9 +553,12 @@ func (ctx *ModuleContext) ApplyRuntimeTransformations() {
 	ctx.entryBlock.Traverse(runtimeVisitor)
 
 	// Change composite imports to operations module.
-	logrus.Traceln("Changing composite imports to internal operations module")
-	importCompositeFuncVisitor := newImportCompositeFuncVisitor(ctx)
-	ctx.entryBlock.Traverse(importCompositeFuncVisitor)
+	// On pure mode, composite imports receive the runtime handles.
+	if !ctx.PureWasm {
+		logrus.Traceln("Changing composite imports to internal operations module")
+		importCompositeFuncVisitor := newImportCompositeFuncVisitor(ctx)
+		ctx.entryBlock.Traverse(importCompositeFuncVisitor)
+	}
 
 	// Fixes bug caused by WABT when parsing wasm to wat generating names.
 	// Elem name is generated wrongly.
@@ -355,6 +584,18 @@ func (ctx *ModuleContext) ApplyRuntimeTransformations() {
 		}
 	}
 
+	// Add the static data to the module memory.
+	logrus.Traceln("Adding static data segments to module")
+	if err := ctx.addDataSegments(); err != nil {
+		logrus.Fatalf("adding static data segments: %v", err)
+	}
+
+	// Add the in-module runtime for the composite values.
+	if ctx.PureWasm {
+		logrus.Traceln("Adding in-module runtime to module")
+		ctx.addPureRuntime()
+	}
+
 	// Add glue code for the runtime changes work.
 	logrus.Traceln("Adding glue functions to module")
 	if err := ctx.addGlueFunctions(); err != nil {
@@ -480,6 +721,15 @@ func (ctx *ModuleContext) setImportFunction(moduleName, exportName string, fn *F
 	ctx.importFunctions[moduleName][exportName] = fn
 }
 
+// importFunctionsCount returns the number of imported functions.
+func (ctx *ModuleContext) importFunctionsCount() int {
+	var res int
+	for _, fns := range ctx.importFunctions {
+		res += len(fns)
+	}
+	return res
+}
+
 // setExportFunction sets a new exported function definition.
 func (ctx *ModuleContext) setExportFunction(exportName string, fn *FunctionDefinition) {
 	ctx.exportFunctions[exportName] = fn
@@ -505,6 +755,16 @@ func (ctx *ModuleContext) setImportGlobal(moduleName, exportName string, global
 	ctx.importGlobals[moduleName][exportName] = global
 }
 
+// setMemory sets a new memory definition.
+func (ctx *ModuleContext) setMemory(name string, mem *MemoryDefinition) {
+	ctx.memories[name] = mem
+}
+
+// setTable sets a new table definition.
+func (ctx *ModuleContext) setTable(name string, table *TableDefinition) {
+	ctx.tables[name] = table
+}
+
 // setExportGlobal sets a new export global definition.
 func (ctx *ModuleContext) setExportGlobal(exportName string, global *GlobalDefinition) {
 	ctx.exportGlobals[exportName] = global
@@ -580,10 +840,10 @@ func (ctx *ModuleContext) addCustomImportFunction(functionIndex string, typeDef
 }
 
 // addType adds a new type to the module.
-func (ctx *ModuleContext) addType(params []string, result string) (*TypeDefinition, error) {
+func (ctx *ModuleContext) addType(params, results []string) (*TypeDefinition, error) {
 	// Transforms definition into code.
 	typeIndex := fmt.Sprintf("$%st%d", wgenerator.CodeIndexPrefix, len(ctx.types))
-	code := wgenerator.FunctionTypeToCode(params, result, typeIndex)
+	code := wgenerator.FunctionTypeToCode(params, results, typeIndex)
 
 	// Parses variable code.
 	codeEl := NewCodeParser(code).parse()
@@ -634,6 +894,7 @@ func (ctx *ModuleContext) addLocal(localIndex, localType, localValue string, fnD
 	// Adds variable element to function context.
 	local := newFunctionLocalDefinition(localBlockInstr, localIndex, localType, localValue, len(fnDef.Locals))
 	fnDef.addLocal(local, valueBlock)
+	ctx.invalidateTypes(fnDef)
 	return local, nil
 }
 
@@ -682,12 +943,15 @@ func (ctx *ModuleContext) addBlocks(entry *element) {
 
 // resolveNewFunctionType resolves the type definition for some new function.
 func (ctx *ModuleContext) resolveNewFunctionType(function *wyaml.FunctionYAML) (*TypeDefinition, error) {
+	if err := checkFunctionResults(function); err != nil {
+		return nil, err
+	}
 	var typeDef *TypeDefinition
 	for _, def := range ctx.types {
 		if ok := compareArguments(function.Args, def.Params); !ok {
 			continue
 		}
-		if function.Result != def.Result {
+		if !equalTypes(function.ResultTypes(), def.Results) {
 			continue
 		}
 		typeDef = def
@@ -704,6 +968,15 @@ func (ctx *ModuleContext) resolveNewFunctionType(function *wyaml.FunctionYAML) (
 	return typeDef, nil
 }
 
+// checkFunctionResults validates the results of some function input.
+// the single result and the list of results cannot be both defined.
+func checkFunctionResults(function *wyaml.FunctionYAML) error {
+	if function.Result != "" && len(function.Results) > 0 {
+		return errors.New("result and results cannot be both defined")
+	}
+	return nil
+}
+
 // typesMapSign creates a map with the type definitions.
 // the map key is the type signature value.
 func (ctx *ModuleContext) typesMapSign() map[string]*TypeDefinition {
@@ -728,6 +1001,7 @@ func (ctx *ModuleContext) addGlueFunctions() error {
 		if err != nil {
 			return fmt.Errorf("operation glue functions: %w", err)
 		}
+		ctx.glueGroups = append(ctx.glueGroups, "operations")
 	}
 
 	// Check args functions.
@@ -740,6 +1014,7 @@ func (ctx *ModuleContext) addGlueFunctions() error {
 		if err != nil {
 			return fmt.Errorf("args glue functions: %w", err)
 		}
+		ctx.glueGroups = append(ctx.glueGroups, "args")
 	}
 
 	// Check zone functions.
@@ -753,6 +1028,7 @@ func (ctx *ModuleContext) addGlueFunctions() error {
 			if err != nil {
 				return fmt.Errorf("zone glue functions: %w", err)
 			}
+			ctx.glueGroups = append(ctx.glueGroups, "zone")
 			break
 		}
 	}
@@ -767,6 +1043,7 @@ func (ctx *ModuleContext) addGlueFunctions() error {
 		if err != nil {
 			return fmt.Errorf("returns glue functions: %w", err)
 		}
+		ctx.glueGroups = append(ctx.glueGroups, "returns")
 	}
 
 	// Check error functions.
@@ -779,6 +1056,7 @@ func (ctx *ModuleContext) addGlueFunctions() error {
 		if err != nil {
 			return fmt.Errorf("error glue functions: %w", err)
 		}
+		ctx.glueGroups = append(ctx.glueGroups, "errors")
 	}
 	ctx.NeedJS = needJS
 	return nil
@@ -787,10 +1065,14 @@ func (ctx *ModuleContext) addGlueFunctions() error {
 // addGlueFunction adds the glue code to a specific function.
 func (ctx *ModuleContext) addGlueFunction(fns []*wgenerator.ImportFunctionDef, types map[string]*TypeDefinition) error {
 	for _, fn := range fns {
-		typeSignature := typeSignature(fn.Result, fn.Params)
+		var results []string
+		if fn.Result != "" {
+			results = []string{fn.Result}
+		}
+		typeSignature := typeSignature(results, fn.Params)
 		typeDef, ok := types[typeSignature]
 		if !ok {
-			typeDefAux, err := ctx.addType(fn.Params, fn.Result)
+			typeDefAux, err := ctx.addType(fn.Params, results)
 			if err != nil {
 				return fmt.Errorf("adding new type: %w", err)
 			}
@@ -818,6 +1100,24 @@ func newGlueFunctionsState() *glueFunctionsState {
 	return &glueFunctionsState{args: make(map[Block]struct{})}
 }
 
+// validateOpaqueVariable returns an error if some variable uses reference or vector types in an unsupported way.
+// these can only be declared as simple variables with no initial value, i.e., initialized with null or zero.
+func validateOpaqueVariable(expr *variable.Expr) error {
+	typ := expr.GetType()
+	if isVarTypeOpaque(varType(typ)) {
+		if expr.Value != nil {
+			return fmt.Errorf("variable of type %s cannot have an initial value", typ)
+		}
+		return nil
+	}
+	for _, t := range expr.Type {
+		if isVarTypeOpaque(varType(t)) {
+			return fmt.Errorf("composite type %s cannot contain values of type %s", typ, t)
+		}
+	}
+	return nil
+}
+
 // compareArguments compares input arguments with the module parameters.
 func compareArguments(args []wyaml.FunctionArgYAML, params []string) bool {
 	if len(args) != len(params) {
@@ -830,3 +1130,16 @@ func compareArguments(args []wyaml.FunctionArgYAML, params []string) bool {
 	}
 	return true
 }
+
+// equalTypes returns if two lists of types are equal.
+func equalTypes(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
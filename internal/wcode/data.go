@@ -0,0 +1,157 @@
+package wcode
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"joao/wasm-manipulator/internal/wgenerator"
+	"joao/wasm-manipulator/internal/wlang"
+)
+
+// memoryPageSize is the size in bytes of a web assembly memory page.
+const memoryPageSize = 65536
+
+// staticDataAddress is the prefix of the placeholders used for the addresses of the static strings.
+// the addresses are only known when all the strings are stored, since they are placed in sorted order.
+const staticDataAddress = "$wmr_data"
+
+// heapBaseExport is the export used by the C and Rust toolchains for the start of the heap.
+// the heap grows from this address to the end of the memory, so the static strings cannot be placed there.
+const heapBaseExport = "__heap_base"
+
+// staticData contains the static strings stored on the module memory.
+// the strings are placed after the initial memory of the module, which is increased to fit them.
+type staticData struct {
+	mutex    *sync.Mutex
+	resolved bool
+	memory   *Instruction
+	base     int
+	values   map[string]string
+}
+
+// newStaticData is a constructor for staticData.
+func newStaticData() *staticData {
+	return &staticData{
+		mutex:  new(sync.Mutex),
+		values: make(map[string]string),
+	}
+}
+
+// InternData stores a static string on the module memory.
+// identical strings are only stored once.
+// returns the placeholder for the address and the length in bytes of the string.
+func (ctx *ModuleContext) InternData(value string) (string, int, error) {
+	data := ctx.staticData
+	data.mutex.Lock()
+	defer data.mutex.Unlock()
+
+	if !data.resolved {
+		if _, ok := ctx.exportGlobals[heapBaseExport]; ok {
+			return "", 0, fmt.Errorf("static data cannot be stored on a module with a heap (%s)", heapBaseExport)
+		}
+		memoryVisitor := newMemoryInstrsVisitor()
+		ctx.entryBlock.TraverseConditional(memoryVisitor)
+		if memoryVisitor.found {
+			if instrImported(memoryVisitor.instr) != nil {
+				return "", 0, errors.New("static data cannot be stored on an imported memory")
+			}
+			if name, ok := memorySizeInstruction(ctx.entryBlock); ok {
+				return "", 0, fmt.Errorf("static data cannot be stored on a memory managed by the module (%s)", name)
+			}
+			limits := instrLimits(memoryVisitor.instr)
+			if len(limits) == 0 {
+				return "", 0, errors.New("unable to find the initial size of the module memory")
+			}
+			initial, _ := strconv.Atoi(limits[0].String())
+			data.memory = memoryVisitor.instr
+			data.base = initial * memoryPageSize
+		}
+		data.resolved = true
+	}
+
+	address, ok := data.values[value]
+	if !ok {
+		address = fmt.Sprintf("%s%d", staticDataAddress, len(data.values))
+		data.values[value] = address
+	}
+	return address, len(value), nil
+}
+
+// addDataSegments adds the stored static strings to the module.
+// the strings are placed in sorted order, and their address placeholders are replaced by the final addresses.
+// the module memory is increased by the pages needed, or created when the module does not have one.
+func (ctx *ModuleContext) addDataSegments() error {
+	data := ctx.staticData
+	if len(data.values) == 0 {
+		return nil
+	}
+	values := make([]string, 0, len(data.values))
+	for v := range data.values {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+
+	var sb strings.Builder
+	addresses := make(map[string]string, len(values))
+	for _, v := range values {
+		addresses[data.values[v]] = strconv.Itoa(data.base + sb.Len())
+		sb.WriteString(v)
+	}
+	for _, t := range collectTexts(ctx.entryBlock) {
+		if address, ok := addresses[t.String()]; ok {
+			setText(t, address)
+		}
+	}
+
+	pages := (sb.Len() + memoryPageSize - 1) / memoryPageSize
+	if data.memory == nil {
+		ctx.addBlocks(NewCodeParser(wgenerator.GetDataMemoryCode(pages)).parse())
+	} else {
+		limits := instrLimits(data.memory)
+		initial, _ := strconv.Atoi(limits[0].String())
+		setText(limits[0], strconv.Itoa(initial+pages))
+		if len(limits) > 1 {
+			if maximum, _ := strconv.Atoi(limits[1].String()); maximum < initial+pages {
+				setText(limits[1], strconv.Itoa(initial+pages))
+			}
+		}
+	}
+	ctx.addBlocks(NewCodeParser(wgenerator.GetDataSegmentCode(data.base, sb.String())).parse())
+	return nil
+}
+
+// memorySizeInstruction returns the name of the first instruction that reads or grows the memory size.
+// a module using them manages the memory after its initial size, e.g. with its own allocator.
+func memorySizeInstruction(block Block) (string, bool) {
+	for _, instr := range collectInstrs(block) {
+		if instr.name == wlang.CodeBlockNameGrowMemory || instr.name == wlang.CodeBlockNameCurrentMemory {
+			return instr.name, true
+		}
+	}
+	return "", false
+}
+
+// instrLimits returns the text blocks with the limits (initial and maximum) of some memory or table instruction.
+func instrLimits(instr *Instruction) []*text {
+	var res []*text
+	for _, v := range instr.values {
+		vText, ok := v.(*text)
+		if !ok {
+			continue
+		}
+		if _, err := strconv.Atoi(vText.String()); err == nil {
+			res = append(res, vText)
+		}
+	}
+	return res
+}
+
+// setText replaces the value of a text block.
+func setText(t *text, value string) {
+	t.sb.Reset()
+	t.sb.WriteString(value)
+}
@@ -0,0 +1,62 @@
+package wcode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestData_InternData(t *testing.T) {
+	moduleCtx := NewModuleContext(NewCodeParser(`(module (memory $m0 2 2) (func $f1))`).Parse())
+	var addresses []string
+	for i, test := range []struct {
+		value  string
+		length int
+	}{
+		{"say \"hi\"\n", 9},
+		{"entering $f1", 12},
+		{"say \"hi\"\n", 9},
+	} {
+		address, length, err := moduleCtx.InternData(test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if length != test.length {
+			t.Errorf("test %d: expected length %d, got %d", i, test.length, length)
+		}
+		addresses = append(addresses, address)
+	}
+	if addresses[0] != addresses[2] || addresses[0] == addresses[1] {
+		t.Errorf("expected identical strings to share the address, got %v", addresses)
+	}
+
+	fnDef, _ := moduleCtx.Function("$f1")
+	fnDef.AddCode("(drop (i32.const " + addresses[0] + ")) (drop (i32.const " + addresses[1] + "))")
+	if err := moduleCtx.addDataSegments(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The strings are placed in sorted order, independently of the order they were stored.
+	code := moduleCtx.String()
+	for _, expected := range []string{
+		"(memory $m0 3 3)",
+		`(data (i32.const 131072) "entering $f1say \22hi\22\0a")`,
+		"(drop (i32.const 131084)) (drop (i32.const 131072))",
+	} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("module does not contain %q:\n%s", expected, code)
+		}
+	}
+
+	moduleCtx = NewModuleContext(NewCodeParser(`(module (import "env" "memory" (memory $m0 1)))`).Parse())
+	if _, _, err := moduleCtx.InternData("value"); err == nil {
+		t.Error("expected error for an imported memory")
+	}
+	moduleCtx = NewModuleContext(NewCodeParser(`(module (memory $m0 1) (global $__heap_base i32 (i32.const 1024)) (export "__heap_base" (global $__heap_base)))`).Parse())
+	if _, _, err := moduleCtx.InternData("value"); err == nil {
+		t.Error("expected error for a module with a heap")
+	}
+	moduleCtx = NewModuleContext(NewCodeParser(`(module (memory $m0 1) (func $alloc (result i32) (memory.grow (i32.const 1))))`).Parse())
+	if _, _, err := moduleCtx.InternData("value"); err == nil {
+		t.Error("expected error for a memory managed by the module")
+	}
+}
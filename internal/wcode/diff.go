@@ -0,0 +1,555 @@
+package wcode
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// diffContextLines is the number of unchanged lines around each change of a diff hunk.
+const diffContextLines = 3
+
+// ModuleDiff contains the differences between an original module and the transformed one.
+type ModuleDiff struct {
+	AddedFunctions   []string
+	RemovedFunctions []string
+	AddedGlobals     []string
+	RemovedGlobals   []string
+	AddedImports     []string
+	RemovedImports   []string
+	AddedExports     []string
+	RemovedExports   []string
+	Functions        []*FunctionDiff
+}
+
+// FunctionDiff contains the changes to the body of some function.
+type FunctionDiff struct {
+	Original    string
+	Transformed string
+	Hunks       []*DiffHunk
+}
+
+// DiffHunk contains a unified diff hunk, with the advices and join-points that produced it.
+type DiffHunk struct {
+	OriginalStart    int
+	OriginalLines    int
+	TransformedStart int
+	TransformedLines int
+	Lines            []string
+	Origins          []*ChangeOrigin
+}
+
+// ChangeOrigin contains the advice and join-point that changed some function.
+type ChangeOrigin struct {
+	Advice    string
+	JoinPoint string
+	lines     []string
+}
+
+// changeOrigins contains the origins of the changes to the module functions.
+type changeOrigins struct {
+	mutex  *sync.Mutex
+	values map[string][]*ChangeOrigin
+}
+
+// newChangeOrigins is a constructor for changeOrigins.
+func newChangeOrigins() *changeOrigins {
+	return &changeOrigins{
+		mutex:  new(sync.Mutex),
+		values: make(map[string][]*ChangeOrigin),
+	}
+}
+
+// AddChangeOrigin stores the advice and join-point that are going to change some function.
+// it must be called before the advice code is applied to the join-point blocks.
+func (ctx *ModuleContext) AddChangeOrigin(fnDef *FunctionDefinition, advice, joinPoint string, blocks []*JoinPointBlock) {
+	origin := &ChangeOrigin{Advice: advice, JoinPoint: joinPoint}
+	for _, jpB := range blocks {
+		if instr, ok := jpB.block.(*Instruction); ok {
+			origin.lines = append(origin.lines, strings.TrimSpace(instructionLine(instr, 0)))
+		}
+	}
+	origins := ctx.changeOrigins
+	origins.mutex.Lock()
+	defer origins.mutex.Unlock()
+	origins.values[fnDef.Name] = append(origins.values[fnDef.Name], origin)
+}
+
+// Diff returns the differences between some original module and the transformed module.
+// the functions are aligned by their index or, when the index changes, by their exported or imported name.
+func (ctx *ModuleContext) Diff(original *ModuleContext) *ModuleDiff {
+	res := &ModuleDiff{}
+
+	matched := make(map[*FunctionDefinition]bool)
+	for _, fnDef := range sortedFunctions(ctx) {
+		origDef, ok := original.alignedFunction(fnDef)
+		if !ok || matched[origDef] {
+			res.AddedFunctions = append(res.AddedFunctions, fnDef.Name)
+			continue
+		}
+		matched[origDef] = true
+		hunks := unifiedDiff(functionLines(origDef), functionLines(fnDef))
+		if len(hunks) == 0 {
+			continue
+		}
+		origins := ctx.changeOrigins.values[fnDef.Name]
+		for _, hunk := range hunks {
+			hunk.Origins = hunkOrigins(hunk, origins)
+		}
+		res.Functions = append(res.Functions, &FunctionDiff{Original: origDef.Name, Transformed: fnDef.Name, Hunks: hunks})
+	}
+	for _, origDef := range sortedFunctions(original) {
+		if !matched[origDef] {
+			res.RemovedFunctions = append(res.RemovedFunctions, origDef.Name)
+		}
+	}
+
+	res.AddedGlobals, res.RemovedGlobals = diffNames(globalNames(original), globalNames(ctx))
+	res.AddedImports, res.RemovedImports = diffNames(importNames(original), importNames(ctx))
+	res.AddedExports, res.RemovedExports = diffNames(exportNames(original), exportNames(ctx))
+	return res
+}
+
+// String returns the differences as a report with unified diffs.
+func (md *ModuleDiff) String() string {
+	var sb strings.Builder
+	writeNames := func(title string, names []string) {
+		if len(names) == 0 {
+			return
+		}
+		sb.WriteString(fmt.Sprintf("%s (%d):\n", title, len(names)))
+		for _, name := range names {
+			sb.WriteString(fmt.Sprintf("  %s\n", name))
+		}
+	}
+	writeNames("added functions", md.AddedFunctions)
+	writeNames("removed functions", md.RemovedFunctions)
+	writeNames("added globals", md.AddedGlobals)
+	writeNames("removed globals", md.RemovedGlobals)
+	writeNames("added imports", md.AddedImports)
+	writeNames("removed imports", md.RemovedImports)
+	writeNames("added exports", md.AddedExports)
+	writeNames("removed exports", md.RemovedExports)
+
+	for _, fn := range md.Functions {
+		sb.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", fn.Original, fn.Transformed))
+		for _, hunk := range fn.Hunks {
+			sb.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@", hunk.OriginalStart, hunk.OriginalLines, hunk.TransformedStart, hunk.TransformedLines))
+			for i, origin := range hunk.Origins {
+				if i > 0 {
+					sb.WriteString(",")
+				}
+				sb.WriteString(fmt.Sprintf(" %s (%s)", origin.Advice, origin.JoinPoint))
+			}
+			sb.WriteString("\n")
+			for _, line := range hunk.Lines {
+				sb.WriteString(line + "\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
+// Summary returns the number of changes of each kind, e.g. "2 changed functions (3 hunks), 1 added globals".
+func (md *ModuleDiff) Summary() string {
+	var parts []string
+	if len(md.Functions) > 0 {
+		var hunks int
+		for _, fn := range md.Functions {
+			hunks += len(fn.Hunks)
+		}
+		parts = append(parts, fmt.Sprintf("%d changed functions (%d hunks)", len(md.Functions), hunks))
+	}
+	for _, count := range []struct {
+		title string
+		names []string
+	}{
+		{"added functions", md.AddedFunctions},
+		{"removed functions", md.RemovedFunctions},
+		{"added globals", md.AddedGlobals},
+		{"removed globals", md.RemovedGlobals},
+		{"added imports", md.AddedImports},
+		{"removed imports", md.RemovedImports},
+		{"added exports", md.AddedExports},
+		{"removed exports", md.RemovedExports},
+	} {
+		if len(count.names) > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", len(count.names), count.title))
+		}
+	}
+	if len(parts) == 0 {
+		return "no changes"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// alignedFunction returns the function of the module aligned with some function of another module.
+func (ctx *ModuleContext) alignedFunction(fnDef *FunctionDefinition) (*FunctionDefinition, bool) {
+	if res, ok := ctx.functions[fnDef.Name]; ok {
+		return res, true
+	}
+	if fnDef.Exported != nil {
+		if res, ok := ctx.exportFunctions[fnDef.Exported.ExportName]; ok {
+			return res, true
+		}
+	}
+	if fnDef.Imported != nil {
+		if res, ok := ctx.importFunctions[fnDef.Imported.ModuleName][fnDef.Imported.ExportName]; ok {
+			return res, true
+		}
+	}
+	return nil, false
+}
+
+// sortedFunctions returns the functions of some module sorted by their order.
+func sortedFunctions(ctx *ModuleContext) []*FunctionDefinition {
+	res := ctx.Functions()
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Index(ctx) < res[j].Index(ctx)
+	})
+	return res
+}
+
+// functionLines returns the code of some function with one instruction per line.
+func functionLines(fnDef *FunctionDefinition) []string {
+	if fnDef.instr == nil {
+		return nil
+	}
+	var res []string
+	appendInstructionLines(&res, fnDef.instr, 0)
+	return res
+}
+
+// appendInstructionLines appends the lines of some instruction, with the nested instructions indented.
+func appendInstructionLines(lines *[]string, instr *Instruction, depth int) {
+	*lines = append(*lines, instructionLine(instr, depth))
+	for _, child := range instructionChildren(instr.values) {
+		appendInstructionLines(lines, child, depth+1)
+	}
+}
+
+// instructionLine returns the line of some instruction, without the nested instructions.
+func instructionLine(instr *Instruction, depth int) string {
+	parts := []string{instr.name}
+	var appendValues func(values []Block)
+	appendValues = func(values []Block) {
+		for _, v := range values {
+			switch b := v.(type) {
+			case *Instruction:
+			case *element:
+				appendValues(b.blocks)
+			default:
+				parts = append(parts, v.String())
+			}
+		}
+	}
+	appendValues(instr.values)
+	return strings.Repeat("  ", depth) + strings.Join(parts, " ")
+}
+
+// instructionChildren returns the instructions nested on some values.
+func instructionChildren(values []Block) []*Instruction {
+	var res []*Instruction
+	for _, v := range values {
+		switch b := v.(type) {
+		case *Instruction:
+			res = append(res, b)
+		case *element:
+			res = append(res, instructionChildren(b.blocks)...)
+		}
+	}
+	return res
+}
+
+// hunkOrigins returns the origins that produced some hunk.
+// the origins of the join-points found on the hunk are preferred, otherwise all the function origins are returned.
+func hunkOrigins(hunk *DiffHunk, origins []*ChangeOrigin) []*ChangeOrigin {
+	var res []*ChangeOrigin
+	for _, origin := range origins {
+		if origin.matches(hunk) {
+			res = append(res, origin)
+		}
+	}
+	if len(res) == 0 {
+		return origins
+	}
+	return res
+}
+
+// matches returns if some join-point instruction is on the original lines of a hunk.
+func (co *ChangeOrigin) matches(hunk *DiffHunk) bool {
+	for _, line := range hunk.Lines {
+		if strings.HasPrefix(line, "+") {
+			continue
+		}
+		for _, jpLine := range co.lines {
+			if strings.TrimSpace(line[1:]) == jpLine {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unifiedDiff returns the unified diff hunks between two lists of lines.
+func unifiedDiff(a, b []string) []*DiffHunk {
+	// The common prefix and suffix are left out of the longest common subsequence table,
+	// since the transformations usually change a small part of the module.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	// Longest common subsequence table.
+	lcs := make([][]int, len(ma)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(mb)+1)
+	}
+	for i := len(ma) - 1; i >= 0; i-- {
+		for j := len(mb) - 1; j >= 0; j-- {
+			if ma[i] == mb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	// Edit script, where each edit is prefixed by ' ', '-' or '+'.
+	type edit struct {
+		op   byte
+		line string
+		i, j int
+	}
+	var edits []edit
+	for k := 0; k < prefix; k++ {
+		edits = append(edits, edit{' ', a[k], k, k})
+	}
+	i, j := 0, 0
+	for i < len(ma) || j < len(mb) {
+		switch {
+		case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+			edits = append(edits, edit{' ', ma[i], prefix + i, prefix + j})
+			i++
+			j++
+		case j < len(mb) && (i == len(ma) || lcs[i][j+1] > lcs[i+1][j]):
+			edits = append(edits, edit{'+', mb[j], prefix + i, prefix + j})
+			j++
+		default:
+			edits = append(edits, edit{'-', ma[i], prefix + i, prefix + j})
+			i++
+		}
+	}
+	for k := 0; k < suffix; k++ {
+		edits = append(edits, edit{' ', a[len(a)-suffix+k], len(a) - suffix + k, len(b) - suffix + k})
+	}
+
+	// Groups the changes with their context lines.
+	var res []*DiffHunk
+	for k := 0; k < len(edits); {
+		if edits[k].op == ' ' {
+			k++
+			continue
+		}
+		start := k - diffContextLines
+		if start < 0 {
+			start = 0
+		}
+		end := k
+		for unchanged := 0; end < len(edits) && unchanged <= 2*diffContextLines; end++ {
+			if edits[end].op == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		for end > k && edits[end-1].op == ' ' {
+			end--
+		}
+		end += diffContextLines
+		if end > len(edits) {
+			end = len(edits)
+		}
+		hunk := &DiffHunk{OriginalStart: edits[start].i + 1, TransformedStart: edits[start].j + 1}
+		for _, e := range edits[start:end] {
+			hunk.Lines = append(hunk.Lines, string(e.op)+e.line)
+			if e.op != '+' {
+				hunk.OriginalLines++
+			}
+			if e.op != '-' {
+				hunk.TransformedLines++
+			}
+		}
+		res = append(res, hunk)
+		k = end
+	}
+	return res
+}
+
+// diffNames returns the names that were added and removed from a list of names.
+func diffNames(original, transformed []string) ([]string, []string) {
+	inOriginal := make(map[string]bool, len(original))
+	for _, name := range original {
+		inOriginal[name] = true
+	}
+	inTransformed := make(map[string]bool, len(transformed))
+	for _, name := range transformed {
+		inTransformed[name] = true
+	}
+	var added, removed []string
+	for _, name := range transformed {
+		if !inOriginal[name] {
+			added = append(added, name)
+		}
+	}
+	for _, name := range original {
+		if !inTransformed[name] {
+			removed = append(removed, name)
+		}
+	}
+	return added, removed
+}
+
+// globalNames returns the sorted indexes of the module globals.
+func globalNames(ctx *ModuleContext) []string {
+	var res []string
+	for name := range ctx.globals {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// importNames returns the sorted names of the module imports.
+func importNames(ctx *ModuleContext) []string {
+	var res []string
+	for moduleName, fns := range ctx.importFunctions {
+		for fieldName := range fns {
+			res = append(res, fmt.Sprintf("%s.%s (func)", moduleName, fieldName))
+		}
+	}
+	for moduleName, globals := range ctx.importGlobals {
+		for fieldName := range globals {
+			res = append(res, fmt.Sprintf("%s.%s (global)", moduleName, fieldName))
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// exportNames returns the sorted names of the module exports.
+func exportNames(ctx *ModuleContext) []string {
+	var res []string
+	for name := range ctx.exportFunctions {
+		res = append(res, fmt.Sprintf("%s (func)", name))
+	}
+	for name := range ctx.exportGlobals {
+		res = append(res, fmt.Sprintf("%s (global)", name))
+	}
+	sort.Strings(res)
+	return res
+}
+
+// ModuleNames contains the names of the elements of a module, used to find the elements added by the transformations.
+type ModuleNames struct {
+	functions []string
+	globals   []string
+	imports   []string
+	locals    map[string][]string
+}
+
+// AddedElements contains the elements added to a module by the transformations.
+type AddedElements struct {
+	Functions []*AddedFunction
+	Globals   []*AddedGlobal
+	Imports   []string
+	Locals    []*AddedLocal
+}
+
+// AddedFunction contains an added function, with the name given by the transformations, if any.
+type AddedFunction struct {
+	Index int
+	Name  string
+	Alias string
+}
+
+// AddedGlobal contains an added global, with the name given by the transformations, if any.
+type AddedGlobal struct {
+	Name  string
+	Alias string
+	Type  string
+}
+
+// AddedLocal contains a local added to some function, with the name given by the transformations, if any.
+type AddedLocal struct {
+	Function      string
+	FunctionIndex int
+	Name          string
+	Alias         string
+	Type          string
+}
+
+// Names returns the names of the module elements.
+func (ctx *ModuleContext) Names() *ModuleNames {
+	res := &ModuleNames{
+		globals: globalNames(ctx),
+		imports: importNames(ctx),
+		locals:  make(map[string][]string),
+	}
+	for _, fnDef := range sortedFunctions(ctx) {
+		res.functions = append(res.functions, fnDef.Name)
+		for _, localDef := range fnDef.LocalsArr() {
+			res.locals[fnDef.Name] = append(res.locals[fnDef.Name], localDef.Name)
+		}
+	}
+	return res
+}
+
+// Added returns the elements of the module that are not on the names of the original module.
+// the locals of the added functions are also included.
+func (ctx *ModuleContext) Added(original *ModuleNames) *AddedElements {
+	res := &AddedElements{}
+	fns := sortedFunctions(ctx)
+	var fnNames []string
+	for _, fnDef := range fns {
+		fnNames = append(fnNames, fnDef.Name)
+	}
+	addedFns, _ := diffNames(original.functions, fnNames)
+	for _, name := range addedFns {
+		fnDef := ctx.functions[name]
+		res.Functions = append(res.Functions, &AddedFunction{Index: fnDef.Index(ctx), Name: name, Alias: ctx.FunctionAlias[name]})
+	}
+
+	addedGlobals, _ := diffNames(original.globals, globalNames(ctx))
+	for _, name := range addedGlobals {
+		globalDef := ctx.globals[name]
+		res.Globals = append(res.Globals, &AddedGlobal{Name: name, Alias: ctx.GlobalAlias[name], Type: globalDef.Type})
+	}
+	res.Imports, _ = diffNames(original.imports, importNames(ctx))
+
+	for _, fnDef := range fns {
+		var localNames []string
+		for _, localDef := range fnDef.LocalsArr() {
+			localNames = append(localNames, localDef.Name)
+		}
+		addedLocals, _ := diffNames(original.locals[fnDef.Name], localNames)
+		for _, name := range addedLocals {
+			res.Locals = append(res.Locals, &AddedLocal{
+				Function:      fnDef.Name,
+				FunctionIndex: fnDef.Index(ctx),
+				Name:          name,
+				Alias:         fnDef.Alias[name],
+				Type:          fnDef.Locals[name].Type,
+			})
+		}
+	}
+	return res
+}
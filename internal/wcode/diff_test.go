@@ -0,0 +1,88 @@
+package wcode
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestModuleContext_Diff(t *testing.T) {
+	original := NewModuleContext(NewCodeParser(diffOriginalCode).Parse())
+	moduleCtx := NewModuleContext(NewCodeParser(diffTransformedCode).Parse())
+
+	fnDef, _ := moduleCtx.Function("$f")
+	call := collectInstrs(fnDef.instr)[0]
+	for _, instr := range collectInstrs(fnDef.instr) {
+		if instr.name == instructionCodeCall {
+			call = instr
+		}
+	}
+	moduleCtx.AddChangeOrigin(fnDef, "trace", "call(log)", []*JoinPointBlock{{block: call}})
+
+	diff := moduleCtx.Diff(original)
+	if strings.Join(diff.AddedFunctions, ",") != "$wmr_f1" || strings.Join(diff.RemovedFunctions, ",") != "$other,$g" {
+		t.Errorf("unexpected added %v and removed %v functions", diff.AddedFunctions, diff.RemovedFunctions)
+	}
+	if strings.Join(diff.AddedExports, ",") != "f1 (func)" || strings.Join(diff.RemovedImports, ",") != "env.other (func)" {
+		t.Errorf("unexpected exports %v and imports %v", diff.AddedExports, diff.RemovedImports)
+	}
+	if len(diff.Functions) != 1 || len(diff.Functions[0].Hunks) != 1 {
+		t.Fatalf("expected a single changed hunk, got %+v", diff.Functions)
+	}
+	hunk := diff.Functions[0].Hunks[0]
+	if len(hunk.Origins) != 1 || hunk.Origins[0].Advice != "trace" {
+		t.Errorf("unexpected hunk origins %+v", hunk.Origins)
+	}
+
+	expectedSummary := "1 changed functions (1 hunks), 1 added functions, 2 removed functions, 1 removed imports, 1 added exports"
+	if summary := diff.Summary(); summary != expectedSummary {
+		t.Errorf("expected summary %q, got %q", expectedSummary, summary)
+	}
+
+	report := diff.String()
+	for _, expected := range []string{"--- $f\n+++ $f\n", "@@ -1,4 +1,5 @@ trace (call(log))", "+    i32.const 2", "-    i32.const 1"} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("report does not contain %q:\n%s", expected, report)
+		}
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	// The common lines around the change are not compared, so large modules are diffed quickly.
+	var a, b []string
+	for i := 0; i < 100000; i++ {
+		a = append(a, fmt.Sprintf("(nop) ;; %d", i))
+	}
+	b = append(append(append(b, a[:50000]...), "(unreachable)"), a[50001:]...)
+
+	hunks := unifiedDiff(a, b)
+	if len(hunks) != 1 {
+		t.Fatalf("expected a single hunk, got %d", len(hunks))
+	}
+	hunk := hunks[0]
+	if hunk.OriginalStart != 50001-diffContextLines || hunk.OriginalLines != 2*diffContextLines+1 || hunk.TransformedLines != 2*diffContextLines+1 {
+		t.Errorf("unexpected hunk position %+v", hunk)
+	}
+	if hunk.Lines[diffContextLines] != "-(nop) ;; 50000" || hunk.Lines[diffContextLines+1] != "+(unreachable)" {
+		t.Errorf("unexpected hunk lines %v", hunk.Lines)
+	}
+}
+
+const diffOriginalCode = `(module
+	(type $t0 (func (param i32)))
+	(type $t1 (func))
+	(import "env" "log" (func $log (type $t0)))
+	(import "env" "other" (func $other (type $t1)))
+	(func $f (type $t1)
+		(call $log (i32.const 1)))
+	(func $g (type $t1)))`
+
+const diffTransformedCode = `(module
+	(type $t0 (func (param i32)))
+	(type $t1 (func))
+	(import "env" "log" (func $log (type $t0)))
+	(func $f (type $t1)
+		(call $log (i32.const 2))
+		(call $wmr_f1))
+	(func $wmr_f1 (type $t1))
+	(export "f1" (func $wmr_f1)))`
@@ -0,0 +1,207 @@
+package wcode
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"joao/wasm-manipulator/internal/wgenerator"
+	"joao/wasm-manipulator/internal/wyaml"
+)
+
+// RenameExport renames some exported function.
+func (ctx *ModuleContext) RenameExport(exportName, newName string) error {
+	instr, fnDef, err := ctx.findExportFunction(exportName)
+	if err != nil {
+		return fmt.Errorf("renaming export: %w", err)
+	}
+	if err := ctx.validateNewExport(newName); err != nil {
+		return fmt.Errorf("renaming export: %w", err)
+	}
+	replaceQuotedValue(instr, 0, newName)
+
+	delete(ctx.exportFunctions, exportName)
+	ctx.setExportFunction(newName, fnDef)
+	if fnDef.Exported != nil && fnDef.Exported.ExportName == exportName {
+		fnDef.Exported = newExportedDefinition(newName)
+	}
+	return nil
+}
+
+// RemoveExport removes some exported function export.
+// the function is kept in the module.
+func (ctx *ModuleContext) RemoveExport(exportName string) error {
+	instr, fnDef, err := ctx.findExportFunction(exportName)
+	if err != nil {
+		return fmt.Errorf("removing export: %w", err)
+	}
+	module, ok := instr.getParent().(*Instruction)
+	if !ok {
+		return errors.New("removing export: export instruction must be on the module")
+	}
+	if err := module.removeChild(instr); err != nil {
+		return fmt.Errorf("removing export: %w", err)
+	}
+
+	delete(ctx.exportFunctions, exportName)
+	if fnDef.Exported != nil && fnDef.Exported.ExportName == exportName {
+		fnDef.Exported = nil
+		for name, v := range ctx.exportFunctions {
+			if v == fnDef {
+				fnDef.Exported = newExportedDefinition(name)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// AliasExport exports some exported function with another name.
+func (ctx *ModuleContext) AliasExport(exportName, alias string) error {
+	_, fnDef, err := ctx.findExportFunction(exportName)
+	if err != nil {
+		return fmt.Errorf("aliasing export: %w", err)
+	}
+	if err := ctx.validateNewExport(alias); err != nil {
+		return fmt.Errorf("aliasing export: %w", err)
+	}
+	ctx.addBlocks(NewCodeParser(wgenerator.ExportFunctionToCode(fnDef.Name, alias)).parse())
+	return nil
+}
+
+// RedirectImport changes the module and field of some imported function.
+func (ctx *ModuleContext) RedirectImport(moduleName, fieldName, newModuleName, newFieldName string) error {
+	fnDef, ok := ctx.ImportFunction(moduleName, fieldName)
+	if !ok {
+		return fmt.Errorf("redirecting import: function %s.%s is not imported", moduleName, fieldName)
+	}
+	if _, ok := ctx.ImportFunction(newModuleName, newFieldName); ok {
+		return fmt.Errorf("redirecting import: function %s.%s is already imported", newModuleName, newFieldName)
+	}
+	instr, ok := fnDef.instr.getParent().(*Instruction)
+	if !ok || instr.name != instructionImport {
+		return errors.New("redirecting import: import instruction not found")
+	}
+	replaceQuotedValue(instr, 0, newModuleName)
+	replaceQuotedValue(instr, 1, newFieldName)
+
+	ctx.removeImportFunction(moduleName, fieldName)
+	ctx.setImportFunction(newModuleName, newFieldName, fnDef)
+	fnDef.Imported = newImportedDefinition(newModuleName, newFieldName)
+	return nil
+}
+
+// ImplementImport replaces some imported function by an internal function that calls the implementation.
+// the function name is kept, so all the references to the imported function remain consistent.
+func (ctx *ModuleContext) ImplementImport(moduleName, fieldName string, impl *FunctionDefinition) (*FunctionDefinition, error) {
+	fnDef, ok := ctx.ImportFunction(moduleName, fieldName)
+	if !ok {
+		return nil, fmt.Errorf("implementing import: function %s.%s is not imported", moduleName, fieldName)
+	}
+	typeDef, ok := ctx.types[fnDef.TypeName]
+	if !ok {
+		return nil, fmt.Errorf("implementing import: type of function %s not found", fnDef.Name)
+	}
+	if !equalTypes(typeDef.Params, funcParamTypes(impl)) || !equalTypes(typeDef.Results, impl.Results) {
+		return nil, fmt.Errorf("implementing import: function %s does not match the type of %s.%s", impl.Name, moduleName, fieldName)
+	}
+	instr, ok := fnDef.instr.getParent().(*Instruction)
+	if !ok || instr.name != instructionImport {
+		return nil, errors.New("implementing import: import instruction not found")
+	}
+	module, ok := instr.getParent().(*Instruction)
+	if !ok {
+		return nil, errors.New("implementing import: import instruction must be on the module")
+	}
+
+	// Generates the function that calls the implementation.
+	function := &wyaml.FunctionYAML{Results: typeDef.Results}
+	args := make([]string, len(typeDef.Params))
+	for i, param := range typeDef.Params {
+		name := fmt.Sprintf("%sp%d", wgenerator.CodeIndexPrefix, i)
+		function.Args = append(function.Args, wyaml.FunctionArgYAML{Name: name, Type: param})
+		args[i] = wgenerator.GetVariableToCode("$"+name, true)
+	}
+	function.Code = fmt.Sprintf("(%s %s %s)", instructionCodeCall, impl.Name, strings.Join(args, " "))
+
+	// Removes the import.
+	if err := module.removeChild(instr); err != nil {
+		return nil, fmt.Errorf("implementing import: %w", err)
+	}
+	ctx.removeImportFunction(moduleName, fieldName)
+	delete(ctx.functions, fnDef.Name)
+	for _, v := range ctx.functions {
+		if v.Imported != nil && v.order > fnDef.order {
+			v.order--
+		}
+	}
+
+	// Adds the internal function with the same name.
+	resDef, err := ctx.addFunction(fnDef.Name, wgenerator.FunctionToCode(function, fnDef.Name, fnDef.TypeName))
+	if err != nil {
+		return nil, fmt.Errorf("implementing import: %w", err)
+	}
+	if fnDef.Exported != nil {
+		for name, v := range ctx.exportFunctions {
+			if v == fnDef {
+				ctx.setExportFunction(name, resDef)
+			}
+		}
+		resDef.Exported = fnDef.Exported
+	}
+	return resDef, nil
+}
+
+// ImportFunction returns the imported function definition by its module and field.
+func (ctx *ModuleContext) ImportFunction(moduleName, fieldName string) (*FunctionDefinition, bool) {
+	res, ok := ctx.importFunctions[moduleName][fieldName]
+	return res, ok
+}
+
+// findExportFunction returns the export instruction and the function definition of some exported function.
+func (ctx *ModuleContext) findExportFunction(exportName string) (*Instruction, *FunctionDefinition, error) {
+	fnDef, ok := ctx.exportFunctions[exportName]
+	if !ok {
+		return nil, nil, fmt.Errorf("function with export name %s not found", exportName)
+	}
+	exportVisitor := newExportInstrsVisitor()
+	ctx.entryBlock.Traverse(exportVisitor)
+	instr, ok := exportVisitor.Export(exportName)
+	if !ok {
+		return nil, nil, fmt.Errorf("export instruction with name %s not found", exportName)
+	}
+	return instr, fnDef, nil
+}
+
+// validateNewExport validates if some export name is not being used.
+func (ctx *ModuleContext) validateNewExport(exportName string) error {
+	if exportName == "" {
+		return errors.New("export name must not be empty")
+	}
+	exportVisitor := newExportInstrsVisitor()
+	ctx.entryBlock.Traverse(exportVisitor)
+	if _, ok := exportVisitor.Export(exportName); ok {
+		return fmt.Errorf("export name %s already exists", exportName)
+	}
+	return nil
+}
+
+// removeImportFunction removes an imported function definition.
+func (ctx *ModuleContext) removeImportFunction(moduleName, fieldName string) {
+	moduleMap, ok := ctx.importFunctions[moduleName]
+	if !ok {
+		return
+	}
+	delete(moduleMap, fieldName)
+	if len(moduleMap) == 0 {
+		delete(ctx.importFunctions, moduleName)
+	}
+}
+
+// replaceQuotedValue replaces the quoted value of some instruction at some index.
+func replaceQuotedValue(instr *Instruction, index int, value string) {
+	qt := NewCodeParser(strconv.Quote(value)).parse().blocks[0]
+	qt.setParent(instr)
+	instr.values[index] = qt
+}
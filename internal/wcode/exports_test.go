@@ -0,0 +1,68 @@
+package wcode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExports_Directives(t *testing.T) {
+	moduleCtx := NewModuleContext(NewCodeParser(exportsModuleCode).Parse())
+
+	if err := moduleCtx.AliasExport("run", "main"); err != nil {
+		t.Fatal(err)
+	}
+	if err := moduleCtx.RenameExport("run", "start"); err != nil {
+		t.Fatal(err)
+	}
+	if err := moduleCtx.RemoveExport("internal"); err != nil {
+		t.Fatal(err)
+	}
+	if err := moduleCtx.RenameExport("main", "start"); err == nil {
+		t.Error("expected error renaming to an existing export name")
+	}
+	if err := moduleCtx.RedirectImport("env", "log", "debug", "print"); err != nil {
+		t.Fatal(err)
+	}
+	impl, _ := moduleCtx.Function("$impl")
+	fnDef, err := moduleCtx.ImplementImport("env", "now", impl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fnDef.Imported != nil || fnDef.Index(moduleCtx) != 3 {
+		t.Errorf("invalid implemented function %+v", fnDef)
+	}
+
+	code := moduleCtx.String()
+	for _, expected := range []string{`(export "start" (func $run))`, `(export "main" (func $run))`, `(import "debug" "print" (func $log (type $t0)))`, `(call $impl)`} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("module does not contain %q:\n%s", expected, code)
+		}
+	}
+	for _, unexpected := range []string{`"internal"`, `"run"`, `"now"`} {
+		if strings.Contains(code, unexpected) {
+			t.Errorf("module contains %q:\n%s", unexpected, code)
+		}
+	}
+	if fn, ok := moduleCtx.ExportFunction("start"); !ok || fn.Name != "$run" {
+		t.Errorf("export start not found")
+	}
+	if fn, ok := moduleCtx.ImportFunction("debug", "print"); !ok || fn.Imported.ModuleName != "debug" {
+		t.Errorf("import debug.print not found")
+	}
+}
+
+var exportsModuleCode = `
+(module
+	(type $t0 (func (param i32)))
+	(type $t1 (func (result i32)))
+	(import "env" "log" (func $log (type $t0)))
+	(import "env" "now" (func $now (type $t1)))
+	(func $run (type $t1) (result i32)
+		(call $log (call $now))
+		(call $now))
+	(func $impl (type $t1) (result i32)
+		(i32.const 0))
+	(export "run" (func $run))
+	(export "internal" (func $impl))
+)
+`
@@ -0,0 +1,410 @@
+package wcode
+
+import (
+	"strconv"
+	"strings"
+
+	"joao/wasm-manipulator/internal/wlang"
+)
+
+// foldArityUnknown is the number of values of some instruction that could not be resolved.
+const foldArityUnknown = -1
+
+// foldEntry represents a block in the folded sequence with the number of values it produces.
+type foldEntry struct {
+	block    Block
+	produced int
+}
+
+// foldLabel represents a label that can be targeted by a branch while folding.
+type foldLabel struct {
+	name    string
+	results int
+}
+
+// codeFolder reconstructs the operand trees of linear (flat) instruction sequences.
+// the instructions are folded only when the number of values consumed and produced is known,
+// otherwise they are kept as siblings, which is still valid code.
+type codeFolder struct {
+	ctx    *ModuleContext
+	labels []*foldLabel
+}
+
+// newCodeFolder is a constructor for codeFolder.
+func newCodeFolder(ctx *ModuleContext, fnDef *FunctionDefinition) *codeFolder {
+	results := foldArityUnknown
+	if fnDef != nil {
+		results = len(fnDef.Results)
+	}
+	return &codeFolder{ctx: ctx, labels: []*foldLabel{{results: results}}}
+}
+
+// foldElement folds the flat instructions of some code element, using the context of some function.
+// the function definition is optional.
+func (ctx *ModuleContext) foldElement(fnDef *FunctionDefinition, el *element) {
+	el.blocks = newCodeFolder(ctx, fnDef).fold(el, el.blocks)
+}
+
+// foldFunction folds the flat instructions of some function body.
+func (ctx *ModuleContext) foldFunction(fnDef *FunctionDefinition) {
+	fnDef.instr.values = newCodeFolder(ctx, fnDef).fold(fnDef.instr, fnDef.instr.values)
+}
+
+// fold folds a sequence of blocks that belongs to some parent.
+func (cf *codeFolder) fold(parent Block, values []Block) []Block {
+	tokens := foldTokens(values)
+	var entries []*foldEntry
+	for i := 0; i < len(tokens); {
+		var entry *foldEntry
+		entry, i = cf.next(tokens, i, &entries)
+		entries = append(entries, entry)
+	}
+	res := make([]Block, len(entries))
+	for i, entry := range entries {
+		res[i] = entry.block
+		if parent != nil {
+			entry.block.setParent(parent)
+		}
+	}
+	return res
+}
+
+// next folds the block starting at some token, popping its operands from the entries.
+// returns the folded entry and the index of the next token.
+func (cf *codeFolder) next(tokens []Block, i int, entries *[]*foldEntry) (*foldEntry, int) {
+	switch token := tokens[i].(type) {
+	case *text:
+		name := token.String()
+		if !isFoldOpcode(name) || name == wlang.CodeBlockNameThen || name == wlang.CodeBlockNameElse || name == wlang.CodeBlockNameEnd {
+			return &foldEntry{block: token, produced: foldArityUnknown}, i + 1
+		}
+		instr := newInstruction()
+		instr.name = name
+		switch name {
+		case wlang.CodeBlockNameBlock, wlang.CodeBlockNameLoop, wlang.CodeBlockNameIf:
+			i = cf.structured(instr, tokens, i+1)
+		default:
+			i = foldImmediates(instr, tokens, i+1)
+		}
+		consumed, produced := cf.arity(instr)
+		if consumed > 0 {
+			cf.popOperands(instr, entries, consumed)
+		}
+		return &foldEntry{block: instr, produced: produced}, i
+	case *Instruction:
+		switch token.name {
+		case wlang.CodeBlockNameBlock, wlang.CodeBlockNameLoop:
+			cf.pushLabel(token)
+			token.values = cf.fold(token, token.values)
+			cf.popLabel()
+		case wlang.CodeBlockNameIf:
+			cf.pushLabel(token)
+			for _, v := range token.values {
+				if vInstr, ok := v.(*Instruction); ok && (vInstr.name == wlang.CodeBlockNameThen || vInstr.name == wlang.CodeBlockNameElse) {
+					vInstr.values = cf.fold(vInstr, vInstr.values)
+				}
+			}
+			cf.popLabel()
+		}
+		_, produced := cf.arity(token)
+		return &foldEntry{block: token, produced: produced}, i + 1
+	default:
+		return &foldEntry{block: token, produced: foldArityUnknown}, i + 1
+	}
+}
+
+// structured builds a flat control flow instruction (block, loop and if) until the matching end.
+// returns the index of the token after the end.
+func (cf *codeFolder) structured(instr *Instruction, tokens []Block, i int) int {
+	i = foldImmediates(instr, tokens, i)
+	cf.pushLabel(instr)
+	defer cf.popLabel()
+
+	var body, elseBody []Block
+	var hasElse bool
+	for depth := 0; i < len(tokens); i++ {
+		name := tokens[i].String()
+		if _, ok := tokens[i].(*text); ok {
+			switch {
+			case name == wlang.CodeBlockNameBlock, name == wlang.CodeBlockNameLoop, name == wlang.CodeBlockNameIf:
+				depth++
+			case name == wlang.CodeBlockNameElse && depth == 0 && instr.name == wlang.CodeBlockNameIf:
+				hasElse = true
+				i = skipFoldLabel(tokens, i+1) - 1
+				continue
+			case name == wlang.CodeBlockNameEnd && depth == 0:
+				i = skipFoldLabel(tokens, i+1)
+				cf.addBody(instr, body, elseBody, hasElse)
+				return i
+			case name == wlang.CodeBlockNameEnd:
+				depth--
+			}
+		}
+		if hasElse {
+			elseBody = append(elseBody, tokens[i])
+		} else {
+			body = append(body, tokens[i])
+		}
+	}
+	cf.addBody(instr, body, elseBody, hasElse)
+	return i
+}
+
+// addBody adds the folded body to some control flow instruction.
+// the if instruction body is wrapped in then and else instructions.
+func (cf *codeFolder) addBody(instr *Instruction, body, elseBody []Block, hasElse bool) {
+	if instr.name != wlang.CodeBlockNameIf {
+		instr.values = append(instr.values, cf.fold(instr, body)...)
+		return
+	}
+	thenInstr := newInstruction()
+	thenInstr.name = wlang.CodeBlockNameThen
+	thenInstr.values = cf.fold(thenInstr, body)
+	thenInstr.setParent(instr)
+	instr.values = append(instr.values, thenInstr)
+	if hasElse {
+		elseInstr := newInstruction()
+		elseInstr.name = wlang.CodeBlockNameElse
+		elseInstr.values = cf.fold(elseInstr, elseBody)
+		elseInstr.setParent(instr)
+		instr.values = append(instr.values, elseInstr)
+	}
+}
+
+// popOperands moves the operands of some instruction from the entries to the instruction.
+// the operands are only moved if the last entries produce exactly the values consumed.
+func (cf *codeFolder) popOperands(instr *Instruction, entries *[]*foldEntry, consumed int) {
+	var values int
+	j := len(*entries)
+	for ; j > 0 && values < consumed; j-- {
+		produced := (*entries)[j-1].produced
+		if produced <= 0 || values+produced > consumed {
+			break
+		}
+		values += produced
+	}
+	if values != consumed {
+		return
+	}
+	var operands []Block
+	for _, entry := range (*entries)[j:] {
+		entry.block.setParent(instr)
+		operands = append(operands, entry.block)
+	}
+	*entries = (*entries)[:j]
+
+	// The condition of some if instruction is placed before the then instruction.
+	if instr.name == wlang.CodeBlockNameIf {
+		index := len(instr.values)
+		for k, v := range instr.values {
+			if vInstr, ok := v.(*Instruction); ok && vInstr.name == wlang.CodeBlockNameThen {
+				index = k
+				break
+			}
+		}
+		instr.values = append(instr.values[:index], append(operands, instr.values[index:]...)...)
+		return
+	}
+	instr.values = append(instr.values, operands...)
+}
+
+// arity returns the number of values consumed from the stack and produced by some instruction.
+// the values already folded inside the instruction are not consumed from the stack.
+func (cf *codeFolder) arity(instr *Instruction) (int, int) {
+	var consumed, produced int
+	switch instr.name {
+	case wlang.CodeBlockNameBlock, wlang.CodeBlockNameLoop, wlang.CodeBlockNameIf:
+		// The block parameters are kept as siblings, since folded operands would belong to the body.
+		_, results := cf.blockArity(instr)
+		if instr.name == wlang.CodeBlockNameIf {
+			return 1, results
+		}
+		return 0, results
+	case instructionCodeCall:
+		callee, ok := cf.ctx.functionByRef(foldImmediate(instr, 0))
+		if !ok {
+			return foldArityUnknown, foldArityUnknown
+		}
+		consumed, produced = len(callee.Parameters()), len(callee.Results)
+	case instructionCodeCallIndirect:
+		typeDef, ok := cf.typeDefinition(instr)
+		if !ok {
+			return foldArityUnknown, foldArityUnknown
+		}
+		consumed, produced = len(typeDef.Params)+1, len(typeDef.Results)
+	case wlang.CodeBlockNameReturn:
+		consumed = cf.labels[0].results
+	case wlang.CodeBlockNameBr, wlang.CodeBlockNameBrIf, wlang.CodeBlockNameBrTable:
+		var ref string
+		for _, v := range instr.values {
+			if _, ok := v.(*text); ok {
+				ref = v.String()
+			}
+		}
+		label, ok := cf.label(ref)
+		if !ok {
+			return foldArityUnknown, foldArityUnknown
+		}
+		consumed = label.results
+		if instr.name != wlang.CodeBlockNameBr {
+			consumed++
+		}
+		if instr.name == wlang.CodeBlockNameBrIf {
+			produced = label.results
+		}
+	default:
+		def, ok := wlang.GetInstrDefinition(instr.name)
+		if !ok {
+			return foldArityUnknown, foldArityUnknown
+		}
+		consumed, produced = def.NArgs, def.NReturns
+	}
+	if consumed < 0 || produced < 0 {
+		return foldArityUnknown, foldArityUnknown
+	}
+	consumed -= len(codeInstructions(instr.values))
+	if consumed < 0 {
+		consumed = 0
+	}
+	return consumed, produced
+}
+
+// blockArity returns the number of parameters and results of some control flow instruction.
+func (cf *codeFolder) blockArity(instr *Instruction) (int, int) {
+	var params, results int
+	for _, v := range instr.values {
+		vInstr, ok := v.(*Instruction)
+		if !ok {
+			continue
+		}
+		switch vInstr.name {
+		case instructionParam:
+			params += len(vInstr.values)
+		case instructionResult:
+			results += len(vInstr.values)
+		}
+	}
+	if typeDef, ok := cf.typeDefinition(instr); ok {
+		return len(typeDef.Params), len(typeDef.Results)
+	}
+	return params, results
+}
+
+// typeDefinition returns the type definition used by some instruction.
+func (cf *codeFolder) typeDefinition(instr *Instruction) (*TypeDefinition, bool) {
+	for _, v := range instr.values {
+		if vInstr, ok := v.(*Instruction); ok && vInstr.name == instructionType && len(vInstr.values) == 1 {
+			typeDef, ok := cf.ctx.types[vInstr.values[0].String()]
+			return typeDef, ok
+		}
+	}
+	return nil, false
+}
+
+// pushLabel adds the label of some control flow instruction.
+func (cf *codeFolder) pushLabel(instr *Instruction) {
+	params, results := cf.blockArity(instr)
+	label := &foldLabel{results: results}
+	if instr.name == wlang.CodeBlockNameLoop {
+		label.results = params // Branches to a loop target its parameters.
+	}
+	if name := foldImmediate(instr, 0); strings.HasPrefix(name, "$") {
+		label.name = name
+	}
+	cf.labels = append(cf.labels, label)
+}
+
+// popLabel removes the last label.
+func (cf *codeFolder) popLabel() {
+	cf.labels = cf.labels[:len(cf.labels)-1]
+}
+
+// label returns the label targeted by some branch, using its name or relative depth.
+func (cf *codeFolder) label(ref string) (*foldLabel, bool) {
+	if depth, err := strconv.Atoi(ref); err == nil {
+		if depth < 0 || depth >= len(cf.labels) {
+			return nil, false
+		}
+		return cf.labels[len(cf.labels)-1-depth], true
+	}
+	for i := len(cf.labels) - 1; i >= 0; i-- {
+		if cf.labels[i].name == ref {
+			return cf.labels[i], true
+		}
+	}
+	return nil, false
+}
+
+// foldTokens splits the text blocks of some sequence into a block for each word.
+func foldTokens(values []Block) []Block {
+	var res []Block
+	for _, v := range values {
+		vText, ok := v.(*text)
+		if !ok {
+			res = append(res, v)
+			continue
+		}
+		fields := strings.Fields(vText.String())
+		if len(fields) == 1 {
+			res = append(res, vText)
+			continue
+		}
+		for _, field := range fields {
+			res = append(res, newText(field))
+		}
+	}
+	return res
+}
+
+// foldImmediates adds the immediates of some instruction, starting at some token.
+// the immediates are the following words that are not instructions, along with the type definitions.
+// returns the index of the next token.
+func foldImmediates(instr *Instruction, tokens []Block, i int) int {
+	for ; i < len(tokens); i++ {
+		switch token := tokens[i].(type) {
+		case *text:
+			if isFoldOpcode(token.String()) {
+				return i
+			}
+		case *Instruction:
+			if token.name != instructionType && token.name != instructionParam && token.name != instructionResult {
+				return i
+			}
+		default:
+			return i
+		}
+		tokens[i].setParent(instr)
+		instr.values = append(instr.values, tokens[i])
+	}
+	return i
+}
+
+// foldImmediate returns the text immediate at some index.
+func foldImmediate(instr *Instruction, i int) string {
+	var count int
+	for _, v := range instr.values {
+		if _, ok := v.(*text); ok {
+			if count == i {
+				return v.String()
+			}
+			count++
+		}
+	}
+	return ""
+}
+
+// skipFoldLabel skips the optional label after an end or else instruction.
+func skipFoldLabel(tokens []Block, i int) int {
+	if i < len(tokens) {
+		if _, ok := tokens[i].(*text); ok && strings.HasPrefix(tokens[i].String(), "$") {
+			return i + 1
+		}
+	}
+	return i
+}
+
+// isFoldOpcode returns if some word is an instruction name.
+func isFoldOpcode(name string) bool {
+	_, ok := wlang.GetInstrDefinition(name)
+	return ok
+}
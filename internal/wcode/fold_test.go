@@ -0,0 +1,52 @@
+package wcode
+
+import (
+	"testing"
+
+	"joao/wasm-manipulator/internal/wtemplate"
+)
+
+func TestFold_FlatFunction(t *testing.T) {
+	moduleCtx := NewModuleContext(NewCodeParser(wtemplate.ClearString(flatCode)).Parse())
+	fnDef, ok := moduleCtx.Function("$f")
+	if !ok {
+		t.Fatal("function $f not found")
+	}
+	expected := "(func $f (type $t1) (param $p i32) (result i32) (local $l i32) " +
+		"(local.set $l (i32.add (local.get $p) (call $g (i32.const 1)))) " +
+		"(block $b (result i32) (if (result i32) (local.get $l) (then (i32.const 2)) (else (br $b (i32.const 3)))))" +
+		")"
+	if got := fnDef.instr.String(); got != expected {
+		t.Errorf("expected folded function:\n%s\ngot:\n%s", expected, got)
+	}
+	for _, v := range fnDef.instr.values {
+		if v.getParent() != fnDef.instr {
+			t.Errorf("block %s does not have the function as parent", v.String())
+		}
+	}
+}
+
+var flatCode = `
+(module
+	(type $t0 (func (param i32) (result i32)))
+	(type $t1 (func (param i32) (result i32)))
+	(func $g (type $t0) (param $x i32) (result i32)
+		local.get $x)
+	(func $f (type $t1) (param $p i32) (result i32)
+		(local $l i32)
+		local.get $p
+		i32.const 1
+		call $g
+		i32.add
+		local.set $l
+		block $b (result i32)
+			local.get $l
+			if (result i32)
+				i32.const 2
+			else
+				i32.const 3
+				br $b
+			end
+		end)
+)
+`
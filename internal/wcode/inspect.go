@@ -0,0 +1,158 @@
+package wcode
+
+import (
+	"sort"
+	"strings"
+)
+
+// ModuleInfo contains the introspection data of the module.
+type ModuleInfo struct {
+	Functions []*FunctionInfo
+	Globals   []*GlobalInfo
+	Types     []*TypeInfo
+	Start     string `json:",omitempty"`
+}
+
+// FunctionInfo contains the introspection data of some function.
+// the fields are the same exposed by the func pointcut data, with the functions called by it.
+type FunctionInfo struct {
+	Index       string
+	Order       int
+	Name        string
+	Type        string   `json:",omitempty"`
+	Params      []string `json:",omitempty"`
+	ParamTypes  []string `json:",omitempty"`
+	Locals      []string `json:",omitempty"`
+	LocalTypes  []string `json:",omitempty"`
+	ResultType  string   `json:",omitempty"`
+	ResultTypes []string `json:",omitempty"`
+	IsImported  bool
+	IsExported  bool
+	IsStart     bool
+	Calls       []string `json:",omitempty"`
+}
+
+// GlobalInfo contains the introspection data of some global.
+type GlobalInfo struct {
+	Index      string
+	Order      int
+	Name       string
+	Type       string
+	Mutable    bool
+	IsImported bool
+	IsExported bool
+}
+
+// TypeInfo contains the introspection data of some type.
+type TypeInfo struct {
+	Index   string
+	Order   int
+	Params  []string `json:",omitempty"`
+	Results []string `json:",omitempty"`
+}
+
+// Inspect returns the introspection data of the module.
+func (ctx *ModuleContext) Inspect() *ModuleInfo {
+	info := &ModuleInfo{}
+	if startFnDef, ok := ctx.StartFunction(); ok {
+		info.Start = startFnDef.Name
+	}
+
+	// Functions sorted by index.
+	for _, fnDef := range ctx.Functions() {
+		data := newFuncData(ctx, fnDef)
+		info.Functions = append(info.Functions, &FunctionInfo{
+			Index:       data.Index,
+			Order:       data.Order,
+			Name:        data.Name,
+			Type:        fnDef.TypeName,
+			Params:      data.Params,
+			ParamTypes:  data.ParamTypes,
+			Locals:      data.Locals,
+			LocalTypes:  data.LocalTypes,
+			ResultType:  data.ResultType,
+			ResultTypes: data.ResultTypes,
+			IsImported:  data.IsImported,
+			IsExported:  data.IsExported,
+			IsStart:     data.IsStart,
+			Calls:       ctx.functionCalls(fnDef),
+		})
+	}
+	sort.Slice(info.Functions, func(i, j int) bool {
+		return info.Functions[i].Order < info.Functions[j].Order
+	})
+
+	// Globals and types sorted by their order on the module.
+	for _, instr := range collectInstrs(ctx.entryBlock) {
+		if len(instr.values) == 0 {
+			continue
+		}
+		name := instr.values[0].String()
+		switch instr.name {
+		case instructionGlobal:
+			global, ok := ctx.globals[name]
+			if !ok {
+				continue
+			}
+			info.Globals = append(info.Globals, &GlobalInfo{
+				Index:      global.Name,
+				Order:      len(info.Globals),
+				Name:       globalName(global),
+				Type:       global.Type,
+				Mutable:    global.Mutable,
+				IsImported: global.Imported != nil,
+				IsExported: global.Exported != nil,
+			})
+		case instructionType:
+			parent, ok := instr.getParent().(*Instruction)
+			if !ok || parent.name != instructionModule {
+				continue
+			}
+			typeDef, ok := ctx.types[name]
+			if !ok {
+				continue
+			}
+			info.Types = append(info.Types, &TypeInfo{
+				Index:   typeDef.Name,
+				Order:   len(info.Types),
+				Params:  typeDef.Params,
+				Results: typeDef.Results,
+			})
+		}
+	}
+	return info
+}
+
+// functionCalls returns the functions called by some function, sorted by name.
+func (ctx *ModuleContext) functionCalls(fnDef *FunctionDefinition) []string {
+	if fnDef.Imported != nil {
+		return nil
+	}
+	calls := make(map[string]struct{})
+	for _, instr := range collectInstrs(fnDef.instr) {
+		if instr.name != instructionCodeCall || len(instr.values) == 0 {
+			continue
+		}
+		if callee, ok := ctx.functionByRef(instr.values[0].String()); ok {
+			calls[callee.Name] = struct{}{}
+		}
+	}
+	var res []string
+	for name := range calls {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// globalName returns the global name for some global definition.
+func globalName(def *GlobalDefinition) string {
+	switch {
+	case def.Exported != nil:
+		return def.Exported.ExportName
+	case def.Imported != nil:
+		return strings.Join([]string{def.Imported.ModuleName, def.Imported.ExportName}, ".")
+	default:
+		return strings.Trim(def.Name, "$")
+	}
+}
@@ -0,0 +1,44 @@
+package wcode
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestModuleContext_Inspect(t *testing.T) {
+	moduleCtx := NewModuleContext(NewCodeParser(inspectModuleCode).Parse())
+	info := moduleCtx.Inspect()
+
+	if len(info.Functions) != 3 {
+		t.Fatalf("expected 3 functions, got %d", len(info.Functions))
+	}
+	if info.Functions[0].Index != "$log" || !info.Functions[0].IsImported {
+		t.Errorf("expected imported function $log first, got %s", info.Functions[0].Index)
+	}
+	add := info.Functions[1]
+	if add.Index != "$add" || !add.IsExported || !reflect.DeepEqual(add.ParamTypes, []string{"i32", "i32"}) {
+		t.Errorf("unexpected function info %+v", add)
+	}
+	main := info.Functions[2]
+	if !reflect.DeepEqual(main.Calls, []string{"$add", "$log"}) {
+		t.Errorf("unexpected function info %+v", main)
+	}
+
+	if len(info.Globals) != 1 || info.Globals[0].Index != "$g" || !info.Globals[0].Mutable {
+		t.Errorf("unexpected globals %+v", info.Globals)
+	}
+	if len(info.Types) != 2 || info.Types[1].Index != "$t1" {
+		t.Errorf("unexpected types %+v", info.Types)
+	}
+}
+
+const inspectModuleCode = `(module
+	(type $t0 (func (param i32)))
+	(type $t1 (func (param i32 i32) (result i32)))
+	(import "env" "log" (func $log (type $t0)))
+	(func $add (type $t1) (param $a i32) (param $b i32) (result i32)
+		(i32.add (local.get $a) (local.get $b)))
+	(func $main
+		(call $log (call $add (i32.const 1) (global.get $g))))
+	(global $g (mut i32) (i32.const 0))
+	(export "add" (func $add)))`
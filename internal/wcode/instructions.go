@@ -33,7 +33,8 @@ var (
 	instructionCodeCallIndirect = "call_indirect"
 	instructionCodeTeeLocal     = "local.tee"
 	instructionCodeSetLocal     = "local.set"
-	instructionCodeGetLocal     = "local.set"
+	instructionCodeGetLocal     = "local.get"
+	instructionCodeGetGlobal    = "global.get"
 	instructionCodeSetGlobal    = "global.set"
 	instructionCodeConst        = "const"
 	instructionCodeReturn       = "return"
@@ -120,6 +121,9 @@ func ReplaceBlocks(jpBlocks []*JoinPointBlock, code string) {
 	if len(jpBlocks) == 0 {
 		return
 	}
+	for _, jpBlock := range jpBlocks {
+		defer jpBlock.invalidateTypes()
+	}
 	block := jpBlocks[0].block
 	parentBlock := block.getParent()
 	if parentBlock == nil {
@@ -194,13 +198,6 @@ func resolveRearrangedBlock(jpBlocks []*JoinPointBlock, blocksLen int) *JoinPoin
 	return res
 }
 
-// replaceBlockWithCode replaces a block for some code.
-func replaceBlockWithCode(block Block, code string) error {
-	// Parse new code blocks.
-	newBlockEl := NewCodeParser(code).parse()
-	return replaceBlock(block, newBlockEl.blocks)
-}
-
 // replaceBlock replaces a block for some code blocks.
 func replaceBlock(block Block, newCodeBlocks []Block) error {
 	parentBlock := block.getParent()
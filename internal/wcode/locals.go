@@ -0,0 +1,133 @@
+package wcode
+
+import (
+	"fmt"
+	"strings"
+
+	"joao/wasm-manipulator/internal/wgenerator"
+	"joao/wasm-manipulator/internal/wparser/variable"
+)
+
+// LocalPool allocates the locals of the advice variables for the join-points of some function.
+// by default, each join-point has its own local for each variable.
+// when pooled, the primitive variables with no initial value are scratch locals, reused for the same variable by the join-points that do not overlap.
+// the reused locals are reset to the zero value at the start of the join-point.
+// when shared, all the join-points use the same local for each variable.
+type LocalPool struct {
+	ctx    *ModuleContext
+	fnDef  *FunctionDefinition
+	shared bool
+	reuse  bool
+	named  map[string]*FunctionLocalDefinition
+	pooled []*pooledLocal
+	resets map[*JoinPointBlock][]*FunctionLocalDefinition
+	count  int
+}
+
+// NewLocalPool is the constructor for LocalPool.
+func (ctx *ModuleContext) NewLocalPool(fnDef *FunctionDefinition, shared, pooled bool) *LocalPool {
+	return &LocalPool{
+		ctx:    ctx,
+		fnDef:  fnDef,
+		shared: shared,
+		reuse:  pooled,
+		named:  make(map[string]*FunctionLocalDefinition),
+		resets: make(map[*JoinPointBlock][]*FunctionLocalDefinition),
+	}
+}
+
+// Local returns the local for some variable on the join-point with the given blocks.
+// a new local is added to the function when none can be reused.
+func (lp *LocalPool) Local(name, value string, blocks []*JoinPointBlock) (*FunctionLocalDefinition, error) {
+	if lp.shared {
+		if local, ok := lp.named[name]; ok {
+			return local, nil
+		}
+		local, err := lp.ctx.AddLocal(value, lp.fnDef)
+		if err != nil {
+			return nil, err
+		}
+		lp.named[name] = local
+		lp.count++
+		return local, nil
+	}
+
+	expr, err := variable.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("parsing local variable value %q: %v", value, err)
+	}
+	typ := expr.GetType()
+
+	// Only single block join-points have a known lifetime.
+	// the locals are only reused for the same variable, so the local alias is the variable name.
+	scratch := lp.reuse && len(blocks) == 1 && expr.Value == nil && IsVarTypeStrPrimitive(typ)
+	if scratch {
+		for _, pooled := range lp.pooled {
+			if pooled.name == name && pooled.local.Type == typ && !pooled.overlaps(blocks[0]) {
+				pooled.owners = append(pooled.owners, blocks[0])
+				lp.resets[blocks[0]] = append(lp.resets[blocks[0]], pooled.local)
+				return pooled.local, nil
+			}
+		}
+	}
+	local, err := lp.ctx.AddLocal(value, lp.fnDef)
+	if err != nil {
+		return nil, err
+	}
+	lp.count++
+	if scratch {
+		lp.pooled = append(lp.pooled, &pooledLocal{name: name, local: local, owners: []*JoinPointBlock{blocks[0]}})
+	}
+	return local, nil
+}
+
+// ResetCode returns the code setting the zero value to the locals reused by the join-point with the given blocks.
+// returns an empty string when the join-point does not reuse any local.
+func (lp *LocalPool) ResetCode(blocks []*JoinPointBlock) string {
+	if len(blocks) != 1 {
+		return ""
+	}
+	var res []string
+	for _, local := range lp.resets[blocks[0]] {
+		if zero := wgenerator.ZeroValueCode(local.Type); zero != "" {
+			res = append(res, wgenerator.SetLocalInstructionToCode(local.Name, zero))
+		} else {
+			res = append(res, wgenerator.SetLocalToCode(local.Name, local.Type, "0"))
+		}
+	}
+	return strings.Join(res, " ")
+}
+
+// Count returns the number of locals added by the pool.
+func (lp *LocalPool) Count() int {
+	return lp.count
+}
+
+// pooledLocal contains a scratch local, the variable it is used for and the join-point blocks using it.
+type pooledLocal struct {
+	name   string
+	local  *FunctionLocalDefinition
+	owners []*JoinPointBlock
+}
+
+// overlaps returns if the lifetime of the local overlaps with some join-point block.
+// the lifetimes overlap when one of the blocks contains the other.
+func (pl *pooledLocal) overlaps(block *JoinPointBlock) bool {
+	for _, owner := range pl.owners {
+		if owner.contains(block) || block.contains(owner) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains returns if the join-point block contains some other block.
+// a join-point block contains itself.
+func (jpB *JoinPointBlock) contains(other *JoinPointBlock) bool {
+	for b := other.block; b != nil; b = b.getParent() {
+		if b == jpB.block {
+			return true
+		}
+	}
+	return false
+}
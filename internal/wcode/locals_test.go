@@ -0,0 +1,87 @@
+package wcode
+
+import (
+	"fmt"
+	"testing"
+
+	"joao/wasm-manipulator/internal/wkeyword"
+)
+
+func TestLocalPool_Local(t *testing.T) {
+	moduleCtx := NewModuleContext(NewCodeParser(localsModuleCode).Parse())
+	fnDef, _ := moduleCtx.Function("$f")
+	outer := fnDef.instr.values[len(fnDef.instr.values)-1].(*Instruction)
+	inner := outer.values[0].(*Instruction)
+	sibling := fnDef.instr.values[len(fnDef.instr.values)-2].(*Instruction)
+	newBlocks := func(b Block) []*JoinPointBlock {
+		return []*JoinPointBlock{newJoinPointBlock(moduleCtx, b, fnDef.instr, wkeyword.NewKwNil(), nil)}
+	}
+
+	pool := moduleCtx.NewLocalPool(fnDef, false, true)
+	outerLocal, _ := pool.Local("v", "i32", newBlocks(outer))
+	innerLocal, _ := pool.Local("v", "i32", newBlocks(inner))
+	siblingLocal, _ := pool.Local("v", "i32", newBlocks(sibling))
+	initLocal, _ := pool.Local("c", "i32 = 1", newBlocks(sibling))
+	if outerLocal == innerLocal {
+		t.Error("overlapping join-points must not share locals")
+	}
+	if siblingLocal != outerLocal && siblingLocal != innerLocal {
+		t.Error("scratch local must be reused by a join-point that does not overlap")
+	}
+	if initLocal == siblingLocal || pool.Count() != 3 {
+		t.Errorf("locals with initial value must not be reused (count %d)", pool.Count())
+	}
+
+	unpooled := moduleCtx.NewLocalPool(fnDef, false, false)
+	first, _ := unpooled.Local("v", "i32", newBlocks(outer))
+	second, _ := unpooled.Local("v", "i32", newBlocks(sibling))
+	if first == second || unpooled.Count() != 2 {
+		t.Error("join-points must have their own locals unless pooled")
+	}
+
+	shared := moduleCtx.NewLocalPool(fnDef, true, false)
+	first, _ = shared.Local("c", "i32 = 1", newBlocks(outer))
+	second, _ = shared.Local("c", "i32 = 1", newBlocks(inner))
+	if first != second || shared.Count() != 1 {
+		t.Error("shared pool must use one local per variable")
+	}
+}
+
+func TestLocalPool_ResetCode(t *testing.T) {
+	moduleCtx := NewModuleContext(NewCodeParser(localsModuleCode).Parse())
+	fnDef, _ := moduleCtx.Function("$f")
+	outer := newJoinPointBlock(moduleCtx, fnDef.instr.values[len(fnDef.instr.values)-1], fnDef.instr, wkeyword.NewKwNil(), nil)
+	sibling := newJoinPointBlock(moduleCtx, fnDef.instr.values[len(fnDef.instr.values)-2], fnDef.instr, wkeyword.NewKwNil(), nil)
+
+	pool := moduleCtx.NewLocalPool(fnDef, false, true)
+	outerLocal, _ := pool.Local("v", "i32", []*JoinPointBlock{outer})
+	outerRef, _ := pool.Local("r", "funcref", []*JoinPointBlock{outer})
+	siblingLocal, _ := pool.Local("v", "i32", []*JoinPointBlock{sibling})
+	siblingRef, _ := pool.Local("r", "funcref", []*JoinPointBlock{sibling})
+	otherLocal, _ := pool.Local("w", "i32", []*JoinPointBlock{sibling})
+	if siblingLocal != outerLocal || siblingRef != outerRef {
+		t.Fatal("scratch locals must be reused by a join-point that does not overlap")
+	}
+	if otherLocal == outerLocal {
+		t.Error("scratch locals must only be reused for the same variable")
+	}
+
+	// The reused locals start with the zero value, as the locals of the join-points that are not pooled.
+	if code := pool.ResetCode([]*JoinPointBlock{outer}); code != "" {
+		t.Errorf("first join-point must not reset the locals: %s", code)
+	}
+	expected := fmt.Sprintf("(local.set %s (i32.const 0)) (local.set %s (ref.null func))", outerLocal.Name, outerRef.Name)
+	if code := pool.ResetCode([]*JoinPointBlock{sibling}); code != expected {
+		t.Errorf("invalid reset code, expected %q, got %q", expected, code)
+	}
+}
+
+var localsModuleCode = `
+(module
+	(type $t0 (func (result i32)))
+	(func $g (type $t0) (result i32)
+		(i32.const 1))
+	(func $f (type $t0) (result i32)
+		(drop (call $g))
+		(i32.eqz (call $g))))
+`
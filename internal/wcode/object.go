@@ -4,39 +4,43 @@ import "strings"
 
 // FuncData contains the func pointcut data.
 type FuncData struct {
-	Index       string
-	Order       int
-	Name        string
-	Params      []string
-	ParamTypes  []string
-	TotalParams int
-	Locals      []string
-	LocalTypes  []string
-	TotalLocals int
-	ResultType  string
-	Code        string
-	IsImported  bool
-	IsExported  bool
-	IsStart     bool
+	Index        string
+	Order        int
+	Name         string
+	Params       []string
+	ParamTypes   []string
+	TotalParams  int
+	Locals       []string
+	LocalTypes   []string
+	TotalLocals  int
+	ResultType   string
+	ResultTypes  []string
+	TotalResults int
+	Code         string
+	IsImported   bool
+	IsExported   bool
+	IsStart      bool
 }
 
 // newFuncData is the constructor for FuncData.
 func newFuncData(ctx *ModuleContext, def *FunctionDefinition) *FuncData {
 	return &FuncData{
-		Index:       def.Name,
-		Order:       def.Index(ctx),
-		Name:        funcName(def),
-		Params:      funcParams(def),
-		ParamTypes:  funcParamTypes(def),
-		TotalParams: len(def.Params),
-		Locals:      funcLocals(def),
-		LocalTypes:  funcLocalTypes(def),
-		TotalLocals: len(def.Locals),
-		ResultType:  def.Result,
-		Code:        def.Code(),
-		IsImported:  def.Imported != nil,
-		IsExported:  def.Exported != nil,
-		IsStart:     def.IsStart,
+		Index:        def.Name,
+		Order:        def.Index(ctx),
+		Name:         funcName(def),
+		Params:       funcParams(def),
+		ParamTypes:   funcParamTypes(def),
+		TotalParams:  len(def.Params),
+		Locals:       funcLocals(def),
+		LocalTypes:   funcLocalTypes(def),
+		TotalLocals:  len(def.Locals),
+		ResultType:   strings.Join(def.Results, " "),
+		ResultTypes:  def.Results,
+		TotalResults: len(def.Results),
+		Code:         def.Code(),
+		IsImported:   def.Imported != nil,
+		IsExported:   def.Exported != nil,
+		IsStart:      def.IsStart,
 	}
 }
 
@@ -75,7 +79,7 @@ func funcParamTypes(def *FunctionDefinition) []string {
 // funcLocals returns the locals for some function definition
 func funcLocals(def *FunctionDefinition) []string {
 	var locals []string
-	for _, local := range def.Locals {
+	for _, local := range def.LocalsArr() {
 		locals = append(locals, local.Name)
 	}
 	return locals
@@ -84,7 +88,7 @@ func funcLocals(def *FunctionDefinition) []string {
 // funcLocalTypes returns the local types for some function definition
 func funcLocalTypes(def *FunctionDefinition) []string {
 	var localTypes []string
-	for _, local := range def.Locals {
+	for _, local := range def.LocalsArr() {
 		localTypes = append(localTypes, local.Type)
 	}
 	return localTypes
@@ -221,6 +225,7 @@ type ReturnsData struct {
 	Func  *FuncData
 	Instr string
 	Type  string
+	Types []string
 }
 
 // newReturns is the constructor for Returns.
@@ -228,6 +233,7 @@ func newReturnsData(ctx *ModuleContext, def *FunctionDefinition, instr Block) *R
 	return &ReturnsData{
 		Func:  newFuncData(ctx, def),
 		Instr: instr.String(),
-		Type:  def.Result,
+		Type:  strings.Join(def.Results, " "),
+		Types: def.Results,
 	}
 }
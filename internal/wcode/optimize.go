@@ -0,0 +1,539 @@
+package wcode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"joao/wasm-manipulator/internal/wgenerator"
+	"joao/wasm-manipulator/internal/wlang"
+)
+
+const instructionCodeNop = "nop"
+
+// Optimize removes the code left behind by the transformations that is not needed.
+// removes the nop instructions of the injected functions and the dead injected locals, coalesces the smart mode temporaries and
+// removes the unreachable injected functions, the unused glue imports and the unused added types.
+// the original code of the module is kept as it is.
+func (ctx *ModuleContext) Optimize() {
+	logrus.Infoln("Optimizing module")
+	defer ctx.invalidateTypes(nil)
+
+	moduleVisitor := newModuleInstrsVisitor()
+	ctx.entryBlock.Traverse(moduleVisitor)
+	module, ok := moduleVisitor.Module()
+	if !ok {
+		logrus.Warnln("Module instruction not found to optimize")
+		return
+	}
+
+	var nops, coalesced, locals int
+	for _, block := range module.values {
+		instr, ok := block.(*Instruction)
+		if !ok || instr.name != instructionFunction || len(instr.values) == 0 {
+			continue
+		}
+		if isInjectedName(instr.values[0].String()) {
+			nops += removeNops(instr)
+		}
+		coalesced += coalesceLocals(instr)
+
+		// Locals referred by index cannot be removed, since the indexes would change.
+		numericVisitor := newNumericLocalsVisitor()
+		instr.Traverse(numericVisitor)
+		if !numericVisitor.found {
+			locals += ctx.removeDeadLocals(instr)
+		}
+	}
+	functions := ctx.removeUnreachableFunctions(module)
+	types := ctx.removeUnusedTypes(module)
+
+	logrus.WithFields(logrus.Fields{
+		"nops":      nops,
+		"coalesced": coalesced,
+		"locals":    locals,
+		"functions": functions,
+		"types":     types,
+	}).Infoln("Module optimized")
+}
+
+// removeNops removes the nop instructions from some function.
+// the original functions are not changed, since their nops cannot be told apart from the injected ones.
+func removeNops(fnInstr *Instruction) int {
+	var count int
+	for _, instr := range collectInstrs(fnInstr) {
+		if instr.name == instructionCodeNop && len(instr.values) == 0 {
+			if parent, ok := instr.getParent().(*Instruction); ok && parent.removeChild(instr) == nil {
+				count++
+			}
+			continue
+		}
+		for i := len(instr.values) - 1; i >= 0; i-- {
+			if _, ok := instr.values[i].(*text); ok && instr.values[i].String() == instructionCodeNop && instr.removeChildByIndex(i) == nil {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// coalesceLocals replaces the locals that are set and got right after by the value itself.
+// e.g. (local.set $t (call $f)) (i32.eqz (local.get $t)) results in (i32.eqz (call $f)).
+// only the injected locals are coalesced.
+func coalesceLocals(fnInstr *Instruction) int {
+	var count int
+	for _, name := range functionLocals(fnInstr) {
+		if !isInjectedName(name) {
+			continue
+		}
+		usage := newLocalUsage(fnInstr, name)
+		if usage.pinned || len(usage.sets) != 1 || len(usage.gets) != 1 || len(usage.tees) != 0 {
+			continue
+		}
+		setInstr, getInstr := usage.sets[0], usage.gets[0]
+		if len(setInstr.values) != 2 {
+			continue
+		}
+		value, ok := setInstr.values[1].(*Instruction)
+		if !ok {
+			continue
+		}
+		parent, ok := setInstr.getParent().(*Instruction)
+		if !ok {
+			continue
+		}
+		index := parent.childIndex(setInstr)
+		if index == -1 || index+1 >= len(parent.values) || !isFirstEvaluated(parent.values[index+1], getInstr, value) {
+			continue
+		}
+		getParent, ok := getInstr.getParent().(*Instruction)
+		if !ok {
+			continue
+		}
+		getParent.values[getParent.childIndex(getInstr)] = value
+		value.setParent(getParent)
+		if err := parent.removeChild(setInstr); err != nil {
+			logrus.Errorf("removing coalesced local set instruction: %v", err)
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+// isFirstEvaluated returns if some target instruction is the first to be evaluated on some block.
+// the operands before it must not be affected by the moved value.
+func isFirstEvaluated(block Block, target *Instruction, value *Instruction) bool {
+	for block != target {
+		instr, ok := block.(*Instruction)
+		if !ok || isStructuredInstruction(instr.name) {
+			return false
+		}
+		var next Block
+		for _, child := range instr.values {
+			childInstr, ok := child.(*Instruction)
+			if !ok {
+				// Immediate values.
+				continue
+			}
+			if childInstr == target || !isPureOperand(childInstr, value) {
+				next = childInstr
+				break
+			}
+		}
+		if next == nil {
+			return false
+		}
+		block = next
+	}
+	return true
+}
+
+// isPureOperand returns if some operand can be evaluated before some value without changing the result.
+func isPureOperand(operand *Instruction, value *Instruction) bool {
+	if strings.HasSuffix(operand.name, "."+instructionCodeConst) {
+		return true
+	}
+	if operand.name != instructionCodeGetLocal || len(operand.values) != 1 {
+		return false
+	}
+	name := operand.values[0].String()
+	for _, t := range collectTexts(value) {
+		if t.String() == name {
+			return false
+		}
+	}
+	return true
+}
+
+// isStructuredInstruction returns if some instruction contains a block of code.
+func isStructuredInstruction(name string) bool {
+	return name == wlang.CodeBlockNameIf || wlang.IsControlFlow(name)
+}
+
+// removeDeadLocals removes the locals that are never read.
+// the values set to these locals are dropped, unless they have no side effects.
+// only the injected locals are removed.
+func (ctx *ModuleContext) removeDeadLocals(fnInstr *Instruction) int {
+	fnDef, _ := ctx.Function(fnInstr.values[0].String())
+	var count int
+	for _, name := range functionLocals(fnInstr) {
+		if !isInjectedName(name) {
+			continue
+		}
+		usage := newLocalUsage(fnInstr, name)
+		if usage.pinned || len(usage.gets) != 0 || len(usage.tees) != 0 {
+			continue
+		}
+		valid := true
+		for _, setInstr := range usage.sets {
+			valid = valid && len(setInstr.values) == 2
+		}
+		if !valid {
+			continue
+		}
+		for _, setInstr := range usage.sets {
+			value := setInstr.values[1]
+			if valueInstr, ok := value.(*Instruction); ok && hasSideEffects(valueInstr) {
+				setInstr.name = wlang.CodeBlockNameDrop
+				setInstr.values = []Block{value}
+				continue
+			}
+			if parent, ok := setInstr.getParent().(*Instruction); ok {
+				if err := parent.removeChild(setInstr); err != nil {
+					logrus.Errorf("removing dead local set instruction: %v", err)
+				}
+			}
+		}
+		if err := fnInstr.removeChild(usage.declaration); err != nil {
+			logrus.Errorf("removing dead local declaration: %v", err)
+			continue
+		}
+		if fnDef != nil {
+			delete(fnDef.Locals, name)
+		}
+		count++
+	}
+	return count
+}
+
+// hasSideEffects returns if the evaluation of some instruction may have side effects.
+func hasSideEffects(instr *Instruction) bool {
+	if !strings.HasSuffix(instr.name, "."+instructionCodeConst) && instr.name != instructionCodeGetLocal && instr.name != instructionCodeGetGlobal {
+		return true
+	}
+	for _, child := range instr.values {
+		if childInstr, ok := child.(*Instruction); ok && hasSideEffects(childInstr) {
+			return true
+		}
+	}
+	return false
+}
+
+// functionLocals returns the names of the locals declared by some function.
+func functionLocals(fnInstr *Instruction) []string {
+	var res []string
+	for _, block := range fnInstr.values {
+		if instr, ok := block.(*Instruction); ok && instr.name == instructionLocal && len(instr.values) == 2 {
+			res = append(res, instr.values[0].String())
+		}
+	}
+	return res
+}
+
+// localUsage contains the instructions that use some local.
+// the local is pinned when it is used in an unknown way, e.g. in flat code.
+type localUsage struct {
+	declaration *Instruction
+	sets        []*Instruction
+	gets        []*Instruction
+	tees        []*Instruction
+	pinned      bool
+}
+
+// newLocalUsage is the constructor for localUsage.
+func newLocalUsage(fnInstr *Instruction, name string) *localUsage {
+	usage := &localUsage{}
+	for _, instr := range collectInstrs(fnInstr) {
+		for i, value := range instr.values {
+			if _, ok := value.(*text); !ok || value.String() != name {
+				continue
+			}
+			if i != 0 {
+				usage.pinned = true
+				continue
+			}
+			switch instr.name {
+			case instructionLocal:
+				usage.declaration = instr
+			case instructionCodeSetLocal:
+				usage.sets = append(usage.sets, instr)
+			case instructionCodeGetLocal:
+				usage.gets = append(usage.gets, instr)
+			case instructionCodeTeeLocal:
+				usage.tees = append(usage.tees, instr)
+			default:
+				usage.pinned = true
+			}
+		}
+	}
+	usage.pinned = usage.pinned || usage.declaration == nil
+	return usage
+}
+
+// removeUnreachableFunctions removes the injected functions and glue imports that are never referred.
+func (ctx *ModuleContext) removeUnreachableFunctions(module *Instruction) int {
+	// Finds the functions that can be removed.
+	candidates := make(map[string]*Instruction)
+	definitions := make(map[string]*Instruction)
+	glueNames := glueFunctionNames()
+	for _, block := range module.values {
+		instr, ok := block.(*Instruction)
+		if !ok {
+			continue
+		}
+		fnInstr := instr
+		if instr.name == instructionImport && len(instr.values) == 3 {
+			fnInstr, _ = instr.values[2].(*Instruction)
+		}
+		if fnInstr == nil || fnInstr.name != instructionFunction || len(fnInstr.values) == 0 {
+			continue
+		}
+		name := fnInstr.values[0].String()
+		if _, ok := glueNames[name]; ok || isInjectedName(name) {
+			candidates[name] = fnInstr
+			definitions[name] = instr
+		}
+	}
+
+	// Marks the functions reachable from the other module instructions.
+	reachable := make(map[string]struct{})
+	var pending []*Instruction
+	mark := func(block Block, self string) {
+		for _, t := range collectTexts(block) {
+			name := t.String()
+			fnInstr, ok := candidates[name]
+			if _, found := reachable[name]; !ok || found || name == self {
+				continue
+			}
+			reachable[name] = struct{}{}
+			pending = append(pending, fnInstr)
+		}
+	}
+	isDefinition := make(map[Block]struct{}, len(definitions))
+	for _, instr := range definitions {
+		isDefinition[instr] = struct{}{}
+	}
+	for _, block := range module.values {
+		if _, ok := isDefinition[block]; !ok {
+			mark(block, "")
+		}
+	}
+	for len(pending) > 0 {
+		fnInstr := pending[0]
+		pending = pending[1:]
+		mark(fnInstr, fnInstr.values[0].String())
+	}
+
+	// The functions referred by index must keep their index, so only the functions after them can be removed.
+	// the indexes are computed before removing any function, since they change with each removal.
+	maxRef := maxFunctionIndexRef(module)
+	indexes := make(map[string]int, len(candidates))
+	for name := range candidates {
+		if fnDef, ok := ctx.functions[name]; ok {
+			indexes[name] = fnDef.Index(ctx)
+		}
+	}
+
+	// Removes the unreachable functions.
+	var count int
+	for name := range candidates {
+		if _, ok := reachable[name]; ok {
+			continue
+		}
+		if index, ok := indexes[name]; ok && index <= maxRef {
+			logrus.Debugf("Keeping unreachable function %s, since the functions are referred by index", name)
+			continue
+		}
+		if err := module.removeChild(definitions[name]); err != nil {
+			logrus.Errorf("removing unreachable function %s: %v", name, err)
+			continue
+		}
+		if fnDef, ok := ctx.functions[name]; ok {
+			if fnDef.Imported != nil {
+				ctx.removeImportFunction(fnDef.Imported.ModuleName, fnDef.Imported.ExportName)
+			}
+			delete(ctx.functions, name)
+		}
+		count++
+	}
+	if count > 0 {
+		ctx.updateFunctionsOrder(module)
+	}
+	return count
+}
+
+// maxFunctionIndexRef returns the highest function index referred by the module code, or -1 if none is referred.
+// the indexes are the numeric immediates of the calls, function references, exports, start and element segments.
+func maxFunctionIndexRef(module *Instruction) int {
+	res := -1
+	for _, instr := range collectInstrs(module) {
+		var values []Block
+		switch instr.name {
+		case instructionCodeCall, returnKeyword + instructionCodeCall, wlang.CodeBlockNameRefFunc, instructionStart:
+			if len(instr.values) > 0 {
+				values = instr.values[:1]
+			}
+		case instructionFunction:
+			if parent, ok := instr.getParent().(*Instruction); ok && parent.name == instructionExport {
+				values = instr.values
+			}
+		case instructionElem:
+			values = instr.values
+		}
+		for _, v := range values {
+			if _, ok := v.(*text); !ok {
+				continue
+			}
+			if index, err := strconv.Atoi(v.String()); err == nil && index > res {
+				res = index
+			}
+		}
+	}
+	return res
+}
+
+// updateFunctionsOrder updates the order of the functions accordingly to the module code.
+func (ctx *ModuleContext) updateFunctionsOrder(module *Instruction) {
+	var imports, functions int
+	for _, block := range module.values {
+		instr, ok := block.(*Instruction)
+		if !ok {
+			continue
+		}
+		if instr.name == instructionImport && len(instr.values) == 3 {
+			instr, _ = instr.values[2].(*Instruction)
+		}
+		if instr == nil || instr.name != instructionFunction || len(instr.values) == 0 {
+			continue
+		}
+		fnDef, ok := ctx.functions[instr.values[0].String()]
+		if !ok {
+			continue
+		}
+		if fnDef.Imported != nil {
+			fnDef.order = imports
+			imports++
+		} else {
+			fnDef.order = functions
+			functions++
+		}
+	}
+}
+
+// removeUnusedTypes removes the added types that are never referred.
+func (ctx *ModuleContext) removeUnusedTypes(module *Instruction) int {
+	used := make(map[string]struct{})
+	for _, instr := range collectInstrs(module) {
+		for i, value := range instr.values {
+			if i == 0 && instr.name == instructionType && instr.getParent() == module {
+				continue
+			}
+			if _, ok := value.(*text); ok {
+				used[value.String()] = struct{}{}
+			}
+		}
+	}
+
+	var count int
+	for i := len(module.values) - 1; i >= 0; i-- {
+		instr, ok := module.values[i].(*Instruction)
+		if !ok || instr.name != instructionType || len(instr.values) == 0 {
+			continue
+		}
+		name := instr.values[0].String()
+		if _, ok := used[name]; ok || !isInjectedName(name) {
+			continue
+		}
+		if err := module.removeChildByIndex(i); err != nil {
+			logrus.Errorf("removing unused type %s: %v", name, err)
+			continue
+		}
+		delete(ctx.types, name)
+		count++
+	}
+	return count
+}
+
+// isInjectedName returns if some index was generated by the transformations.
+func isInjectedName(name string) bool {
+	return strings.HasPrefix(name, "$"+wgenerator.CodeIndexPrefix)
+}
+
+// glueFunctionNames returns the names of all the glue functions.
+func glueFunctionNames() map[string]struct{} {
+	res := make(map[string]struct{})
+	groups := [][]*wgenerator.ImportFunctionDef{
+		wgenerator.OperationFunctions(),
+		wgenerator.ArgsFunctions(),
+		wgenerator.ZoneFunctions(),
+		wgenerator.ReturnsFunctions(),
+		wgenerator.ErrorFunctions(),
+	}
+	for _, fns := range groups {
+		for _, fn := range fns {
+			res[fmt.Sprintf("$%s.%s", fn.ModuleName, fn.ExportedName)] = struct{}{}
+		}
+	}
+	return res
+}
+
+// collectTexts returns all the text blocks of some block.
+func collectTexts(block Block) []*text {
+	textVisitor := newTextsVisitor()
+	block.Traverse(textVisitor)
+	return textVisitor.texts
+}
+
+// textsVisitor is responsible to group all the text blocks.
+type textsVisitor struct {
+	visitorAdapter
+	texts []*text
+}
+
+// newTextsVisitor is the constructor for textsVisitor.
+func newTextsVisitor() *textsVisitor {
+	return &textsVisitor{}
+}
+
+// VisitText visits a text block.
+func (tv *textsVisitor) VisitText(t *text) bool {
+	tv.texts = append(tv.texts, t)
+	return true
+}
+
+// collectInstrs returns all the instructions of some block.
+func collectInstrs(block Block) []*Instruction {
+	instrVisitor := newInstrsVisitor()
+	block.Traverse(instrVisitor)
+	return instrVisitor.instrs
+}
+
+// instrsVisitor is responsible to group all the instructions.
+type instrsVisitor struct {
+	visitorAdapter
+	instrs []*Instruction
+}
+
+// newInstrsVisitor is the constructor for instrsVisitor.
+func newInstrsVisitor() *instrsVisitor {
+	return &instrsVisitor{}
+}
+
+// VisitInstruction visits an instruction block.
+func (iv *instrsVisitor) VisitInstruction(instr *Instruction) bool {
+	iv.instrs = append(iv.instrs, instr)
+	return true
+}
@@ -0,0 +1,119 @@
+package wcode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOptimize(t *testing.T) {
+	moduleCtx := NewModuleContext(NewCodeParser(optimizeModuleCode).Parse())
+	moduleCtx.Optimize()
+
+	code := moduleCtx.String()
+	for _, expected := range []string{
+		`(i32.eqz (call $g))`,
+		`(drop (call $g))`,
+		`(local $used i32)`,
+		`(func $wmr_f1`,
+		`(import "env" "log"`,
+		`(type $wmr_t1`,
+	} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("module does not contain %q:\n%s", expected, code)
+		}
+	}
+	for _, unexpected := range []string{`nop`, `$wmr_l0_i32`, `$wmr_dead`, `$wmr_const`, `$wmr_f2`, `$operations.clear`, `$wmr_t2`, `$wmr_t3`} {
+		if strings.Contains(code, unexpected) {
+			t.Errorf("module contains %q:\n%s", unexpected, code)
+		}
+	}
+	if _, ok := moduleCtx.Function("$wmr_f2"); ok {
+		t.Error("unreachable function must be removed from the context")
+	}
+	if fnDef, ok := moduleCtx.Function("$wmr_f1"); !ok || fnDef.Index(moduleCtx) != 3 {
+		t.Error("invalid index for the reachable function")
+	}
+}
+
+func TestOptimize_IndexReferences(t *testing.T) {
+	moduleCtx := NewModuleContext(NewCodeParser(optimizeIndexesCode).Parse())
+	moduleCtx.Optimize()
+
+	// Removing the glue import would change the index of $g, called by index.
+	code := moduleCtx.String()
+	for _, expected := range []string{`$operations.clear`, `(call 2)`} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("module does not contain %q:\n%s", expected, code)
+		}
+	}
+	if strings.Contains(code, `$wmr_f1`) {
+		t.Errorf("module contains the unreachable function after the referred functions:\n%s", code)
+	}
+	if fnDef, ok := moduleCtx.Function("$g"); !ok || fnDef.Index(moduleCtx) != 2 {
+		t.Error("invalid index for the function referred by index")
+	}
+}
+
+func TestOptimize_OriginalCode(t *testing.T) {
+	moduleCtx := NewModuleContext(NewCodeParser(optimizeOriginalCode).Parse())
+	expected := moduleCtx.String()
+	moduleCtx.Optimize()
+
+	// The unused locals, the nops and the set and get pairs of the original functions are kept.
+	if code := moduleCtx.String(); code != expected {
+		t.Errorf("original functions must not be changed, expected:\n%s\ngot:\n%s", expected, code)
+	}
+	if fnDef, ok := moduleCtx.Function("$f"); !ok || len(fnDef.Locals) != 2 {
+		t.Error("original locals must be kept on the context")
+	}
+}
+
+var optimizeModuleCode = `
+(module
+	(type $t0 (func (result i32)))
+	(type $wmr_t1 (func (param i32)))
+	(type $wmr_t2 (func (param f64)))
+	(type $wmr_t3 (func))
+	(import "env" "log" (func $log (type $wmr_t1)))
+	(import "operations" "clear" (func $operations.clear (type $wmr_t3)))
+	(func $g (type $t0) (result i32)
+		(i32.const 1))
+	(func $f (export "f") (type $t0) (result i32)
+		(local $wmr_l0_i32 i32) (local $wmr_dead i32) (local $wmr_const i32) (local $used i32)
+		(local.set $wmr_dead (call $g))
+		(local.set $wmr_const (i32.const 2))
+		(local.set $used (i32.const 3))
+		(call $wmr_f1 (i32.add (local.get $used) (local.get $used)))
+		(local.set $wmr_l0_i32 (call $g))
+		(i32.eqz (local.get $wmr_l0_i32)))
+	(func $wmr_f1 (type $wmr_t1) (param $p i32)
+		(nop)
+		(call $log (local.get $p)))
+	(func $wmr_f2 (type $wmr_t2) (param $p f64)
+		(call $operations.clear)))
+`
+
+var optimizeIndexesCode = `
+(module
+	(type $t0 (func (result i32)))
+	(type $wmr_t1 (func))
+	(import "env" "log" (func $log (type $wmr_t1)))
+	(import "operations" "clear" (func $operations.clear (type $wmr_t1)))
+	(func $g (type $t0) (result i32)
+		(i32.const 1))
+	(func $f (export "f") (type $t0) (result i32)
+		(call 2))
+	(func $wmr_f1 (type $wmr_t1)
+		(call $log)))`
+
+var optimizeOriginalCode = `
+(module
+	(type $t0 (func (result i32)))
+	(func $g (type $t0) (result i32)
+		(i32.const 1))
+	(func $f (type $t0) (result i32)
+		(local $unused i32) (local $t i32)
+		(nop)
+		(local.set $t (call $g))
+		(i32.eqz (local.get $t))))
+`
@@ -3,6 +3,7 @@ package wcode
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"unicode"
 
@@ -557,6 +558,7 @@ type evaluation struct {
 	*blockImpl
 	sb     *strings.Builder
 	blocks []Block
+	advice string
 }
 
 // newEvaluation is a constructor for evaluation.
@@ -1159,22 +1161,39 @@ func (parser *CodeParser) parseEvaluationRef(start int) (Block, int) {
 	i := start + 1
 	for i < parser.Len() {
 		r := rune(parser.Index(i))
-		if unicode.IsSpace(r) || r == '(' || r == ')' || r == '[' {
+		if unicode.IsSpace(r) || r == '(' || r == ')' || r == '[' || r == '.' {
 			break
 		}
 		i++
 	}
 	ref := newEvaluationRef(parser.code[start:i])
 	for i < parser.Len() {
-		r := rune(parser.Index(i))
-		if r != '[' {
-			break
+		switch parser.Index(i) {
+		case '[':
+			i = addBlock(ref, i, parser.parseEvaluationIndex(ref))
+		case '.':
+			i = addBlock(ref, i, parser.parseEvaluationField(ref))
+		default:
+			return ref, i
 		}
-		i = addBlock(ref, i, parser.parseEvaluationIndex(ref))
 	}
 	return ref, i
 }
 
+// parseEvaluationField parses the access to a struct field on evaluation references.
+// the field is handled as an index with the field name (ex. #a.b is the same as #a["b"]).
+func (parser *CodeParser) parseEvaluationField(identifier Block) func(int) (Block, int) {
+	return func(start int) (Block, int) {
+		evalIndex := newEvaluationIndex(identifier)
+		i := start + 1
+		for i < parser.Len() && wutils.IsIdentifier(rune(parser.Index(i))) {
+			i++
+		}
+		evalIndex.push(newEvaluationText(strconv.Quote(parser.code[start+1 : i])))
+		return evalIndex, i - 1
+	}
+}
+
 // parseMultilineComment parses multi-line comment blocks.
 func (parser *CodeParser) parseMultilineComment(start int) (Block, int) {
 	cmt := newComment()
@@ -68,6 +68,17 @@ func TestStructureParser_Evaluation(t *testing.T) {
 	fmt.Println(res.String())
 }
 
+func TestStructureParser_EvaluationField(t *testing.T) {
+	p := &CodeParser{
+		code: "(local.set #rec.count /#rec.count + 1/)",
+	}
+	res := p.parse()
+	expected := `(local.set #rec[/"count"/] /#rec.count + 1/)`
+	if res.String() != expected {
+		t.Errorf("expected %s, got %s", expected, res.String())
+	}
+}
+
 var longCode = `(func $e (type $t0)
     (local $l1 i64)
     (local.set $l1 (i64.const 100000))
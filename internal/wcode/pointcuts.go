@@ -3,6 +3,8 @@ package wcode
 import (
 	"joao/wasm-manipulator/internal/wparser/lex"
 	"strings"
+
+	"github.com/sirupsen/logrus"
 )
 
 // funcVisitor represents the module visitor for the func pointcut.
@@ -143,21 +145,39 @@ func (rv *returnsVisitor) VisitInstruction(instr *Instruction) bool {
 
 	// Append the last instruction as return.
 	returns := visitor.instrs
+	var implicitReturn Block
 	if lastReturn := instr.values[len(instr.values)-1]; len(returns) == 0 || lastReturn != returns[len(returns)-1] {
-		if fnDef.Result == "" {
+		if len(fnDef.Results) == 0 {
 			lastReturn = NewCodeParser("(return)").parse().blocks[0]
 			fnDef.instr.values = append(fnDef.instr.values, lastReturn)
 			lastReturn.setParent(fnDef.instr)
 		}
 		returns = append(returns, lastReturn)
+		implicitReturn = lastReturn
 	}
 
 	// Call visitor for each return instruction.
 	for _, ret := range returns {
 		returnsData := newReturnsData(rv.context, fnDef, ret)
-		if env, ok := rv.filter(rv.context, returnsData); ok {
-			rv.visitor.VisitReturns(ret, returnsData, env)
+		env, ok := rv.filter(rv.context, returnsData)
+		if !ok {
+			continue
+		}
+		if ret == implicitReturn && !rv.producesResults(fnDef, ret) {
+			logrus.Fatalf("returns of function %s: the last instruction does not produce all the results (%s), "+
+				"which requires a return instruction", fnDef.Name, strings.Join(fnDef.Results, " "))
 		}
+		rv.visitor.VisitReturns(ret, returnsData, env)
 	}
 	return true
 }
+
+// producesResults returns if the last instruction of a function produces all its results.
+// the last instruction of a multi-value function only covers one of the results when they are produced separately.
+func (rv *returnsVisitor) producesResults(fnDef *FunctionDefinition, last Block) bool {
+	if len(fnDef.Results) <= 1 {
+		return true
+	}
+	instrType, ok := rv.context.InstructionType(fnDef, last)
+	return ok && len(instrType.Produced) == len(fnDef.Results)
+}
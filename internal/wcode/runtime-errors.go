@@ -0,0 +1,81 @@
+package wcode
+
+// RuntimeErrorContext contains the provenance data of a runtime expression.
+// it is used by the runtime to report the errors found while evaluating the expression.
+type RuntimeErrorContext struct {
+	ID            int
+	Advice        string
+	Function      string
+	FunctionIndex int
+	Expression    string
+}
+
+// runtimeErrorContext contains the provenance data of a runtime expression while the module is being transformed.
+type runtimeErrorContext struct {
+	advice     string
+	fnDef      *FunctionDefinition
+	expression string
+}
+
+// newRuntimeErrorContext is a constructor for runtimeErrorContext.
+func newRuntimeErrorContext(advice string, fnDef *FunctionDefinition, expression string) *runtimeErrorContext {
+	return &runtimeErrorContext{
+		advice:     advice,
+		fnDef:      fnDef,
+		expression: expression,
+	}
+}
+
+// SetEvaluationsAdvice sets the advice of the function evaluations without advice.
+// it must be called after the advice code is applied to the function.
+func (ctx *ModuleContext) SetEvaluationsAdvice(fnDef *FunctionDefinition, advice string) {
+	if fnDef.instr == nil {
+		return
+	}
+	fnDef.instr.Traverse(newEvaluationAdviceVisitor(advice))
+}
+
+// ErrorContexts returns the provenance data of all the runtime expressions.
+// the identifier of each context is the one passed to the error module by the expression code.
+func (ctx *ModuleContext) ErrorContexts() []RuntimeErrorContext {
+	res := make([]RuntimeErrorContext, len(ctx.errorContexts))
+	for i, errCtx := range ctx.errorContexts {
+		res[i] = RuntimeErrorContext{
+			ID:            i,
+			Advice:        errCtx.advice,
+			Function:      errCtx.fnDef.Name,
+			FunctionIndex: errCtx.fnDef.Index(ctx),
+			Expression:    errCtx.expression,
+		}
+	}
+	return res
+}
+
+// addErrorContext registers the provenance data of an evaluation.
+// returns the identifier of the context.
+func (ctx *ModuleContext) addErrorContext(fnDef *FunctionDefinition, eval *evaluation) int {
+	ctx.errorContexts = append(ctx.errorContexts, newRuntimeErrorContext(eval.advice, fnDef, eval.CleanString()))
+	return len(ctx.errorContexts) - 1
+}
+
+// evaluationAdviceVisitor is used to set the advice of the evaluations.
+type evaluationAdviceVisitor struct {
+	*visitorAdapter
+	advice string
+}
+
+// newEvaluationAdviceVisitor is a constructor for evaluationAdviceVisitor.
+func newEvaluationAdviceVisitor(advice string) *evaluationAdviceVisitor {
+	return &evaluationAdviceVisitor{
+		visitorAdapter: new(visitorAdapter),
+		advice:         advice,
+	}
+}
+
+// VisitEvaluation visits an evaluation block.
+func (v *evaluationAdviceVisitor) VisitEvaluation(eval *evaluation) bool {
+	if eval.advice == "" {
+		eval.advice = v.advice
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package wcode
+
+import "sort"
+
+// RuntimeComposite contains a composite value used by the runtime expressions.
+// these values are kept on the javascript zones, so they make the module require the javascript glue code.
+type RuntimeComposite struct {
+	Function      string
+	FunctionIndex int
+	Value         string
+	Type          string
+}
+
+// runtimeComposite contains a composite value used by the runtime expressions while the module is being transformed.
+type runtimeComposite struct {
+	fnDef *FunctionDefinition
+	value string
+	typ   string
+}
+
+// addRuntimeComposite registers a composite value used by the runtime expressions of some function.
+// the values used more than once by the same function are only registered once.
+func (ctx *ModuleContext) addRuntimeComposite(fnDef *FunctionDefinition, value, typ string) {
+	for _, composite := range ctx.composites {
+		if composite.fnDef == fnDef && composite.value == value {
+			return
+		}
+	}
+	ctx.composites = append(ctx.composites, &runtimeComposite{fnDef: fnDef, value: value, typ: typ})
+}
+
+// RuntimeComposites returns the composite values used by the runtime expressions, sorted by function.
+func (ctx *ModuleContext) RuntimeComposites() []RuntimeComposite {
+	res := make([]RuntimeComposite, len(ctx.composites))
+	for i, composite := range ctx.composites {
+		res[i] = RuntimeComposite{
+			Function:      composite.fnDef.Name,
+			FunctionIndex: composite.fnDef.Index(ctx),
+			Value:         composite.value,
+			Type:          composite.typ,
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].FunctionIndex < res[j].FunctionIndex
+	})
+	return res
+}
+
+// GlueFunctions returns the groups of glue functions imported by the module, e.g., operations or zone.
+// the module requires the javascript code when some group is imported.
+func (ctx *ModuleContext) GlueFunctions() []string {
+	return ctx.glueGroups
+}
@@ -0,0 +1,301 @@
+package wcode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"joao/wasm-manipulator/internal/wgenerator"
+)
+
+// pureCompositeType is the web assembly type used to represent the composite values on the in-module runtime.
+const pureCompositeType = "i32"
+
+// applyPureCompositeTransformations replaces the composite variables by handles of the in-module runtime.
+// every composite local is initialized at the function start.
+func (ctx *ModuleContext) applyPureCompositeTransformations() {
+	// Initialize composite locals.
+	for _, fnDef := range ctx.functions {
+		if fnDef.Imported != nil || fnDef.instr == nil {
+			continue
+		}
+		var code []string
+		for _, local := range fnDef.LocalsArr() {
+			if IsVarTypeStrPrimitive(local.Type) {
+				continue
+			}
+			valueCode, err := pureInitialValueCode(local.Type, local.initialValue)
+			if err != nil {
+				logrus.Fatalf("initializing local %s of function %s on the in-module runtime: %v", local.Name, fnDef.Name, err)
+			}
+			code = append(code, wgenerator.SetLocalInstructionToCode(local.Name, valueCode))
+		}
+		if len(code) == 0 {
+			continue
+		}
+		if err := ctx.addCodeAtFuncStart(strings.Join(code, "\n"), fnDef); err != nil {
+			logrus.Fatalf("adding composite locals initialization to function %s: %v", fnDef.Name, err)
+		}
+		ctx.usePureRuntime = true
+	}
+
+	// Replace composite types by runtime handles.
+	propsVisitor := newPureCompositePropsVisitor()
+	ctx.entryBlock.Traverse(propsVisitor)
+	ctx.usePureRuntime = ctx.usePureRuntime || propsVisitor.found
+}
+
+// addPureRuntime adds the in-module runtime to the module.
+// a new memory is created when the module does not have one.
+func (ctx *ModuleContext) addPureRuntime() {
+	if !ctx.usePureRuntime {
+		return
+	}
+	memoryVisitor := newMemoryInstrsVisitor()
+	ctx.entryBlock.Traverse(memoryVisitor)
+	code := wgenerator.GetPureRuntimeCode(!memoryVisitor.found)
+	ctx.addBlocks(NewCodeParser(code).parse())
+}
+
+// useRuntimeComposite registers a composite value used by a runtime expression of some function.
+// returns an error on pure mode, where the composite values cannot be used by the runtime expressions.
+func (ctx *ModuleContext) useRuntimeComposite(fnDef *FunctionDefinition, name, typ string) error {
+	if !ctx.PureWasm {
+		ctx.addRuntimeComposite(fnDef, name, typ)
+		return nil
+	}
+	return fmt.Errorf("%s has a composite type and cannot be used by runtime expressions on pure wasm mode", name)
+}
+
+// pureInitialValueCode returns the instruction that creates a composite value on the in-module runtime.
+func pureInitialValueCode(typeStr, initialValue string) (string, error) {
+	typ, err := newVariableType(typeStr)
+	if err != nil {
+		return "", fmt.Errorf("resolving composite type: %w", err)
+	}
+	var value interface{}
+	switch {
+	case typ.Type() == varTypeString, typ.Type() == varTypeIdentifier:
+		value = initialValue
+	case initialValue != "":
+		if err := json.Unmarshal([]byte(initialValue), &value); err != nil {
+			return "", fmt.Errorf("parsing initial value %s: %w", initialValue, err)
+		}
+	}
+	return pureValueCode(typ, value)
+}
+
+// pureValueCode returns the instruction that creates a composite value on the in-module runtime.
+func pureValueCode(typ variableType, value interface{}) (string, error) {
+	switch t := typ.(type) {
+	case *simpleType:
+		if t.Type() != varTypeString && t.Type() != varTypeIdentifier {
+			return "", fmt.Errorf("type %s is not a composite type", t)
+		}
+		var str string
+		if value != nil {
+			str = fmt.Sprint(value)
+		}
+		return wgenerator.GetPureStringCode(str), nil
+	case *arrayType:
+		var values []string
+		list, ok := value.([]interface{})
+		if value != nil && !ok {
+			return "", fmt.Errorf("value %v is not valid for type %s", value, t)
+		}
+		for _, elem := range list {
+			slot, err := pureSlotCode(t.value, elem)
+			if err != nil {
+				return "", fmt.Errorf("array element: %w", err)
+			}
+			values = append(values, slot)
+		}
+		return wgenerator.GetPureArrayCode(values), nil
+	case *mapType:
+		var keys, values []string
+		entries, ok := value.([]interface{})
+		if value != nil && !ok {
+			return "", fmt.Errorf("value %v is not valid for type %s", value, t)
+		}
+		for _, entry := range entries {
+			pair, ok := entry.([]interface{})
+			if !ok || len(pair) != 2 {
+				return "", fmt.Errorf("map entry %v must be a key-value pair", entry)
+			}
+			key, err := pureSlotCode(t.key, pair[0])
+			if err != nil {
+				return "", fmt.Errorf("map key: %w", err)
+			}
+			val, err := pureSlotCode(t.value, pair[1])
+			if err != nil {
+				return "", fmt.Errorf("map value: %w", err)
+			}
+			keys, values = append(keys, key), append(values, val)
+		}
+		stringKeys := t.key.Type() == varTypeString || t.key.Type() == varTypeIdentifier
+		return wgenerator.GetPureMapCode(stringKeys, keys, values), nil
+	case *structType:
+		// Structs are maps with string keys, with every field set (null value when missing).
+		var keys, values []string
+		fields, ok := value.(map[string]interface{})
+		if value != nil && !ok {
+			return "", fmt.Errorf("value %v is not valid for type %s", value, t)
+		}
+		for name := range fields {
+			if _, ok := t.Field(name); !ok {
+				return "", fmt.Errorf("field %s not found on type %s", name, t)
+			}
+		}
+		for _, field := range t.fields {
+			key, err := pureSlotCode(newSimpleType(varTypeString), field.name)
+			if err != nil {
+				return "", fmt.Errorf("struct field %s: %w", field.name, err)
+			}
+			val, err := pureSlotCode(field.value, fields[field.name])
+			if err != nil {
+				return "", fmt.Errorf("struct field %s: %w", field.name, err)
+			}
+			keys, values = append(keys, key), append(values, val)
+		}
+		return wgenerator.GetPureMapCode(true, keys, values), nil
+	}
+	return "", fmt.Errorf("type %s is not supported by the in-module runtime", typ)
+}
+
+// pureSlotCode returns the instruction that stores a value on an array, map or struct slot.
+func pureSlotCode(typ variableType, value interface{}) (string, error) {
+	if !isVarTypePrimitive(typ.Type()) {
+		code, err := pureValueCode(typ, value)
+		if err != nil {
+			return "", err
+		}
+		return wgenerator.GetPureSlotCode(typ.String(), code), nil
+	}
+	var str string
+	switch v := value.(type) {
+	case nil:
+		// Empty by design. Null value is used.
+	case float64:
+		str = strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		str = v
+	default:
+		return "", fmt.Errorf("value %v is not valid for type %s", value, typ)
+	}
+	return wgenerator.GetPureSlotCode(string(typ.Type()), str), nil
+}
+
+// pureGlobalCompositeVisitor is a visitor used to initialize the global composite variables on the in-module runtime.
+type pureGlobalCompositeVisitor struct {
+	*visitorAdapter
+	ctx        *ModuleContext
+	startFn    *FunctionDefinition
+	foundCount int
+}
+
+// newPureGlobalCompositeVisitor is a constructor for pureGlobalCompositeVisitor.
+func newPureGlobalCompositeVisitor(ctx *ModuleContext, startFn *FunctionDefinition) *pureGlobalCompositeVisitor {
+	return &pureGlobalCompositeVisitor{
+		visitorAdapter: new(visitorAdapter),
+		ctx:            ctx,
+		startFn:        startFn,
+	}
+}
+
+// VisitInstruction handles some instruction block.
+func (gv *pureGlobalCompositeVisitor) VisitInstruction(instr *Instruction) bool {
+	if instr.name != instructionGlobal || len(instr.values) == 0 {
+		return false
+	}
+
+	// Find global definition.
+	globalName := instr.values[0].String()
+	globalDef, ok := gv.ctx.globals[globalName]
+	if !ok || IsVarTypeStrPrimitive(globalDef.Type) {
+		return false
+	}
+
+	// Replace the global declaration by a runtime handle.
+	parent, ok := instr.getParent().(*Instruction)
+	if !ok {
+		logrus.Fatalf("global instruction %s has an invalid parent block type", globalName)
+	}
+	code := fmt.Sprintf("(%s %s (%s %s) (%s.%s 0))", instructionGlobal, globalName, instructionMutable,
+		pureCompositeType, pureCompositeType, instructionCodeConst)
+	if err := parent.replaceChildWithCode(instr, code); err != nil {
+		logrus.Fatalf("replacing global instruction %s: %v", globalName, err)
+	}
+
+	// Add the initialization code to the start function.
+	valueCode, err := pureInitialValueCode(globalDef.Type, globalDef.initialValue)
+	if err != nil {
+		logrus.Fatalf("initializing global %s on the in-module runtime: %v", globalName, err)
+	}
+	setGlobalEl := NewCodeParser(fmt.Sprintf("(%s %s %s)", instructionCodeSetGlobal, globalName, valueCode)).parse()
+	gv.startFn.addInstrsAtStart(setGlobalEl.blocks)
+
+	gv.ctx.usePureRuntime = true
+	gv.foundCount++
+	return true
+}
+
+// pureCompositePropsVisitor is the visitor used to replace composite properties by runtime handles.
+type pureCompositePropsVisitor struct {
+	*visitorAdapter
+	found bool
+}
+
+// newPureCompositePropsVisitor is a constructor for pureCompositePropsVisitor.
+func newPureCompositePropsVisitor() *pureCompositePropsVisitor {
+	return &pureCompositePropsVisitor{visitorAdapter: new(visitorAdapter)}
+}
+
+// VisitInstruction handles some instructions block.
+func (pv *pureCompositePropsVisitor) VisitInstruction(instr *Instruction) bool {
+	switch instr.name {
+	case instructionParam, instructionLocal, instructionResult:
+	default:
+		return false
+	}
+	var changed bool
+	for i, value := range instr.values {
+		valueStr := value.String()
+		if strings.HasPrefix(valueStr, "$") || IsVarTypeStrPrimitive(valueStr) || !IsVarTypeStrValid(valueStr) {
+			continue
+		}
+		if err := instr.replaceChildByIndex(i, []Block{newText(pureCompositeType)}); err != nil {
+			logrus.Errorf("replacing composite type %s by a runtime handle: %v", valueStr, err)
+			continue
+		}
+		changed = true
+	}
+	pv.found = pv.found || changed
+	return changed
+}
+
+// memoryInstrsVisitor is responsible to find if the module declares or imports a memory.
+type memoryInstrsVisitor struct {
+	*visitorAdapter
+	found bool
+	instr *Instruction
+}
+
+// newMemoryInstrsVisitor is a constructor for memoryInstrsVisitor.
+func newMemoryInstrsVisitor() *memoryInstrsVisitor {
+	return &memoryInstrsVisitor{visitorAdapter: new(visitorAdapter)}
+}
+
+// VisitInstruction handles some instructions block.
+func (mv *memoryInstrsVisitor) VisitInstruction(instr *Instruction) bool {
+	if instr.name != instructionMemory {
+		return false
+	}
+	if !mv.found {
+		mv.instr = instr
+	}
+	mv.found = true
+	return true
+}
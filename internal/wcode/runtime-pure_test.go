@@ -0,0 +1,83 @@
+package wcode
+
+import (
+	"strings"
+	"testing"
+
+	"joao/wasm-manipulator/internal/wtemplate"
+)
+
+func TestRuntimePure_CompositeVariables(t *testing.T) {
+	moduleCtx := NewModuleContext(NewCodeParser(wtemplate.ClearString(pureRuntimeModuleCode)).Parse())
+	moduleCtx.PureWasm = true
+	if _, err := moduleCtx.AddGlobal(`string = "hello"`); err != nil {
+		t.Fatal(err)
+	}
+	fnDef, ok := moduleCtx.Function("$f1")
+	if !ok {
+		t.Fatal("function $f1 not found")
+	}
+	if _, err := moduleCtx.AddLocal(`map[string]i32 = [["a", 1], ["b", 2]]`, fnDef); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := moduleCtx.AddLocal(`[]f32 = [1.5, 2]`, fnDef); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := moduleCtx.AddLocal(`struct{name:string,count:i32} = {"name": "a"}`, fnDef); err != nil {
+		t.Fatal(err)
+	}
+	moduleCtx.ApplyRuntimeTransformations()
+
+	if moduleCtx.NeedJS {
+		t.Error("pure wasm module must not need javascript")
+	}
+	code := moduleCtx.String()
+	for _, expected := range []string{"(memory $wmr_rt.memory 1)", "(func $wmr_rt.alloc", "(call $wmr_rt.map_new (i32.const 1))", "(call $wmr_rt.array_push"} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("module does not contain %q:\n%s", expected, code)
+		}
+	}
+	for _, unexpected := range []string{" string", "map[", "[]f32", "struct{", "(import"} {
+		if strings.Contains(code, unexpected) {
+			t.Errorf("module contains %q:\n%s", unexpected, code)
+		}
+	}
+}
+
+func TestRuntimePure_InitialValues(t *testing.T) {
+	code, err := pureInitialValueCode("string", "abcde")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "(call $wmr_rt.string_write (call $wmr_rt.string_write (call $wmr_rt.string_new (i32.const 5)) (i32.const 0) (i32.const 1633837924)) (i32.const 4) (i32.const 1694498816))"
+	if code != expected {
+		t.Errorf("expected %s, got %s", expected, code)
+	}
+	if _, err := pureInitialValueCode("[]i32", "[1,"); err == nil {
+		t.Error("expected error for an invalid initial value")
+	}
+
+	// Structs are maps with string keys, including the nested structs and the fields without value.
+	code, err = pureInitialValueCode("struct{a:i32,b:struct{c:f64}}", `{"b": {"c": 1.5}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"(call $wmr_rt.map_new (i32.const 1))", "(i64.extend_i32_s (i32.const 0))", "(i64.reinterpret_f64 (f64.const 1.5))"} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("struct code does not contain %q: %s", expected, code)
+		}
+	}
+	if strings.Count(code, "$wmr_rt.map_set") != 3 {
+		t.Errorf("expected a map entry for each struct field: %s", code)
+	}
+	if _, err := pureInitialValueCode("struct{a:i32}", `{"x": 1}`); err == nil {
+		t.Error("expected error for an unknown struct field")
+	}
+}
+
+var pureRuntimeModuleCode = `
+(module
+	(type $t0 (func (result i32)))
+	(func $f1 (type $t0) (result i32) (i32.const 0))
+)
+`
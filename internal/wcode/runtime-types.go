@@ -1,15 +1,19 @@
 package wcode
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"regexp"
 	"strconv"
+	"strings"
 )
 
 var (
-	arrayTypeRegex = regexp.MustCompile(`\[\]\w[\w\d]*`)
-	mapTypeRegex   = regexp.MustCompile(`^map\[\w[\w\d]*\](.)+`)
+	arrayTypeRegex  = regexp.MustCompile(`\[\]\w[\w\d]*`)
+	mapTypeRegex    = regexp.MustCompile(`^map\[\w[\w\d]*\](.)+`)
+	structTypeRegex = regexp.MustCompile(`^struct\{(.*)\}$`)
+	fieldNameRegex  = regexp.MustCompile(`^[a-zA-Z_][\w]*$`)
 )
 
 const (
@@ -21,6 +25,10 @@ const (
 	varTypeArray      varType = "array"
 	varTypeI64        varType = "i64"
 	varTypeF64        varType = "f64"
+	varTypeStruct     varType = "struct"
+	varTypeFuncref    varType = "funcref"
+	varTypeExternref  varType = "externref"
+	varTypeV128       varType = "v128"
 )
 
 var varTypeCodes map[string]int
@@ -49,6 +57,7 @@ func fillVarTypeCodes() {
 		fmt.Sprintf("%s_%s", varTypeMap, varTypeF64),
 		fmt.Sprintf("%s_%s", varTypeMap, varTypeString),
 		string(varTypeArray),
+		string(varTypeStruct),
 	}
 	varTypeCodes = make(map[string]int)
 	for i, t := range types {
@@ -72,6 +81,29 @@ func newVariableType(typeStr string) (variableType, error) {
 	switch typeVar := varType(typeStr); {
 	case typeVar == varTypeIdentifier, typeVar == varTypeString, typeVar == varTypeI32, typeVar == varTypeF32, typeVar == varTypeF64:
 		return newSimpleType(typeVar), nil
+	case structTypeRegex.MatchString(typeStr):
+		fieldsStr := structTypeRegex.FindStringSubmatch(typeStr)[1]
+		var fields []*structField
+		names := make(map[string]struct{})
+		for _, fieldStr := range splitStructFields(fieldsStr) {
+			parts := strings.SplitN(fieldStr, ":", 2)
+			if len(parts) != 2 || !fieldNameRegex.MatchString(parts[0]) {
+				return nil, fmt.Errorf("field %s is invalid for struct", fieldStr)
+			}
+			if _, ok := names[parts[0]]; ok {
+				return nil, fmt.Errorf("field %s is duplicated on struct", parts[0])
+			}
+			names[parts[0]] = struct{}{}
+			fieldType, err := newVariableType(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("type %s is invalid for field %s: %w", parts[1], parts[0], err)
+			}
+			fields = append(fields, &structField{parts[0], fieldType})
+		}
+		if len(fields) == 0 {
+			return nil, errors.New("struct must have at least one field")
+		}
+		return newStructType(fields), nil
 	case mapTypeRegex.MatchString(typeStr):
 		var keyStart, keyEnd int
 		for pos, char := range typeStr {
@@ -104,6 +136,30 @@ func newVariableType(typeStr string) (variableType, error) {
 	return nil, fmt.Errorf("type %s is not valid", typeStr)
 }
 
+// splitStructFields splits the fields of a struct type.
+// the commas of nested structs are ignored.
+func splitStructFields(fieldsStr string) []string {
+	var res []string
+	var depth, start int
+	for i, c := range fieldsStr {
+		switch c {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				res = append(res, fieldsStr[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if start < len(fieldsStr) {
+		res = append(res, fieldsStr[start:])
+	}
+	return res
+}
+
 // simpleType is for variables with a simple type.
 type simpleType struct {
 	value varType
@@ -195,13 +251,64 @@ func (t mapType) Code() int {
 	return getComplexCode(t.value.Code(), t.Label)
 }
 
+// structField is a field of a struct type.
+type structField struct {
+	name  string
+	value variableType
+}
+
+// structType is for variables of type struct.
+type structType struct {
+	fields []*structField
+}
+
+// newStructType is a constructor for structType.
+func newStructType(fields []*structField) *structType {
+	return &structType{fields}
+}
+
+// String returns the string value for the type.
+func (t structType) String() string {
+	var fields []string
+	for _, field := range t.fields {
+		fields = append(fields, fmt.Sprintf("%s:%s", field.name, field.value))
+	}
+	return fmt.Sprintf("struct{%s}", strings.Join(fields, ","))
+}
+
+// Type returns the base type.
+func (t structType) Type() varType {
+	return varTypeStruct
+}
+
+// Label returns the type label.
+func (t structType) Label() string {
+	return string(t.Type())
+}
+
+// Code returns the type code.
+// the fields are not part of the code, their values are handled dynamically by the runtime.
+func (t structType) Code() int {
+	return varTypeCode(t.Label())
+}
+
+// Field returns the type of some field.
+func (t structType) Field(name string) (variableType, bool) {
+	for _, field := range t.fields {
+		if field.name == name {
+			return field.value, true
+		}
+	}
+	return nil, false
+}
+
 // IsVarTypeStrPrimitive returns if the variable in string format has a primitive type.
 func IsVarTypeStrPrimitive(t string) bool {
 	return isVarTypePrimitive(varType(t))
 }
 
-// isVarTypeStrValid returns if the variable type in string format is valid.
-func isVarTypeStrValid(t string) bool {
+// IsVarTypeStrValid returns if the variable type in string format is valid.
+func IsVarTypeStrValid(t string) bool {
 	return isVarTypeValid(varType(t))
 }
 
@@ -210,7 +317,7 @@ func isVarTypeValid(vt varType) bool {
 	t := string(vt)
 	switch {
 	case vt == varTypeI32, vt == varTypeF32, vt == varTypeF64, vt == varTypeString, vt == varTypeIdentifier,
-		arrayTypeRegex.MatchString(t), mapTypeRegex.MatchString(t):
+		isVarTypeOpaque(vt), arrayTypeRegex.MatchString(t), mapTypeRegex.MatchString(t), structTypeRegex.MatchString(t):
 		return true
 	default:
 		return false
@@ -220,6 +327,8 @@ func isVarTypeValid(vt varType) bool {
 // isVarSimpleTypeValid returns if the variable has a simple type.
 func isVarSimpleTypeValid(t string) bool {
 	switch vt := varType(t); {
+	case isVarTypeOpaque(vt):
+		return false
 	case IsVarTypeStrPrimitive(t), vt == varTypeString, vt == varTypeIdentifier:
 		return true
 	default:
@@ -230,13 +339,24 @@ func isVarSimpleTypeValid(t string) bool {
 // isVarTypePrimitive returns if the variable has a primitive type.
 func isVarTypePrimitive(t varType) bool {
 	switch t {
-	case varTypeI32, varTypeF32, varTypeI64, varTypeF64:
+	case varTypeI32, varTypeF32, varTypeI64, varTypeF64, varTypeFuncref, varTypeExternref, varTypeV128:
 		return true
 	default:
 		return false
 	}
 }
 
+// isVarTypeReference returns if the variable has a reference type.
+func isVarTypeReference(t varType) bool {
+	return t == varTypeFuncref || t == varTypeExternref
+}
+
+// isVarTypeOpaque returns if the variable has a type that the runtime only passes through (references and vectors).
+// opaque values are never stored on composite types.
+func isVarTypeOpaque(t varType) bool {
+	return isVarTypeReference(t) || t == varTypeV128
+}
+
 // getComplexCode returns the complex type code.
 func getComplexCode(subTypeCode int, labelFn func()string) int {
 	minSubTypeShiftV := strconv.FormatInt(int64(len(varTypeCodes)), 2)
@@ -0,0 +1,24 @@
+package wcode
+
+import "testing"
+
+func TestRuntimeTypes_Struct(t *testing.T) {
+	typ, err := newVariableType("struct{name:string,times:[]f64,inner:struct{a:i32}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ.Code() != varTypeCode(string(varTypeStruct)) {
+		t.Errorf("unexpected struct code %d", typ.Code())
+	}
+	if err := validateStructMember(typ, `"times"][0`); err != nil {
+		t.Error(err)
+	}
+	if err := validateStructMember(typ, `"missing"`); err == nil {
+		t.Error("expected error for a missing field")
+	}
+	for _, invalid := range []string{"struct{}", "struct{a:i32,a:f32}", "struct{a}", "struct{a:bool}"} {
+		if _, err := newVariableType(invalid); err == nil {
+			t.Errorf("expected error for type %s", invalid)
+		}
+	}
+}
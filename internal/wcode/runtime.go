@@ -429,13 +429,13 @@ func (rv *runtimeVisitor) getZoneVariable(fnDef *FunctionDefinition, blockStr st
 	}
 
 	if !ok { // Not found on the function. Must be a global!
-		return rv.getZoneGlobalVariable(name, key)
+		return rv.getZoneGlobalVariable(fnDef, name, key)
 	} // Found on the function the local index for the name.
 	return rv.getZoneFunctionVariable(fnDef, name, key, index)
 }
 
 // getZoneGlobalVariable returns the global variable definition.
-func (rv *runtimeVisitor) getZoneGlobalVariable(name, key string) (evaluationZoneTarget, error) {
+func (rv *runtimeVisitor) getZoneGlobalVariable(fnDef *FunctionDefinition, name, key string) (evaluationZoneTarget, error) {
 	// Check if the variable is global.
 	index, ok := rv.ctx.AliasKey(name)
 	if !ok {
@@ -446,6 +446,9 @@ func (rv *runtimeVisitor) getZoneGlobalVariable(name, key string) (evaluationZon
 		return nil, fmt.Errorf("could not find the global definition for %s", index)
 	}
 	if !IsVarTypeStrPrimitive(globalDef.Type) {
+		if err := rv.ctx.useRuntimeComposite(fnDef, fmt.Sprintf("global %s", name), globalDef.Type); err != nil {
+			return nil, err
+		}
 		// Set global of complex types must be added to start function.
 		return nil, nil
 	}
@@ -460,7 +463,7 @@ func (rv *runtimeVisitor) getZoneGlobalVariable(name, key string) (evaluationZon
 func (rv *runtimeVisitor) getZoneFunctionVariable(fnDef *FunctionDefinition, name, key, index string) (evaluationZoneTarget, error) {
 	// Check function parameters.
 	if param, ok := fnDef.Params[index]; ok {
-		if ok := isVarTypeStrValid(param.Type); !ok {
+		if ok := IsVarTypeStrValid(param.Type); !ok {
 			return nil, fmt.Errorf("param identifier %s on evaluation has an invalid type (%s)", param.Name, param.Type)
 		}
 		typ, err := newVariableType(param.Type)
@@ -468,6 +471,9 @@ func (rv *runtimeVisitor) getZoneFunctionVariable(fnDef *FunctionDefinition, nam
 			return nil, fmt.Errorf("getting parameter on function definition type: %w", err)
 		}
 		if !IsVarTypeStrPrimitive(param.Type) {
+			if err := rv.ctx.useRuntimeComposite(fnDef, fmt.Sprintf("param %s", name), param.Type); err != nil {
+				return nil, err
+			}
 			return newEvalCompositeZoneTarget(name, key, strconv.Itoa(param.order), typ.Code(), evaluationZoneTypeParam), nil
 		}
 		return newEvalPrimitiveZoneTarget(name, index, typ, evaluationZoneTypeParam), nil
@@ -475,7 +481,7 @@ func (rv *runtimeVisitor) getZoneFunctionVariable(fnDef *FunctionDefinition, nam
 
 	// Check function locals.
 	if local, ok := fnDef.Locals[index]; ok {
-		if ok := isVarTypeStrValid(local.Type); !ok {
+		if ok := IsVarTypeStrValid(local.Type); !ok {
 			return nil, fmt.Errorf("local identifier %s on evaluation has an invalid type (%s)", local.Name, local.Type)
 		}
 		typ, err := newVariableType(local.Type)
@@ -483,6 +489,9 @@ func (rv *runtimeVisitor) getZoneFunctionVariable(fnDef *FunctionDefinition, nam
 			return nil, fmt.Errorf("getting local on function definition type: %w", err)
 		}
 		if !IsVarTypeStrPrimitive(local.Type) {
+			if err := rv.ctx.useRuntimeComposite(fnDef, fmt.Sprintf("local %s", name), local.Type); err != nil {
+				return nil, err
+			}
 			return newEvalCompositeZoneTarget(name, key, local.initialValue, typ.Code(), evaluationZoneTypeLocal), nil
 		}
 		return newEvalPrimitiveZoneTarget(name, index, typ, evaluationZoneTypeLocal), nil
@@ -606,18 +615,27 @@ func (rv *runtimeVisitor) visitGlobalEvaluation(changes *runtimeChanges, eval *e
 func (rv *runtimeVisitor) visitReturnEvaluation(fnDef *FunctionDefinition, changes *runtimeChanges, eval *evaluation) error {
 	parent := eval.parent.(*Instruction)
 
-	if IsVarTypeStrPrimitive(fnDef.Result) {
-		return rv.handleEvaluationPrimitive(rv.ctx, changes, parent, eval, varType(fnDef.Result))
+	if len(fnDef.Results) != 1 {
+		return fmt.Errorf("returning an evaluation requires function %s to have a single result, found %d", fnDef.Name, len(fnDef.Results))
 	}
-	return rv.handleReturnEvaluationComposite(changes, parent, eval, varType(fnDef.Result))
+	if !fnDef.ReturnsComposite() {
+		return rv.handleEvaluationPrimitive(rv.ctx, changes, parent, eval, varType(fnDef.result()))
+	}
+	return rv.handleReturnEvaluationComposite(changes, parent, eval, varType(fnDef.result()))
 }
 
 // visitReturnEvaluationRef visits the evaluation reference block used on some return instruction.
 func (rv *runtimeVisitor) visitReturnEvaluationRef(fnDef *FunctionDefinition, changes *runtimeChanges, ref *evaluationRef) error {
 	parent := ref.parent.(*Instruction)
+	if err := rv.ctx.useRuntimeComposite(fnDef, fmt.Sprintf("result of function %s", fnDef.Name), fnDef.result()); err != nil {
+		return err
+	}
 
 	// Add extra code for the composite evaluation.
-	if err := rv.addEvaluationRefCompositeReturnCode(changes, parent, ref, varType(fnDef.Result)); err != nil {
+	if !fnDef.ReturnsComposite() {
+		return fmt.Errorf("returning a composite reference requires function %s to have a single composite result", fnDef.Name)
+	}
+	if err := rv.addEvaluationRefCompositeReturnCode(changes, parent, ref, varType(fnDef.result())); err != nil {
 		return fmt.Errorf("adding code for composite return evaluation reference: %w", err)
 	}
 
@@ -658,7 +676,8 @@ func (rv *runtimeVisitor) addEvaluationPrimitiveCode(changes *runtimeChanges, ev
 	}
 
 	// Add argument code for primitive
-	newStartCode, newOperationsCount := wgenerator.GetPrimitiveEvalStartCode(eval.CleanString(), localName, string(localType), changes.operationsCount)
+	exprID := rv.ctx.addErrorContext(changes.fnDef, eval)
+	newStartCode, newOperationsCount := wgenerator.GetPrimitiveEvalStartCode(eval.CleanString(), exprID, localName, string(localType), changes.operationsCount)
 	if err := AddCodeToControlFlow(eval, newStartCode, 0); err != nil {
 		return fmt.Errorf("adding evaluation start blocks of type primitive to function: %v", err)
 	}
@@ -675,9 +694,11 @@ func (rv *runtimeVisitor) addEvaluationCompositeCallCode(changes *runtimeChanges
 		rv.glueFunctions.args[parent] = struct{}{}
 	}
 	rv.glueFunctions.operations = true
+	rv.glueFunctions.err = true
 
 	// Add composite argument code for the call.
-	newStartCode, newOperationsCount := wgenerator.GetCompositeCallEvalStartCode(eval.CleanString(), argIndex, pushPopArgs, changes.operationsCount)
+	exprID := rv.ctx.addErrorContext(changes.fnDef, eval)
+	newStartCode, newOperationsCount := wgenerator.GetCompositeCallEvalStartCode(eval.CleanString(), exprID, argIndex, pushPopArgs, changes.operationsCount)
 	if err := AddCodeToControlFlow(eval, newStartCode, 0); err != nil {
 		return fmt.Errorf("adding evaluation start blocks of type %s to function: %v", typ.Type(), err)
 	}
@@ -702,10 +723,13 @@ func (rv *runtimeVisitor) addEvaluationCompositeCallCode(changes *runtimeChanges
 		return nil
 	}
 	fnDef := changes.fnDef
-	localName := rv.ctx.generateLocalIndexStr(fnDef.Result, "result") // result is an arbitrary name for the local that saves the result
+	if len(fnDef.Results) != 1 {
+		return fmt.Errorf("composite call arguments on the return of function %s require a single result, found %d", fnDef.Name, len(fnDef.Results))
+	}
+	localName := rv.ctx.generateLocalIndexStr(fnDef.result(), "result") // result is an arbitrary name for the local that saves the result
 	// Mark function to add a new local (ex. local_i32_1)
 	if _, ok := changes.localsToAdd[localName]; !ok {
-		changes.localsToAdd[localName] = newAddedEvaluationTarget(newEvaluationTarget(localName, localName, varType(fnDef.Result)))
+		changes.localsToAdd[localName] = newAddedEvaluationTarget(newEvaluationTarget(localName, localName, varType(fnDef.result())))
 	}
 	returnInstr.name = instructionCodeSetLocal
 	returnInstr.values = append([]Block{newText(localName)}, returnInstr.values...)
@@ -721,9 +745,11 @@ func (rv *runtimeVisitor) addEvaluationCompositeCallCode(changes *runtimeChanges
 // addEvaluationCompositeReturnCode adds the composite code originated from the return evaluation.
 func (rv *runtimeVisitor) addEvaluationCompositeReturnCode(changes *runtimeChanges, parent *Instruction, eval *evaluation, typ varType) error {
 	rv.glueFunctions.returns = true
+	rv.glueFunctions.err = true
 
 	// Add composite code for the return.
-	newCode, newOperationsCount := wgenerator.GetCompositeReturnEvalCode(eval.CleanString(), changes.operationsCount)
+	exprID := rv.ctx.addErrorContext(changes.fnDef, eval)
+	newCode, newOperationsCount := wgenerator.GetCompositeReturnEvalCode(eval.CleanString(), exprID, changes.operationsCount)
 	if err := AddCodeToControlFlowIndexFn(eval, newCode, func(values []Block, index int) int {
 		if len(values) == 0 || index == 0 {
 			return index
@@ -781,6 +807,10 @@ func (rv *runtimeVisitor) handleCallEvaluationPrimitive(changes *runtimeChanges,
 
 // handleCallEvaluationComposite handles the call evaluation for a composite argument.
 func (rv *runtimeVisitor) handleCallEvaluationComposite(changes *runtimeChanges, parent *Instruction, eval *evaluation, typ variableType, paramIndex int) error {
+	if err := rv.ctx.useRuntimeComposite(changes.fnDef, fmt.Sprintf("argument %d", paramIndex), typ.String()); err != nil {
+		return err
+	}
+
 	// Add extra code for the composite evaluation.
 	if err := rv.addEvaluationCompositeCallCode(changes, parent, eval, typ, paramIndex); err != nil {
 		return fmt.Errorf("adding code for composite evaluation: %w", err)
@@ -825,11 +855,17 @@ func (rv *runtimeVisitor) handleEvaluationPrimitive(aliasMap AliasDefinition, ch
 
 // handleVariableEvaluationComposite handles the evaluation for composite variables.
 func (rv *runtimeVisitor) handleVariableEvaluationComposite(changes *runtimeChanges, parent *Instruction, eval *evaluation, target *evaluationTarget, isLocal bool) error {
+	if err := rv.ctx.useRuntimeComposite(changes.fnDef, fmt.Sprintf("variable %s", target.alias), string(target.typ)); err != nil {
+		return err
+	}
+
 	// Mark module context to add glue functions.
 	rv.glueFunctions.operations = true
+	rv.glueFunctions.err = true
 
 	// Add zone code for composite.
-	newStartCode, newOperationsCount := wgenerator.GetCompositeZoneEvalStartCode(target.alias, target.key, eval.CleanString(), isLocal, changes.operationsCount)
+	exprID := rv.ctx.addErrorContext(changes.fnDef, eval)
+	newStartCode, newOperationsCount := wgenerator.GetCompositeZoneEvalStartCode(target.alias, target.key, eval.CleanString(), exprID, isLocal, changes.operationsCount)
 	if err := AddCodeToControlFlow(eval, newStartCode, 0); err != nil {
 		return fmt.Errorf("adding evaluation start blocks of type composite to function: %v", err)
 	}
@@ -855,6 +891,10 @@ func (rv *runtimeVisitor) handleVariableEvaluationComposite(changes *runtimeChan
 
 // handleReturnEvaluationComposite handles the evaluation for composite returns.
 func (rv *runtimeVisitor) handleReturnEvaluationComposite(changes *runtimeChanges, parent *Instruction, eval *evaluation, typ varType) error {
+	if err := rv.ctx.useRuntimeComposite(changes.fnDef, fmt.Sprintf("result of function %s", changes.fnDef.Name), string(typ)); err != nil {
+		return err
+	}
+
 	// Add extra code for the composite evaluation.
 	if err := rv.addEvaluationCompositeReturnCode(changes, parent, eval, typ); err != nil {
 		return fmt.Errorf("adding code for composite return evaluation: %w", err)
@@ -922,7 +962,7 @@ func (rv *runtimeVisitor) findCallArgumentTarget(fnDef *FunctionDefinition, call
 		if block == eval {
 			paramType := callee.Parameters()[i-1].Type
 			// Validate argument type
-			if ok := isVarTypeStrValid(paramType); !ok {
+			if ok := IsVarTypeStrValid(paramType); !ok {
 				return nil, fmt.Errorf("parameter type %s is not valid", paramType)
 			}
 			typ, err := newVariableType(paramType)
@@ -950,6 +990,9 @@ func (rv *runtimeVisitor) findLocalType(fnDef *FunctionDefinition, instr *Instru
 		if err != nil {
 			return nil, fmt.Errorf("getting parameter type: %w", err)
 		}
+		if err := validateStructMember(typ, ref.key); err != nil {
+			return nil, fmt.Errorf("parameter %s: %w", ref.name, err)
+		}
 		return newEvaluationTargetMember(ref.name, ref.key, ref.index, typ.Type()), nil
 	}
 	if local, ok := fnDef.Locals[ref.index]; ok {
@@ -958,6 +1001,9 @@ func (rv *runtimeVisitor) findLocalType(fnDef *FunctionDefinition, instr *Instru
 		if err != nil {
 			return nil, fmt.Errorf("getting local type: %w", err)
 		}
+		if err := validateStructMember(typ, ref.key); err != nil {
+			return nil, fmt.Errorf("local %s: %w", ref.name, err)
+		}
 		return newEvaluationTargetMember(ref.name, ref.key, ref.index, typ.Type()), nil
 	}
 	return nil, fmt.Errorf("local named %s not found", ref.name)
@@ -978,21 +1024,45 @@ func (rv *runtimeVisitor) findGlobalType(instr *Instruction) (*evaluationTarget,
 		if err != nil {
 			return nil, fmt.Errorf("getting global type: %w", err)
 		}
+		if err := validateStructMember(typ, ref.key); err != nil {
+			return nil, fmt.Errorf("global %s: %w", ref.name, err)
+		}
 		return newEvaluationTargetMember(ref.name, ref.key, ref.index, typ.Type()), nil
 	}
 	return nil, fmt.Errorf("global named %s not found", ref.name)
 }
 
+// validateStructMember validates the member key used to reference a variable of type struct.
+// the first key must be the name of a struct field.
+func validateStructMember(typ variableType, key string) error {
+	st, ok := typ.(*structType)
+	if !ok || key == "" {
+		return nil
+	}
+	fieldKey := strings.SplitN(key, "][", 2)[0]
+	field, err := strconv.Unquote(fieldKey)
+	if err != nil {
+		return fmt.Errorf("field %s of struct must be a string", fieldKey)
+	}
+	if _, ok := st.Field(field); !ok {
+		return fmt.Errorf("field %s not found on type %s", field, st)
+	}
+	return nil
+}
+
 // findOtherType finds another unknown instruction type.
 func (rv *runtimeVisitor) findOtherType(fnDef *FunctionDefinition, parentInstr *Instruction, eval *evaluation) (variableType, error) {
 	if parentInstr.name == instructionFunction {
-		if fnDef.Result == "" {
+		if len(fnDef.Results) == 0 {
 			return nil, fmt.Errorf("could not get the instruction type %s because the function has no result type", parentInstr.name)
 		}
-		if !IsVarTypeStrPrimitive(fnDef.Result) {
-			return nil, fmt.Errorf("invalid result type %s on function", fnDef.Result)
+		if len(fnDef.Results) > 1 {
+			return nil, fmt.Errorf("could not get the instruction type %s because the function has %d results", parentInstr.name, len(fnDef.Results))
+		}
+		if fnDef.ReturnsComposite() {
+			return nil, fmt.Errorf("invalid result type %s on function", fnDef.result())
 		}
-		return newVariableType(fnDef.Result)
+		return newVariableType(fnDef.result())
 	}
 	parentDef, ok := wlang.GetInstrDefinition(parentInstr.name)
 	if !ok {
@@ -1220,6 +1290,9 @@ func (gv *globalCompositeVisitor) VisitInstruction(instr *Instruction) bool {
 		gv.ctx.runtimeChanges[gv.startFn.Name].hasNewZone = true
 	}
 
+	// Registers the global as a composite value of the start function.
+	gv.ctx.addRuntimeComposite(gv.startFn, fmt.Sprintf("global %s", gv.ctx.GlobalAlias[globalDef.Name]), globalDef.Type)
+
 	// Increments found count.
 	gv.foundCount++
 	return true
@@ -1257,7 +1330,7 @@ func (cr *compositeReturnFuncVisitor) VisitInstruction(instr *Instruction) bool
 	}
 
 	// Check if result type is primitive.
-	if fnDef.Result == "" || IsVarTypeStrPrimitive(fnDef.Result) {
+	if !fnDef.ReturnsComposite() {
 		return false
 	} // If not is composite.
 
@@ -1380,7 +1453,7 @@ func (iv *importCompositeFuncVisitor) VisitInstruction(instr *Instruction) bool
 			break
 		}
 	}
-	isComposite = isComposite || fnDef.Result != "" && !IsVarTypeStrPrimitive(fnDef.Result)
+	isComposite = isComposite || fnDef.ReturnsComposite()
 	if !isComposite {
 		return false
 	}
@@ -1409,9 +1482,12 @@ func newElemTableFixVisitor() *elemTableFixVisitor {
 
 // VisitInstruction handles some instructions block.
 func (ev *elemTableFixVisitor) VisitInstruction(instr *Instruction) bool {
-	if instr.name != instructionElem || !isInternalInstruction(instr) {
+	if instr.name != instructionElem || !isInternalInstruction(instr) || len(instr.values) == 0 {
 		return false
 	}
+	if _, ok := instr.values[0].(*Instruction); ok {
+		return false // Elements without index (e.g. added by the context) are not affected.
+	}
 	err := instr.replaceChildByIndex(0, []Block{newText("0")}) // Only one table must exist on module.
 	if err != nil {
 		logrus.Errorf("changing elem element index: replacing child by index: %v", err)
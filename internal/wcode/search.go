@@ -3,6 +3,7 @@ package wcode
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 
@@ -117,13 +118,32 @@ func (jpB *JoinPointBlock) FuncDefinition() *FunctionDefinition {
 	return nil
 }
 
+// Type returns the stack types of the join-point block.
+func (jpB *JoinPointBlock) Type() (*InstructionType, bool) {
+	fnDef := jpB.FuncDefinition()
+	if fnDef == nil {
+		return nil, false
+	}
+	return jpB.context.InstructionType(fnDef, jpB.block)
+}
+
+// invalidateTypes removes the cached stack types of the join-point function, after changing its code.
+func (jpB *JoinPointBlock) invalidateTypes() {
+	if jpB.context == nil {
+		return
+	}
+	if fnDef := jpB.FuncDefinition(); fnDef != nil {
+		jpB.context.invalidateTypes(fnDef)
+	}
+}
+
 // Instr returns the instruction block of the join-point block.
 func (jpB *JoinPointBlock) Instr() Block {
 	return jpB.block
 }
 
 // applyNonSmart applies non smart modification
-func (jpB *JoinPointBlock) applyNonSmart(fnDef *FunctionDefinition, code string) error {
+func (jpB *JoinPointBlock) applyNonSmart(fnDef *FunctionDefinition, code string, codeEl *element) error {
 	resultType, err := resolveResultType(jpB.context, fnDef, jpB.block)
 	if err != nil {
 		logrus.Warn(err)
@@ -131,7 +151,7 @@ func (jpB *JoinPointBlock) applyNonSmart(fnDef *FunctionDefinition, code string)
 	if resultType != "" && resultType != wlang.Any {
 		logrus.Warnf("Applying advice on instruction that requires a result type %s (Code: %s)", resultType, wtemplate.ClearString(code))
 	}
-	return replaceBlockWithCode(jpB.block, code)
+	return replaceBlock(jpB.block, codeEl.blocks)
 }
 
 // generateLocal generates local variable
@@ -154,7 +174,11 @@ func (jpB *JoinPointBlock) generateLocal(fnDef *FunctionDefinition, smartData *j
 
 // Instr returns the instruction block of the join-point block.
 func (jpB *JoinPointBlock) Apply(code string, smart bool) error {
+	// The stack types of the function change with the new code.
+	defer jpB.invalidateTypes()
+
 	codeEl := NewCodeParser(code).parse()
+	jpB.context.foldElement(jpB.FuncDefinition(), codeEl)
 
 	// No need for smart changes.
 	if len(codeEl.blocks) == 1 {
@@ -169,7 +193,7 @@ func (jpB *JoinPointBlock) Apply(code string, smart bool) error {
 
 	// Apply code on non-smart mode.
 	if !smart {
-		return jpB.applyNonSmart(fnDef, code)
+		return jpB.applyNonSmart(fnDef, code, codeEl)
 	}
 
 	// Checks expected type for the block result.
@@ -199,7 +223,7 @@ func (jpB *JoinPointBlock) Apply(code string, smart bool) error {
 		target := codeEl.findEqual(jpB.block)
 		if target == nil {
 			logrus.Warn("Original instruction not found on the new code...")
-			return jpB.applyNonSmart(fnDef, code)
+			return jpB.applyNonSmart(fnDef, code, codeEl)
 		}
 
 		// Generates a local index to handle the result.
@@ -340,9 +364,21 @@ func resolveResultType(context *ModuleContext, fnDef *FunctionDefinition, block
 	if !ok {
 		return "", nil
 	}
+
+	// Check the stack types analysis to get the produced type.
+	if instrType, ok := context.InstructionType(fnDef, blockInstr); ok {
+		if len(instrType.Produced) > 1 {
+			return "", fmt.Errorf("instruction %s produces multiple values (%s), which is not supported", blockInstr.name, instrType)
+		}
+		if len(instrType.Produced) == 1 && instrType.Produced[0] != wlang.Any {
+			return instrType.Produced[0], nil
+		}
+	}
+
+	// Check the instructions that produce no value or an unknown type.
 	switch n := blockInstr.name; {
 	case n == instructionFunction, n == instructionCodeReturn:
-		return wlang.CodeBlockType(fnDef.Result), nil
+		return functionResultType(fnDef)
 	case n == instructionCodeCall, n == instructionCodeCallIndirect:
 		return resolveResultType(context, fnDef, block.getParent())
 	case n == instructionCodeTeeLocal, n == instructionCodeSetLocal, n == instructionCodeGetLocal:
@@ -354,7 +390,7 @@ func resolveResultType(context *ModuleContext, fnDef *FunctionDefinition, block
 			return wlang.CodeBlockType(param.Type), nil
 		}
 		return "", fmt.Errorf("could not find local with name %s", localName)
-	case n == instructionCodeGetLocal, n == instructionCodeSetGlobal:
+	case n == instructionCodeGetGlobal, n == instructionCodeSetGlobal:
 		globalName := blockInstr.values[0].String()
 		if global, ok := context.globals[globalName]; ok {
 			return wlang.CodeBlockType(global.Type), nil
@@ -366,7 +402,7 @@ func resolveResultType(context *ModuleContext, fnDef *FunctionDefinition, block
 		return "", nil
 	}
 	if blockDef.Returns[0] != wlang.Any {
-		return wlang.CodeBlockType(fnDef.Result), nil
+		return blockDef.Returns[0], nil
 	}
 
 	// Check parent instruction to get the argument type.
@@ -379,7 +415,10 @@ func resolveResultType(context *ModuleContext, fnDef *FunctionDefinition, block
 		return wlang.Any, nil
 	}
 	if parentInstr.name == instructionFunction || parentInstr.name == instructionCodeReturn {
-		return wlang.CodeBlockType(fnDef.Result), nil
+		return functionResultType(fnDef)
+	}
+	if typ, ok := selectResultType(parentInstr); ok && parentInstr.childIndex(block) < len(parentInstr.values)-1 {
+		return typ, nil
 	}
 	parentDef, ok := wlang.GetInstrDefinition(parentInstr.name)
 	if !ok {
@@ -397,6 +436,28 @@ func resolveResultType(context *ModuleContext, fnDef *FunctionDefinition, block
 	return resolveResultType(context, fnDef, parent)
 }
 
+// functionResultType returns the result type of some function, or an empty type when it has no results.
+// the functions with multiple results are not supported, since their results cannot be kept on a single local.
+func functionResultType(fnDef *FunctionDefinition) (wlang.CodeBlockType, error) {
+	if len(fnDef.Results) > 1 {
+		return "", fmt.Errorf("function %s has multiple results (%s), which is not supported", fnDef.Name, strings.Join(fnDef.Results, " "))
+	}
+	return wlang.CodeBlockType(fnDef.result()), nil
+}
+
+// selectResultType returns the result type declared on some typed select instruction, ex. (select (result externref) ...).
+// the operands of a typed select, except the condition, have the declared type.
+func selectResultType(instr *Instruction) (wlang.CodeBlockType, bool) {
+	if instr.name != wlang.CodeBlockNameSelect || len(instr.values) == 0 {
+		return "", false
+	}
+	resultInstr, ok := instr.values[0].(*Instruction)
+	if !ok || resultInstr.name != instructionResult || len(resultInstr.values) != 1 {
+		return "", false
+	}
+	return wlang.CodeBlockType(resultInstr.values[0].String()), true
+}
+
 // resolveApplySmartData returns the necessary data for the apply advice operation when it is using the smart mode.
 func resolveApplySmartData(context *ModuleContext, fnDef *FunctionDefinition, block Block) (*joinPointBlockSmartData, error) {
 	if false {
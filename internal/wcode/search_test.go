@@ -2,8 +2,13 @@ package wcode
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/sirupsen/logrus"
+
+	"joao/wasm-manipulator/internal/wkeyword"
+	"joao/wasm-manipulator/internal/wlang"
 	"joao/wasm-manipulator/internal/wtemplate"
 )
 
@@ -30,6 +35,142 @@ func TestJoinPointSearch_RemoveDuplicates(t *testing.T) {
 	fmt.Println(res, fn.values[3].(*Instruction).values[0].String())
 }
 
+func TestResolveResultType(t *testing.T) {
+	moduleCtx := NewModuleContext(NewCodeParser(wtemplate.ClearString(resultTypeSearchCode)).Parse())
+	fnDef, ok := moduleCtx.Function("$f")
+	if !ok {
+		t.Fatal("function $f not found")
+	}
+	wrap := fnDef.instr.values[len(fnDef.instr.values)-1].(*Instruction)
+	add := wrap.values[0].(*Instruction)
+	// The code not yet placed on the function has no stack types, so the global is used.
+	drop := NewCodeParser("(drop (global.get $g))").parse().blocks[0].(*Instruction)
+	tests := []struct {
+		block    Block
+		expected wlang.CodeBlockType
+	}{
+		{wrap, wlang.I64},
+		{add, wlang.I32},
+		{add.values[0], wlang.I32},
+		{add.values[0].(*Instruction).values[0], wlang.F32},
+		{add.values[1], wlang.I32},
+		{drop.values[0], wlang.F32},
+	}
+	for _, test := range tests {
+		if typ, err := resolveResultType(moduleCtx, fnDef, test.block); err != nil || typ != test.expected {
+			t.Errorf("expected type %s for %s, got %q (%v)", test.expected, test.block, typ, err)
+		}
+	}
+}
+
+func TestResolveResultType_MultiValue(t *testing.T) {
+	moduleCtx := NewModuleContext(NewCodeParser(wtemplate.ClearString(multiValueSearchCode)).Parse())
+	fnDef, ok := moduleCtx.Function("$f")
+	if !ok {
+		t.Fatal("function $f not found")
+	}
+	ret := fnDef.instr.values[len(fnDef.instr.values)-1].(*Instruction)
+	call := ret.values[0].(*Instruction)
+	for _, block := range []Block{fnDef.instr, ret, call} {
+		if typ, err := resolveResultType(moduleCtx, fnDef, block); err == nil {
+			t.Errorf("expected multi-value error for %s, got %q", block, typ)
+		}
+	}
+
+	jpBlock := newJoinPointBlock(moduleCtx, call, fnDef.instr, wkeyword.NewKwNil(), nil)
+	if err := jpBlock.Apply("(nop) (target (call $g))", true); err == nil || !strings.Contains(err.Error(), "multiple values") {
+		t.Errorf("expected multi-value error on smart mode, got %v", err)
+	}
+}
+
+func TestJoinPointBlock_ApplySmartVector(t *testing.T) {
+	moduleCtx := NewModuleContext(NewCodeParser(wtemplate.ClearString(vectorSearchCode)).Parse())
+	fnDef, ok := moduleCtx.Function("$f")
+	if !ok {
+		t.Fatal("function $f not found")
+	}
+	extract := fnDef.instr.values[len(fnDef.instr.values)-1].(*Instruction)
+	add := extract.values[1].(*Instruction)
+	if typ, err := resolveResultType(moduleCtx, fnDef, add); err != nil || typ != wlang.V128 {
+		t.Fatalf("expected type v128, got %q (%v)", typ, err)
+	}
+
+	jpBlock := newJoinPointBlock(moduleCtx, add, fnDef.instr, wkeyword.NewKwNil(), nil)
+	if err := jpBlock.Apply("(nop) (target (i32x4.add (local.get $v) (local.get $v)))", true); err != nil {
+		t.Fatal(err)
+	}
+	code := fnDef.instr.String()
+	for _, expected := range []string{"(local $wmr_l1_v128 v128)", "(local.set $wmr_l1_v128 (i32x4.add", "(i32x4.extract_lane 0 (local.get $wmr_l1_v128))"} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("function does not contain %q:\n%s", expected, code)
+		}
+	}
+}
+
+func TestFindReturns_MultiValue(t *testing.T) {
+	moduleCtx := NewModuleContext(NewCodeParser(wtemplate.ClearString(multiValueSearchCode)).Parse())
+	logger := logrus.StandardLogger()
+	exitFn := logger.ExitFunc
+	defer func() { logger.ExitFunc = exitFn }()
+	logger.ExitFunc = func(int) { panic("exit") }
+
+	findReturns := func(name string) (found int, failed bool) {
+		defer func() { failed = recover() != nil }()
+		fnDef, ok := moduleCtx.Function(name)
+		if !ok {
+			t.Fatalf("function %s not found", name)
+		}
+		jpBlock := newJoinPointBlock(moduleCtx, fnDef.instr, fnDef.instr, wkeyword.NewKwNil(), nil)
+		return len(moduleCtx.FindReturns(jpBlock, func(*ModuleContext, *ReturnsData) (map[string]wkeyword.Object, bool) {
+			return nil, true
+		}).Found()), false
+	}
+
+	// The results produced by separate instructions require a return instruction.
+	for _, test := range []struct {
+		name   string
+		failed bool
+	}{
+		{"$g", true},
+		{"$f", false},
+		{"$h", false},
+	} {
+		if found, failed := findReturns(test.name); failed != test.failed || !failed && found != 1 {
+			t.Errorf("function %s: expected failure %t, got %t with %d returns", test.name, test.failed, failed, found)
+		}
+	}
+}
+
+var resultTypeSearchCode = `
+(module
+	(type $t0 (func (param i32) (result i64)))
+	(global $g (mut f32) (f32.const 0))
+	(func $f (type $t0) (param $p i32) (result i64)
+		(i64.extend_i32_u (i32.add (i32.trunc_f32_s (global.get $g)) (local.get $p))))
+)
+`
+
+var multiValueSearchCode = `
+(module
+	(type $t0 (func (result i32 i64)))
+	(func $g (type $t0) (result i32 i64)
+		(i32.const 1) (i64.const 2))
+	(func $f (type $t0) (result i32 i64)
+		(return (call $g)))
+	(func $h (type $t0) (result i32 i64)
+		(call $g))
+)
+`
+
+var vectorSearchCode = `
+(module
+	(type $t0 (func (result i32)))
+	(func $f (type $t0) (result i32)
+		(local $v v128)
+		(i32x4.extract_lane 0 (i32x4.add (local.get $v) (local.get $v))))
+)
+`
+
 var longSearchCode = `(func $e (type $t0)
     (local $l1 i64)
     (local.set $l1 (i64.const 100000))
@@ -0,0 +1,376 @@
+package wcode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MemoryDefinition contains the definitions data for some memory instruction.
+type MemoryDefinition struct {
+	Name     string
+	Min      int
+	Max      *int
+	Shared   bool
+	Imported *ImportedDefinition
+	Exported *ExportedDefinition
+	order    int
+	instr    *Instruction
+}
+
+// newMemoryDefinition is the constructor for MemoryDefinition.
+func newMemoryDefinition(instr *Instruction) *MemoryDefinition {
+	return &MemoryDefinition{instr: instr}
+}
+
+// Index returns the memory index value on the module context.
+func (mem *MemoryDefinition) Index(ctx *ModuleContext) int {
+	if mem.Imported != nil {
+		return mem.order
+	}
+	var importsCount int
+	for _, v := range ctx.memories {
+		if v.Imported != nil {
+			importsCount++
+		}
+	}
+	return mem.order + importsCount
+}
+
+// TableDefinition contains the definitions data for some table instruction.
+type TableDefinition struct {
+	Name     string
+	Min      int
+	Max      *int
+	Type     string
+	Imported *ImportedDefinition
+	Exported *ExportedDefinition
+	order    int
+	instr    *Instruction
+}
+
+// newTableDefinition is the constructor for TableDefinition.
+func newTableDefinition(instr *Instruction) *TableDefinition {
+	return &TableDefinition{instr: instr}
+}
+
+// Index returns the table index value on the module context.
+func (table *TableDefinition) Index(ctx *ModuleContext) int {
+	if table.Imported != nil {
+		return table.order
+	}
+	var importsCount int
+	for _, v := range ctx.tables {
+		if v.Imported != nil {
+			importsCount++
+		}
+	}
+	return table.order + importsCount
+}
+
+// DataSegmentDefinition contains the definitions data for some data instruction.
+// the memory is empty when the segment targets the first memory of the module.
+// the offset is empty when the segment is passive.
+type DataSegmentDefinition struct {
+	Name   string
+	Memory string
+	Offset string
+	Value  string
+	order  int
+	instr  *Instruction
+}
+
+// newDataSegmentDefinition is the constructor for DataSegmentDefinition.
+func newDataSegmentDefinition(instr *Instruction) *DataSegmentDefinition {
+	return &DataSegmentDefinition{instr: instr}
+}
+
+// Index returns the data segment index value on the module context.
+func (data *DataSegmentDefinition) Index() int {
+	return data.order
+}
+
+// ElementSegmentDefinition contains the definitions data for some elem instruction.
+// the table is empty when the segment targets the first table of the module.
+// the offset is empty when the segment is passive or declarative.
+type ElementSegmentDefinition struct {
+	Name      string
+	Table     string
+	Offset    string
+	Functions []string
+	order     int
+	instr     *Instruction
+}
+
+// newElementSegmentDefinition is the constructor for ElementSegmentDefinition.
+func newElementSegmentDefinition(instr *Instruction) *ElementSegmentDefinition {
+	return &ElementSegmentDefinition{instr: instr}
+}
+
+// Index returns the element segment index value on the module context.
+func (elem *ElementSegmentDefinition) Index() int {
+	return elem.order
+}
+
+// memoryContextVisitor is responsible to fill the memories data for the module context.
+type memoryContextVisitor struct {
+	visitorAdapter
+	ctx *ModuleContext
+}
+
+// newMemoryContextVisitor is the constructor for memoryContextVisitor.
+func newMemoryContextVisitor(ctx *ModuleContext) *memoryContextVisitor {
+	return &memoryContextVisitor{ctx: ctx}
+}
+
+// VisitInstruction visits an instruction block.
+func (mc *memoryContextVisitor) VisitInstruction(instr *Instruction) bool {
+	if instr.name != instructionMemory || !(isImportInstruction(instr) || isInternalInstruction(instr)) {
+		return false
+	}
+	mem := newMemoryDefinition(instr)
+	mem.Imported = instrImported(instr)
+	mem.Exported = instrExported(instr)
+	for _, v := range mc.ctx.memories {
+		if (v.Imported != nil) == (mem.Imported != nil) {
+			mem.order++
+		}
+	}
+	mem.Min, mem.Max = instrLimitsValues(instr)
+	for _, v := range instr.values {
+		if vText, ok := v.(*text); ok && vText.String() == "shared" {
+			mem.Shared = true
+		}
+	}
+	mem.Name = instrName(instr, strconv.Itoa(mem.Index(mc.ctx)))
+	mc.ctx.setMemory(mem.Name, mem)
+	return true
+}
+
+// tableContextVisitor is responsible to fill the tables data for the module context.
+type tableContextVisitor struct {
+	visitorAdapter
+	ctx *ModuleContext
+}
+
+// newTableContextVisitor is the constructor for tableContextVisitor.
+func newTableContextVisitor(ctx *ModuleContext) *tableContextVisitor {
+	return &tableContextVisitor{ctx: ctx}
+}
+
+// VisitInstruction visits an instruction block.
+func (tc *tableContextVisitor) VisitInstruction(instr *Instruction) bool {
+	if instr.name != instructionTable || !(isImportInstruction(instr) || isInternalInstruction(instr)) {
+		return false
+	}
+	table := newTableDefinition(instr)
+	table.Imported = instrImported(instr)
+	table.Exported = instrExported(instr)
+	for _, v := range tc.ctx.tables {
+		if (v.Imported != nil) == (table.Imported != nil) {
+			table.order++
+		}
+	}
+	table.Min, table.Max = instrLimitsValues(instr)
+	for _, v := range instr.values {
+		vText, ok := v.(*text)
+		if !ok {
+			continue
+		}
+		if value := vText.String(); !strings.HasPrefix(value, "$") && !isNumeric(value) {
+			table.Type = value
+		}
+	}
+	table.Name = instrName(instr, strconv.Itoa(table.Index(tc.ctx)))
+	tc.ctx.setTable(table.Name, table)
+	return true
+}
+
+// dataContextVisitor is responsible to fill the data segments for the module context.
+type dataContextVisitor struct {
+	visitorAdapter
+	ctx *ModuleContext
+}
+
+// newDataContextVisitor is the constructor for dataContextVisitor.
+func newDataContextVisitor(ctx *ModuleContext) *dataContextVisitor {
+	return &dataContextVisitor{ctx: ctx}
+}
+
+// VisitInstruction visits an instruction block.
+func (dc *dataContextVisitor) VisitInstruction(instr *Instruction) bool {
+	if instr.name != instructionData || !isInternalInstruction(instr) {
+		return false
+	}
+	data := newDataSegmentDefinition(instr)
+	data.order = len(dc.ctx.dataSegments)
+	data.Name = instrName(instr, strconv.Itoa(data.order))
+	var value strings.Builder
+	for _, v := range instr.values {
+		switch b := v.(type) {
+		case *quoted:
+			value.WriteString(b.element.String())
+		case *Instruction:
+			if b.name == instructionMemory && len(b.values) == 1 {
+				data.Memory = b.values[0].String()
+			} else if offset, ok := offsetValue(b); ok {
+				data.Offset = offset
+			}
+		}
+	}
+	data.Value = value.String()
+	dc.ctx.dataSegments = append(dc.ctx.dataSegments, data)
+	return true
+}
+
+// elemContextVisitor is responsible to fill the element segments for the module context.
+type elemContextVisitor struct {
+	visitorAdapter
+	ctx *ModuleContext
+}
+
+// newElemContextVisitor is the constructor for elemContextVisitor.
+func newElemContextVisitor(ctx *ModuleContext) *elemContextVisitor {
+	return &elemContextVisitor{ctx: ctx}
+}
+
+// VisitInstruction visits an instruction block.
+func (ec *elemContextVisitor) VisitInstruction(instr *Instruction) bool {
+	if instr.name != instructionElem || !isInternalInstruction(instr) {
+		return false
+	}
+	elem := newElementSegmentDefinition(instr)
+	elem.order = len(ec.ctx.elemSegments)
+	for _, v := range instr.values {
+		switch b := v.(type) {
+		case *text:
+			value := b.String()
+			switch {
+			case elem.Offset == "" && len(elem.Functions) == 0 && strings.HasPrefix(value, "$") && elem.Name == "":
+				elem.Name = value
+			case elem.Offset == "" && len(elem.Functions) == 0 && isNumeric(value):
+				elem.Table = value
+			case value == "func" || value == "funcref" || value == "declare":
+			default:
+				elem.Functions = append(elem.Functions, value)
+			}
+		case *Instruction:
+			switch {
+			case b.name == instructionTable && len(b.values) == 1:
+				elem.Table = b.values[0].String()
+			case b.name == "ref.func" && len(b.values) == 1:
+				elem.Functions = append(elem.Functions, b.values[0].String())
+			case b.name == "item":
+				for _, itemValue := range b.values {
+					if itemInstr, ok := itemValue.(*Instruction); ok && itemInstr.name == "ref.func" && len(itemInstr.values) == 1 {
+						elem.Functions = append(elem.Functions, itemInstr.values[0].String())
+					} else if itemValue.String() != "ref.func" {
+						elem.Functions = append(elem.Functions, itemValue.String())
+					}
+				}
+			default:
+				if offset, ok := offsetValue(b); ok {
+					elem.Offset = offset
+				}
+			}
+		}
+	}
+	if elem.Name == "" {
+		elem.Name = strconv.Itoa(elem.order)
+	}
+	ec.ctx.elemSegments = append(ec.ctx.elemSegments, elem)
+	return true
+}
+
+// instrName returns the name of some module instruction, or a default value when the instruction is not named.
+func instrName(instr *Instruction, defaultName string) string {
+	if len(instr.values) > 0 {
+		if name := instr.values[0].String(); strings.HasPrefix(name, "$") {
+			return name
+		}
+	}
+	return defaultName
+}
+
+// instrImported returns the imported definition for some memory or table instruction.
+// the import may wrap the instruction or be declared inline.
+func instrImported(instr *Instruction) *ImportedDefinition {
+	importInstr, ok := instr.parent.(*Instruction)
+	if !ok || importInstr.name != instructionImport {
+		importInstr = nil
+		for _, v := range instr.values {
+			if vInstr, ok := v.(*Instruction); ok && vInstr.name == instructionImport {
+				importInstr = vInstr
+				break
+			}
+		}
+	}
+	if importInstr == nil {
+		return nil
+	}
+	if len(importInstr.values) < 2 {
+		logrus.Fatalf("invalid import %s instruction", instr.name)
+	}
+	return newImportedDefinition(strings.Trim(importInstr.values[0].String(), "\""),
+		strings.Trim(importInstr.values[1].String(), "\""))
+}
+
+// instrExported returns the inline exported definition for some memory or table instruction.
+func instrExported(instr *Instruction) *ExportedDefinition {
+	for _, v := range instr.values {
+		if vInstr, ok := v.(*Instruction); ok && vInstr.name == instructionExport && len(vInstr.values) == 1 {
+			return newExportedDefinition(strings.Trim(vInstr.values[0].String(), "\""))
+		}
+	}
+	return nil
+}
+
+// instrLimitsValues returns the limits (initial and maximum) of some memory or table instruction.
+func instrLimitsValues(instr *Instruction) (int, *int) {
+	var min int
+	var max *int
+	limits := instrLimits(instr)
+	if len(limits) > 0 {
+		min, _ = strconv.Atoi(limits[0].String())
+	}
+	if len(limits) > 1 {
+		value, _ := strconv.Atoi(limits[1].String())
+		max = &value
+	}
+	return min, max
+}
+
+// offsetValue returns the offset expression of some data or elem instruction.
+func offsetValue(instr *Instruction) (string, bool) {
+	switch {
+	case instr.name == "offset":
+		var values []string
+		for _, v := range instr.values {
+			values = append(values, v.String())
+		}
+		return strings.Join(values, " "), true
+	case strings.HasSuffix(instr.name, "."+instructionCodeConst), instr.name == "global.get":
+		return instr.String(), true
+	default:
+		return "", false
+	}
+}
+
+// isNumeric returns if some value is an integer.
+func isNumeric(value string) bool {
+	_, err := strconv.Atoi(value)
+	return err == nil
+}
+
+// validateLimits returns an error if some memory or table limits are invalid.
+func validateLimits(min int, max *int) error {
+	if min < 0 {
+		return fmt.Errorf("initial size %d must not be negative", min)
+	}
+	if max != nil && *max < min {
+		return fmt.Errorf("maximum size %d is lower than the initial size %d", *max, min)
+	}
+	return nil
+}
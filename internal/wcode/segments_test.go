@@ -0,0 +1,92 @@
+package wcode
+
+import (
+	"strings"
+	"testing"
+
+	"joao/wasm-manipulator/internal/wyaml"
+)
+
+func TestSegments_FillContext(t *testing.T) {
+	moduleCtx := NewModuleContext(NewCodeParser(segmentsModuleCode).Parse())
+
+	memories := moduleCtx.Memories()
+	if len(memories) != 2 {
+		t.Fatalf("expected 2 memories, got %d", len(memories))
+	}
+	if mem := memories[0]; mem.Name != "$M0" || mem.Imported == nil || mem.Min != 1 || mem.Index(moduleCtx) != 0 {
+		t.Errorf("invalid imported memory %+v", mem)
+	}
+	if mem := memories[1]; mem.Name != "$M1" || mem.Exported == nil || mem.Exported.ExportName != "mem" || mem.Min != 2 || mem.Max == nil || *mem.Max != 10 {
+		t.Errorf("invalid memory %+v", mem)
+	}
+	table, ok := moduleCtx.Table("0")
+	if !ok || table.Name != "$T0" || table.Type != "funcref" || table.Min != 2 || table.Exported == nil {
+		t.Errorf("invalid table %+v", table)
+	}
+	data := moduleCtx.DataSegments()
+	if len(data) != 1 || data[0].Name != "$d0" || data[0].Memory != "$M1" || data[0].Offset != "(i32.const 1024)" || data[0].Value != `ab\00` {
+		t.Errorf("invalid data segments %+v", data)
+	}
+	elems := moduleCtx.ElementSegments()
+	if len(elems) != 1 || elems[0].Offset != "(i32.const 0)" || strings.Join(elems[0].Functions, ",") != "$f1,$f1" {
+		t.Errorf("invalid element segments %+v", elems)
+	}
+}
+
+func TestSegments_Add(t *testing.T) {
+	moduleCtx := NewModuleContext(NewCodeParser(segmentsModuleCode).Parse())
+
+	max := 4
+	exportName := "scratch"
+	if _, err := moduleCtx.AddMemory(&wyaml.MemoryYAML{Min: 1, Max: &max, Exported: &exportName}); err == nil {
+		t.Error("expected error for a module that already has a memory")
+	}
+	emptyCtx := NewModuleContext(NewCodeParser(`(module (func $f1))`).Parse())
+	if _, err := emptyCtx.AddMemory(&wyaml.MemoryYAML{Min: 2, Max: new(int)}); err == nil {
+		t.Error("expected error for invalid memory limits")
+	}
+	mem, err := emptyCtx.AddMemory(&wyaml.MemoryYAML{Min: 1, Max: &max, Exported: &exportName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mem.Index(emptyCtx) != 0 || mem.Exported == nil || !strings.Contains(emptyCtx.String(), `(memory $wmr_m0 (export "scratch") 1 4)`) {
+		t.Errorf("invalid added memory %+v", mem)
+	}
+	table, err := moduleCtx.AddTable(&wyaml.TableYAML{Min: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := moduleCtx.AddData(&wyaml.DataYAML{Memory: "$M1", Offset: 8, Value: "hi\n"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := moduleCtx.AddElem(&wyaml.ElemYAML{Table: table.Name, Functions: []string{"$f1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := moduleCtx.AddElem(&wyaml.ElemYAML{Functions: []string{"$unknown"}}); err == nil {
+		t.Error("expected error for an unknown function")
+	}
+	if len(moduleCtx.DataSegments()) != 2 || len(moduleCtx.ElementSegments()) != 2 {
+		t.Errorf("segments were not added to the module context")
+	}
+
+	code := moduleCtx.String()
+	for _, expected := range []string{
+		`(table $wmr_t1 1 funcref)`,
+		`(data $wmr_d1 (memory $M1) (i32.const 8) "hi\0a")`,
+		`(elem (table $wmr_t1) (i32.const 0) func $f1)`,
+	} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("module does not contain %q:\n%s", expected, code)
+		}
+	}
+}
+
+var segmentsModuleCode = `(module
+	(import "env" "memory" (memory $M0 1))
+	(func $f1)
+	(table $T0 2 2 funcref)
+	(memory $M1 (export "mem") 2 10)
+	(export "table" (table $T0))
+	(elem (i32.const 0) func $f1 $f1)
+	(data $d0 (memory $M1) (i32.const 1024) "ab\00"))`
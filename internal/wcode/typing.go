@@ -0,0 +1,475 @@
+package wcode
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"joao/wasm-manipulator/internal/wlang"
+)
+
+// InstructionType contains the stack types consumed and produced by some instruction.
+// an unreachable instruction is placed after some instruction that never continues (br, br_table, return or unreachable).
+// a polymorphic instruction is one of those, so the stack after it can have any type.
+type InstructionType struct {
+	Consumed    []wlang.CodeBlockType
+	Produced    []wlang.CodeBlockType
+	Unreachable bool
+	Polymorphic bool
+}
+
+// newInstructionType is a constructor for InstructionType.
+func newInstructionType(consumed, produced []wlang.CodeBlockType) *InstructionType {
+	return &InstructionType{Consumed: consumed, Produced: produced}
+}
+
+// String returns the produced types separated by spaces.
+// returns void when no value is produced and polymorphic when the stack is polymorphic.
+func (t *InstructionType) String() string {
+	if t.Polymorphic {
+		return "polymorphic"
+	}
+	if len(t.Produced) == 0 {
+		return "void"
+	}
+	values := make([]string, len(t.Produced))
+	for i, v := range t.Produced {
+		values[i] = string(v)
+	}
+	return strings.Join(values, " ")
+}
+
+// instructionTypes caches the stack types of the functions code.
+// the types of a function are analysed on the first request and kept until its code changes.
+// the kept functions are not analysed again until they are released, even if their code changes.
+type instructionTypes struct {
+	mutex *sync.Mutex
+	types map[*FunctionDefinition]map[*Instruction]*InstructionType
+	kept  map[*FunctionDefinition]bool
+}
+
+// newInstructionTypes is a constructor for instructionTypes.
+func newInstructionTypes() *instructionTypes {
+	return &instructionTypes{
+		mutex: new(sync.Mutex),
+		types: make(map[*FunctionDefinition]map[*Instruction]*InstructionType),
+		kept:  make(map[*FunctionDefinition]bool),
+	}
+}
+
+// InstructionType returns the stack types of some instruction inside a function.
+// the function instruction produces the function results.
+func (ctx *ModuleContext) InstructionType(fnDef *FunctionDefinition, block Block) (*InstructionType, bool) {
+	instr, ok := block.(*Instruction)
+	if !ok {
+		return nil, false
+	}
+	if instr == fnDef.instr {
+		return newInstructionType(nil, toBlockTypes(fnDef.Results)), true
+	}
+	res, ok := ctx.InstructionTypes(fnDef)[instr]
+	return res, ok
+}
+
+// InstructionTypes returns the stack types of all the code instructions inside a function.
+// the analysis is cached until the function changes, so the result must not be modified.
+func (ctx *ModuleContext) InstructionTypes(fnDef *FunctionDefinition) map[*Instruction]*InstructionType {
+	cache := ctx.instrTypes
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	return ctx.cachedTypes(fnDef)
+}
+
+// cachedTypes returns the cached stack types of some function, analysing them when not cached.
+// the cache must be locked by the caller.
+func (ctx *ModuleContext) cachedTypes(fnDef *FunctionDefinition) map[*Instruction]*InstructionType {
+	cache := ctx.instrTypes
+	if res, ok := cache.types[fnDef]; ok {
+		return res
+	}
+	analysis := newTypeAnalysis(ctx, fnDef)
+	analysis.sequence(fnDef.instr.values, false)
+	cache.types[fnDef] = analysis.types
+	return analysis.types
+}
+
+// KeepTypes analyses the stack types of some functions and keeps them until they are released.
+// the join-points of a function are changed concurrently, so it cannot be analysed while they are applied.
+// the new code has no stack types, while the original instructions keep the types they had before the changes.
+func (ctx *ModuleContext) KeepTypes(fnDefs []*FunctionDefinition) {
+	cache := ctx.instrTypes
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	for _, fnDef := range fnDefs {
+		ctx.cachedTypes(fnDef)
+		cache.kept[fnDef] = true
+	}
+}
+
+// ReleaseTypes removes the kept stack types of some functions, after all their join-points are applied.
+func (ctx *ModuleContext) ReleaseTypes(fnDefs []*FunctionDefinition) {
+	cache := ctx.instrTypes
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	for _, fnDef := range fnDefs {
+		delete(cache.kept, fnDef)
+		delete(cache.types, fnDef)
+	}
+}
+
+// invalidateTypes removes the cached stack types of some function, after changing its code.
+// the types of all the functions are removed when no function is defined.
+// the kept types are only removed when released.
+func (ctx *ModuleContext) invalidateTypes(fnDef *FunctionDefinition) {
+	cache := ctx.instrTypes
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if fnDef == nil {
+		for fnDef := range cache.types {
+			if !cache.kept[fnDef] {
+				delete(cache.types, fnDef)
+			}
+		}
+		return
+	}
+	if !cache.kept[fnDef] {
+		delete(cache.types, fnDef)
+	}
+}
+
+// typeLabel represents a label that can be targeted by a branch.
+type typeLabel struct {
+	name    string
+	results []wlang.CodeBlockType
+}
+
+// typeAnalysis annotates the code instructions of some function with their stack types.
+type typeAnalysis struct {
+	ctx    *ModuleContext
+	fnDef  *FunctionDefinition
+	types  map[*Instruction]*InstructionType
+	labels []*typeLabel
+}
+
+// newTypeAnalysis is a constructor for typeAnalysis.
+func newTypeAnalysis(ctx *ModuleContext, fnDef *FunctionDefinition) *typeAnalysis {
+	return &typeAnalysis{
+		ctx:    ctx,
+		fnDef:  fnDef,
+		types:  make(map[*Instruction]*InstructionType),
+		labels: []*typeLabel{{results: toBlockTypes(fnDef.Results)}},
+	}
+}
+
+// sequence analyses a sequence of instructions.
+// the instructions after a polymorphic instruction are unreachable.
+func (ta *typeAnalysis) sequence(values []Block, unreachable bool) {
+	for _, instr := range codeInstructions(values) {
+		res := ta.instruction(instr, unreachable)
+		unreachable = unreachable || res.Polymorphic
+	}
+}
+
+// instruction analyses some instruction and its operands.
+func (ta *typeAnalysis) instruction(instr *Instruction, unreachable bool) *InstructionType {
+	var res *InstructionType
+	switch instr.name {
+	case wlang.CodeBlockNameBlock, wlang.CodeBlockNameLoop:
+		res = ta.block(instr, unreachable, nil)
+	case wlang.CodeBlockNameIf:
+		res = ta.block(instr, unreachable, []wlang.CodeBlockType{wlang.I32})
+	default:
+		var operands []*InstructionType
+		for _, operand := range codeInstructions(instr.values) {
+			operandType := ta.instruction(operand, unreachable)
+			operands = append(operands, operandType)
+			unreachable = unreachable || operandType.Polymorphic
+		}
+		res = ta.plain(instr, operands)
+	}
+	res.Unreachable = res.Unreachable || unreachable
+	ta.types[instr] = res
+	return res
+}
+
+// block analyses a control flow instruction with its own label (block, loop and if).
+// a branch to a loop continues the loop, so its label consumes the loop params instead of its results.
+func (ta *typeAnalysis) block(instr *Instruction, unreachable bool, consumed []wlang.CodeBlockType) *InstructionType {
+	params, results := ta.blockSignature(instr)
+	consumed = append(params, consumed...)
+	label := &typeLabel{results: results}
+	if instr.name == wlang.CodeBlockNameLoop {
+		label.results = params
+	}
+	if len(instr.values) > 0 {
+		if name := instr.values[0].String(); strings.HasPrefix(name, "$") {
+			label.name = name
+		}
+	}
+	ta.labels = append(ta.labels, label)
+	defer func() { ta.labels = ta.labels[:len(ta.labels)-1] }()
+
+	if instr.name != wlang.CodeBlockNameIf {
+		ta.sequence(instr.values, unreachable)
+		return newInstructionType(consumed, results)
+	}
+	for _, v := range codeInstructions(instr.values) {
+		if v.name == wlang.CodeBlockNameThen || v.name == wlang.CodeBlockNameElse {
+			ta.sequence(v.values, unreachable)
+			ta.types[v] = newInstructionType(nil, results)
+			continue
+		}
+		ta.instruction(v, unreachable) // Condition.
+	}
+	return newInstructionType(consumed, results)
+}
+
+// blockSignature returns the param and result types of some control flow instruction.
+func (ta *typeAnalysis) blockSignature(instr *Instruction) ([]wlang.CodeBlockType, []wlang.CodeBlockType) {
+	var params, results []wlang.CodeBlockType
+	for _, v := range instr.values {
+		vInstr, ok := v.(*Instruction)
+		if !ok {
+			continue
+		}
+		switch vInstr.name {
+		case instructionParam:
+			for _, t := range vInstr.values {
+				params = append(params, wlang.CodeBlockType(t.String()))
+			}
+		case instructionResult:
+			for _, t := range vInstr.values {
+				results = append(results, wlang.CodeBlockType(t.String()))
+			}
+		case instructionType:
+			if len(vInstr.values) == 1 {
+				if typeDef, ok := ta.ctx.types[vInstr.values[0].String()]; ok {
+					params, results = toBlockTypes(typeDef.Params), toBlockTypes(typeDef.Results)
+				}
+			}
+		}
+	}
+	return params, results
+}
+
+// plain analyses an instruction with no label, using its operands types.
+func (ta *typeAnalysis) plain(instr *Instruction, operands []*InstructionType) *InstructionType {
+	immediate := func(i int) string {
+		var count int
+		for _, v := range instr.values {
+			if vText, ok := v.(*text); ok {
+				if count == i {
+					return vText.String()
+				}
+				count++
+			}
+		}
+		return ""
+	}
+	switch instr.name {
+	case instructionCodeGetLocal, instructionCodeSetLocal, instructionCodeTeeLocal:
+		t := ta.localType(immediate(0))
+		switch instr.name {
+		case instructionCodeGetLocal:
+			return newInstructionType(nil, list(t))
+		case instructionCodeSetLocal:
+			return newInstructionType(list(t), nil)
+		default:
+			return newInstructionType(list(t), list(t))
+		}
+	case instructionCodeGetGlobal, instructionCodeSetGlobal:
+		t := wlang.Any
+		if global, ok := ta.ctx.globals[immediate(0)]; ok {
+			t = wlang.CodeBlockType(global.Type)
+		}
+		if instr.name == instructionCodeGetGlobal {
+			return newInstructionType(nil, list(t))
+		}
+		return newInstructionType(list(t), nil)
+	case instructionCodeCall:
+		if callee, ok := ta.ctx.functionByRef(immediate(0)); ok {
+			return newInstructionType(toBlockTypes(funcParamTypes(callee)), toBlockTypes(callee.Results))
+		}
+	case instructionCodeCallIndirect:
+		for _, v := range instr.values {
+			if vInstr, ok := v.(*Instruction); ok && vInstr.name == instructionType && len(vInstr.values) == 1 {
+				if typeDef, ok := ta.ctx.types[vInstr.values[0].String()]; ok {
+					return newInstructionType(append(toBlockTypes(typeDef.Params), wlang.I32), toBlockTypes(typeDef.Results))
+				}
+			}
+		}
+	case wlang.CodeBlockNameReturn:
+		res := newInstructionType(toBlockTypes(ta.fnDef.Results), nil)
+		res.Polymorphic = true
+		return res
+	case wlang.CodeBlockNameBr, wlang.CodeBlockNameBrTable:
+		var consumed []wlang.CodeBlockType
+		if label, ok := ta.label(immediate(len(instr.values) - len(operands) - 1)); ok {
+			consumed = append(consumed, label.results...)
+		}
+		if instr.name == wlang.CodeBlockNameBrTable {
+			consumed = append(consumed, wlang.I32)
+		}
+		res := newInstructionType(consumed, nil)
+		res.Polymorphic = true
+		return res
+	case wlang.CodeBlockNameBrIf:
+		label, _ := ta.label(immediate(0))
+		var results []wlang.CodeBlockType
+		if label != nil {
+			results = label.results
+		}
+		return newInstructionType(append(append([]wlang.CodeBlockType(nil), results...), wlang.I32), results)
+	case wlang.CodeBlockNameUnreachable:
+		res := newInstructionType(nil, nil)
+		res.Polymorphic = true
+		return res
+	case wlang.CodeBlockNameDrop:
+		return newInstructionType(list(operandType(operands, 0)), nil)
+	case wlang.CodeBlockNameSelect:
+		t, ok := selectResultType(instr)
+		if !ok {
+			t = operandType(operands, 0)
+		}
+		return newInstructionType(list(t, t, wlang.I32), list(t))
+	case wlang.CodeBlockNameRefNull:
+		if immediate(0) == "extern" {
+			return newInstructionType(nil, list(wlang.Externref))
+		}
+		return newInstructionType(nil, list(wlang.Funcref))
+	case wlang.CodeBlockNameTableGet:
+		return newInstructionType(list(wlang.I32), list(ta.tableType(immediate(0))))
+	case wlang.CodeBlockNameTableSet:
+		return newInstructionType(list(wlang.I32, ta.tableType(immediate(0))), nil)
+	}
+
+	// Use the language definitions, replacing the unknown types by the operands types.
+	def, ok := wlang.GetInstrDefinition(instr.name)
+	if !ok {
+		var consumed []wlang.CodeBlockType
+		for i := range operands {
+			consumed = append(consumed, operandType(operands, i))
+		}
+		return newInstructionType(consumed, nil)
+	}
+	consumed := append([]wlang.CodeBlockType(nil), def.Args...)
+	for i, t := range consumed {
+		if t == wlang.Any && i < len(operands) {
+			consumed[i] = operandType(operands, i)
+		}
+	}
+	produced := append([]wlang.CodeBlockType(nil), def.Returns...)
+	for i, t := range produced {
+		if t == wlang.Any && len(operands) > 0 {
+			produced[i] = operandType(operands, 0)
+		}
+	}
+	return newInstructionType(consumed, produced)
+}
+
+// localType returns the type of some local or parameter, using its name or index.
+func (ta *typeAnalysis) localType(ref string) wlang.CodeBlockType {
+	if param, ok := ta.fnDef.Params[ref]; ok {
+		return wlang.CodeBlockType(param.Type)
+	}
+	if local, ok := ta.fnDef.Locals[ref]; ok {
+		return wlang.CodeBlockType(local.Type)
+	}
+	index, err := strconv.Atoi(ref)
+	if err != nil {
+		return wlang.Any
+	}
+	params := ta.fnDef.Parameters()
+	if index < len(params) {
+		return wlang.CodeBlockType(params[index].Type)
+	}
+	if locals := ta.fnDef.LocalsArr(); index-len(params) < len(locals) {
+		return wlang.CodeBlockType(locals[index-len(params)].Type)
+	}
+	return wlang.Any
+}
+
+// functionByRef returns some function definition, using its name or index.
+func (ctx *ModuleContext) functionByRef(ref string) (*FunctionDefinition, bool) {
+	if fnDef, ok := ctx.functions[ref]; ok {
+		return fnDef, true
+	}
+	index, err := strconv.Atoi(ref)
+	if err != nil {
+		return nil, false
+	}
+	for _, fnDef := range ctx.functions {
+		if fnDef.Index(ctx) == index {
+			return fnDef, true
+		}
+	}
+	return nil, false
+}
+
+// tableType returns the element type of some table, the first table being used by default.
+func (ta *typeAnalysis) tableType(ref string) wlang.CodeBlockType {
+	if ref == "" {
+		ref = "0"
+	}
+	if table, ok := ta.ctx.Table(ref); ok && table.Type != "" {
+		return wlang.CodeBlockType(table.Type)
+	}
+	return wlang.Any
+}
+
+// label returns the label targeted by some branch, using its name or relative depth.
+func (ta *typeAnalysis) label(ref string) (*typeLabel, bool) {
+	if depth, err := strconv.Atoi(ref); err == nil {
+		if depth < 0 || depth >= len(ta.labels) {
+			return nil, false
+		}
+		return ta.labels[len(ta.labels)-1-depth], true
+	}
+	for i := len(ta.labels) - 1; i >= 0; i-- {
+		if ta.labels[i].name == ref {
+			return ta.labels[i], true
+		}
+	}
+	return nil, false
+}
+
+// codeInstructions returns the code instructions of some values.
+// the immediates and the definitions (type, param, result, local, export and import) are ignored.
+func codeInstructions(values []Block) []*Instruction {
+	var res []*Instruction
+	for _, v := range values {
+		vInstr, ok := v.(*Instruction)
+		if !ok {
+			continue
+		}
+		switch vInstr.name {
+		case instructionType, instructionParam, instructionResult, instructionLocal, instructionExport, instructionImport:
+		// Empty by design.
+		default:
+			res = append(res, vInstr)
+		}
+	}
+	return res
+}
+
+// operandType returns the first type produced by some operand.
+func operandType(operands []*InstructionType, i int) wlang.CodeBlockType {
+	if i >= len(operands) || len(operands[i].Produced) == 0 {
+		return wlang.Any
+	}
+	return operands[i].Produced[0]
+}
+
+// toBlockTypes converts a list of types to code block types.
+func toBlockTypes(values []string) []wlang.CodeBlockType {
+	var res []wlang.CodeBlockType
+	for _, v := range values {
+		res = append(res, wlang.CodeBlockType(v))
+	}
+	return res
+}
+
+// list returns a list of code block types.
+func list(t ...wlang.CodeBlockType) []wlang.CodeBlockType {
+	return t
+}
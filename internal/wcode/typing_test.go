@@ -0,0 +1,155 @@
+package wcode
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"joao/wasm-manipulator/internal/wkeyword"
+	"joao/wasm-manipulator/internal/wtemplate"
+)
+
+func TestModuleContext_InstructionTypes(t *testing.T) {
+	moduleCtx := NewModuleContext(NewCodeParser(wtemplate.ClearString(typingCode)).Parse())
+	fnDef, ok := moduleCtx.Function("$f")
+	if !ok {
+		t.Fatal("function $f not found")
+	}
+	types := moduleCtx.InstructionTypes(fnDef)
+	expected := map[string]string{
+		"call":             "f64",
+		"block":            "i64",
+		"i64.extend_i32_s": "i64",
+		"local.get":        "i32",
+		"select":           "i64",
+		"return":           "polymorphic",
+		"drop":             "void",
+		"f64.const":        "f64",
+	}
+	unreachable := map[string]bool{"drop": true, "f64.const": true}
+	for instr, typ := range types {
+		if v, ok := expected[instr.name]; ok && typ.String() != v {
+			t.Errorf("instruction %s: expected type %q, got %q", instr.name, v, typ.String())
+		}
+		if typ.Unreachable != unreachable[instr.name] {
+			t.Errorf("instruction %s: expected unreachable %t, got %t", instr.name, unreachable[instr.name], typ.Unreachable)
+		}
+	}
+	if typ, ok := moduleCtx.InstructionType(fnDef, fnDef.instr); !ok || typ.String() != "i64" {
+		t.Errorf("function: expected type i64, got %v", typ)
+	}
+}
+
+func TestModuleContext_InstructionTypesCache(t *testing.T) {
+	moduleCtx := NewModuleContext(NewCodeParser(wtemplate.ClearString(typingCode)).Parse())
+	fnDef, ok := moduleCtx.Function("$f")
+	if !ok {
+		t.Fatal("function $f not found")
+	}
+	types := moduleCtx.InstructionTypes(fnDef)
+	if cached := moduleCtx.InstructionTypes(fnDef); fmt.Sprintf("%p", cached) != fmt.Sprintf("%p", types) {
+		t.Error("expected the types of the function to be cached")
+	}
+
+	var drop *Instruction
+	for instr := range types {
+		if instr.name == "drop" {
+			drop = instr
+		}
+	}
+	jpBlock := newJoinPointBlock(moduleCtx, drop, fnDef.instr, wkeyword.NewKwNil(), nil)
+	if err := jpBlock.Apply("(nop)", false); err != nil {
+		t.Fatal(err)
+	}
+	types = moduleCtx.InstructionTypes(fnDef)
+	if _, ok := types[drop]; ok {
+		t.Error("expected the types to be analysed again after changing the function")
+	}
+	var found bool
+	for instr, typ := range types {
+		found = found || instr.name == "nop" && typ.Unreachable
+	}
+	if !found {
+		t.Error("expected the types of the new code")
+	}
+}
+
+func TestModuleContext_KeepTypes(t *testing.T) {
+	moduleCtx := NewModuleContext(NewCodeParser(wtemplate.ClearString(typingCode)).Parse())
+	fnDef, ok := moduleCtx.Function("$f")
+	if !ok {
+		t.Fatal("function $f not found")
+	}
+	moduleCtx.KeepTypes([]*FunctionDefinition{fnDef})
+	types := moduleCtx.InstructionTypes(fnDef)
+
+	// The kept types are not analysed again while the join-points are changed, only after being released.
+	var drop *Instruction
+	for instr := range types {
+		if instr.name == "drop" {
+			drop = instr
+		}
+	}
+	jpBlock := newJoinPointBlock(moduleCtx, drop, fnDef.instr, wkeyword.NewKwNil(), nil)
+	if err := jpBlock.Apply("(nop)", false); err != nil {
+		t.Fatal(err)
+	}
+	if kept := moduleCtx.InstructionTypes(fnDef); fmt.Sprintf("%p", kept) != fmt.Sprintf("%p", types) {
+		t.Error("expected the types to be kept while changing the function")
+	}
+	moduleCtx.ReleaseTypes([]*FunctionDefinition{fnDef})
+	if _, ok := moduleCtx.InstructionTypes(fnDef)[drop]; ok {
+		t.Error("expected the types to be analysed again after being released")
+	}
+}
+
+func TestModuleContext_InstructionTypesLoop(t *testing.T) {
+	moduleCtx := NewModuleContext(NewCodeParser(wtemplate.ClearString(typingLoopCode)).Parse())
+	fnDef, ok := moduleCtx.Function("$f")
+	if !ok {
+		t.Fatal("function $f not found")
+	}
+
+	// A branch to a loop consumes and keeps the loop params, while the loop produces its results.
+	expected := map[string]string{"loop": "i64 -> i32", "br_if": "i64 i32 -> i64"}
+	for instr, typ := range moduleCtx.InstructionTypes(fnDef) {
+		v, ok := expected[instr.name]
+		if !ok {
+			continue
+		}
+		consumed := make([]string, len(typ.Consumed))
+		for i, c := range typ.Consumed {
+			consumed[i] = string(c)
+		}
+		if got := strings.Join(consumed, " ") + " -> " + typ.String(); got != v {
+			t.Errorf("instruction %s: expected type %q, got %q", instr.name, v, got)
+		}
+	}
+}
+
+var typingLoopCode = `
+(module
+	(type $t0 (func (param i32) (result i32)))
+	(func $f (type $t0) (param $p i32) (result i32)
+		(i64.const 0)
+		(loop $l0 (param i64) (result i32)
+			(br_if $l0 (i64.const 1) (local.get $p))
+			(drop)
+			(local.get $p)))
+)
+`
+
+var typingCode = `
+(module
+	(type $t0 (func (result f64)))
+	(type $t1 (func (param i32) (result i64)))
+	(func $g (type $t0) (result f64)
+		(f64.const 1))
+	(func $f (type $t1) (param $p i32) (result i64)
+		(call $g)
+		(return
+			(block $b (result i64)
+				(select (i64.extend_i32_s (local.get $p)) (i64.extend_i32_s (local.get 0)) (local.get $p))))
+		(drop (f64.const 2)))
+)
+`
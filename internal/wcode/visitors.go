@@ -115,7 +115,7 @@ type FunctionDefinition struct {
 	Name     string
 	TypeName string
 	Params   map[string]*FunctionParamDefinition
-	Result   string
+	Results  []string
 	Locals   map[string]*FunctionLocalDefinition
 	Imported *ImportedDefinition
 	Exported *ExportedDefinition
@@ -140,11 +140,7 @@ func (fn *FunctionDefinition) Index(ctx *ModuleContext) int {
 	if fn.Imported != nil {
 		return fn.order
 	}
-	var importsCount int
-	for _, v := range ctx.importFunctions {
-		importsCount += len(v)
-	}
-	return fn.order + importsCount
+	return fn.order + ctx.importFunctionsCount()
 }
 
 // Parameters returns all the parameters sorted.
@@ -187,6 +183,21 @@ func (fn *FunctionDefinition) AliasKey(value string) (string, bool) {
 	return "", false
 }
 
+// result returns the result type when the function returns a single value.
+// returns an empty string when the function has no results or multiple results.
+func (fn *FunctionDefinition) result() string {
+	if len(fn.Results) != 1 {
+		return ""
+	}
+	return fn.Results[0]
+}
+
+// ReturnsComposite returns if the function returns a composite value.
+// composite values are only supported as the single result of a function.
+func (fn *FunctionDefinition) ReturnsComposite() bool {
+	return len(fn.Results) == 1 && !IsVarTypeStrPrimitive(fn.Results[0])
+}
+
 // Code returns the function code,
 // ignoring the non-expression instructions.
 func (fn *FunctionDefinition) Code() string {
@@ -391,9 +402,9 @@ func newFunctionLocalDefinition(instr *Instruction, name, typ, value string, ord
 
 // TypeDefinition contains the definitions data for some type instruction.
 type TypeDefinition struct {
-	Name   string
-	Params []string
-	Result string
+	Name    string
+	Params  []string
+	Results []string
 }
 
 // newTypeDefinition is a constructor for TypeDefinition.
@@ -403,7 +414,7 @@ func newTypeDefinition() *TypeDefinition {
 
 // signature returns the type signature.
 func (typ *TypeDefinition) signature() string {
-	return typeSignature(typ.Result, typ.Params)
+	return typeSignature(typ.Results, typ.Params)
 }
 
 // GlobalDefinition contains the definitions data for some global instruction.
@@ -487,6 +498,10 @@ func (ec *exportedContextVisitor) VisitInstruction(instr *Instruction) bool {
 		ec.inspectFuncExportInstruction(instr)
 	case instructionGlobal:
 		ec.inspectGlobalExportInstruction(instr)
+	case instructionMemory:
+		ec.inspectMemoryExportInstruction(instr)
+	case instructionTable:
+		ec.inspectTableExportInstruction(instr)
 	default:
 		return false
 	}
@@ -526,6 +541,34 @@ func (ec *exportedContextVisitor) inspectGlobalExportInstruction(instr *Instruct
 	ec.ctx.setExportGlobal(exportedName, global)
 }
 
+// inspectMemoryExportInstruction inspects export memory instruction.
+func (ec *exportedContextVisitor) inspectMemoryExportInstruction(instr *Instruction) {
+	parentInstr, ok := instr.parent.(*Instruction)
+	if !ok || len(instr.values) != 1 || len(parentInstr.values) != 2 {
+		logrus.Fatalf("invalid export memory instruction")
+	}
+	memName := instr.values[0].String()
+	mem, ok := ec.ctx.Memory(memName)
+	if !ok {
+		logrus.Fatalf("invalid exported memory: memory with name %s not found in module", memName)
+	}
+	mem.Exported = newExportedDefinition(strings.Trim(parentInstr.values[0].String(), "\""))
+}
+
+// inspectTableExportInstruction inspects export table instruction.
+func (ec *exportedContextVisitor) inspectTableExportInstruction(instr *Instruction) {
+	parentInstr, ok := instr.parent.(*Instruction)
+	if !ok || len(instr.values) != 1 || len(parentInstr.values) != 2 {
+		logrus.Fatalf("invalid export table instruction")
+	}
+	tableName := instr.values[0].String()
+	table, ok := ec.ctx.Table(tableName)
+	if !ok {
+		logrus.Fatalf("invalid exported table: table with name %s not found in module", tableName)
+	}
+	table.Exported = newExportedDefinition(strings.Trim(parentInstr.values[0].String(), "\""))
+}
+
 // typeContextVisitor is responsible to fill the types data for the module context.
 type typeContextVisitor struct {
 	visitorAdapter
@@ -567,10 +610,9 @@ func (tc *typeContextVisitor) inspectTypeInstruction(instr *Instruction) {
 				typ.Params = append(typ.Params, paramType.String())
 			}
 		case "result":
-			if len(arg.values) != 1 {
-				break
+			for _, resultType := range arg.values {
+				typ.Results = append(typ.Results, resultType.String())
 			}
-			typ.Result = arg.values[0].String()
 		default:
 			canRun = false
 		}
@@ -612,7 +654,7 @@ func (fc *functionContextVisitor) inspectFuncImportedInstruction(instr *Instruct
 		logrus.Fatalf("invalid import function instruction")
 	}
 	fn := newFunctionDefinition(instr)
-	fn.order = len(fc.ctx.importFunctions)
+	fn.order = fc.ctx.importFunctionsCount()
 	fn.Name = instr.values[0].String()
 	fn.Imported = fn.instrToImported(instr)
 	typeInstr, ok := instr.values[1].(*Instruction)
@@ -628,7 +670,7 @@ func (fc *functionContextVisitor) inspectFuncImportedInstruction(instr *Instruct
 		name := strconv.Itoa(i)
 		fn.Params[name] = newFunctionParamDefinition(name, param, i)
 	}
-	fn.Result = typeDef.Result
+	fn.Results = append([]string(nil), typeDef.Results...)
 	fc.ctx.setFunction(fn.Name, fn)
 	fc.ctx.setImportFunction(fn.Imported.ModuleName, fn.Imported.ExportName, fn)
 }
@@ -640,7 +682,7 @@ func (fc *functionContextVisitor) inspectFuncInstruction(instr *Instruction) {
 	}
 
 	fn := newFunctionDefinition(instr)
-	fn.order = len(fc.ctx.functions) - len(fc.ctx.importFunctions)
+	fn.order = len(fc.ctx.functions) - fc.ctx.importFunctionsCount()
 	fn.Name = instr.values[0].String()
 	fn.Imported = fn.instrToImported(instr)
 
@@ -663,10 +705,9 @@ func (fc *functionContextVisitor) inspectFuncInstruction(instr *Instruction) {
 			name := arg.values[0].String()
 			fn.Params[name] = newFunctionParamDefinition(name, arg.values[1].String(), len(fn.Params))
 		case instructionResult:
-			if len(arg.values) != 1 {
-				break
+			for _, resultType := range arg.values {
+				fn.Results = append(fn.Results, resultType.String())
 			}
-			fn.Result = arg.values[0].String()
 		case instructionLocal:
 			if len(arg.values) != 2 {
 				break
@@ -680,6 +721,28 @@ func (fc *functionContextVisitor) inspectFuncInstruction(instr *Instruction) {
 	fc.ctx.setFunction(fn.Name, fn)
 }
 
+// foldContextVisitor is responsible to fold the flat code of the module functions.
+type foldContextVisitor struct {
+	visitorAdapter
+	ctx *ModuleContext
+}
+
+// newFoldContextVisitor is the constructor for foldContextVisitor.
+func newFoldContextVisitor(ctx *ModuleContext) *foldContextVisitor {
+	return &foldContextVisitor{ctx: ctx}
+}
+
+// VisitInstruction visits an instruction block.
+func (fc *foldContextVisitor) VisitInstruction(instr *Instruction) bool {
+	if instr.name != instructionFunction || !isInternalInstruction(instr) || len(instr.values) == 0 {
+		return false
+	}
+	if fnDef, ok := fc.ctx.functions[instr.values[0].String()]; ok && fnDef.instr == instr {
+		fc.ctx.foldFunction(fnDef)
+	}
+	return true
+}
+
 // globalContextVisitor is responsible to fill the globals data for the module context.
 type globalContextVisitor struct {
 	visitorAdapter
@@ -787,6 +850,36 @@ func (mc *moduleInstrsVisitor) Module() (*Instruction, bool) {
 	return nil, false
 }
 
+// exportInstrsVisitor is responsible to group all the export instructions.
+type exportInstrsVisitor struct {
+	visitorAdapter
+	exports []*Instruction
+}
+
+// newExportInstrsVisitor is the constructor for exportInstrsVisitor.
+func newExportInstrsVisitor() *exportInstrsVisitor {
+	return &exportInstrsVisitor{}
+}
+
+// VisitInstruction visits an instruction block.
+func (ev *exportInstrsVisitor) VisitInstruction(instr *Instruction) bool {
+	if instr.name != instructionExport || len(instr.values) != 2 {
+		return false
+	}
+	ev.exports = append(ev.exports, instr)
+	return true
+}
+
+// Export returns the export instruction with some name.
+func (ev *exportInstrsVisitor) Export(exportName string) (*Instruction, bool) {
+	for _, instr := range ev.exports {
+		if strings.Trim(instr.values[0].String(), "\"") == exportName {
+			return instr, true
+		}
+	}
+	return nil, false
+}
+
 // targetInstrsVisitor is responsible to find all the target instructions.
 type targetInstrsVisitor struct {
 	visitorAdapter
@@ -880,6 +973,7 @@ func isFunctionTypeInstruction(instr *Instruction) bool {
 }
 
 // typeSignature returns the type signature.
-func typeSignature(result string, params []string) string {
-	return fmt.Sprintf("%s_%s", result, strings.Join(params, "_"))
+// the results are joined with a different separator so multi-value results never collide with the parameters.
+func typeSignature(results []string, params []string) string {
+	return fmt.Sprintf("%s_%s", strings.Join(results, ","), strings.Join(params, "_"))
 }
@@ -2,9 +2,11 @@ package wcode
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"joao/wasm-manipulator/internal/wtemplate"
+	"joao/wasm-manipulator/internal/wyaml"
 )
 
 func TestVisitors_FunctionContext(t *testing.T) {
@@ -35,6 +37,96 @@ func TestVisitors_GlobalContext(t *testing.T) {
 	fmt.Printf("%+v\n%+v\n", moduleCtx, moduleCtx.globals)
 }
 
+func TestVisitors_MultiValueResults(t *testing.T) {
+	moduleCtx := NewModuleContext(NewCodeParser(wtemplate.ClearString(multiValueVisitorCode)).Parse())
+	fnDef, ok := moduleCtx.Function("$f1")
+	if !ok {
+		t.Fatal("function $f1 not found")
+	}
+	if strings.Join(fnDef.Results, " ") != "i32 i64" {
+		t.Errorf("expected results i32 i64, got %v", fnDef.Results)
+	}
+	if data := newFuncData(moduleCtx, fnDef); data.ResultType != "i32 i64" || data.TotalResults != 2 {
+		t.Errorf("unexpected function data results %q (%d)", data.ResultType, data.TotalResults)
+	}
+
+	typesCount := len(moduleCtx.types)
+	added, err := moduleCtx.AddFunction(&wyaml.FunctionYAML{Results: []string{"i32", "i64"}, Code: "(i32.const 1) (i64.const 2)"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added.TypeName != "$t0" || len(moduleCtx.types) != typesCount {
+		t.Errorf("expected type $t0 to be reused, got %s", added.TypeName)
+	}
+	if _, err := moduleCtx.AddFunction(&wyaml.FunctionYAML{Result: "i32", Results: []string{"i32", "i64"}}); err == nil {
+		t.Error("expected error when both the result and the results are defined")
+	}
+	if _, err := moduleCtx.AddFunction(&wyaml.FunctionYAML{Results: []string{"i32", "string"}}); err == nil {
+		t.Error("expected error for a composite multi-value result")
+	}
+}
+
+func TestVisitors_ReferenceTypes(t *testing.T) {
+	moduleCtx := NewModuleContext(NewCodeParser(wtemplate.ClearString(multiValueVisitorCode)).Parse())
+	if _, err := moduleCtx.AddGlobal("externref"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := moduleCtx.AddGlobal("externref = 1"); err == nil {
+		t.Error("expected error for a reference with an initial value")
+	}
+	if _, err := moduleCtx.AddGlobal("[]funcref"); err == nil {
+		t.Error("expected error for an array of references")
+	}
+	fnDef, err := moduleCtx.AddFunction(&wyaml.FunctionYAML{
+		Args:   []wyaml.FunctionArgYAML{{Name: "ref", Type: "externref"}},
+		Result: "externref",
+		Code:   "(local.get $ref)",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fnDef.ReturnsComposite() || !fnDef.Parameters()[0].IsPrimitive() {
+		t.Error("references must be passed through as primitive values")
+	}
+	code := moduleCtx.String()
+	for _, expected := range []string{"(mut externref) (ref.null extern)", "(param externref) (result externref)"} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("module does not contain %q:\n%s", expected, code)
+		}
+	}
+}
+
+func TestVisitors_FunctionIndexes(t *testing.T) {
+	moduleCtx := NewModuleContext(NewCodeParser(wtemplate.ClearString(importsVisitorCode)).Parse())
+	for i, name := range []string{"$log", "$abort", "$print", "$f3", "$f4"} {
+		fnDef, ok := moduleCtx.Function(name)
+		if !ok {
+			t.Fatalf("function %s not found", name)
+		}
+		if index := fnDef.Index(moduleCtx); index != i {
+			t.Errorf("expected function %s with index %d, got %d", name, i, index)
+		}
+	}
+}
+
+var multiValueVisitorCode = `
+(module
+	(type $t0 (func (result i32 i64)))
+	(func $f1 (type $t0) (result i32) (result i64) (i32.const 0) (i64.const 0))
+)
+`
+
+var importsVisitorCode = `
+(module
+	(type $t0 (func (param i32)))
+	(import "env" "log" (func $log (type $t0)))
+	(import "env" "abort" (func $abort (type $t0)))
+	(import "console" "print" (func $print (type $t0)))
+	(func $f3 (type $t0) (param $p0 i32) (call $log (local.get $p0)))
+	(func $f4 (type $t0) (param $p0 i32) (call $f3 (local.get $p0)))
+)
+`
+
 var longFunctionVisitorCode = `
 (module
 	(type $t1 (func (param i32) (result i32)))
@@ -25,6 +25,16 @@ const (
 	ConfigAllowEmpty      = "allow_empty"
 	ConfigVerbose         = "verbose"
 	ConfigIgnoreOrder     = "ignore_order"
+	ConfigPureWasm        = "pure_wasm"
+	ConfigOutFlat         = "out_flat"
+	ConfigOptimize        = "optimize"
+	ConfigFormat          = "format"
+	ConfigOutDiff         = "out_diff"
+	ConfigOutDir          = "out_dir"
+	ConfigWorkers         = "workers"
+	ConfigAddr            = "addr"
+	ConfigWatch           = "watch"
+	ConfigOutReport       = "out_report"
 )
 
 var (
@@ -43,6 +53,16 @@ var (
 		ConfigAllowEmpty:      false,
 		ConfigVerbose:         false,
 		ConfigIgnoreOrder:     false,
+		ConfigPureWasm:        false,
+		ConfigOutFlat:         false,
+		ConfigOptimize:        false,
+		ConfigFormat:          "text",
+		ConfigOutDiff:         "",
+		ConfigOutDir:          "output",
+		ConfigWorkers:         0,
+		ConfigAddr:            "localhost:8080",
+		ConfigWatch:           false,
+		ConfigOutReport:       "",
 	}
 )
 
@@ -64,6 +84,16 @@ type ToolConfig struct {
 	AllowEmpty          bool     `mapstructure:"allow_empty"`
 	Verbose             bool     `mapstructure:"verbose"`
 	ConfigIgnoreOrder   bool     `mapstructure:"ignore_order"`
+	PureWasm            bool     `mapstructure:"pure_wasm"`
+	OutputFlat          bool     `mapstructure:"out_flat"`
+	Optimize            bool     `mapstructure:"optimize"`
+	Format              string   `mapstructure:"format"`
+	OutputDiff          string   `mapstructure:"out_diff"`
+	OutputDir           string   `mapstructure:"out_dir"`
+	Workers             int      `mapstructure:"workers"`
+	Addr                string   `mapstructure:"addr"`
+	Watch               bool     `mapstructure:"watch"`
+	OutputReport        string   `mapstructure:"out_report"`
 }
 
 // Get returns the tool configurations.
@@ -71,6 +101,12 @@ func Get() ToolConfig {
 	return config
 }
 
+// Set replaces the tool configurations.
+// it is used by the worker processes, that execute with the configurations of the main process.
+func Set(c ToolConfig) {
+	config = c
+}
+
 // SetupViperConfigs sets up the viper configurations.
 func SetupViperConfigs() {
 	// Update runtime defaults.
@@ -105,6 +141,16 @@ func SetupViperConfigs() {
 	pflag.Bool(ConfigAllowEmpty, viper.GetBool(ConfigAllowEmpty), "allow execution even with no advices found (applies global transformations)")
 	pflag.Bool(ConfigVerbose, viper.GetBool(ConfigVerbose), "the tool is executed in verbose mode")
 	pflag.Bool(ConfigIgnoreOrder, viper.GetBool(ConfigIgnoreOrder), "skips the advice order field")
+	pflag.Bool(ConfigPureWasm, viper.GetBool(ConfigPureWasm), "composite types are handled by an in-module runtime instead of the javascript glue")
+	pflag.Bool(ConfigOutFlat, viper.GetBool(ConfigOutFlat), "the output module is printed as flat (non-folded) WAT code instead of WASM")
+	pflag.Bool(ConfigOptimize, viper.GetBool(ConfigOptimize), "removes the unused code left behind by the transformations from the output module")
+	pflag.String(ConfigFormat, viper.GetString(ConfigFormat), "output format of the commands (text or json)")
+	pflag.String(ConfigOutDiff, viper.GetString(ConfigOutDiff), "output filename for the per-function diff between the original and the transformed module")
+	pflag.String(ConfigOutDir, viper.GetString(ConfigOutDir), "output directory for the modules transformed by the batch command")
+	pflag.Int(ConfigWorkers, viper.GetInt(ConfigWorkers), "number of modules transformed concurrently by the batch and serve commands (0 uses the number of CPUs)")
+	pflag.String(ConfigAddr, viper.GetString(ConfigAddr), "address where the serve command listens for requests")
+	pflag.Bool(ConfigWatch, viper.GetBool(ConfigWatch), "transforms the input module again each time it or the transformation file changes")
+	pflag.String(ConfigOutReport, viper.GetString(ConfigOutReport), "output filename for the json report of the transformation")
 	pflag.Parse()
 
 	err = viper.BindPFlags(pflag.CommandLine)
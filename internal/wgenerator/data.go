@@ -0,0 +1,34 @@
+package wgenerator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DataMemoryIndex is the index of the memory created to store the module data.
+const DataMemoryIndex = "$" + CodeIndexPrefix + "data.memory"
+
+// GetDataMemoryCode returns the code of a new memory with some number of pages.
+func GetDataMemoryCode(pages int) string {
+	return fmt.Sprintf("(memory %s %d)", DataMemoryIndex, pages)
+}
+
+// GetDataSegmentCode returns the code of a data segment that stores some value at an address.
+func GetDataSegmentCode(address int, value string) string {
+	return fmt.Sprintf("(data (i32.const %d) \"%s\")", address, escapeDataString(value))
+}
+
+// escapeDataString escapes a value to be used as a WAT string.
+// every byte that is not a printable ASCII character is written as an hexadecimal escape.
+func escapeDataString(value string) string {
+	var sb strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c < 0x20 || c >= 0x7f || c == '"' || c == '\\' {
+			fmt.Fprintf(&sb, "\\%02x", c)
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
@@ -18,13 +18,14 @@ var jsTemplate *template.Template
 
 // jsTemplateIn contains the input data for the javascript template.
 type jsTemplateIn struct {
-	InternalFns []jsInternalFnIn
-	ExternalFns []jsExternalFnIn
+	InternalFns   []jsInternalFnIn
+	ExternalFns   []jsExternalFnIn
+	ErrorContexts []JsErrorContext
 }
 
 // newJsTemplateIn is a constructor for jsTemplateIn.
-func newJsTemplateIn(internalFns []jsInternalFnIn, externalFns []jsExternalFnIn) jsTemplateIn {
-	return jsTemplateIn{internalFns, externalFns}
+func newJsTemplateIn(internalFns []jsInternalFnIn, externalFns []jsExternalFnIn, errorContexts []JsErrorContext) jsTemplateIn {
+	return jsTemplateIn{internalFns, externalFns, errorContexts}
 }
 
 // jsInternalFnIn contains the input data for the javascript internal function.
@@ -82,6 +83,16 @@ func (fnDef JsFunctionDefinition) IsImported() bool {
 	return fnDef.Scope == JsScopeTypeImported
 }
 
+// JsErrorContext contains the provenance data of a runtime expression.
+// it is reported to the error handler when the expression fails.
+type JsErrorContext struct {
+	ID            int
+	Advice        string
+	Function      string
+	FunctionIndex int
+	Expression    string
+}
+
 // JsArgumentDefinition contains the definition data for some function argument.
 type JsArgumentDefinition struct {
 	Code int
@@ -118,7 +129,8 @@ const (
 )
 
 // GetJsCode returns the javascript code for some definition.
-func GetJsCode(functions []JsFunctionDefinition) (string, error) {
+// the error contexts are used to identify the expression that originated a runtime error.
+func GetJsCode(functions []JsFunctionDefinition, errorContexts []JsErrorContext) (string, error) {
 	var internalFns []jsInternalFnIn
 	var externalFns []jsExternalFnIn
 	for _, fn := range functions {
@@ -133,7 +145,7 @@ func GetJsCode(functions []JsFunctionDefinition) (string, error) {
 		}
 	}
 	buf := new(bytes.Buffer)
-	if err := jsTemplate.Execute(buf, newJsTemplateIn(internalFns, externalFns)); err != nil {
+	if err := jsTemplate.Execute(buf, newJsTemplateIn(internalFns, externalFns, errorContexts)); err != nil {
 		return "", err
 	}
 	if len(internalFns) == 0 && len(externalFns) == 0 {
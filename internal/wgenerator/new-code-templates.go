@@ -7,10 +7,10 @@ import (
 )
 
 var (
-	globalTemplateStr   = `(global {{ .Name }} (mut {{ .Type }}) ({{ .Type }}.const {{ if (ne .Value "") }}{{ .Value }}{{ else }}0{{ end }}))`
-	typeTemplateStr     = `(type {{ .Name }} (func {{ with .Params }}(param{{ range . }} {{ . }}{{ end }}){{ end }} {{ if (ne .Result "") }}(result {{ .Result }}){{ end }}))`
+	globalTemplateStr   = `(global {{ .Name }} (mut {{ .Type }}) {{ if (ne .ZeroValue "") }}{{ .ZeroValue }}{{ else }}({{ .Type }}.const {{ if (ne .Value "") }}{{ .Value }}{{ else }}0{{ end }}){{ end }})`
+	typeTemplateStr     = `(type {{ .Name }} (func {{ with .Params }}(param{{ range . }} {{ . }}{{ end }}){{ end }} {{ with .Results }}(result{{ range . }} {{ . }}{{ end }}){{ end }}))`
 	functionTemplateStr = `(func {{ .Name }} (type {{ .TypeName }})
-	{{ with .Params }}{{ range . }}(param {{ .Name }} {{ .Type }}) {{ end }}{{ end }} {{ if (ne .Result "") }}(result {{ .Result }}){{ end }}
+	{{ with .Params }}{{ range . }}(param {{ .Name }} {{ .Type }}) {{ end }}{{ end }} {{ with .Results }}(result{{ range . }} {{ . }}{{ end }}){{ end }}
 	{{ printf "%s" .Code }}
 )`
 	startFunctionTemplateStr  = `(start {{ .Name }})`
@@ -20,6 +20,10 @@ var (
 	setLocalTemplateStr       = `(local.set {{ .Name }} ({{ .Type }}.const {{ .Value }}))`
 	setLocalInstrTemplateStr  = `(local.set {{ .Name }} {{ .Instruction }})`
 	getVariableTemplateStr    = `{{ if .IsLocal }}(local.get {{ .Name }}){{ else }}(global.get {{ .Name }}){{ end }}`
+	memoryTemplateStr         = `(memory {{ .Name }}{{ with .Exported }} (export "{{ . }}"){{ end }} {{ .Min }}{{ with .Max }} {{ . }}{{ end }})`
+	tableTemplateStr          = `(table {{ .Name }}{{ with .Exported }} (export "{{ . }}"){{ end }} {{ .Min }}{{ with .Max }} {{ . }}{{ end }} {{ .Type }})`
+	dataTemplateStr           = `(data {{ .Name }}{{ if (ne .Memory "") }} (memory {{ .Memory }}){{ end }} (i32.const {{ .Offset }}) "{{ .Value }}")`
+	elemTemplateStr           = `(elem{{ if (ne .Table "") }} (table {{ .Table }}){{ end }} (i32.const {{ .Offset }}) func{{ range .Functions }} {{ . }}{{ end }})`
 )
 
 var (
@@ -33,6 +37,10 @@ var (
 	setLocalTemplate       *template.Template
 	setLocalInstrTemplate  *template.Template
 	getVariableTemplate    *template.Template
+	memoryTemplate         *template.Template
+	tableTemplate          *template.Template
+	dataTemplate           *template.Template
+	elemTemplate           *template.Template
 )
 
 // init initializes the templates.
@@ -88,4 +96,24 @@ func init() {
 	if err != nil {
 		logrus.Fatal(err)
 	}
+	// Parse memory template.
+	memoryTemplate, err = template.New("memory-template").Parse(memoryTemplateStr)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	// Parse table template.
+	tableTemplate, err = template.New("table-template").Parse(tableTemplateStr)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	// Parse data template.
+	dataTemplate, err = template.New("data-template").Parse(dataTemplateStr)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	// Parse elem template.
+	elemTemplate, err = template.New("elem-template").Parse(elemTemplateStr)
+	if err != nil {
+		logrus.Fatal(err)
+	}
 }
@@ -15,7 +15,7 @@ type functionTemplateIn struct {
 	Name     string
 	TypeName string
 	Params   []*functionTemplateReferenceIn
-	Result   string
+	Results  []string
 	Code     string
 }
 
@@ -29,7 +29,7 @@ func newFunctionTemplateIn(expr *wyaml.FunctionYAML, name, typeName string) *fun
 		Name:     name,
 		TypeName: typeName,
 		Params:   params,
-		Result:   expr.Result,
+		Results:  expr.ResultTypes(),
 		Code:     expr.Code,
 	}
 }
@@ -73,18 +73,22 @@ type functionTemplateReferenceIn struct {
 }
 
 // globalTemplateIn contains the input data for the global template.
+// the reference and vector globals are initialized with their zero value instruction.
 type globalTemplateIn struct {
-	Name  string
-	Type  string
-	Value string
+	Name      string
+	Type      string
+	Value     string
+	ZeroValue string
 }
 
 // newGlobalTemplateIn is the constructor for globalTemplateIn.
 func newGlobalTemplateIn(expr *variable.Expr, name string) *globalTemplateIn {
+	typ := expr.GetType()
 	return &globalTemplateIn{
-		Name:  name,
-		Type:  expr.GetType(),
-		Value: expr.GetValue("0"),
+		Name:      name,
+		Type:      typ,
+		Value:     expr.GetValue("0"),
+		ZeroValue: ZeroValueCode(typ),
 	}
 }
 
@@ -145,17 +149,77 @@ func newGetVariableTemplateIn(name string, isLocal bool) *getVariableTemplateIn
 
 // typeTemplateIn contains the input data for the type template.
 type typeTemplateIn struct {
-	Name   string
-	Result string
-	Params []string
+	Name    string
+	Results []string
+	Params  []string
 }
 
 // newTypeTemplateIn is a constructor for typeTemplateIn.
-func newTypeTemplateIn(params []string, result, name string) *typeTemplateIn {
+func newTypeTemplateIn(params, results []string, name string) *typeTemplateIn {
 	return &typeTemplateIn{
+		Name:    name,
+		Results: results,
+		Params:  params,
+	}
+}
+
+// memoryTemplateIn contains the input data for the memory template.
+type memoryTemplateIn struct {
+	Name     string
+	Min      int
+	Max      *int
+	Exported *string
+}
+
+// newMemoryTemplateIn is the constructor for memoryTemplateIn.
+func newMemoryTemplateIn(memory *wyaml.MemoryYAML, name string) *memoryTemplateIn {
+	return &memoryTemplateIn{
+		Name:     name,
+		Min:      memory.Min,
+		Max:      memory.Max,
+		Exported: memory.Exported,
+	}
+}
+
+// tableTemplateIn contains the input data for the table template.
+type tableTemplateIn struct {
+	Name     string
+	Min      int
+	Max      *int
+	Type     string
+	Exported *string
+}
+
+// newTableTemplateIn is the constructor for tableTemplateIn.
+func newTableTemplateIn(table *wyaml.TableYAML, name string) *tableTemplateIn {
+	typ := table.Type
+	if typ == "" {
+		typ = "funcref"
+	}
+	return &tableTemplateIn{
+		Name:     name,
+		Min:      table.Min,
+		Max:      table.Max,
+		Type:     typ,
+		Exported: table.Exported,
+	}
+}
+
+// dataTemplateIn contains the input data for the data template.
+type dataTemplateIn struct {
+	Name   string
+	Memory string
+	Offset int
+	Value  string
+}
+
+// newDataTemplateIn is the constructor for dataTemplateIn.
+func newDataTemplateIn(data *wyaml.DataYAML, name string) *dataTemplateIn {
+	return &dataTemplateIn{
 		Name:   name,
-		Result: result,
-		Params: params,
+		Memory: data.Memory,
+		Offset: data.Offset,
+		Value:  escapeDataString(data.Value),
 	}
 }
 
@@ -195,9 +259,9 @@ func ImportFunctionToCode(name, typeName, moduleName, exportName string) string
 }
 
 // FunctionTypeToCode returns the wat code containing the function type.
-func FunctionTypeToCode(params []string, result string, name string) string {
+func FunctionTypeToCode(params, results []string, name string) string {
 	buf := new(bytes.Buffer)
-	if err := typeTemplate.Execute(buf, newTypeTemplateIn(params, result, name)); err != nil {
+	if err := typeTemplate.Execute(buf, newTypeTemplateIn(params, results, name)); err != nil {
 		logrus.Fatal(err)
 	}
 	return buf.String()
@@ -212,6 +276,21 @@ func GlobalVariableToCode(expr *variable.Expr, name string) string {
 	return buf.String()
 }
 
+// ZeroValueCode returns the wat code containing the zero value of some reference or vector type.
+// returns an empty string when the type is initialized with a constant instruction.
+func ZeroValueCode(typ string) string {
+	switch typ {
+	case "funcref":
+		return "(ref.null func)"
+	case "externref":
+		return "(ref.null extern)"
+	case "v128":
+		return "(v128.const i64x2 0 0)"
+	default:
+		return ""
+	}
+}
+
 // LocalVariableToCode returns the wat code containing the local variable.
 func LocalVariableToCode(name string, typ string) string {
 	buf := new(bytes.Buffer)
@@ -247,3 +326,40 @@ func GetVariableToCode(name string, isLocal bool) string {
 	}
 	return buf.String()
 }
+
+// MemoryToCode returns the wat code containing the memory.
+func MemoryToCode(memory *wyaml.MemoryYAML, name string) string {
+	buf := new(bytes.Buffer)
+	if err := memoryTemplate.Execute(buf, newMemoryTemplateIn(memory, name)); err != nil {
+		logrus.Fatal(err)
+	}
+	return buf.String()
+}
+
+// TableToCode returns the wat code containing the table.
+func TableToCode(table *wyaml.TableYAML, name string) string {
+	buf := new(bytes.Buffer)
+	if err := tableTemplate.Execute(buf, newTableTemplateIn(table, name)); err != nil {
+		logrus.Fatal(err)
+	}
+	return buf.String()
+}
+
+// DataToCode returns the wat code containing the data segment.
+func DataToCode(data *wyaml.DataYAML, name string) string {
+	buf := new(bytes.Buffer)
+	if err := dataTemplate.Execute(buf, newDataTemplateIn(data, name)); err != nil {
+		logrus.Fatal(err)
+	}
+	return buf.String()
+}
+
+// ElemToCode returns the wat code containing the element segment.
+// element segments are not named, as the first value of the instruction is reserved for the table.
+func ElemToCode(elem *wyaml.ElemYAML) string {
+	buf := new(bytes.Buffer)
+	if err := elemTemplate.Execute(buf, elem); err != nil {
+		logrus.Fatal(err)
+	}
+	return buf.String()
+}
@@ -61,6 +61,7 @@ var (
 		newErrorFunction("new", ""),
 		newErrorFunction("set", "", "i32"),
 		newErrorFunction("print", ""),
+		newErrorFunction("context", "", "i32"),
 	}
 )
 
@@ -134,47 +135,53 @@ func newErrorFunction(exportedName string, result string, params ...string) *Imp
 
 // primitiveEvalTemplateIn contains the input data for the evaluation of primitives.
 type primitiveEvalTemplateIn struct {
-	EvalExpr  []int32
-	Type      string
-	LocalName string
-	BlockName string
+	EvalExpr     []int32
+	ExpressionID int
+	Type         string
+	LocalName    string
+	BlockName    string
 }
 
 // newPrimitiveEvalTemplateIn is a constructor for primitiveEvalTemplateIn.
-func newPrimitiveEvalTemplateIn(expr, local, typ, block string) *primitiveEvalTemplateIn {
+func newPrimitiveEvalTemplateIn(expr string, exprID int, local, typ, block string) *primitiveEvalTemplateIn {
 	return &primitiveEvalTemplateIn{
-		EvalExpr:  stringToInt32(expr),
-		Type:      typ,
-		LocalName: local,
-		BlockName: block,
+		EvalExpr:     stringToInt32(expr),
+		ExpressionID: exprID,
+		Type:         typ,
+		LocalName:    local,
+		BlockName:    block,
 	}
 }
 
 // compositeCallEvalTemplateIn contains the input data for the evaluation of composite types on a call.
 type compositeCallEvalTemplateIn struct {
-	EvalExpr []int32
-	ArgIndex int
-	PushArgs bool
+	EvalExpr     []int32
+	ExpressionID int
+	ArgIndex     int
+	PushArgs     bool
 }
 
 // newCompositeCallEvalTemplateIn is a constructor for compositeEvalTemplateIn.
-func newCompositeCallEvalTemplateIn(expr string, index int, pushArgs bool) *compositeCallEvalTemplateIn {
+func newCompositeCallEvalTemplateIn(expr string, exprID int, index int, pushArgs bool) *compositeCallEvalTemplateIn {
 	return &compositeCallEvalTemplateIn{
-		EvalExpr: stringToInt32(expr),
-		ArgIndex: index,
-		PushArgs: pushArgs,
+		EvalExpr:     stringToInt32(expr),
+		ExpressionID: exprID,
+		ArgIndex:     index,
+		PushArgs:     pushArgs,
 	}
 }
 
 // compositeReturnEvalTemplateIn contains the input data for the evaluation of composite types on a return.
 type compositeReturnEvalTemplateIn struct {
-	EvalExpr []int32
+	EvalExpr     []int32
+	ExpressionID int
 }
 
 // newCompositeReturnEvalTemplateIn is a constructor for compositeReturnEvalTemplateIn.
-func newCompositeReturnEvalTemplateIn(expr string) *compositeReturnEvalTemplateIn {
+func newCompositeReturnEvalTemplateIn(expr string, exprID int) *compositeReturnEvalTemplateIn {
 	return &compositeReturnEvalTemplateIn{
-		EvalExpr: stringToInt32(expr),
+		EvalExpr:     stringToInt32(expr),
+		ExpressionID: exprID,
 	}
 }
 
@@ -194,23 +201,25 @@ func newCompositeReturnEvalRefTemplateIn(name, key string) *compositeReturnEvalR
 
 // compositeZoneEvalTemplateIn contains the input data for the evaluation of composite zones.
 type compositeZoneEvalTemplateIn struct {
-	Name      []int32
-	Key       []int32
-	EvalExpr  []int32
-	BlockName string
-	LoopName  string
-	IsLocal   bool
+	Name         []int32
+	Key          []int32
+	EvalExpr     []int32
+	ExpressionID int
+	BlockName    string
+	LoopName     string
+	IsLocal      bool
 }
 
 // compositeZoneEvalTemplateIn is a constructor for compositeZoneEvalTemplateIn.
-func newCompositeZoneEvalTemplateIn(name, key, expr, block, loop string, isLocal bool) *compositeZoneEvalTemplateIn {
+func newCompositeZoneEvalTemplateIn(name, key, expr string, exprID int, block, loop string, isLocal bool) *compositeZoneEvalTemplateIn {
 	return &compositeZoneEvalTemplateIn{
-		Name:      stringToInt32(name),
-		Key:       stringToInt32(key),
-		EvalExpr:  stringToInt32(expr),
-		BlockName: block,
-		LoopName:  loop,
-		IsLocal:   isLocal,
+		Name:         stringToInt32(name),
+		Key:          stringToInt32(key),
+		EvalExpr:     stringToInt32(expr),
+		ExpressionID: exprID,
+		BlockName:    block,
+		LoopName:     loop,
+		IsLocal:      isLocal,
 	}
 }
 
@@ -287,10 +296,11 @@ func newZoneParamCompositeTemplateIn(name, index, blockName, loopName string) *z
 }
 
 // GetPrimitiveEvalStartCode returns the code to add before a primitive variable operation on an evaluation.
-func GetPrimitiveEvalStartCode(expression, localName, localType string, opCount int) (string, int) {
+// the expression identifier is passed to the error module to identify the expression on runtime errors.
+func GetPrimitiveEvalStartCode(expression string, exprID int, localName, localType string, opCount int) (string, int) {
 	buf := new(bytes.Buffer)
 	if err := primitiveEvalTemplateStart.Execute(buf,
-		newPrimitiveEvalTemplateIn(expression, localName, localType, uniqueBlockName(opCount)),
+		newPrimitiveEvalTemplateIn(expression, exprID, localName, localType, uniqueBlockName(opCount)),
 	); err != nil {
 		logrus.Fatal(err)
 	}
@@ -298,10 +308,10 @@ func GetPrimitiveEvalStartCode(expression, localName, localType string, opCount
 }
 
 // GetCompositeCallEvalStartCode returns the code to add before a call evaluation.
-func GetCompositeCallEvalStartCode(expression string, index int, shouldPushArgs bool, opCount int) (string, int) {
+func GetCompositeCallEvalStartCode(expression string, exprID int, index int, shouldPushArgs bool, opCount int) (string, int) {
 	buf := new(bytes.Buffer)
 	if err := compositeCallEvalTemplateStart.Execute(buf,
-		newCompositeCallEvalTemplateIn(expression, index, shouldPushArgs),
+		newCompositeCallEvalTemplateIn(expression, exprID, index, shouldPushArgs),
 	); err != nil {
 		logrus.Fatal(err)
 	}
@@ -309,10 +319,10 @@ func GetCompositeCallEvalStartCode(expression string, index int, shouldPushArgs
 }
 
 // GetCompositeReturnEvalCode returns the code to add on a composite return evaluation.
-func GetCompositeReturnEvalCode(expression string, opCount int) (string, int) {
+func GetCompositeReturnEvalCode(expression string, exprID int, opCount int) (string, int) {
 	buf := new(bytes.Buffer)
 	if err := compositeReturnEvalTemplate.Execute(buf,
-		newCompositeReturnEvalTemplateIn(expression),
+		newCompositeReturnEvalTemplateIn(expression, exprID),
 	); err != nil {
 		logrus.Fatal(err)
 	}
@@ -340,10 +350,10 @@ func GetCompositeCallEvalEndCode(shouldPopArgs bool, opCount int) (string, int)
 }
 
 // GetCompositeZoneEvalStartCode returns the code to add before using a variable in evaluation.
-func GetCompositeZoneEvalStartCode(name, key, expression string, isLocal bool, opCount int) (string, int) {
+func GetCompositeZoneEvalStartCode(name, key, expression string, exprID int, isLocal bool, opCount int) (string, int) {
 	buf := new(bytes.Buffer)
 	if err := compositeZoneEvalTemplateStart.Execute(buf,
-		newCompositeZoneEvalTemplateIn(name, key, expression, uniqueBlockName(opCount), uniqueLoopName(opCount+1), isLocal),
+		newCompositeZoneEvalTemplateIn(name, key, expression, exprID, uniqueBlockName(opCount), uniqueLoopName(opCount+1), isLocal),
 	); err != nil {
 		logrus.Fatal(err)
 	}
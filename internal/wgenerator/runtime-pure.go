@@ -0,0 +1,109 @@
+package wgenerator
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	_ "embed"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PureRuntimePrefix is the prefix used on the index of every element of the in-module runtime.
+const PureRuntimePrefix = "$" + CodeIndexPrefix + "rt."
+
+var (
+	//go:embed resources/pure-runtime-template.go.txt
+	pureRuntimeTemplateStr string
+
+	pureRuntimeTemplate *template.Template
+)
+
+// init initializes the templates.
+func init() {
+	var err error
+	pureRuntimeTemplate, err = template.New("pure-runtime-template").Parse(pureRuntimeTemplateStr)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+// pureRuntimeTemplateIn contains the input data for the in-module runtime template.
+type pureRuntimeTemplateIn struct {
+	Prefix    string
+	NewMemory bool
+}
+
+// PureRuntimeFunction returns the index of some function of the in-module runtime.
+func PureRuntimeFunction(name string) string {
+	return PureRuntimePrefix + name
+}
+
+// GetPureRuntimeCode returns the code of the in-module runtime (allocator, strings, arrays and maps).
+func GetPureRuntimeCode(newMemory bool) string {
+	buf := new(bytes.Buffer)
+	if err := pureRuntimeTemplate.Execute(buf, &pureRuntimeTemplateIn{PureRuntimePrefix, newMemory}); err != nil {
+		logrus.Fatal(err)
+	}
+	return buf.String()
+}
+
+// GetPureStringCode returns an instruction that creates a new string on the in-module runtime.
+func GetPureStringCode(value string) string {
+	code := fmt.Sprintf("(call %s (i32.const %d))", PureRuntimeFunction("string_new"), len(value))
+	for i, word := range stringToInt32(value) {
+		code = fmt.Sprintf("(call %s %s (i32.const %d) (i32.const %d))", PureRuntimeFunction("string_write"), code, i*bytesOn32bits, word)
+	}
+	return code
+}
+
+// GetPureArrayCode returns an instruction that creates a new array on the in-module runtime.
+// the values must be instructions that result in i64 slots (see GetPureSlotCode).
+func GetPureArrayCode(values []string) string {
+	code := fmt.Sprintf("(call %s (i32.const %d))", PureRuntimeFunction("array_new"), len(values))
+	for _, value := range values {
+		code = fmt.Sprintf("(call %s %s %s)", PureRuntimeFunction("array_push"), code, value)
+	}
+	return code
+}
+
+// GetPureMapCode returns an instruction that creates a new map on the in-module runtime.
+// the keys and values must be instructions that result in i64 slots (see GetPureSlotCode).
+func GetPureMapCode(stringKeys bool, keys, values []string) string {
+	var stringKeysCode int
+	if stringKeys {
+		stringKeysCode = 1
+	}
+	code := fmt.Sprintf("(call %s (i32.const %d))", PureRuntimeFunction("map_new"), stringKeysCode)
+	for i := 0; i < len(keys) && i < len(values); i++ {
+		code = fmt.Sprintf("(call %s %s %s %s)", PureRuntimeFunction("map_set"), code, keys[i], values[i])
+	}
+	return code
+}
+
+// GetPureSlotCode returns an instruction that converts a value into the i64 slot used by arrays and maps.
+// primitive values must be constants, while the remaining types must be instructions resulting in a runtime handle.
+func GetPureSlotCode(typ, value string) string {
+	switch typ {
+	case "i32":
+		return fmt.Sprintf("(i64.extend_i32_s (i32.const %s))", defaultConstValue(value))
+	case "i64":
+		return fmt.Sprintf("(i64.const %s)", defaultConstValue(value))
+	case "f32":
+		return fmt.Sprintf("(i64.extend_i32_u (i32.reinterpret_f32 (f32.const %s)))", defaultConstValue(value))
+	case "f64":
+		return fmt.Sprintf("(i64.reinterpret_f64 (f64.const %s))", defaultConstValue(value))
+	default:
+		return fmt.Sprintf("(i64.extend_i32_u %s)", value)
+	}
+}
+
+// defaultConstValue returns the value to use on a constant instruction.
+func defaultConstValue(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "0"
+	}
+	return value
+}
@@ -15,6 +15,11 @@ type KeywordsMap interface {
 	Get(k string) (interface{}, KeywordType, bool)
 }
 
+// ValueTypesKeywordsMap is implemented by any keywords map that can retrieve the value type of a keyword.
+type ValueTypesKeywordsMap interface {
+	ValueType(k string) (string, bool)
+}
+
 // StringValuesMap represents a keyword map of strings.
 type StringValuesMap map[string]string
 
@@ -83,3 +88,25 @@ func (m ObjectValuesMap) Get(k string) (interface{}, KeywordType, bool) {
 	}
 	return nil, KeywordTypeUnknown, false
 }
+
+// ValueTypesMap represents a keyword map of value types.
+// it does not provide keywords, only the value type of keywords provided by other maps.
+type ValueTypesMap map[string]string
+
+// Is returns the keyword type.
+// always KeywordTypeUnknown since no keyword is provided.
+func (m ValueTypesMap) Is(string) KeywordType {
+	return KeywordTypeUnknown
+}
+
+// Get return the keyword if presented in the map.
+// always not found since no keyword is provided.
+func (m ValueTypesMap) Get(string) (interface{}, KeywordType, bool) {
+	return nil, KeywordTypeUnknown, false
+}
+
+// ValueType returns the value type of some keyword.
+func (m ValueTypesMap) ValueType(k string) (string, bool) {
+	v, ok := m[k]
+	return v, ok
+}
@@ -6,7 +6,6 @@ import (
 	"strings"
 
 	"github.com/fatih/structs"
-	"github.com/sirupsen/logrus"
 
 	"joao/wasm-manipulator/pkg/wutils"
 )
@@ -24,6 +23,12 @@ type Object interface {
 	Len() int
 }
 
+// failf stops the expression using some object with an error.
+// the error is recovered and reported by the expressions parser.
+func failf(format string, args ...interface{}) {
+	panic(fmt.Errorf(format, args...))
+}
+
 // KwObject is the object type for a keyword object.
 type KwObject struct {
 	Val interface{} `json:"-"`
@@ -43,7 +48,7 @@ func (to *KwObject) Value() string {
 func (to KwObject) String() string {
 	rv, err := to.assertObject()
 	if err != nil {
-		logrus.Fatalf("accessing string format in non-object element: %v", err)
+		failf("accessing string format in non-object element: %v", err)
 	}
 	if rv.Kind() == reflect.Map {
 		return to.stringMap(rv)
@@ -72,7 +77,7 @@ func (to KwObject) stringStruct(rv reflect.Value) string {
 func (to KwObject) Slice() []Object {
 	rv, err := to.assertObject()
 	if err != nil {
-		logrus.Fatalf("accessing property in non-object element: %v", err)
+		failf("accessing property in non-object element: %v", err)
 	}
 	if rv.Kind() == reflect.Map {
 		return to.sliceMap(rv)
@@ -84,7 +89,7 @@ func (to KwObject) Slice() []Object {
 func (to KwObject) StringSlice() []string {
 	rv, err := to.assertObject()
 	if err != nil {
-		logrus.Fatalf("accessing property in non-object element: %v", err)
+		failf("accessing property in non-object element: %v", err)
 	}
 	if rv.Kind() == reflect.Map {
 		return to.stringSliceMap(rv)
@@ -132,7 +137,7 @@ func (to KwObject) stringSliceStruct(rv reflect.Value) []string {
 func (to KwObject) KeysSlice() []string {
 	rv, err := to.assertObject()
 	if err != nil {
-		logrus.Fatalf("accessing property in non-object element: %v", err)
+		failf("accessing property in non-object element: %v", err)
 	}
 	if rv.Kind() == reflect.Map {
 		return to.keysSliceMap(rv)
@@ -162,15 +167,15 @@ func (to KwObject) keysSliceStruct(rv reflect.Value) []string {
 func (to *KwObject) Join(o Object) {
 	kwO, ok := o.(*KwObject)
 	if !ok {
-		logrus.Fatalf("objects must always be joint with another object")
+		failf("objects must always be joint with another object")
 	}
 	rv, err := to.assertObject()
 	if err != nil {
-		logrus.Fatalf("joining object in non-object element: %v", err)
+		failf("joining object in non-object element: %v", err)
 	}
 	rvO, err := kwO.assertObject()
 	if err != nil {
-		logrus.Fatalf("joining non-object element: %v", err)
+		failf("joining non-object element: %v", err)
 	}
 	if rv.Kind() != reflect.Map {
 		rv = reflect.ValueOf(structs.Map(to.Val))
@@ -193,7 +198,7 @@ func (to *KwObject) Join(o Object) {
 func (to *KwObject) Prop(k string) Object {
 	rv, err := to.assertObject()
 	if err != nil {
-		logrus.Fatalf("accessing property in non-object element: %v", err)
+		failf("accessing property in non-object element: %v", err)
 	}
 	if rv.Kind() == reflect.Map {
 		return to.propMap(rv, k)
@@ -224,7 +229,7 @@ func (to *KwObject) propStruct(rv reflect.Value, k string) Object {
 func (to *KwObject) RemoveProp(k string) Object {
 	rv, err := to.assertObject()
 	if err != nil {
-		logrus.Fatalf("removing property in non-object element: %v", err)
+		failf("removing property in non-object element: %v", err)
 	}
 	if rv.Kind() == reflect.Map {
 		return to.removePropMap(rv, k)
@@ -270,7 +275,7 @@ func (to *KwObject) removePropStruct(rv reflect.Value, k string) Object {
 func (to *KwObject) ReplacePropValue(k, v string) Object {
 	rv, err := to.assertObject()
 	if err != nil {
-		logrus.Fatalf("replacing property value in non-object element: %v", err)
+		failf("replacing property value in non-object element: %v", err)
 	}
 	if rv.Kind() == reflect.Map {
 		return to.replacePropValueMap(rv, k, v)
@@ -319,7 +324,7 @@ func (to *KwObject) replacePropValueStruct(rv reflect.Value, k, v string) Object
 
 // Index returns the value underlying the passed index as a keyword object itself.
 func (to *KwObject) Index(int) Object {
-	logrus.Fatalf("accessing index in non-array element: expected array but got object")
+	failf("accessing index in non-array element: expected array but got object")
 	return NewKwNil()
 }
 
@@ -327,7 +332,7 @@ func (to *KwObject) Index(int) Object {
 func (to *KwObject) Len() int {
 	rv, err := to.assertObject()
 	if err != nil {
-		logrus.Fatalf("error getting the object length: %v", err)
+		failf("error getting the object length: %v", err)
 	}
 	if rv.Kind() == reflect.Map {
 		return to.Len()
@@ -368,7 +373,7 @@ func (ta KwArray) String() string {
 func (ta KwArray) Slice() []Object {
 	rv, err := ta.assertArray()
 	if err != nil {
-		logrus.Fatalf("retrieving string slice from non-array element: %v", err)
+		failf("retrieving string slice from non-array element: %v", err)
 	}
 	var res []Object
 	rLen := rv.Len()
@@ -382,7 +387,7 @@ func (ta KwArray) Slice() []Object {
 func (ta KwArray) StringSlice() []string {
 	rv, err := ta.assertArray()
 	if err != nil {
-		logrus.Fatalf("retrieving string slice from non-array element: %v", err)
+		failf("retrieving string slice from non-array element: %v", err)
 	}
 	var res []string
 	rLen := rv.Len()
@@ -396,7 +401,7 @@ func (ta KwArray) StringSlice() []string {
 func (ta *KwArray) RemoveValue(k string) Object {
 	rv, err := ta.assertArray()
 	if err != nil {
-		logrus.Fatalf("removing value from non-array element: %v", err)
+		failf("removing value from non-array element: %v", err)
 	}
 	rLen := rv.Len()
 	if rLen == 0 {
@@ -416,7 +421,7 @@ func (ta *KwArray) RemoveValue(k string) Object {
 func (ta *KwArray) ReplaceValue(k, v string) Object {
 	rv, err := ta.assertArray()
 	if err != nil {
-		logrus.Fatalf("replacing value from non-array element: %v", err)
+		failf("replacing value from non-array element: %v", err)
 	}
 	rLen := rv.Len()
 	if rLen == 0 {
@@ -438,15 +443,15 @@ func (ta *KwArray) ReplaceValue(k, v string) Object {
 func (ta *KwArray) Join(a Object) {
 	kwA, ok := a.(*KwArray)
 	if !ok {
-		logrus.Fatalf("array must always be joint with another array")
+		failf("array must always be joint with another array")
 	}
 	rv, err := ta.assertArray()
 	if err != nil {
-		logrus.Fatalf("joining array in non-array element: %v", err)
+		failf("joining array in non-array element: %v", err)
 	}
 	rvA, err := kwA.assertArray()
 	if err != nil {
-		logrus.Fatalf("joining non-array element: %v", err)
+		failf("joining non-array element: %v", err)
 	}
 	var res []interface{}
 	rLen := rv.Len()
@@ -462,7 +467,7 @@ func (ta *KwArray) Join(a Object) {
 
 // Prop returns the property value of the current object as a keyword object itself.
 func (ta *KwArray) Prop(string) Object {
-	logrus.Fatalf("accessing property in non-object element: expected object but got array")
+	failf("accessing property in non-object element: expected object but got array")
 	return NewKwNil()
 }
 
@@ -470,7 +475,7 @@ func (ta *KwArray) Prop(string) Object {
 func (ta *KwArray) Index(i int) Object {
 	rv, err := ta.assertArray()
 	if err != nil {
-		logrus.Fatalf("accessing index in non-array element: %v", err)
+		failf("accessing index in non-array element: %v", err)
 	}
 	if rlen := rv.Len(); i >= rlen {
 		return NewKwNil()
@@ -482,7 +487,7 @@ func (ta *KwArray) Index(i int) Object {
 func (ta *KwArray) Len() int {
 	rv, err := ta.assertArray()
 	if err != nil {
-		logrus.Fatalf("error getting the array length: %v", err)
+		failf("error getting the array length: %v", err)
 	}
 	return rv.Len()
 }
@@ -528,12 +533,12 @@ func (tp KwPrimitive) StringSlice() []string {
 
 // Join joins an object to the corrent one.
 func (tp KwPrimitive) Join(a Object) {
-	logrus.Fatalf("primitives cannot be joint")
+	failf("primitives cannot be joint")
 }
 
 // Prop returns the property value of the current object as a keyword object itself.
 func (tp *KwPrimitive) Prop(string) Object {
-	logrus.Fatalf("accessing property in non-object element: expected object but got primitive")
+	failf("accessing property in non-object element: expected object but got primitive")
 	return NewKwNil()
 }
 
@@ -541,11 +546,11 @@ func (tp *KwPrimitive) Prop(string) Object {
 func (tp *KwPrimitive) Index(i int) Object {
 	rv := reflect.Indirect(reflect.ValueOf(tp.Val))
 	if rkind := rv.Kind(); rkind != reflect.String {
-		logrus.Fatalf("invalid index access in primitive element: expected string but got %s", rkind)
+		failf("invalid index access in primitive element: expected string but got %s", rkind)
 	}
 	val := rv.String()
 	if valLen := len(val); i >= valLen {
-		logrus.Fatalf("accessing index in string element: index out of range (length=%d, index=%d)", valLen, i)
+		failf("accessing index in string element: index out of range (length=%d, index=%d)", valLen, i)
 	}
 	return NewKwPrimitive(string(val[i]))
 }
@@ -554,7 +559,7 @@ func (tp *KwPrimitive) Index(i int) Object {
 func (tp *KwPrimitive) Len() int {
 	rv := reflect.Indirect(reflect.ValueOf(tp.Val))
 	if rkind := rv.Kind(); rkind != reflect.String {
-		logrus.Fatalf("unable to get the length of an element with type %s", rkind)
+		failf("unable to get the length of an element with type %s", rkind)
 	}
 	return len(rv.Interface().(string))
 }
@@ -590,18 +595,18 @@ func (tn KwNil) StringSlice() []string {
 
 // Join joins an object to the corrent one.
 func (tn KwNil) Join(a Object) {
-	logrus.Fatalf("nil values cannot be joint")
+	failf("nil values cannot be joint")
 }
 
 // Prop returns the property value of the current object as a keyword object itself.
 func (tn *KwNil) Prop(string) Object {
-	logrus.Fatalf("accessing property in nil element")
+	failf("accessing property in nil element")
 	return tn
 }
 
 // Index returns the value underlying the passed index as a keyword object itself.
 func (tn *KwNil) Index(int) Object {
-	logrus.Fatalf("accessing index in nil element")
+	failf("accessing index in nil element")
 	return tn
 }
 
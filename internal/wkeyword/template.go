@@ -1,7 +1,6 @@
 package wkeyword
 
 import (
-	"github.com/sirupsen/logrus"
 	"joao/wasm-manipulator/internal/wtemplate"
 )
 
@@ -22,7 +21,7 @@ func (tk *TemplateKeyword) Context() *wtemplate.TemplateContext {
 	ctxMap := *tk.results.context
 	ctx, ok := ctxMap[tk.Key]
 	if !ok {
-		logrus.Fatalf("template context not found %q", tk.Key)
+		failf("template context not found %q", tk.Key)
 	}
 	return ctx
 }
@@ -32,7 +31,7 @@ func (tk *TemplateKeyword) Result() []*wtemplate.SearchValue {
 	resultsMap := *tk.results.results
 	result, ok := resultsMap[tk.Key]
 	if !ok {
-		logrus.Fatalf("template result not found %q", tk.Key)
+		failf("template result not found %q", tk.Key)
 	}
 	return result
 }
@@ -93,4 +92,3 @@ func (t *TemplateResults) Get(k string) (interface{}, KeywordType, bool) {
 	}
 	return NewTemplateKeyword(k, result[0].Iter[0].Found, t), KeywordTypeTemplate, true
 }
-
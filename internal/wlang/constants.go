@@ -71,6 +71,12 @@ const (
 	CodeBlockNameI64Eqz    = "i64.eqz"
 
 	// Floating-Point Arithmetic Instructions
+	CodeBlockNameF32Add      = "f32.add"
+	CodeBlockNameF64Add      = "f64.add"
+	CodeBlockNameF32Sub      = "f32.sub"
+	CodeBlockNameF64Sub      = "f64.sub"
+	CodeBlockNameF32Mul      = "f32.mul"
+	CodeBlockNameF64Mul      = "f64.mul"
 	CodeBlockNameF32Div      = "f32.div"
 	CodeBlockNameF64Div      = "f64.div"
 	CodeBlockNameF32Sqrt     = "f32.sqrt"
@@ -117,6 +123,10 @@ const (
 	CodeBlockNameI64GeU = "i64.ge_u"
 
 	// Floating-Point Comparison Instructions
+	CodeBlockNameF32Eq = "f32.eq"
+	CodeBlockNameF64Eq = "f64.eq"
+	CodeBlockNameF32Ne = "f32.ne"
+	CodeBlockNameF64Ne = "f64.ne"
 	CodeBlockNameF32Lt = "f32.lt"
 	CodeBlockNameF64Lt = "f64.lt"
 	CodeBlockNameF32Le = "f32.le"
@@ -186,16 +196,31 @@ const (
 	// Additional Memory-Related Instructions
 	CodeBlockNameGrowMemory    = "memory.grow"
 	CodeBlockNameCurrentMemory = "memory.size"
+
+	// Reference Instructions
+	CodeBlockNameRefNull   = "ref.null"
+	CodeBlockNameRefIsNull = "ref.is_null"
+	CodeBlockNameRefFunc   = "ref.func"
+
+	// Table Instructions
+	CodeBlockNameTableGet  = "table.get"
+	CodeBlockNameTableSet  = "table.set"
+	CodeBlockNameTableSize = "table.size"
+	CodeBlockNameTableGrow = "table.grow"
+	CodeBlockNameTableFill = "table.fill"
 )
 
 type CodeBlockType string
 
 const (
-	I32 CodeBlockType = "i32"
-	I64 CodeBlockType = "i64"
-	F32 CodeBlockType = "f32"
-	F64 CodeBlockType = "f64"
-	Any CodeBlockType = "any"
+	I32       CodeBlockType = "i32"
+	I64       CodeBlockType = "i64"
+	F32       CodeBlockType = "f32"
+	F64       CodeBlockType = "f64"
+	V128      CodeBlockType = "v128"
+	Funcref   CodeBlockType = "funcref"
+	Externref CodeBlockType = "externref"
+	Any       CodeBlockType = "any"
 )
 
 var (
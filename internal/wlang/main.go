@@ -105,6 +105,12 @@ func init() {
 	addCodeBlockDefinition(CodeBlockNameI64Eqz, list(I64), list(I64))
 
 	// Floating-Point Arithmetic Instructions
+	addCodeBlockDefinition(CodeBlockNameF32Add, list(F32, F32), list(F32))
+	addCodeBlockDefinition(CodeBlockNameF64Add, list(F64, F64), list(F64))
+	addCodeBlockDefinition(CodeBlockNameF32Sub, list(F32, F32), list(F32))
+	addCodeBlockDefinition(CodeBlockNameF64Sub, list(F64, F64), list(F64))
+	addCodeBlockDefinition(CodeBlockNameF32Mul, list(F32, F32), list(F32))
+	addCodeBlockDefinition(CodeBlockNameF64Mul, list(F64, F64), list(F64))
 	addCodeBlockDefinition(CodeBlockNameF32Div, list(F32, F32), list(F32))
 	addCodeBlockDefinition(CodeBlockNameF64Div, list(F64, F64), list(F64))
 	addCodeBlockDefinition(CodeBlockNameF32Sqrt, list(F32), list(F32))
@@ -151,6 +157,10 @@ func init() {
 	addCodeBlockDefinition(CodeBlockNameI64GeU, list(I64, I64), list(I32))
 
 	// Floating-Point Comparison Instructions
+	addCodeBlockDefinition(CodeBlockNameF32Eq, list(F32, F32), list(I32))
+	addCodeBlockDefinition(CodeBlockNameF64Eq, list(F64, F64), list(I32))
+	addCodeBlockDefinition(CodeBlockNameF32Ne, list(F32, F32), list(I32))
+	addCodeBlockDefinition(CodeBlockNameF64Ne, list(F64, F64), list(I32))
 	addCodeBlockDefinition(CodeBlockNameF32Lt, list(F32, F32), list(I32))
 	addCodeBlockDefinition(CodeBlockNameF64Lt, list(F64, F64), list(I32))
 	addCodeBlockDefinition(CodeBlockNameF32Le, list(F32, F32), list(I32))
@@ -207,6 +217,10 @@ func init() {
 	addCodeBlockDefinition(CodeBlockNameLoadI64I8U, list(Any), list(I64))
 	addCodeBlockDefinition(CodeBlockNameLoadI64I16U, list(Any), list(I64))
 	addCodeBlockDefinition(CodeBlockNameLoadI64I32U, list(Any), list(I64))
+	addCodeBlockDefinition(CodeBlockNameI32Store, list(Any, I32), list())
+	addCodeBlockDefinition(CodeBlockNameI64Store, list(Any, I64), list())
+	addCodeBlockDefinition(CodeBlockNameF32Store, list(Any, F32), list())
+	addCodeBlockDefinition(CodeBlockNameF64Store, list(Any, F64), list())
 	addCodeBlockDefinition(CodeBlockNameStoreI32I8, list(Any, I32), list())
 	addCodeBlockDefinition(CodeBlockNameStoreI32I16, list(Any, I32), list())
 	addCodeBlockDefinition(CodeBlockNameStoreI64I8, list(Any, I64), list())
@@ -214,8 +228,24 @@ func init() {
 	addCodeBlockDefinition(CodeBlockNameStoreI64I32, list(Any, I64), list())
 
 	// Additional Memory-Related Instructions
-	addCodeBlockDefinition(CodeBlockNameCurrentMemory, list(I32), list(I32))
-	addCodeBlockDefinition(CodeBlockNameGrowMemory, list(), list(I32))
+	addCodeBlockDefinition(CodeBlockNameCurrentMemory, list(), list(I32))
+	addCodeBlockDefinition(CodeBlockNameGrowMemory, list(I32), list(I32))
+
+	// Reference Instructions
+	addCodeBlockDefinition(CodeBlockNameRefNull, list(), list(Any))
+	addCodeBlockDefinition(CodeBlockNameRefIsNull, list(Any), list(I32))
+	addCodeBlockDefinition(CodeBlockNameRefFunc, list(), list(Funcref))
+
+	// Table Instructions
+	// the table index is an optional immediate, so the arguments types are not fixed.
+	addCodeBlockDefinition(CodeBlockNameTableGet, list(Any), list(Any))
+	addCodeBlockDefinition(CodeBlockNameTableSet, list(Any, Any), list())
+	addCodeBlockDefinition(CodeBlockNameTableSize, list(), list(I32))
+	addCodeBlockDefinition(CodeBlockNameTableGrow, list(Any, Any), list(I32))
+	addCodeBlockDefinition(CodeBlockNameTableFill, list(Any, Any, Any), list())
+
+	// Vector Instructions
+	addSimdDefinitions()
 }
 
 // addCodeBlockDefinition adds a code definitions to the package level map.
@@ -0,0 +1,115 @@
+package wlang
+
+import "fmt"
+
+const (
+	// Vector Instructions
+	CodeBlockNameV128Const     = "v128.const"
+	CodeBlockNameV128Load      = "v128.load"
+	CodeBlockNameV128Store     = "v128.store"
+	CodeBlockNameV128Not       = "v128.not"
+	CodeBlockNameV128And       = "v128.and"
+	CodeBlockNameV128AndNot    = "v128.andnot"
+	CodeBlockNameV128Or        = "v128.or"
+	CodeBlockNameV128Xor       = "v128.xor"
+	CodeBlockNameV128Bitselect = "v128.bitselect"
+	CodeBlockNameV128AnyTrue   = "v128.any_true"
+	CodeBlockNameI8x16Shuffle  = "i8x16.shuffle"
+	CodeBlockNameI8x16Swizzle  = "i8x16.swizzle"
+)
+
+// simdShape contains the definition data for some vector shape.
+type simdShape struct {
+	name    string
+	lane    CodeBlockType
+	integer bool
+}
+
+// simdShapes are the vector shapes with the scalar type of their lanes.
+var simdShapes = []simdShape{
+	{"i8x16", I32, true},
+	{"i16x8", I32, true},
+	{"i32x4", I32, true},
+	{"i64x2", I64, true},
+	{"f32x4", F32, false},
+	{"f64x2", F64, false},
+}
+
+// addSimdDefinitions adds the vector instructions definitions.
+// the lane indices and memory offsets are immediates, so they are not part of the arguments.
+func addSimdDefinitions() {
+	addCodeBlockDefinition(CodeBlockNameV128Const, list(), list(V128))
+	addCodeBlockDefinition(CodeBlockNameV128Load, list(Any), list(V128))
+	addCodeBlockDefinition(CodeBlockNameV128Store, list(Any, V128), list())
+	addCodeBlockDefinition(CodeBlockNameV128Not, list(V128), list(V128))
+	addCodeBlockDefinition(CodeBlockNameV128And, list(V128, V128), list(V128))
+	addCodeBlockDefinition(CodeBlockNameV128AndNot, list(V128, V128), list(V128))
+	addCodeBlockDefinition(CodeBlockNameV128Or, list(V128, V128), list(V128))
+	addCodeBlockDefinition(CodeBlockNameV128Xor, list(V128, V128), list(V128))
+	addCodeBlockDefinition(CodeBlockNameV128Bitselect, list(V128, V128, V128), list(V128))
+	addCodeBlockDefinition(CodeBlockNameV128AnyTrue, list(V128), list(I32))
+	addCodeBlockDefinition(CodeBlockNameI8x16Shuffle, list(V128, V128), list(V128))
+	addCodeBlockDefinition(CodeBlockNameI8x16Swizzle, list(V128, V128), list(V128))
+	for _, load := range []string{"8x8_s", "8x8_u", "16x4_s", "16x4_u", "32x2_s", "32x2_u", "8_splat", "16_splat", "32_splat", "64_splat", "32_zero", "64_zero"} {
+		addCodeBlockDefinition(fmt.Sprintf("%s%s", CodeBlockNameV128Load, load), list(Any), list(V128))
+	}
+
+	for _, shape := range simdShapes {
+		unary := []string{"neg", "abs"}
+		binary := []string{"add", "sub", "eq", "ne"}
+		if shape.integer {
+			if shape.name != "i8x16" && shape.name != "i16x8" {
+				binary = append(binary, "mul")
+			} else {
+				binary = append(binary, "add_sat_s", "add_sat_u", "sub_sat_s", "sub_sat_u", "avgr_u")
+				if shape.name == "i16x8" {
+					binary = append(binary, "mul")
+				}
+			}
+			if shape.name != "i64x2" {
+				binary = append(binary, "lt_s", "lt_u", "gt_s", "gt_u", "le_s", "le_u", "ge_s", "ge_u", "min_s", "min_u", "max_s", "max_u")
+			} else {
+				binary = append(binary, "lt_s", "gt_s", "le_s", "ge_s")
+			}
+			for _, op := range []string{"shl", "shr_s", "shr_u"} {
+				addCodeBlockDefinition(fmt.Sprintf("%s.%s", shape.name, op), list(V128, I32), list(V128))
+			}
+			for _, op := range []string{"all_true", "bitmask"} {
+				addCodeBlockDefinition(fmt.Sprintf("%s.%s", shape.name, op), list(V128), list(I32))
+			}
+		} else {
+			unary = append(unary, "sqrt", "ceil", "floor", "trunc", "nearest")
+			binary = append(binary, "mul", "div", "min", "max", "pmin", "pmax", "lt", "gt", "le", "ge")
+		}
+		for _, op := range unary {
+			addCodeBlockDefinition(fmt.Sprintf("%s.%s", shape.name, op), list(V128), list(V128))
+		}
+		for _, op := range binary {
+			addCodeBlockDefinition(fmt.Sprintf("%s.%s", shape.name, op), list(V128, V128), list(V128))
+		}
+
+		// Lane instructions.
+		addCodeBlockDefinition(fmt.Sprintf("%s.splat", shape.name), list(shape.lane), list(V128))
+		addCodeBlockDefinition(fmt.Sprintf("%s.replace_lane", shape.name), list(V128, shape.lane), list(V128))
+		if shape.name == "i8x16" || shape.name == "i16x8" {
+			addCodeBlockDefinition(fmt.Sprintf("%s.extract_lane_s", shape.name), list(V128), list(shape.lane))
+			addCodeBlockDefinition(fmt.Sprintf("%s.extract_lane_u", shape.name), list(V128), list(shape.lane))
+		} else {
+			addCodeBlockDefinition(fmt.Sprintf("%s.extract_lane", shape.name), list(V128), list(shape.lane))
+		}
+	}
+
+	// Conversion instructions.
+	for _, op := range []string{
+		"i8x16.narrow_i16x8_s", "i8x16.narrow_i16x8_u", "i16x8.narrow_i32x4_s", "i16x8.narrow_i32x4_u",
+		"i16x8.extend_low_i8x16_s", "i16x8.extend_high_i8x16_s", "i16x8.extend_low_i8x16_u", "i16x8.extend_high_i8x16_u",
+		"i32x4.extend_low_i16x8_s", "i32x4.extend_high_i16x8_s", "i32x4.extend_low_i16x8_u", "i32x4.extend_high_i16x8_u",
+		"i64x2.extend_low_i32x4_s", "i64x2.extend_high_i32x4_s", "i64x2.extend_low_i32x4_u", "i64x2.extend_high_i32x4_u",
+		"i32x4.trunc_sat_f32x4_s", "i32x4.trunc_sat_f32x4_u", "i32x4.trunc_sat_f64x2_s_zero", "i32x4.trunc_sat_f64x2_u_zero",
+		"f32x4.convert_i32x4_s", "f32x4.convert_i32x4_u", "f64x2.convert_low_i32x4_s", "f64x2.convert_low_i32x4_u",
+		"f32x4.demote_f64x2_zero", "f64x2.promote_low_f32x4", "i8x16.popcnt",
+	} {
+		addCodeBlockDefinition(op, list(V128), list(V128))
+	}
+	addCodeBlockDefinition("i32x4.dot_i16x8_s", list(V128, V128), list(V128))
+}
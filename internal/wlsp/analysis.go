@@ -0,0 +1,462 @@
+package wlsp
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"joao/wasm-manipulator/internal/waspect"
+	"joao/wasm-manipulator/internal/wcode"
+	"joao/wasm-manipulator/internal/wparser/lex"
+	"joao/wasm-manipulator/internal/wyaml"
+)
+
+// diagnosticSource is the source of the diagnostics published by the server.
+const diagnosticSource = "wmr"
+
+var (
+	// keyLineRegex matches the lines with a yaml or json key, capturing the indentation and the key.
+	keyLineRegex = regexp.MustCompile(`^(\s*(?:-\s+)?)"?([\w$.-]+)"?\s*:`)
+	// methodPrefixRegex matches a static expression ending on a transformation method name.
+	methodPrefixRegex = regexp.MustCompile(`:\s*\w*$`)
+	// fieldPrefixRegex matches a static expression ending on a keyword field, capturing the keyword path.
+	fieldPrefixRegex = regexp.MustCompile(`([a-zA-Z_][\w.]*)\.\w*$`)
+	// functionNamePrefixRegex matches a pointcut ending on the function name of the func or call pointcuts.
+	functionNamePrefixRegex = regexp.MustCompile(`\b(func|call)\(\s*(\*|void|\w+|\(.*?\)|%[^%]*%)\s+\$?\w*$`)
+	// templateNamePrefixRegex matches a pointcut ending on the template name of the template pointcut.
+	templateNamePrefixRegex = regexp.MustCompile(`\btemplate\(\s*\w*$`)
+	// wordRegex matches the words that can be hovered.
+	wordRegex = regexp.MustCompile(`[\w$.]+`)
+)
+
+// pointcutKeywords contains the data models of the keywords added by the pointcuts to the advice context.
+var pointcutKeywords = map[string]reflect.Type{
+	"func":    reflect.TypeOf(wcode.FuncData{}),
+	"call":    reflect.TypeOf(wcode.CallData{}),
+	"args":    reflect.TypeOf(wcode.ArgsData{}),
+	"returns": reflect.TypeOf(wcode.ReturnsData{}),
+}
+
+// pointcutSyntax contains the syntax of the pointcut functions.
+var pointcutSyntax = map[string]string{
+	"func":     "func(@return @function(@parameters?)<, @scope>?)",
+	"call":     "call(@return @function(@parameters?))",
+	"args":     "args(<@argument <, @argument>*>?)",
+	"returns":  "returns(@type)",
+	"template": "template(@template <, @validation>?)",
+}
+
+// fieldDescriptions contains the descriptions of the data model fields, by model and field name.
+var fieldDescriptions = map[string]string{
+	"FuncData.Index":        "Name of the function's index.",
+	"FuncData.Order":        "Order of the function's index.",
+	"FuncData.Name":         "If exported, consists of the exported name of the function. Otherwise, it is equal to the index name.",
+	"FuncData.Params":       "List of the names of the parameter indices.",
+	"FuncData.ParamTypes":   "List of the types of the parameters.",
+	"FuncData.TotalParams":  "Total number of parameters.",
+	"FuncData.Locals":       "List of the names of the local variable indices.",
+	"FuncData.LocalTypes":   "List of the types of the local variables.",
+	"FuncData.TotalLocals":  "Total number of local variables.",
+	"FuncData.ResultType":   "Type of the function's result. Multi-value results are separated by spaces.",
+	"FuncData.ResultTypes":  "List of the types of the function's results.",
+	"FuncData.TotalResults": "Total number of results.",
+	"FuncData.Code":         "Instructions of the function in textual format.",
+	"FuncData.IsImported":   "Whether the function is imported.",
+	"FuncData.IsExported":   "Whether the function is exported.",
+	"FuncData.IsStart":      "Whether the function is initially executed.",
+	"CallData.Callee":       "Data of the invoked function.",
+	"CallData.Caller":       "Data of the function that invoked.",
+	"CallData.Args":         "List with information about the arguments.",
+	"CallData.TotalArgs":    "Total number of arguments.",
+	"ArgsData.Callee":       "Data of the invoked function.",
+	"ArgsData.Caller":       "Data of the function that invoked.",
+	"ArgsData.Args":         "List with information about the arguments.",
+	"ArgsData.TotalArgs":    "Total number of arguments.",
+	"ArgData.Type":          "Type of the argument.",
+	"ArgData.Order":         "Order of the argument in the call.",
+	"ArgData.Instr":         "WAT code of the argument.",
+	"ReturnsData.Func":      "Data of the function that contains the return instruction.",
+	"ReturnsData.Type":      "Type of the return instruction. Multi-value results are separated by spaces.",
+	"ReturnsData.Types":     "List of the types of the return instruction.",
+	"ReturnsData.Instr":     "WAT code of the return instruction.",
+}
+
+// document is a transformation file opened by the client.
+type document struct {
+	uri   string
+	text  string
+	lines []string
+	// transformation is the last valid transformation of the document, used by the completion.
+	transformation *wyaml.BaseYAML
+}
+
+// newDocument is the constructor for document.
+func newDocument(uri string) *document {
+	return &document{uri: uri}
+}
+
+// update replaces the document content, returning its diagnostics.
+func (d *document) update(text string) []Diagnostic {
+	d.text = text
+	d.lines = strings.Split(text, "\n")
+	transformation, source, issues, err := wyaml.ParseSource(strings.TrimPrefix(d.uri, "file://"), text)
+	if err != nil {
+		return []Diagnostic{d.diagnostic(&wyaml.Issue{Position: wyaml.Position{Line: 1, Column: 1}, Severity: wyaml.SeverityError, Message: err.Error()})}
+	}
+	if transformation != nil {
+		d.transformation = transformation
+		issues = append(issues, waspect.Check(transformation, source)...)
+	}
+	diagnostics := make([]Diagnostic, 0, len(issues))
+	for _, issue := range issues {
+		diagnostics = append(diagnostics, d.diagnostic(issue))
+	}
+	return diagnostics
+}
+
+// diagnostic returns the diagnostic of some issue, ranging to the end of its line.
+func (d *document) diagnostic(issue *wyaml.Issue) Diagnostic {
+	start := Position{Line: issue.Line - 1, Character: issue.Column - 1}
+	end := start
+	if start.Line >= 0 && start.Line < len(d.lines) {
+		end.Character = len(strings.TrimRight(d.lines[start.Line], " \t\r"))
+	}
+	severity := diagnosticSeverityError
+	if issue.Severity == wyaml.SeverityWarning {
+		severity = diagnosticSeverityWarning
+	}
+	return Diagnostic{Range: Range{Start: start, End: end}, Severity: severity, Source: diagnosticSource, Message: issue.Message}
+}
+
+// prefix returns the text of the line before some position.
+func (d *document) prefix(pos Position) string {
+	if pos.Line < 0 || pos.Line >= len(d.lines) {
+		return ""
+	}
+	line := d.lines[pos.Line]
+	if pos.Character > len(line) {
+		return line
+	}
+	return line[:pos.Character]
+}
+
+// keyPath returns the path of keys of the value on some position.
+// the path is found by the indentation of the lines, being resilient to the documents with invalid syntax.
+func (d *document) keyPath(pos Position) []string {
+	var path []string
+	prefix := d.prefix(pos)
+	indent := len(prefix) - len(strings.TrimLeft(prefix, " \t"))
+	if match := keyLineRegex.FindStringSubmatch(prefix); match != nil {
+		path = append(path, match[2])
+		indent = len(match[1])
+	}
+	for i := pos.Line - 1; i >= 0 && indent > 0; i-- {
+		line := d.lines[i]
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		match := keyLineRegex.FindStringSubmatch(line)
+		if match == nil || len(match[1]) >= indent {
+			continue
+		}
+		path = append([]string{match[2]}, path...)
+		indent = len(match[1])
+	}
+	return path
+}
+
+// isPointcutPath returns if some key path refers to a pointcut.
+func isPointcutPath(path []string) bool {
+	switch {
+	case len(path) == 0:
+		return false
+	case path[len(path)-1] == "pointcut":
+		return true
+	default:
+		return len(path) == 2 && path[0] == "pointcuts"
+	}
+}
+
+// isCodePath returns if some key path refers to code with static expressions.
+func isCodePath(path []string) bool {
+	if len(path) == 0 {
+		return false
+	}
+	switch path[len(path)-1] {
+	case "advice", "code", "start":
+		return true
+	}
+	return false
+}
+
+// expressionPrefix returns the static expression before some position, if the position is inside one.
+func expressionPrefix(prefix string) (string, bool) {
+	if strings.Count(prefix, "%")%2 == 0 {
+		return "", false
+	}
+	return prefix[strings.LastIndex(prefix, "%")+1:], true
+}
+
+// complete returns the completion items for some position.
+func (d *document) complete(pos Position, module *wcode.ModuleInfo) []CompletionItem {
+	path := d.keyPath(pos)
+	prefix := d.prefix(pos)
+	if i := strings.Index(prefix, ":"); len(path) > 0 && i >= 0 && keyLineRegex.MatchString(prefix) {
+		prefix = prefix[i+1:]
+	}
+	switch {
+	case isPointcutPath(path):
+		return d.completePointcut(prefix, module)
+	case isCodePath(path):
+		expr, ok := expressionPrefix(prefix)
+		if !ok {
+			return nil
+		}
+		return d.completeExpression(path, expr)
+	}
+	return nil
+}
+
+// completePointcut returns the completion items for a pointcut.
+func (d *document) completePointcut(prefix string, module *wcode.ModuleInfo) []CompletionItem {
+	var res []CompletionItem
+	switch {
+	case functionNamePrefixRegex.MatchString(prefix):
+		if module == nil {
+			return nil
+		}
+		for _, fn := range module.Functions {
+			detail := fmt.Sprintf("(%s) -> (%s)", strings.Join(fn.ParamTypes, " "), strings.Join(fn.ResultTypes, " "))
+			res = append(res, CompletionItem{Label: fn.Name, Kind: completionKindFunction, Detail: detail})
+			if fn.Index != "" && fn.Index != "$"+fn.Name {
+				res = append(res, CompletionItem{Label: fn.Index, Kind: completionKindFunction, Detail: detail})
+			}
+		}
+	case templateNamePrefixRegex.MatchString(prefix):
+		if d.transformation == nil {
+			return nil
+		}
+		for _, name := range sortedKeys(d.transformation.Templates) {
+			res = append(res, CompletionItem{Label: name, Kind: completionKindVariable, Detail: "template"})
+		}
+	default:
+		for _, name := range sortedKeys(pointcutSyntax) {
+			res = append(res, CompletionItem{Label: name, Kind: completionKindKeyword, Detail: pointcutSyntax[name]})
+		}
+		if d.transformation != nil {
+			for _, name := range sortedKeys(d.transformation.Pointcuts) {
+				res = append(res, CompletionItem{Label: name, Kind: completionKindFunction, Detail: d.transformation.Pointcuts[name]})
+			}
+		}
+	}
+	return res
+}
+
+// completeExpression returns the completion items for a static expression.
+func (d *document) completeExpression(path []string, expr string) []CompletionItem {
+	var res []CompletionItem
+	switch {
+	case methodPrefixRegex.MatchString(expr):
+		for _, name := range lex.MethodNames() {
+			res = append(res, CompletionItem{Label: name, Kind: completionKindMethod, Detail: "transformation method"})
+		}
+	case fieldPrefixRegex.MatchString(expr):
+		typ, ok := resolveKeyword(strings.Split(fieldPrefixRegex.FindStringSubmatch(expr)[1], "."))
+		if !ok || typ.Kind() != reflect.Struct {
+			return nil
+		}
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			res = append(res, CompletionItem{Label: field.Name, Kind: completionKindField, Detail: typeName(field.Type)})
+		}
+	default:
+		res = append(res, CompletionItem{Label: "this", Kind: completionKindKeyword, Detail: "join-point code"})
+		for _, name := range sortedKeys(pointcutKeywords) {
+			res = append(res, CompletionItem{Label: name, Kind: completionKindKeyword, Detail: typeName(pointcutKeywords[name])})
+		}
+		for _, name := range d.scope(path) {
+			res = append(res, CompletionItem{Label: name, Kind: completionKindVariable})
+		}
+	}
+	return res
+}
+
+// scope returns the names of the context declarations available on the code with some key path.
+func (d *document) scope(path []string) []string {
+	if d.transformation == nil {
+		return nil
+	}
+	context := d.transformation.Aspects.Context
+	names := make(map[string]bool)
+	for _, m := range []interface{}{
+		context.Variables, context.Functions, context.Memories, context.Tables,
+		context.Clones, context.Imports, context.Data, context.Elems,
+	} {
+		for _, name := range sortedKeys(m) {
+			names[name] = true
+		}
+	}
+	if len(path) > 2 && path[0] == "aspects" && path[1] == "advices" {
+		for name := range d.transformation.Aspects.Advices[path[2]].Variables {
+			names[name] = true
+		}
+	}
+	if len(path) > 3 && path[1] == "context" && path[2] == "functions" {
+		function := context.Functions[path[3]]
+		for name := range function.Variables {
+			names[name] = true
+		}
+		for _, arg := range function.Args {
+			names[arg.Name] = true
+		}
+	}
+	return sortedKeys(names)
+}
+
+// hover returns the hover information for some position, or nil if there is none.
+func (d *document) hover(pos Position) *Hover {
+	if pos.Line < 0 || pos.Line >= len(d.lines) {
+		return nil
+	}
+	line := d.lines[pos.Line]
+	var word string
+	var wordRange Range
+	for _, loc := range wordRegex.FindAllStringIndex(line, -1) {
+		if loc[0] <= pos.Character && pos.Character <= loc[1] {
+			// Only the path until the hovered segment is used, ex. the hover on func of func.Name refers to func.
+			end := loc[1]
+			if i := strings.Index(line[pos.Character:loc[1]], "."); i >= 0 {
+				end = pos.Character + i
+			}
+			word = line[loc[0]:end]
+			wordRange = Range{Start: Position{Line: pos.Line, Character: loc[0]}, End: Position{Line: pos.Line, Character: end}}
+			break
+		}
+	}
+	if word == "" {
+		return nil
+	}
+
+	var value string
+	path := d.keyPath(pos)
+	switch {
+	case isPointcutPath(path):
+		value = d.pointcutHover(word)
+	case isCodePath(path):
+		if _, ok := expressionPrefix(line[:wordRange.Start.Character]); ok {
+			value = keywordHover(strings.Split(word, "."))
+		}
+	}
+	if value == "" {
+		return nil
+	}
+	return &Hover{Contents: markupContent{Kind: "markdown", Value: value}, Range: &wordRange}
+}
+
+// pointcutHover returns the hover information of some word on a pointcut.
+func (d *document) pointcutHover(word string) string {
+	if syntax, ok := pointcutSyntax[word]; ok {
+		res := fmt.Sprintf("```\n%s\n```", syntax)
+		if typ, ok := pointcutKeywords[word]; ok {
+			res += fmt.Sprintf("\n\nAdds `%s` (%s) to the advice context.\n\n%s", word, typeName(typ), fieldsTable(typ))
+		}
+		return res
+	}
+	if d.transformation != nil {
+		if pc, ok := d.transformation.Pointcuts[word]; ok {
+			return fmt.Sprintf("pointcut `%s`\n```\n%s\n```", word, pc)
+		}
+		if templ, ok := d.transformation.Templates[word]; ok {
+			return fmt.Sprintf("template `%s`\n```\n%s\n```", word, templ)
+		}
+	}
+	return ""
+}
+
+// keywordHover returns the hover information of some keyword path on a static expression.
+func keywordHover(path []string) string {
+	typ, ok := resolveKeyword(path)
+	if !ok {
+		return ""
+	}
+	name := path[len(path)-1]
+	res := fmt.Sprintf("`%s` %s", name, typeName(typ))
+	if len(path) > 1 {
+		parent, _ := resolveKeyword(path[:len(path)-1])
+		if description, ok := fieldDescriptions[parent.Name()+"."+name]; ok {
+			res += "\n\n" + description
+		}
+	}
+	if typ.Kind() == reflect.Struct {
+		res += "\n\n" + fieldsTable(typ)
+	}
+	return res
+}
+
+// resolveKeyword returns the data model of some keyword path, ex. call.Callee is FuncData.
+// the pointers and slices are resolved to their elements.
+func resolveKeyword(path []string) (reflect.Type, bool) {
+	typ, ok := pointcutKeywords[path[0]]
+	if !ok {
+		return nil, false
+	}
+	for _, name := range path[1:] {
+		for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice {
+			typ = typ.Elem()
+		}
+		if typ.Kind() != reflect.Struct {
+			return nil, false
+		}
+		field, ok := typ.FieldByName(name)
+		if !ok {
+			return nil, false
+		}
+		typ = field.Type
+	}
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ, true
+}
+
+// fieldsTable returns the fields of some data model as a markdown table.
+func fieldsTable(typ reflect.Type) string {
+	var sb strings.Builder
+	sb.WriteString("|Name|Type|Description|\n|-|-|-|\n")
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		fmt.Fprintf(&sb, "|%s|%s|%s|\n", field.Name, typeName(field.Type), fieldDescriptions[typ.Name()+"."+field.Name])
+	}
+	return sb.String()
+}
+
+// typeName returns the name of some data model type, as on the language documentation.
+func typeName(typ reflect.Type) string {
+	switch typ.Kind() {
+	case reflect.Ptr:
+		return typeName(typ.Elem())
+	case reflect.Slice:
+		return fmt.Sprintf("Array<%s>", typeName(typ.Elem()))
+	case reflect.Struct:
+		return strings.TrimSuffix(typ.Name(), "Data")
+	case reflect.Int:
+		return "i32"
+	case reflect.Bool:
+		return "boolean"
+	default:
+		return typ.Kind().String()
+	}
+}
+
+// sortedKeys returns the keys of some map with string keys, sorted.
+func sortedKeys(m interface{}) []string {
+	keys := reflect.ValueOf(m).MapKeys()
+	res := make([]string, len(keys))
+	for i, k := range keys {
+		res[i] = k.String()
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,202 @@
+package wlsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// JSON-RPC error codes used by the server.
+const (
+	errorCodeParse          = -32700
+	errorCodeMethodNotFound = -32601
+	errorCodeInvalidParams  = -32602
+)
+
+// Diagnostic severities of the protocol.
+const (
+	diagnosticSeverityError   = 1
+	diagnosticSeverityWarning = 2
+)
+
+// Completion item kinds of the protocol.
+const (
+	completionKindMethod   = 2
+	completionKindFunction = 3
+	completionKindField    = 5
+	completionKindVariable = 6
+	completionKindKeyword  = 14
+)
+
+// textDocumentSyncFull is the synchronization kind where the clients send the full content of the documents.
+const textDocumentSyncFull = 1
+
+// message is a JSON-RPC request or notification sent by the client.
+// the notifications have no id.
+type message struct {
+	ID     *json.RawMessage `json:"id"`
+	Method string           `json:"method"`
+	Params json.RawMessage  `json:"params"`
+}
+
+// responseError is the error of a JSON-RPC response.
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Position is a zero-based position on a text document.
+// the character is counted in bytes, the documents are expected to be ascii.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a range on a text document.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Diagnostic is a problem found on a text document.
+type Diagnostic struct {
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity"`
+	Source   string `json:"source"`
+	Message  string `json:"message"`
+}
+
+// CompletionItem is a completion proposal.
+type CompletionItem struct {
+	Label  string `json:"label"`
+	Kind   int    `json:"kind,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Hover is the information shown when hovering some symbol.
+type Hover struct {
+	Contents markupContent `json:"contents"`
+	Range    *Range        `json:"range,omitempty"`
+}
+
+// markupContent is a markdown content.
+type markupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// textDocumentItem is a text document sent by the client.
+type textDocumentItem struct {
+	URI  string `json:"uri"`
+	Text string `json:"text"`
+}
+
+// textDocumentIdentifier identifies a text document.
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+// didOpenParams are the params of the textDocument/didOpen notification.
+type didOpenParams struct {
+	TextDocument textDocumentItem `json:"textDocument"`
+}
+
+// didChangeParams are the params of the textDocument/didChange notification.
+type didChangeParams struct {
+	TextDocument   textDocumentIdentifier `json:"textDocument"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+// didCloseParams are the params of the textDocument/didClose notification.
+type didCloseParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+// textDocumentPositionParams are the params of the requests on some document position.
+type textDocumentPositionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+// publishDiagnosticsParams are the params of the textDocument/publishDiagnostics notification.
+type publishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// conn reads and writes the JSON-RPC messages, using the base protocol framing (Content-Length header).
+type conn struct {
+	reader *textproto.Reader
+	mutex  *sync.Mutex
+	writer io.Writer
+}
+
+// newConn is the constructor for conn.
+func newConn(r io.Reader, w io.Writer) *conn {
+	return &conn{
+		reader: textproto.NewReader(bufio.NewReader(r)),
+		mutex:  &sync.Mutex{},
+		writer: w,
+	}
+}
+
+// read reads the next message.
+func (c *conn) read() (*message, error) {
+	header, err := c.reader.ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid content length: %w", err)
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(c.reader.R, content); err != nil {
+		return nil, fmt.Errorf("reading message content: %w", err)
+	}
+	var msg message
+	if err := json.Unmarshal(content, &msg); err != nil {
+		return nil, &responseError{Code: errorCodeParse, Message: err.Error()}
+	}
+	return &msg, nil
+}
+
+// reply writes the response with the result of some request.
+func (c *conn) reply(id *json.RawMessage, result interface{}) error {
+	return c.write(map[string]interface{}{"jsonrpc": "2.0", "id": id, "result": result})
+}
+
+// replyError writes the response with the error of some request.
+func (c *conn) replyError(id *json.RawMessage, err *responseError) error {
+	return c.write(map[string]interface{}{"jsonrpc": "2.0", "id": id, "error": err})
+}
+
+// notify writes a notification.
+func (c *conn) notify(method string, params interface{}) error {
+	return c.write(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+// write writes some message.
+func (c *conn) write(msg interface{}) error {
+	content, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("encoding message: %w", err)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, err := fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n%s", len(content), content); err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+	return nil
+}
+
+// Error returns the error message.
+func (e *responseError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,167 @@
+package wlsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/sirupsen/logrus"
+
+	"joao/wasm-manipulator/internal/wcode"
+)
+
+// Server is a language server for the transformation files, communicating with the client through JSON-RPC.
+// it publishes the diagnostics of the documents and answers to the completion and hover requests.
+type Server struct {
+	conn      *conn
+	documents map[string]*document
+	module    *wcode.ModuleInfo
+	shutdown  bool
+}
+
+// NewServer is the constructor for Server.
+// the module is optional, being used to complete the function names on the pointcuts.
+func NewServer(r io.Reader, w io.Writer, module *wcode.ModuleInfo) *Server {
+	return &Server{
+		conn:      newConn(r, w),
+		documents: make(map[string]*document),
+		module:    module,
+	}
+}
+
+// Run handles the client messages until the exit notification or the end of the input.
+func (s *Server) Run() error {
+	for {
+		msg, err := s.conn.read()
+		if err != nil {
+			var respErr *responseError
+			switch {
+			case errors.As(err, &respErr):
+				if err := s.conn.replyError(nil, respErr); err != nil {
+					return err
+				}
+				continue
+			case errors.Is(err, io.EOF):
+				return nil
+			default:
+				return fmt.Errorf("reading client message: %w", err)
+			}
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit without shutdown request")
+			}
+			return nil
+		}
+		result, respErr := s.handle(msg)
+		if msg.ID == nil {
+			if respErr != nil {
+				logrus.Warnf("handling notification %q: %s", msg.Method, respErr.Message)
+			}
+			continue
+		}
+		if respErr != nil {
+			err = s.conn.replyError(msg.ID, respErr)
+		} else {
+			err = s.conn.reply(msg.ID, result)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handle handles some request or notification, returning the result for the requests.
+func (s *Server) handle(msg *message) (interface{}, *responseError) {
+	switch msg.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync": textDocumentSyncFull,
+				"completionProvider": map[string]interface{}{
+					"triggerCharacters": []string{".", ":", "%", "(", " "},
+				},
+				"hoverProvider": true,
+			},
+			"serverInfo": map[string]string{"name": "wmr"},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		doc := newDocument(params.TextDocument.URI)
+		s.documents[doc.uri] = doc
+		s.publishDiagnostics(doc.uri, doc.update(params.TextDocument.Text))
+		return nil, nil
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		doc, ok := s.documents[params.TextDocument.URI]
+		if !ok || len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		// The full content is sent on each change (textDocumentSyncFull).
+		text := params.ContentChanges[len(params.ContentChanges)-1].Text
+		s.publishDiagnostics(doc.uri, doc.update(text))
+		return nil, nil
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		delete(s.documents, params.TextDocument.URI)
+		s.publishDiagnostics(params.TextDocument.URI, []Diagnostic{})
+		return nil, nil
+	case "textDocument/completion":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		doc, ok := s.documents[params.TextDocument.URI]
+		if !ok {
+			return []CompletionItem{}, nil
+		}
+		items := doc.complete(params.Position, s.module)
+		if items == nil {
+			items = []CompletionItem{}
+		}
+		return items, nil
+	case "textDocument/hover":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		doc, ok := s.documents[params.TextDocument.URI]
+		if !ok {
+			return nil, nil
+		}
+		if hover := doc.hover(params.Position); hover != nil {
+			return hover, nil
+		}
+		return nil, nil
+	case "initialized", "$/cancelRequest", "$/setTrace":
+		return nil, nil
+	default:
+		return nil, &responseError{Code: errorCodeMethodNotFound, Message: fmt.Sprintf("method %q not found", msg.Method)}
+	}
+}
+
+// publishDiagnostics sends the diagnostics of some document to the client.
+func (s *Server) publishDiagnostics(uri string, diagnostics []Diagnostic) {
+	err := s.conn.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: uri, Diagnostics: diagnostics})
+	if err != nil {
+		logrus.Errorf("publishing diagnostics: %v", err)
+	}
+}
+
+// invalidParams returns the response error for some params that could not be decoded.
+func invalidParams(err error) *responseError {
+	return &responseError{Code: errorCodeInvalidParams, Message: err.Error()}
+}
@@ -0,0 +1,138 @@
+package wlsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"joao/wasm-manipulator/internal/wcode"
+)
+
+const serverTestURI = "file:///tmp/transformation.yml"
+
+const serverTestInput = `templates:
+  t1: "{{x}}"
+pointcuts:
+  p1: (i32 x) => args(x)
+aspects:
+  advices:
+    a1:
+      pointcut: (i32.param[0] v) => func(* log (..)) && call(* * (..)) && p1(v)
+      advice: |
+        %func.Name% %call.Callee.Name% %func.Params:join(",")%
+    a2:
+      pointcut: () => template(t2)
+      advice: nop
+`
+
+func TestServer(t *testing.T) {
+	var in bytes.Buffer
+	requests := []struct {
+		id     int
+		method string
+		params interface{}
+	}{
+		{1, "initialize", map[string]interface{}{}},
+		{0, "textDocument/didOpen", map[string]interface{}{"textDocument": map[string]string{"uri": serverTestURI, "text": serverTestInput}}},
+		{2, "textDocument/completion", positionParams(9, 15)},
+		{3, "textDocument/completion", positionParams(9, 33)},
+		{4, "textDocument/completion", positionParams(9, 53)},
+		{5, "textDocument/completion", positionParams(7, 50)},
+		{6, "textDocument/completion", positionParams(7, 44)},
+		{7, "textDocument/hover", positionParams(9, 11)},
+		{8, "textDocument/hover", positionParams(9, 27)},
+		{9, "shutdown", nil},
+		{0, "exit", nil},
+	}
+	for _, req := range requests {
+		msg := map[string]interface{}{"jsonrpc": "2.0", "method": req.method, "params": req.params}
+		if req.id != 0 {
+			msg["id"] = req.id
+		}
+		content, _ := json.Marshal(msg)
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(content), content)
+	}
+
+	var out bytes.Buffer
+	module := &wcode.ModuleInfo{Functions: []*wcode.FunctionInfo{{Index: "$f0", Name: "main"}}}
+	if err := NewServer(&in, &out, module).Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	responses := make(map[int]json.RawMessage)
+	var diagnostics []Diagnostic
+	for _, part := range strings.Split(out.String(), "Content-Length: ")[1:] {
+		var msg struct {
+			ID     int
+			Method string
+			Params publishDiagnosticsParams
+			Result json.RawMessage
+		}
+		if err := json.Unmarshal([]byte(part[strings.Index(part, "{"):]), &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Method == "textDocument/publishDiagnostics" {
+			diagnostics = msg.Params.Diagnostics
+			continue
+		}
+		responses[msg.ID] = msg.Result
+	}
+
+	// Diagnostics.
+	if len(diagnostics) != 1 || diagnostics[0].Range.Start.Line != 11 || !strings.Contains(diagnostics[0].Message, `unknown template "t2"`) {
+		t.Errorf("unexpected diagnostics %+v", diagnostics)
+	}
+
+	// Completion.
+	labels := func(id int) string {
+		var items []CompletionItem
+		if err := json.Unmarshal(responses[id], &items); err != nil {
+			t.Fatal(err)
+		}
+		var res []string
+		for _, item := range items {
+			res = append(res, item.Label)
+		}
+		return strings.Join(res, ",")
+	}
+	if got := labels(2); !strings.HasPrefix(got, "Index,Order,Name,Params") {
+		t.Errorf("func fields: got %s", got)
+	}
+	if got := labels(3); !strings.HasPrefix(got, "Index,Order,Name,Params") {
+		t.Errorf("call.Callee fields: got %s", got)
+	}
+	if got := labels(4); !strings.Contains(got, "join") || !strings.Contains(got, "map") {
+		t.Errorf("methods: got %s", got)
+	}
+	if got := labels(5); got != "args,call,func,returns,template,p1" {
+		t.Errorf("pointcut functions: got %s", got)
+	}
+	if got := labels(6); got != "main,$f0" {
+		t.Errorf("module functions: got %s", got)
+	}
+
+	// Hover.
+	var hover Hover
+	if err := json.Unmarshal(responses[7], &hover); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hover.Contents.Value, "`func` Func") || !strings.Contains(hover.Contents.Value, "|IsExported|boolean|") {
+		t.Errorf("func hover: got %s", hover.Contents.Value)
+	}
+	if err := json.Unmarshal(responses[8], &hover); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hover.Contents.Value, "`Callee` Func\n\nData of the invoked function.") {
+		t.Errorf("call.Callee hover: got %s", hover.Contents.Value)
+	}
+}
+
+// positionParams returns the params of a request on some position of the test document.
+func positionParams(line, character int) map[string]interface{} {
+	return map[string]interface{}{
+		"textDocument": map[string]string{"uri": serverTestURI},
+		"position":     map[string]int{"line": line, "character": character},
+	}
+}
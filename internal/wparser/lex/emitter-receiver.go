@@ -6,8 +6,6 @@ import (
 	"strings"
 	"sync"
 
-	"github.com/sirupsen/logrus"
-
 	"joao/wasm-manipulator/internal/wkeyword"
 	"joao/wasm-manipulator/internal/wtemplate"
 	"joao/wasm-manipulator/pkg/wutils"
@@ -149,7 +147,7 @@ func (to *TextOnlyReceiver) VisitStringSlice(v []string) {
 func (to *TextOnlyReceiver) VisitSearch(v wtemplate.OutboundOperation) {
 	value, err := wtemplate.GetValue(v)
 	if err != nil {
-		logrus.Fatalf("getting template value on text only receiver: %v", err)
+		failf("getting template value on text only receiver: %v", err)
 	}
 	to.ch <- value
 }
@@ -188,7 +186,7 @@ func (so *SliceOnlyReceiver) VisitStringSlice(v []string) {
 func (so *SliceOnlyReceiver) VisitSearch(v wtemplate.OutboundOperation) {
 	value, err := wtemplate.GetValue(v)
 	if err != nil {
-		logrus.Fatalf("getting template value on text only receiver: %v", err)
+		failf("getting template value on text only receiver: %v", err)
 	}
 	so.VisitString(value)
 }
@@ -231,7 +229,7 @@ func (so *ObjectOnlyReceiver) VisitStringSlice(v []string) {
 func (so *ObjectOnlyReceiver) VisitSearch(v wtemplate.OutboundOperation) {
 	value, err := wtemplate.GetValue(v)
 	if err != nil {
-		logrus.Fatalf("getting template value on text only receiver: %v", err)
+		failf("getting template value on text only receiver: %v", err)
 	}
 	so.VisitString(value)
 }
@@ -259,7 +257,7 @@ func (tn *NumberOnlyReceiver) Value() float64 {
 func (tn *NumberOnlyReceiver) VisitString(v string) {
 	val, err := strconv.ParseFloat(v, 64)
 	if err != nil {
-		logrus.Fatalf("invalid expression: parsing float on number only receiver: %s must be a number", v)
+		failf("invalid expression: parsing float on number only receiver: %s must be a number", v)
 	}
 	tn.ch <- val
 
@@ -267,14 +265,14 @@ func (tn *NumberOnlyReceiver) VisitString(v string) {
 
 // VisitStringSlice receives a string slice value.
 func (tn *NumberOnlyReceiver) VisitStringSlice(v []string) {
-	logrus.Fatalf("invalid expression: %v must be a number", v)
+	failf("invalid expression: %v must be a number", v)
 }
 
 // VisitSearch receives a search value.
 func (tn *NumberOnlyReceiver) VisitSearch(v wtemplate.OutboundOperation) {
 	value, err := wtemplate.GetValue(v)
 	if err != nil {
-		logrus.Fatalf("getting template value on number only receiver: %v", err)
+		failf("getting template value on number only receiver: %v", err)
 	}
 	tn.VisitString(value)
 }
@@ -283,7 +281,7 @@ func (tn *NumberOnlyReceiver) VisitSearch(v wtemplate.OutboundOperation) {
 func (tn *NumberOnlyReceiver) VisitObject(v wkeyword.Object) {
 	res, err := strconv.ParseFloat(v.String(), 64)
 	if err != nil {
-		logrus.Fatal(err)
+		panic(err)
 	}
 	tn.ch <- res
 }
@@ -316,7 +314,7 @@ func (br *BooleanReceiver) VisitStringSlice(vs []string) {
 func (br *BooleanReceiver) VisitSearch(v wtemplate.OutboundOperation) {
 	value, err := wtemplate.GetValue(v)
 	if err != nil {
-		logrus.Fatalf("error method call: boolean receiver failed while getting template value: %v", err)
+		failf("error method call: boolean receiver failed while getting template value: %v", err)
 	}
 	br.VisitString(value)
 }
@@ -451,12 +449,13 @@ func (erb *EmitterReceiverBridge) VisitObject(v wkeyword.Object) {
 // AccumEmitterReceiverBridge is an emitter/receiver that aggregates a list of emitters.
 // The result is the junction of the values emitted by the added emitters.
 type AccumEmitterReceiverBridge struct {
+	ctx    *ParsingContext
 	values []Emitter
 }
 
 // newAccumEmitterReceiverBridge is a constructor for AccumEmitterReceiverBridge.
-func newAccumEmitterReceiverBridge() *AccumEmitterReceiverBridge {
-	return &AccumEmitterReceiverBridge{}
+func newAccumEmitterReceiverBridge(ctx *ParsingContext) *AccumEmitterReceiverBridge {
+	return &AccumEmitterReceiverBridge{ctx: ctx}
 }
 
 func (ab *AccumEmitterReceiverBridge) Last() Emitter {
@@ -475,7 +474,8 @@ func (ab *AccumEmitterReceiverBridge) Accept(e Receiver) {
 	sb := new(strings.Builder)
 	receiver := newTextOnlyReceiver()
 	for _, v := range ab.values {
-		go v.Accept(receiver)
+		v := v
+		ab.ctx.run(func() { v.Accept(receiver) })
 		sb.WriteString(receiver.Value())
 
 	}
@@ -531,7 +531,7 @@ func (ner *NegationEmitterReceiver) VisitStringSlice(vs []string) {
 func (ner *NegationEmitterReceiver) VisitSearch(v wtemplate.OutboundOperation) {
 	value, err := wtemplate.GetValue(v)
 	if err != nil {
-		logrus.Fatalf("error method call: method 'negation' failed while getting template value: %v", err)
+		failf("error method call: method 'negation' failed while getting template value: %v", err)
 	}
 	ner.VisitString(value)
 }
@@ -618,7 +618,7 @@ func (mi *MethodIndexEmitter) VisitStringSlice(v []string) {
 func (mi *MethodIndexEmitter) VisitSearch(v wtemplate.OutboundOperation) {
 	value, err := wtemplate.GetValue(v)
 	if err != nil {
-		logrus.Fatalf("error method call: access value by 'index' failed while getting template value: %v", err)
+		failf("error method call: access value by 'index' failed while getting template value: %v", err)
 	}
 	mi.VisitString(value)
 }
@@ -657,9 +657,9 @@ func (mm *MethodMapEmitter) Accept(e Receiver) {
 // VisitString receives a string value.
 func (mm *MethodMapEmitter) VisitString(v string) {
 	receiver := newTextOnlyReceiver()
-	clonedCtx := newParsingContext(mm.ctx.orderMap, append([]wkeyword.KeywordsMap{}, mm.ctx.keywordsMaps...))
+	clonedCtx := mm.ctx.clone()
 	mm.lambda.Execute(clonedCtx, nil, newObjectEmitter(wkeyword.NewKwArray([]interface{}{v, "0"})))
-	go mm.lambda.argument.Execute(clonedCtx, receiver, nil)
+	clonedCtx.run(func() { mm.lambda.argument.Execute(clonedCtx, receiver, nil) })
 	res := receiver.Value()
 	mm.lambda.Clear(clonedCtx)
 	mm.chString <- res
@@ -670,9 +670,9 @@ func (mm *MethodMapEmitter) VisitStringSlice(vs []string) {
 	var res []string
 	receiver := newTextOnlyReceiver()
 	for i, v := range vs {
-		clonedCtx := newParsingContext(mm.ctx.orderMap, append([]wkeyword.KeywordsMap{}, mm.ctx.keywordsMaps...))
+		clonedCtx := mm.ctx.clone()
 		mm.lambda.Execute(clonedCtx, nil, newObjectEmitter(wkeyword.NewKwArray([]interface{}{v, strconv.Itoa(i)})))
-		go mm.lambda.argument.Execute(clonedCtx, receiver, nil)
+		clonedCtx.run(func() { mm.lambda.argument.Execute(clonedCtx, receiver, nil) })
 		res = append(res, receiver.Value())
 		mm.lambda.Clear(clonedCtx)
 	}
@@ -683,7 +683,7 @@ func (mm *MethodMapEmitter) VisitStringSlice(vs []string) {
 func (mm *MethodMapEmitter) VisitSearch(v wtemplate.OutboundOperation) {
 	value, err := wtemplate.GetValue(v)
 	if err != nil {
-		logrus.Fatalf("error method call: method 'map' failed while getting template value: %v", err)
+		failf("error method call: method 'map' failed while getting template value: %v", err)
 	}
 	mm.VisitStringSlice([]string{value})
 }
@@ -698,9 +698,9 @@ func (mm *MethodMapEmitter) VisitObject(v wkeyword.Object) {
 	receiver := newObjectOnlyReceiver()
 	objectSlice := v.Slice()
 	for i, v := range objectSlice {
-		clonedCtx := newParsingContext(mm.ctx.orderMap, append([]wkeyword.KeywordsMap{}, mm.ctx.keywordsMaps...))
+		clonedCtx := mm.ctx.clone()
 		mm.lambda.Execute(clonedCtx, nil, newObjectEmitter(wkeyword.NewKwArray([]interface{}{v, strconv.Itoa(i)})))
-		go mm.lambda.argument.Execute(clonedCtx, receiver, nil)
+		clonedCtx.run(func() { mm.lambda.argument.Execute(clonedCtx, receiver, nil) })
 		res = append(res, receiver.Value())
 		mm.lambda.Clear(clonedCtx)
 	}
@@ -735,9 +735,9 @@ func (mf *MethodFilterEmitter) VisitString(v string) {
 	sb := new(strings.Builder)
 	booleanReceiver := newBooleanReceiver()
 	for i, c := range v {
-		clonedCtx := newParsingContext(mf.ctx.orderMap, append([]wkeyword.KeywordsMap{}, mf.ctx.keywordsMaps...))
+		clonedCtx := mf.ctx.clone()
 		mf.lambda.Execute(mf.ctx, nil, newObjectEmitter(wkeyword.NewKwArray([]interface{}{v, strconv.Itoa(i)})))
-		go mf.lambda.argument.Execute(clonedCtx, booleanReceiver, nil)
+		clonedCtx.run(func() { mf.lambda.argument.Execute(clonedCtx, booleanReceiver, nil) })
 		if booleanReceiver.Value() == True {
 			sb.WriteRune(c)
 		}
@@ -751,9 +751,9 @@ func (mf *MethodFilterEmitter) VisitStringSlice(vs []string) {
 	var res []string
 	booleanReceiver := newBooleanReceiver()
 	for i, v := range vs {
-		clonedCtx := newParsingContext(mf.ctx.orderMap, append([]wkeyword.KeywordsMap{}, mf.ctx.keywordsMaps...))
+		clonedCtx := mf.ctx.clone()
 		mf.lambda.Execute(clonedCtx, nil, newObjectEmitter(wkeyword.NewKwArray([]interface{}{v, strconv.Itoa(i)})))
-		go mf.lambda.argument.Execute(clonedCtx, booleanReceiver, nil)
+		clonedCtx.run(func() { mf.lambda.argument.Execute(clonedCtx, booleanReceiver, nil) })
 		if booleanReceiver.Value() == True {
 			res = append(res, v)
 		}
@@ -766,7 +766,7 @@ func (mf *MethodFilterEmitter) VisitStringSlice(vs []string) {
 func (mf *MethodFilterEmitter) VisitSearch(v wtemplate.OutboundOperation) {
 	value, err := wtemplate.GetValue(v)
 	if err != nil {
-		logrus.Fatalf("error method call: method 'filter' failed while getting template value: %v", err)
+		failf("error method call: method 'filter' failed while getting template value: %v", err)
 	}
 	mf.VisitString(value)
 }
@@ -841,11 +841,11 @@ func (ma *MethodAssertEmitter) VisitObject(v wkeyword.Object) {
 // the value is received using a custom emitter.
 func (ma *MethodAssertEmitter) Assert(e Emitter) {
 	receiver := newMethodAssertValidator(ma.ctx)
-	clonedCtx := newParsingContext(ma.ctx.orderMap, append([]wkeyword.KeywordsMap{}, ma.ctx.keywordsMaps...))
+	clonedCtx := ma.ctx.clone()
 	ma.lambda.Execute(clonedCtx, nil, e)
 	defer ma.lambda.Clear(clonedCtx)
-	go ma.lambda.argument.Execute(clonedCtx, receiver, nil)
-	ma.failed <- !StringToBool(ReadString(receiver))
+	clonedCtx.run(func() { ma.lambda.argument.Execute(clonedCtx, receiver, nil) })
+	ma.failed <- !StringToBool(ReadString(ma.ctx, receiver))
 }
 
 func (ma *MethodAssertEmitter) checkResult() {
@@ -890,7 +890,7 @@ func (mav *MethodAssertValidator) VisitStringSlice(vs []string) {
 func (mav *MethodAssertValidator) VisitSearch(v wtemplate.OutboundOperation) {
 	value, err := wtemplate.GetValue(v)
 	if err != nil {
-		logrus.Fatalf("error method call: validating expression: method 'assert'' failed while getting template value: %v", err)
+		failf("error method call: validating expression: method 'assert'' failed while getting template value: %v", err)
 	}
 	mav.VisitString(value)
 }
@@ -940,7 +940,7 @@ func (mm *MethodRepeatEmitter) VisitStringSlice(v []string) {
 func (mm *MethodRepeatEmitter) VisitSearch(v wtemplate.OutboundOperation) {
 	value, err := wtemplate.GetValue(v)
 	if err != nil {
-		logrus.Fatalf("error method call: method 'map' failed while getting template value: %v", err)
+		failf("error method call: method 'map' failed while getting template value: %v", err)
 	}
 	mm.VisitString(value)
 }
@@ -981,7 +981,7 @@ func (mj *MethodJoinEmitter) VisitStringSlice(v []string) {
 
 // VisitSearch receives a search value.
 func (mj *MethodJoinEmitter) VisitSearch(wtemplate.OutboundOperation) {
-	logrus.Fatalf("invalid method call: method 'join' cannot be called in template type values")
+	failf("invalid method call: method 'join' cannot be called in template type values")
 }
 
 // VisitObject receives an object value.
@@ -1014,14 +1014,14 @@ func (ms *MethodSplitEmitter) VisitString(v string) {
 
 // VisitStringSlice receives a string slice value.
 func (ms *MethodSplitEmitter) VisitStringSlice([]string) {
-	logrus.Fatalf("invalid method call: method 'split' cannot be called in string slice values")
+	failf("invalid method call: method 'split' cannot be called in string slice values")
 }
 
 // VisitSearch receives a search value.
 func (ms *MethodSplitEmitter) VisitSearch(v wtemplate.OutboundOperation) {
 	value, err := wtemplate.GetValue(v)
 	if err != nil {
-		logrus.Fatalf("error method call: method 'split' failed while getting template value: %v", err)
+		failf("error method call: method 'split' failed while getting template value: %v", err)
 	}
 	ms.VisitString(value)
 }
@@ -1029,7 +1029,7 @@ func (ms *MethodSplitEmitter) VisitSearch(v wtemplate.OutboundOperation) {
 // VisitObject receives an object value.
 func (ms *MethodSplitEmitter) VisitObject(v wkeyword.Object) {
 	if !wkeyword.IsPrimitive(v) {
-		logrus.Fatalf("invalid method call: method 'split' cannot be called in arrays/maps")
+		failf("invalid method call: method 'split' cannot be called in arrays/maps")
 	}
 	ms.VisitString(v.String())
 }
@@ -1081,7 +1081,7 @@ func (ms *MethodSliceEmitter) VisitStringSlice(v []string) {
 func (ms *MethodSliceEmitter) VisitSearch(v wtemplate.OutboundOperation) {
 	value, err := wtemplate.GetValue(v)
 	if err != nil {
-		logrus.Fatalf("error method call: method 'slice' failed while getting template value: %v", err)
+		failf("error method call: method 'slice' failed while getting template value: %v", err)
 	}
 	ms.VisitString(value)
 }
@@ -1142,7 +1142,7 @@ func (ms *MethodSpliceEmitter) VisitStringSlice(v []string) {
 func (ms *MethodSpliceEmitter) VisitSearch(v wtemplate.OutboundOperation) {
 	value, err := wtemplate.GetValue(v)
 	if err != nil {
-		logrus.Fatalf("error method call: method 'splice' failed while getting template value: %v", err)
+		failf("error method call: method 'splice' failed while getting template value: %v", err)
 	}
 	ms.VisitString(value)
 }
@@ -1186,7 +1186,7 @@ func (mc *MethodCountEmitter) VisitStringSlice(v []string) {
 func (mc *MethodCountEmitter) VisitSearch(v wtemplate.OutboundOperation) {
 	value, err := wtemplate.GetValue(v)
 	if err != nil {
-		logrus.Fatalf("error method call: method 'count' failed while getting template value: %v", err)
+		failf("error method call: method 'count' failed while getting template value: %v", err)
 	}
 	mc.VisitString(value)
 }
@@ -1236,7 +1236,7 @@ func (mc *MethodContainsEmitter) VisitStringSlice(vs []string) {
 func (mc *MethodContainsEmitter) VisitSearch(v wtemplate.OutboundOperation) {
 	value, err := wtemplate.GetValue(v)
 	if err != nil {
-		logrus.Fatalf("error method call: method 'contains' failed while getting template value: %v", err)
+		failf("error method call: method 'contains' failed while getting template value: %v", err)
 	}
 	mc.VisitString(value)
 }
@@ -1356,15 +1356,15 @@ func (mr *MethodReplaceEmitter) Accept(e Receiver) {
 
 // VisitString receives a string value.
 func (mr *MethodReplaceEmitter) VisitString(v string) {
-	oldValue := ReadString(mr.old)
-	newValue := ReadString(mr.new)
+	oldValue := ReadString(mr.ctx, mr.old)
+	newValue := ReadString(mr.ctx, mr.new)
 	mr.chString <- strings.ReplaceAll(v, oldValue, newValue)
 }
 
 // VisitStringSlice receives a string slice value.
 func (mr *MethodReplaceEmitter) VisitStringSlice(vs []string) {
-	oldValue := ReadString(mr.old)
-	newValue := ReadString(mr.new)
+	oldValue := ReadString(mr.ctx, mr.old)
+	newValue := ReadString(mr.ctx, mr.new)
 	mr.visitStringSlice(vs, oldValue, newValue)
 }
 
@@ -1372,15 +1372,15 @@ func (mr *MethodReplaceEmitter) VisitStringSlice(vs []string) {
 func (mr *MethodReplaceEmitter) VisitSearch(v wtemplate.OutboundOperation) {
 	oldContext := mr.old.(*ContextBoolEmitterReceiver)
 	newContext := mr.new.(*ContextBoolEmitterReceiver)
-	oldValue := ReadString(mr.old)
-	newValue := ReadString(mr.new)
+	oldValue := ReadString(mr.ctx, mr.old)
+	newValue := ReadString(mr.ctx, mr.new)
 	mr.chSearch <- wtemplate.NewReplaceOp(v, wtemplate.NewReplaceOpArg(oldValue, oldContext.value), wtemplate.NewReplaceOpArg(newValue, newContext.value))
 }
 
 // VisitObject receives an object value.
 func (mr *MethodReplaceEmitter) VisitObject(v wkeyword.Object) {
-	oldValue := ReadString(mr.old)
-	newValue := ReadString(mr.new)
+	oldValue := ReadString(mr.ctx, mr.old)
+	newValue := ReadString(mr.ctx, mr.new)
 	if wkeyword.IsPrimitive(v) || wkeyword.IsNil(v) {
 		mr.chString <- strings.ReplaceAll(v.String(), oldValue, newValue)
 	} else if wkeyword.IsObject(v) {
@@ -1424,12 +1424,12 @@ func (mm *MethodSelectEmitter) Accept(e Receiver) {
 
 // VisitString receives a string value.
 func (mm *MethodSelectEmitter) VisitString(string) {
-	logrus.Fatalf("invalid method call: method 'select' cannot be called in string type values")
+	failf("invalid method call: method 'select' cannot be called in string type values")
 }
 
 // VisitStringSlice receives a string slice value.
 func (mm *MethodSelectEmitter) VisitStringSlice([]string) {
-	logrus.Fatalf("invalid method call: method 'select' cannot be called in string slice type values")
+	failf("invalid method call: method 'select' cannot be called in string slice type values")
 }
 
 // VisitSearch receives a search value.
@@ -1439,7 +1439,7 @@ func (mm *MethodSelectEmitter) VisitSearch(v wtemplate.OutboundOperation) {
 
 // VisitObject receives an object value.
 func (mm *MethodSelectEmitter) VisitObject(wkeyword.Object) {
-	logrus.Fatalf("invalid method call: method 'select' cannot be called in object type values")
+	failf("invalid method call: method 'select' cannot be called in object type values")
 }
 
 // ObjectPropertyEmitter is the receiver/emitter for ana object property access.
@@ -1460,17 +1460,17 @@ func (mop *ObjectPropertyEmitter) Accept(e Receiver) {
 
 // VisitString receives a string value.
 func (mop *ObjectPropertyEmitter) VisitString(string) {
-	logrus.Fatalf("invalid method call: cannot access properties in string type values")
+	failf("invalid method call: cannot access properties in string type values")
 }
 
 // VisitStringSlice receives a string slice value.
 func (mop *ObjectPropertyEmitter) VisitStringSlice([]string) {
-	logrus.Fatalf("invalid method call: cannot access properties in string slice type values")
+	failf("invalid method call: cannot access properties in string slice type values")
 }
 
 // VisitSearch receives a search value.
 func (mop *ObjectPropertyEmitter) VisitSearch(wtemplate.OutboundOperation) {
-	logrus.Fatalf("invalid method call: cannot access properties in search type values")
+	failf("invalid method call: cannot access properties in search type values")
 }
 
 // VisitObject receives an object value.
@@ -1512,7 +1512,7 @@ func (mo *MethodOrderEmitter) VisitStringSlice(v []string) {
 func (mo *MethodOrderEmitter) VisitSearch(v wtemplate.OutboundOperation) {
 	value, err := wtemplate.GetValue(v)
 	if err != nil {
-		logrus.Fatalf("getting template value on method order: %v", err)
+		failf("getting template value on method order: %v", err)
 	}
 	mo.VisitString(value)
 }
@@ -1569,7 +1569,7 @@ func (mr *MethodReverseEmitter) VisitStringSlice(v []string) {
 func (mr *MethodReverseEmitter) VisitSearch(v wtemplate.OutboundOperation) {
 	value, err := wtemplate.GetValue(v)
 	if err != nil {
-		logrus.Fatalf("error method call: method 'reverse' failed while getting template value: %v", err)
+		failf("error method call: method 'reverse' failed while getting template value: %v", err)
 	}
 	mr.VisitString(value)
 }
@@ -1582,3 +1582,93 @@ func (mr *MethodReverseEmitter) VisitObject(v wkeyword.Object) {
 		mr.VisitString(v.String())
 	}
 }
+
+// MethodConcatEmitter is the receiver/emitter for the method concat.
+// the method concat appends some value to the received value, emitting it as a string.
+type MethodConcatEmitter struct {
+	*EmitterReceiverBridge
+	value string
+}
+
+// newMethodConcatEmitter is a constructor for EmitterReceiver.
+func newMethodConcatEmitter(_ *ParsingContext, value string) EmitterReceiver {
+	return &MethodConcatEmitter{newEmitterReceiverBridge(), value}
+}
+
+// Accept accepts and visits a receiver.
+func (mc *MethodConcatEmitter) Accept(e Receiver) {
+	e.VisitString(<-mc.chString)
+}
+
+// VisitString receives a string value.
+func (mc *MethodConcatEmitter) VisitString(v string) {
+	mc.chString <- v + mc.value
+}
+
+// VisitStringSlice receives a string slice value.
+func (mc *MethodConcatEmitter) VisitStringSlice(v []string) {
+	mc.VisitString(strings.Join(v, ""))
+}
+
+// VisitSearch receives a search value.
+func (mc *MethodConcatEmitter) VisitSearch(v wtemplate.OutboundOperation) {
+	value, err := wtemplate.GetValue(v)
+	if err != nil {
+		failf("error method call: method 'concat' failed while getting template value: %v", err)
+	}
+	mc.VisitString(value)
+}
+
+// VisitObject receives an object value.
+func (mc *MethodConcatEmitter) VisitObject(v wkeyword.Object) {
+	mc.VisitString(v.String())
+}
+
+// MethodDataEmitter is the receiver/emitter for the method data.
+// the method data stores the received value on a data segment of the module,
+// emitting the address and the length of the value as WAT constants.
+type MethodDataEmitter struct {
+	*EmitterReceiverBridge
+	ctx *ParsingContext
+}
+
+// newMethodDataEmitter is a constructor for MethodDataEmitter.
+func newMethodDataEmitter(ctx *ParsingContext) *MethodDataEmitter {
+	return &MethodDataEmitter{newEmitterReceiverBridge(), ctx}
+}
+
+// Accept accepts and visits a receiver.
+func (md *MethodDataEmitter) Accept(e Receiver) {
+	e.VisitString(<-md.chString)
+}
+
+// VisitString receives a string value.
+func (md *MethodDataEmitter) VisitString(v string) {
+	if md.ctx.data == nil {
+		failf("invalid method call: method 'data' is not available without a module")
+	}
+	address, length, err := md.ctx.data.InternData(v)
+	if err != nil {
+		failf("error method call: method 'data' failed while storing value: %v", err)
+	}
+	md.chString <- fmt.Sprintf("(i32.const %s) (i32.const %d)", address, length)
+}
+
+// VisitStringSlice receives a string slice value.
+func (md *MethodDataEmitter) VisitStringSlice(v []string) {
+	md.VisitString(strings.Join(v, ""))
+}
+
+// VisitSearch receives a search value.
+func (md *MethodDataEmitter) VisitSearch(v wtemplate.OutboundOperation) {
+	value, err := wtemplate.GetValue(v)
+	if err != nil {
+		failf("error method call: method 'data' failed while getting template value: %v", err)
+	}
+	md.VisitString(value)
+}
+
+// VisitObject receives an object value.
+func (md *MethodDataEmitter) VisitObject(v wkeyword.Object) {
+	md.VisitString(v.String())
+}
@@ -1,10 +1,7 @@
 package lex
 
 import (
-	"log"
 	"strconv"
-
-	"github.com/sirupsen/logrus"
 )
 
 type TokenNodeType int
@@ -96,17 +93,17 @@ func (node *MethodTokenNode) Execute(r *ParsingContext, visitor Receiver, visite
 	childBridge := newEmitterReceiverBridge()
 	methodBridge := newEmitterReceiverBridge()
 	defer func() {
-		if r := recover(); r != nil {
-			if r != PanicAssertMethod {
-				logrus.Fatalf("method token node panic: %v", r)
+		if v := recover(); v != nil {
+			if v != PanicAssertMethod {
+				panic(v)
 			}
-			go methodBridge.Accept(newEmptierReceiver())
+			r.run(func() { methodBridge.Accept(newEmptierReceiver()) })
 			visitor.VisitString("")
 			childBridge.Close()
 			panic(PanicAssertMethod)
 		}
 	}()
-	go childBridge.Accept(methodBridge)
+	r.run(func() { childBridge.Accept(methodBridge) })
 	node.child.Execute(r, childBridge, visited)
 	node.method.Execute(r, visitor, methodBridge)
 }
@@ -129,7 +126,7 @@ func (node *NegationWrapperTokenNode) Set(t Token) Token {
 
 func (node *NegationWrapperTokenNode) Execute(r *ParsingContext, visitor Receiver, visited Emitter) {
 	negation := newNegationEmitterReceiver()
-	go negation.Accept(visitor)
+	r.run(func() { negation.Accept(visitor) })
 	node.child.Execute(r, negation, visited)
 }
 
@@ -165,7 +162,7 @@ type OperationLogicalTokenNode struct {
 // Execute executes the token functionality.
 func (node *OperationLogicalTokenNode) Execute(r *ParsingContext, visitor Receiver, visited Emitter) {
 	receiver := newBooleanReceiver()
-	go node.left.Execute(r, receiver, visited)
+	r.run(func() { node.left.Execute(r, receiver, visited) })
 	leftValue := receiver.Value()
 	if node.nodeType == TokenTypeOpAnd {
 		if leftValue == False {
@@ -178,7 +175,7 @@ func (node *OperationLogicalTokenNode) Execute(r *ParsingContext, visitor Receiv
 			return
 		}
 	}
-	go node.right.Execute(r, receiver, visited)
+	r.run(func() { node.right.Execute(r, receiver, visited) })
 	visitor.VisitString(receiver.Value())
 }
 
@@ -191,8 +188,8 @@ type OperationEqualTokenNode struct {
 func (node *OperationEqualTokenNode) Execute(r *ParsingContext, visitor Receiver, visited Emitter) {
 	leftReceiver := newTextOnlyReceiver()
 	rightReceiver := newTextOnlyReceiver()
-	go node.left.Execute(r, leftReceiver, visited)
-	go node.right.Execute(r, rightReceiver, visited)
+	r.run(func() { node.left.Execute(r, leftReceiver, visited) })
+	r.run(func() { node.right.Execute(r, rightReceiver, visited) })
 	leftValue := leftReceiver.Value()
 	rightValue := rightReceiver.Value()
 	if node.nodeType == TokenTypeOpEqual {
@@ -211,8 +208,8 @@ type OperationCompareNumbersTokenNode struct {
 func (node *OperationCompareNumbersTokenNode) Execute(r *ParsingContext, visitor Receiver, visited Emitter) {
 	leftReceiver := newNumberOnlyReceiver()
 	rightReceiver := newNumberOnlyReceiver()
-	go node.left.Execute(r, leftReceiver, visited)
-	go node.right.Execute(r, rightReceiver, visited)
+	r.run(func() { node.left.Execute(r, leftReceiver, visited) })
+	r.run(func() { node.right.Execute(r, rightReceiver, visited) })
 	leftValue := leftReceiver.Value()
 	rightValue := rightReceiver.Value()
 	switch node.nodeType {
@@ -238,8 +235,8 @@ type OperationModifyNumbersTokenNode struct {
 func (node *OperationModifyNumbersTokenNode) Execute(r *ParsingContext, visitor Receiver, visited Emitter) {
 	leftReceiver := newNumberOnlyReceiver()
 	rightReceiver := newNumberOnlyReceiver()
-	go node.left.Execute(r, leftReceiver, visited)
-	go node.right.Execute(r, rightReceiver, visited)
+	r.run(func() { node.left.Execute(r, leftReceiver, visited) })
+	r.run(func() { node.right.Execute(r, rightReceiver, visited) })
 	leftValue := leftReceiver.Value()
 	rightValue := rightReceiver.Value()
 	formatInt := func(n int) string {
@@ -293,7 +290,7 @@ func parseExpr(expr []*TokenNode, stacks *parseStacks) Token {
 			TokenTypeOpBitwiseLeft, TokenTypeOpBitwiseRight, TokenTypeOpPlus, TokenTypeOpMinus, TokenTypeOpMultiplication, TokenTypeOpDivision, TokenTypeOpRemainder:
 			handleParseOpNode(expr[i].t, stacks)
 		default:
-			logrus.Fatalf("unknown token expression node %v", e.t)
+			failf("unknown token expression node %v", e.t)
 		}
 	}
 	return returnParseExpr(stacks)
@@ -306,10 +303,10 @@ func returnParseExpr(stacks *parseStacks) Token {
 		doOperation(stacks)
 	}
 	if len(stacks.blockStack) != 1 {
-		log.Fatalf("group ended with %d nodes left on blocks stack... expected 1", len(stacks.blockStack))
+		failf("group ended with %d nodes left on blocks stack... expected 1", len(stacks.blockStack))
 	}
 	if len(stacks.opStack) != 0 {
-		log.Fatalf("group ended with %d nodes left on operations stack... expected 0", len(stacks.blockStack))
+		failf("group ended with %d nodes left on operations stack... expected 0", len(stacks.blockStack))
 	}
 	return popBlock(&stacks.blockStack)
 }
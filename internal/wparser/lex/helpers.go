@@ -25,44 +25,44 @@ type OneIntArgBasicMethodFn func(*ParsingContext, int) EmitterReceiver
 // the string argument comes from emitted value of the argument token.
 func InvokeOneStringArgBasicMethodWithToken(fn OneStringArgBasicMethodFn, argument Token, r *ParsingContext, visitor Receiver, visited Emitter) {
 	receiver := newTextOnlyReceiver()
-	go argument.Execute(r, receiver, nil)
+	r.run(func() { argument.Execute(r, receiver, nil) })
 	method := fn(r, <-receiver.ch)
-	go method.Accept(visitor)
+	r.run(func() { method.Accept(visitor) })
 	visited.Accept(method)
 }
 
 // InvokeOneStringArgBasicMethodWithString invoke tokens that have a string as an argument.
 func InvokeOneStringArgBasicMethodWithString(fn OneStringArgBasicMethodFn, argument string, r *ParsingContext, visitor Receiver, visited Emitter) {
 	method := fn(r, argument)
-	go method.Accept(visitor)
+	r.run(func() { method.Accept(visitor) })
 	visited.Accept(method)
 }
 
 // InvokeOneIntArgBasicMethodWithString invoke tokens that have an int as an argument.
 func InvokeOneIntArgBasicMethodWithString(fn OneIntArgBasicMethodFn, argument int, r *ParsingContext, visitor Receiver, visited Emitter) {
 	method := fn(r, argument)
-	go method.Accept(visitor)
+	r.run(func() { method.Accept(visitor) })
 	visited.Accept(method)
 }
 
 // ReadString reads the string value from an emitter.
-func ReadString(e Emitter) string {
+func ReadString(r *ParsingContext, e Emitter) string {
 	receiver := newTextOnlyReceiver()
-	go e.Accept(receiver)
+	r.run(func() { e.Accept(receiver) })
 	return receiver.Value()
 }
 
 // ReadString reads the string slice value from an emitter.
-func ReadSlice(e Emitter) []string {
+func ReadSlice(r *ParsingContext, e Emitter) []string {
 	receiver := newSliceOnlyReceiver()
-	go e.Accept(receiver)
+	r.run(func() { e.Accept(receiver) })
 	return receiver.Value()
 }
 
 // ReadObject reads any object value from an emitter.
-func ReadObject(e Emitter) wkeyword.Object {
+func ReadObject(r *ParsingContext, e Emitter) wkeyword.Object {
 	receiver := newObjectOnlyReceiver()
-	go e.Accept(receiver)
+	r.run(func() { e.Accept(receiver) })
 	return receiver.Value()
 }
 
@@ -0,0 +1,57 @@
+package lex
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// methodNames contains the names of the transformation methods.
+var methodNames = map[string]struct{}{
+	MethodTypeString: {}, MethodTypeType: {}, MethodTypeMap: {}, MethodTypeRepeat: {}, MethodTypeJoin: {},
+	MethodTypeSplit: {}, MethodTypeCount: {}, MethodTypeContains: {}, MethodTypeAssert: {}, MethodTypeReplace: {},
+	MethodTypeRemove: {}, MethodTypeFilter: {}, MethodTypeSlice: {}, MethodTypeSplice: {}, MethodTypeSelect: {},
+	MethodTypeOrder: {}, MethodTypeReverse: {}, MethodTypeConcat: {}, MethodTypeData: {},
+}
+
+// MethodNames returns the names of the transformation methods, sorted.
+func MethodNames() []string {
+	res := make([]string, 0, len(methodNames))
+	for name := range methodNames {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Identifiers returns the variables referred by the static expressions of a code input, without parsing them.
+// only the variables outside of the method arguments are returned, since the arguments can refer to template variables.
+// returns an error for invalid expressions or unknown methods.
+func Identifiers(input string) ([]string, error) {
+	var res []string
+	var err error
+	var depth int
+	_, ch := Lex("Identifiers", input)
+	for item := range ch {
+		if err != nil {
+			continue
+		}
+		switch item.t {
+		case ItemTypeError:
+			err = errors.New(item.v)
+		case ItemTypeMethodName:
+			if _, ok := methodNames[item.v]; !ok {
+				err = fmt.Errorf("unknown method %q", item.v)
+			}
+		case ItemTypeMethodArgStart:
+			depth++
+		case ItemTypeMethodArgEnd:
+			depth--
+		case ItemTypeIdentifier:
+			if depth == 0 {
+				res = append(res, item.v)
+			}
+		}
+	}
+	return res, err
+}
@@ -43,6 +43,8 @@ const (
 	MethodTypeSelect   = "select"
 	MethodTypeOrder    = "order"
 	MethodTypeReverse  = "reverse"
+	MethodTypeConcat   = "concat"
+	MethodTypeData     = "data"
 )
 
 // Token represents a token for the parsing flux.
@@ -50,18 +52,62 @@ type Token interface {
 	Execute(*ParsingContext, Receiver, Emitter)
 }
 
+// DataSegments is implemented by those who store static strings on the module memory.
+type DataSegments interface {
+	InternData(value string) (address string, length int, err error)
+}
+
 // Parse parses a code input.
-func Parse(input string, orderMap map[string]int, keywordMaps ...wkeyword.KeywordsMap) *ParseResult {
-	return parse(input, newParsingContext(orderMap, keywordMaps))
+// the errors found while parsing are logged as fatal.
+func Parse(input string, orderMap map[string]int, data DataSegments, keywordMaps ...wkeyword.KeywordsMap) *ParseResult {
+	res, err := parse(input, newParsingContext(orderMap, data, keywordMaps))
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	return res
+}
+
+// Evaluate parses a code input, returning its output.
+// unlike Parse, the errors found while parsing are returned.
+func Evaluate(input string, orderMap map[string]int, data DataSegments, keywordMaps ...wkeyword.KeywordsMap) (string, error) {
+	res, err := parse(input, newParsingContext(orderMap, data, keywordMaps))
+	if err != nil {
+		return "", err
+	}
+	return res.Output, nil
 }
 
 // parse parses a code input.
-func parse(input string, ctx *ParsingContext) *ParseResult {
+// the parsing stops with the first error found, either while parsing the tokens or executing them.
+func parse(input string, ctx *ParsingContext) (res *ParseResult, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = failureError(v)
+		}
+	}()
 	_, ch := Lex("Parser", input)
 	parsedTokens := parseText(ctx, ch)
 	receiver := newTextOnlyReceiver()
-	go parsedTokens.Execute(ctx, receiver, nil)
-	return newParseResult(ctx, input, receiver.Value())
+	ctx.run(func() { parsedTokens.Execute(ctx, receiver, nil) })
+	select {
+	case output := <-receiver.ch:
+		return newParseResult(ctx, input, output), nil
+	case <-ctx.failure.done:
+		return nil, ctx.failure.err
+	}
+}
+
+// failf stops the parsing with some error.
+func failf(format string, args ...interface{}) {
+	panic(fmt.Errorf(format, args...))
+}
+
+// failureError returns the error for the value of a recovered panic.
+func failureError(v interface{}) error {
+	if err, ok := v.(error); ok {
+		return err
+	}
+	return fmt.Errorf("%v", v)
 }
 
 // ParseResult contains the parse process result.
@@ -83,13 +129,56 @@ func newParseResult(ctx *ParsingContext, input string, output string) *ParseResu
 // ParsingContext contains all the context for the parsing process.
 type ParsingContext struct {
 	orderMap     map[string]int
+	data         DataSegments
 	keywordsMaps []wkeyword.KeywordsMap
 	mutex        *sync.Mutex
+	failure      *parsingFailure
 }
 
 // newParsingContext is a constructor for ParsingContext.
-func newParsingContext(orderMap map[string]int, keywordsMaps []wkeyword.KeywordsMap) *ParsingContext {
-	return &ParsingContext{orderMap: orderMap, keywordsMaps: keywordsMaps, mutex: new(sync.Mutex)}
+func newParsingContext(orderMap map[string]int, data DataSegments, keywordsMaps []wkeyword.KeywordsMap) *ParsingContext {
+	return &ParsingContext{orderMap: orderMap, data: data, keywordsMaps: keywordsMaps, mutex: new(sync.Mutex), failure: newParsingFailure()}
+}
+
+// clone returns a copy of the parsing context with its own keyword maps, e.g., for the lambdas.
+// the copy shares the failure of the parsing process.
+func (r *ParsingContext) clone() *ParsingContext {
+	res := newParsingContext(r.orderMap, r.data, append([]wkeyword.KeywordsMap{}, r.keywordsMaps...))
+	res.failure = r.failure
+	return res
+}
+
+// run executes some function on a new goroutine.
+// the function fails by panicking, which stops the parsing process with its error.
+func (r *ParsingContext) run(fn func()) {
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				r.failure.fail(failureError(v))
+			}
+		}()
+		fn()
+	}()
+}
+
+// parsingFailure contains the first error found while executing the tokens of a parsing process.
+type parsingFailure struct {
+	once *sync.Once
+	err  error
+	done chan struct{}
+}
+
+// newParsingFailure is a constructor for parsingFailure.
+func newParsingFailure() *parsingFailure {
+	return &parsingFailure{once: new(sync.Once), done: make(chan struct{})}
+}
+
+// fail sets the error of the parsing process, if none was set before.
+func (f *parsingFailure) fail(err error) {
+	f.once.Do(func() {
+		f.err = err
+		close(f.done)
+	})
 }
 
 // shift removes the first keyword map from the parsing context and returns it.
@@ -119,6 +208,21 @@ func (r *ParsingContext) unshift(v wkeyword.KeywordsMap) {
 	r.mutex.Unlock()
 }
 
+// valueType returns the value type of some keyword, if any keywords map provides it.
+func (r *ParsingContext) valueType(k string) (string, bool) {
+	if k == "" {
+		return "", false
+	}
+	for _, keywordsMap := range r.keywordsMaps {
+		if typesMap, ok := keywordsMap.(wkeyword.ValueTypesKeywordsMap); ok {
+			if typ, ok := typesMap.ValueType(k); ok {
+				return typ, true
+			}
+		}
+	}
+	return "", false
+}
+
 // Tokens
 
 // TextToken represents the token for text.
@@ -162,12 +266,13 @@ func (t StringToken) Execute(r *ParsingContext, visitor Receiver, visited Emitte
 	wg := new(sync.WaitGroup)
 	wg.Add(totalBlocks)
 	for i, block := range t.blocks {
-		go func(i int, b Token) {
+		i, b := i, block
+		r.run(func() {
+			defer wg.Done()
 			receiver := newTextOnlyReceiver()
-			go b.Execute(r, receiver, visited)
+			r.run(func() { b.Execute(r, receiver, visited) })
 			res[i] = receiver.Value()
-			wg.Done()
-		}(i, block)
+		})
 	}
 	wg.Wait()
 
@@ -182,11 +287,12 @@ type KeywordToken struct {
 // Execute executes the token functionality.
 func (t *KeywordToken) Execute(r *ParsingContext, visitor Receiver, visited Emitter) {
 	var bridge EmitterReceiver = newEmitterReceiverBridge()
-	accum := newAccumEmitterReceiverBridge()
+	accum := newAccumEmitterReceiverBridge(r)
 
 	ch := make(chan bool)
 	for _, block := range t.blocks {
-		go executeMethodBlock(block, r, bridge, visited, ch)
+		block := block
+		r.run(func() { executeMethodBlock(block, r, bridge, visited, ch) })
 		bridge.Accept(accum)
 		if !<-ch {
 			newTextEmitter("").Accept(visitor)
@@ -202,7 +308,7 @@ func executeMethodBlock(b Token, r *ParsingContext, visitor Receiver, visited Em
 	defer func() {
 		if r := recover(); r != nil {
 			if r != PanicAssertMethod {
-				logrus.Fatalf("method block panic: %v", r)
+				panic(r)
 			}
 			ch <- false
 		}
@@ -242,11 +348,11 @@ func (t IdentifierToken) Execute(r *ParsingContext, visitor Receiver, _ Emitter)
 				visitor.VisitObject(val.(wkeyword.Object))
 				return
 			default:
-				logrus.Fatalf("unknown variable %q", t.name)
+				failf("unknown variable %q", t.name)
 			}
 		}
 	}
-	logrus.Fatalf("parsing code keyword variables: %s not found in scope", t.name)
+	failf("parsing code keyword variables: %s not found in scope", t.name)
 }
 
 // IdentifierPropertyToken represents the token for identifier properties.
@@ -262,7 +368,7 @@ func newObjectPropertyToken(property string) *IdentifierPropertyToken {
 // Execute executes the token functionality.
 func (t IdentifierPropertyToken) Execute(r *ParsingContext, visitor Receiver, visited Emitter) {
 	method := newObjectPropertyEmitter(r, t.property)
-	go method.Accept(visitor)
+	r.run(func() { method.Accept(visitor) })
 	visited.Accept(method)
 }
 
@@ -296,18 +402,27 @@ type MethodStringToken struct {
 }
 
 // Execute executes the token functionality.
-func (t *MethodStringToken) Execute(_ *ParsingContext, visitor Receiver, visited Emitter) {
-	visitor.VisitString(ReadString(visited))
+func (t *MethodStringToken) Execute(r *ParsingContext, visitor Receiver, visited Emitter) {
+	visitor.VisitString(ReadString(r, visited))
 }
 
 // MethodTypeToken represents the token for method type.
+// the keyword is defined when the method is applied directly to some keyword.
 type MethodTypeToken struct {
+	keyword string
 }
 
 // Execute executes the token functionality.
-func (t *MethodTypeToken) Execute(_ *ParsingContext, visitor Receiver, visited Emitter) {
+// the value type of the keyword is used if some keywords map provides it.
+func (t *MethodTypeToken) Execute(r *ParsingContext, visitor Receiver, visited Emitter) {
 	method := newMethodTypeEmitter()
-	go method.Accept(visitor)
+	if typ, ok := r.valueType(t.keyword); ok {
+		r.run(func() { visited.Accept(method) })
+		<-method.chString
+		visitor.VisitString(typ)
+		return
+	}
+	r.run(func() { method.Accept(visitor) })
 	visited.Accept(method)
 }
 
@@ -321,9 +436,9 @@ type MethodLambda struct {
 // Execute executes the token functionality.
 func (t *MethodLambda) Execute(r *ParsingContext, _ Receiver, visited Emitter) {
 	if len(t.keys) < 1 && len(t.keys) > 2 {
-		logrus.Fatalf("lambda has one or two argument")
+		failf("lambda has one or two argument")
 	}
-	readValue := ReadObject(visited)
+	readValue := ReadObject(r, visited)
 	var values *wkeyword.KwArray
 	switch v := readValue.(type) {
 	case *wkeyword.KwArray:
@@ -332,7 +447,7 @@ func (t *MethodLambda) Execute(r *ParsingContext, _ Receiver, visited Emitter) {
 		values = wkeyword.NewKwArray([]interface{}{v})
 	}
 	if vLen := values.Len(); len(t.keys) > vLen {
-		logrus.Fatalf("lambda expects %d arguments but only got %d", len(t.keys), vLen)
+		failf("lambda expects %d arguments but only got %d", len(t.keys), vLen)
 	}
 	var valuesToAdd []wkeyword.KeyValueObject
 	for i, k := range t.keys {
@@ -354,7 +469,7 @@ type MethodMapToken struct {
 // Execute executes the token functionality.
 func (t *MethodMapToken) Execute(r *ParsingContext, visitor Receiver, visited Emitter) {
 	method := newMethodMapEmitterReceiver(r, t.lambda)
-	go method.Accept(visitor)
+	r.run(func() { method.Accept(visitor) })
 	visited.Accept(method)
 }
 
@@ -366,7 +481,7 @@ type MethodFilterToken struct {
 // Execute executes the token functionality.
 func (t *MethodFilterToken) Execute(r *ParsingContext, visitor Receiver, visited Emitter) {
 	method := newMethodFilterEmitter(r, t.lambda)
-	go method.Accept(visitor)
+	r.run(func() { method.Accept(visitor) })
 	visited.Accept(method)
 }
 
@@ -378,7 +493,7 @@ type MethodAssertToken struct {
 // Execute executes the token functionality.
 func (t *MethodAssertToken) Execute(r *ParsingContext, visitor Receiver, visited Emitter) {
 	method := newMethodAssertEmitter(r, t.lambda)
-	go visited.Accept(method)
+	r.run(func() { visited.Accept(method) })
 	method.Accept(visitor)
 }
 
@@ -390,7 +505,7 @@ type MethodRepeatToken struct {
 // Execute executes the token functionality.
 func (t *MethodRepeatToken) Execute(r *ParsingContext, visitor Receiver, visited Emitter) {
 	method := newMethodRepeatEmitter(r, t.times)
-	go method.Accept(visitor)
+	r.run(func() { method.Accept(visitor) })
 	visited.Accept(method)
 }
 
@@ -423,7 +538,7 @@ type MethodSliceToken struct {
 // Execute executes the token functionality.
 func (t *MethodSliceToken) Execute(r *ParsingContext, visitor Receiver, visited Emitter) {
 	method := newMethodSliceEmitter(r, t.start, t.end)
-	go method.Accept(visitor)
+	r.run(func() { method.Accept(visitor) })
 	visited.Accept(method)
 }
 
@@ -436,7 +551,7 @@ type MethodSpliceToken struct {
 // Execute executes the token functionality.
 func (t *MethodSpliceToken) Execute(r *ParsingContext, visitor Receiver, visited Emitter) {
 	method := newMethodSpliceEmitter(r, t.start, t.end)
-	go method.Accept(visitor)
+	r.run(func() { method.Accept(visitor) })
 	visited.Accept(method)
 }
 
@@ -445,9 +560,9 @@ type MethodCountToken struct {
 }
 
 // Execute executes the token functionality.
-func (t *MethodCountToken) Execute(_ *ParsingContext, visitor Receiver, visited Emitter) {
+func (t *MethodCountToken) Execute(r *ParsingContext, visitor Receiver, visited Emitter) {
 	method := newMethodCountEmitter()
-	go method.Accept(visitor)
+	r.run(func() { method.Accept(visitor) })
 	visited.Accept(method)
 }
 
@@ -473,10 +588,10 @@ func (t *MethodReplaceToken) Execute(r *ParsingContext, visitor Receiver, visite
 	_, newIsReference := t.new.(*ReferenceToken)
 	oldEmitter := newContextBoolEmitterReceiver(newEmitterReceiverBridge(), oldIsReference)
 	newEmitter := newContextBoolEmitterReceiver(newEmitterReceiverBridge(), newIsReference)
-	go t.old.Execute(r, oldEmitter, visited)
-	go t.new.Execute(r, newEmitter, visited)
+	r.run(func() { t.old.Execute(r, oldEmitter, visited) })
+	r.run(func() { t.new.Execute(r, newEmitter, visited) })
 	method := newMethodReplaceEmitter(r, oldEmitter, newEmitter)
-	go method.Accept(visitor)
+	r.run(func() { method.Accept(visitor) })
 	visited.Accept(method)
 }
 
@@ -507,7 +622,7 @@ type MethodOrderToken struct {
 // Execute executes the token functionality.
 func (t *MethodOrderToken) Execute(ctx *ParsingContext, visitor Receiver, visited Emitter) {
 	method := newMethodOrderEmitter(ctx)
-	go method.Accept(visitor)
+	ctx.run(func() { method.Accept(visitor) })
 	visited.Accept(method)
 }
 
@@ -518,7 +633,28 @@ type MethodReverseToken struct {
 // Execute executes the token functionality.
 func (t *MethodReverseToken) Execute(ctx *ParsingContext, visitor Receiver, visited Emitter) {
 	method := newMethodReverseEmitter(ctx)
-	go method.Accept(visitor)
+	ctx.run(func() { method.Accept(visitor) })
+	visited.Accept(method)
+}
+
+// MethodConcatToken represents the token for method concat.
+type MethodConcatToken struct {
+	argument Token
+}
+
+// Execute executes the token functionality.
+func (t *MethodConcatToken) Execute(r *ParsingContext, visitor Receiver, visited Emitter) {
+	InvokeOneStringArgBasicMethodWithToken(newMethodConcatEmitter, t.argument, r, visitor, visited)
+}
+
+// MethodDataToken represents the token for method data.
+type MethodDataToken struct {
+}
+
+// Execute executes the token functionality.
+func (t *MethodDataToken) Execute(ctx *ParsingContext, visitor Receiver, visited Emitter) {
+	method := newMethodDataEmitter(ctx)
+	ctx.run(func() { method.Accept(visitor) })
 	visited.Accept(method)
 }
 
@@ -536,10 +672,10 @@ func parseText(ctx *ParsingContext, ch <-chan Item) Token {
 		case ItemTypeEOF:
 			return res
 		default:
-			logrus.Fatalf("error parsing text: unable to parse item {%s   %s}", item.t, item.v)
+			failf("error parsing text: unable to parse item {%s   %s}", item.t, item.v)
 		}
 	}
-	logrus.Fatalf("unexpected end while parsing text")
+	failf("unexpected end while parsing text")
 	return nil
 }
 
@@ -555,10 +691,10 @@ func parseString(ctx *ParsingContext, ch <-chan Item) Token {
 		case ItemTypeStringEnd:
 			return res
 		default:
-			logrus.Fatalf("error parsing string: unable to parse item {%s   %s}", item.t, item.v)
+			failf("error parsing string: unable to parse item {%s   %s}", item.t, item.v)
 		}
 	}
-	logrus.Fatalf("unexpected end while parsing string")
+	failf("unexpected end while parsing string")
 	return nil
 }
 
@@ -584,12 +720,12 @@ func parseKeywordGroup(ctx *ParsingContext, ch <-chan Item, stop ItemType) Token
 			case ItemTypeNumber:
 				v, err := newNumberToken(item.v)
 				if err != nil {
-					logrus.Fatalf("error parsing method args: creating number token: %v", err)
+					failf("error parsing method args: creating number token: %v", err)
 				}
 				expr = append(expr, newTokenNode(TokenTypeValue, v))
 				return
 			default:
-				logrus.Fatalf("invalid keyword start (error %s - %s)", item.t, item.v)
+				failf("invalid keyword start (error %s - %s)", item.t, item.v)
 			}
 		}
 	}
@@ -610,7 +746,13 @@ func parseKeywordGroup(ctx *ParsingContext, ch <-chan Item, stop ItemType) Token
 		case ItemTypeIndexStart:
 			expr = append(expr, newTokenNode(TokenTypeMethod, parseIndex(ctx, ch)))
 		case ItemTypeMethodStart:
-			expr = append(expr, newTokenNode(TokenTypeMethod, parseMethod(ctx, ch)))
+			method := parseMethod(ctx, ch)
+			if methodType, ok := method.(*MethodTypeToken); ok && len(expr) == 1 {
+				if identifier, ok := expr[0].Token.(*IdentifierToken); ok {
+					methodType.keyword = identifier.name
+				}
+			}
+			expr = append(expr, newTokenNode(TokenTypeMethod, method))
 		default:
 			if tokenType, ok := opItemTypeToTokenType(item.t); ok {
 				expr = append(expr, newTokenNode(tokenType, nil))
@@ -632,7 +774,7 @@ func parseIndex(_ *ParsingContext, ch <-chan Item) Token {
 	item := assertParse(ch, ItemTypeNumber, "value access by index: empty index")
 	index, err := strconv.ParseInt(item.v, 10, 32)
 	if err != nil {
-		logrus.Fatalf("value access by index: first argument must be of type int")
+		failf("value access by index: first argument must be of type int")
 	}
 	res.index = int(index)
 	assertParse(ch, ItemTypeIndexEnd, "value access by index: unclosed access")
@@ -646,10 +788,10 @@ func parseMethod(ctx *ParsingContext, ch <-chan Item) Token {
 		case ItemTypeMethodName:
 			return selectMethod(ctx, item.v, ch)
 		default:
-			logrus.Fatalf("error parsing method: unable to parse item {%s   %s}", item.t, item.v)
+			failf("error parsing method: unable to parse item {%s   %s}", item.t, item.v)
 		}
 	}
-	logrus.Fatalf("unexpected end while parsing method")
+	failf("unexpected end while parsing method")
 	return nil
 }
 
@@ -690,8 +832,12 @@ func selectMethod(ctx *ParsingContext, name string, ch <-chan Item) Token {
 		return parseMethodOrder(ctx, ch)
 	case MethodTypeReverse:
 		return parseMethodReverse(ctx, ch)
+	case MethodTypeConcat:
+		return parseMethodConcat(ctx, ch)
+	case MethodTypeData:
+		return parseMethodData(ctx, ch)
 	default:
-		logrus.Fatalf("unknown method name %q", name)
+		failf("unknown method name %q", name)
 		return nil
 	}
 }
@@ -723,7 +869,7 @@ func parseMethodMap(ctx *ParsingContext, ch <-chan Item) Token {
 		item = <-ch
 	}
 	if item.t != ItemTypeMethodArgStart {
-		logrus.Fatalf(fmt.Sprintf("method map: empty lambda body: expected %s got %s", ItemTypeMethodArgStart, item.t))
+		failf(fmt.Sprintf("method map: empty lambda body: expected %s got %s", ItemTypeMethodArgStart, item.t))
 	}
 	res.lambda.argument = parseMethodArg(ctx, ch)
 	assertParse(ch, ItemTypeLambdaEnd, "method map: unclosed lambda")
@@ -745,7 +891,7 @@ func parseMethodFilter(ctx *ParsingContext, ch <-chan Item) Token {
 		item = <-ch
 	}
 	if item.t != ItemTypeMethodArgStart {
-		logrus.Fatalf(fmt.Sprintf("method filter: empty lambda body: expected %s got %s", ItemTypeMethodArgStart, item.t))
+		failf(fmt.Sprintf("method filter: empty lambda body: expected %s got %s", ItemTypeMethodArgStart, item.t))
 	}
 	res.lambda.argument = parseMethodArg(ctx, ch)
 	assertParse(ch, ItemTypeLambdaEnd, "method filter: unclosed lambda")
@@ -761,7 +907,7 @@ func parseMethodSlice(_ *ParsingContext, ch <-chan Item) Token {
 	item := assertParse(ch, ItemTypeNumber, "method slice: empty first argument")
 	start, err := strconv.Atoi(item.v)
 	if err != nil {
-		logrus.Fatalf("method slice: first argument must be of type int")
+		failf("method slice: first argument must be of type int")
 	}
 	res.start = &start
 	assertParse(ch, ItemTypeMethodArgEnd, "method slice: unclosed first argument")
@@ -770,12 +916,12 @@ func parseMethodSlice(_ *ParsingContext, ch <-chan Item) Token {
 		return res
 	}
 	if item.t != ItemTypeMethodArgStart {
-		logrus.Fatalf(fmt.Sprintf("method slice: unclosed method: expected %s got %s", ItemTypeMethodEnd, item.t))
+		failf(fmt.Sprintf("method slice: unclosed method: expected %s got %s", ItemTypeMethodEnd, item.t))
 	}
 	item = assertParse(ch, ItemTypeNumber, "method slice: empty second argument")
 	end, err := strconv.Atoi(item.v)
 	if err != nil {
-		logrus.Fatalf("method slice: second argument must be of type int")
+		failf("method slice: second argument must be of type int")
 	}
 	res.end = &end
 	assertParse(ch, ItemTypeMethodArgEnd, "method slice: unclosed first argument")
@@ -790,7 +936,7 @@ func parseMethodSplice(_ *ParsingContext, ch <-chan Item) Token {
 	item := assertParse(ch, ItemTypeNumber, "method splice: empty first argument")
 	start, err := strconv.Atoi(item.v)
 	if err != nil {
-		logrus.Fatalf("method splice: first argument must be of type int")
+		failf("method splice: first argument must be of type int")
 	}
 	res.start = &start
 	assertParse(ch, ItemTypeMethodArgEnd, "method splice: unclosed first argument")
@@ -799,12 +945,12 @@ func parseMethodSplice(_ *ParsingContext, ch <-chan Item) Token {
 		return res
 	}
 	if item.t != ItemTypeMethodArgStart {
-		logrus.Fatalf(fmt.Sprintf("method splice: unclosed method: expected %s got %s", ItemTypeMethodEnd, item.t))
+		failf(fmt.Sprintf("method splice: unclosed method: expected %s got %s", ItemTypeMethodEnd, item.t))
 	}
 	item = assertParse(ch, ItemTypeNumber, "method splice: empty second argument")
 	end, err := strconv.Atoi(item.v)
 	if err != nil {
-		logrus.Fatalf("method splice: second argument must be of type int")
+		failf("method splice: second argument must be of type int")
 	}
 	res.end = &end
 	assertParse(ch, ItemTypeMethodArgEnd, "method splice: unclosed first argument")
@@ -819,7 +965,7 @@ func parseMethodRepeat(_ *ParsingContext, ch <-chan Item) Token {
 	item := assertParse(ch, ItemTypeNumber, "method repeat: first argument must be a number")
 	times, err := strconv.ParseFloat(item.v, 64)
 	if err != nil {
-		logrus.Fatalf("method repeat: first argument is invalid")
+		failf("method repeat: first argument is invalid")
 	}
 	res.times = int(times)
 	assertParse(ch, ItemTypeMethodArgEnd, "method repeat: unclosed first argument")
@@ -874,7 +1020,7 @@ func parseMethodAssert(ctx *ParsingContext, ch <-chan Item) Token {
 		item = <-ch
 	}
 	if item.t != ItemTypeMethodArgStart {
-		logrus.Fatalf(fmt.Sprintf("method assert: empty lambda body: expected %s got %s", ItemTypeMethodArgStart, item.t))
+		failf(fmt.Sprintf("method assert: empty lambda body: expected %s got %s", ItemTypeMethodArgStart, item.t))
 	}
 	res.lambda.argument = parseMethodArg(ctx, ch)
 	assertParse(ch, ItemTypeLambdaEnd, "method assert: unclosed lambda")
@@ -893,7 +1039,7 @@ func parseMethodReplace(ctx *ParsingContext, ch <-chan Item) Token {
 	case ItemTypeStringStart:
 		res.old = parseString(ctx, ch)
 	default:
-		logrus.Fatalf(fmt.Sprintf("method replace: first argument must be a variable reference of template or a string but got %s", item.t))
+		failf(fmt.Sprintf("method replace: first argument must be a variable reference of template or a string but got %s", item.t))
 	}
 	assertParse(ch, ItemTypeMethodArgEnd, "method replace: unclosed first argument")
 	assertParse(ch, ItemTypeMethodArgStart, "method replace: must declare the second argument")
@@ -903,7 +1049,7 @@ func parseMethodReplace(ctx *ParsingContext, ch <-chan Item) Token {
 	case ItemTypeStringStart:
 		res.new = parseString(ctx, ch)
 	default:
-		logrus.Fatalf(fmt.Sprintf("method replace: second argument must be a variable reference of template or a string but got %s", item.t))
+		failf(fmt.Sprintf("method replace: second argument must be a variable reference of template or a string but got %s", item.t))
 	}
 	assertParse(ch, ItemTypeMethodArgEnd, "method replace: unclosed second argument")
 	assertParse(ch, ItemTypeMethodEnd, "method replace: unclosed method")
@@ -920,7 +1066,7 @@ func parseMethodRemove(ctx *ParsingContext, ch <-chan Item) Token {
 	case ItemTypeStringStart:
 		res.argument = parseString(ctx, ch)
 	default:
-		logrus.Fatalf(fmt.Sprintf("method remove: argument must be a variable reference of template or a string but got %s", item.t))
+		failf(fmt.Sprintf("method remove: argument must be a variable reference of template or a string but got %s", item.t))
 	}
 	assertParse(ch, ItemTypeMethodArgEnd, "method remove: unclosed argument")
 	assertParse(ch, ItemTypeMethodEnd, "method remove: unclosed method")
@@ -952,6 +1098,22 @@ func parseMethodReverse(_ *ParsingContext, ch <-chan Item) Token {
 	return res
 }
 
+// parseMethodConcat parses the method concat content.
+func parseMethodConcat(ctx *ParsingContext, ch <-chan Item) Token {
+	res := &MethodConcatToken{}
+	assertParse(ch, ItemTypeMethodArgStart, "method concat: must have one argument")
+	res.argument = parseMethodArg(ctx, ch)
+	assertParse(ch, ItemTypeMethodEnd, "method concat: unclosed method")
+	return res
+}
+
+// parseMethodData parses the method data content.
+func parseMethodData(_ *ParsingContext, ch <-chan Item) Token {
+	res := &MethodDataToken{}
+	assertParse(ch, ItemTypeMethodEnd, "method data: must have no arguments")
+	return res
+}
+
 // parseMethodArg parses a method argument.
 func parseMethodArg(ctx *ParsingContext, ch <-chan Item) Token {
 	return parseKeywordGroup(ctx, ch, ItemTypeMethodArgEnd)
@@ -962,7 +1124,7 @@ func assertParse(ch <-chan Item, t ItemType, m string) Item {
 	if v.t == t {
 		return v
 	}
-	logrus.Fatalf(fmt.Sprintf("%s: expected %s got %s (%s)", m, t, v.t, v.v))
+	failf(fmt.Sprintf("%s: expected %s got %s (%s)", m, t, v.t, v.v))
 	return Item{}
 }
 
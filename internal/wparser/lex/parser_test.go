@@ -149,6 +149,7 @@ func TestEmitterReceiver(t *testing.T) {
 		"%'147':split(''):splice(0,1)%",
 		"%'147':slice(1)%",
 		"%'147':split(''):slice(0,1)%",
+		"%'entering ':concat(print)%",
 	}
 	for _, v := range values {
 		func(code string, v string) {
@@ -161,7 +162,7 @@ func TestEmitterReceiver(t *testing.T) {
 
 				templatesMap, templatesContextMap, searchResultsMap = setupTemplateTest(t)
 
-				res := parse(v, newParsingContext(nil, []wkeyword.KeywordsMap{
+				res, err := parse(v, newParsingContext(nil, nil, []wkeyword.KeywordsMap{
 					wkeyword.NewTemplateResults(
 						&templatesContextMap,
 						&templatesMap,
@@ -187,6 +188,9 @@ func TestEmitterReceiver(t *testing.T) {
 						},
 					),
 				}))
+				if err != nil {
+					t.Fatal(err)
+				}
 				fmt.Printf("\n Input: %q\nOutput: %q\n\n", v, res.Output)
 			})
 		}(moduleCode, v)
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/alecthomas/participle/v2"
+	plexer "github.com/alecthomas/participle/v2/lexer"
 	"github.com/alecthomas/participle/v2/lexer/stateful"
 )
 
@@ -64,6 +65,16 @@ type Method struct {
 	Returns *returnsMethod  `| ( @@ )`
 	Templ   *templateMethod `| ( @@ )`
 	Other   *OtherMethod    `| ( @@ )`
+	Tokens  []plexer.Token
+}
+
+// String returns the method as it was written on the pointcut expression.
+func (m *Method) String() string {
+	var sb strings.Builder
+	for _, token := range m.Tokens {
+		sb.WriteString(token.Value)
+	}
+	return strings.TrimSpace(sb.String())
 }
 
 // funcMethod is the pointcut method func.
@@ -121,6 +132,45 @@ type FuncDefinition struct {
 	Scope      FunctionScope         `("," @("imported" | "exported" | "internal" | "start"))?`
 }
 
+// Variables returns the names of the variables defined by the function definition.
+func (d *FuncDefinition) Variables() []string {
+	var res []string
+	add := func(name string) {
+		if name != "" {
+			res = append(res, name)
+		}
+	}
+	if r := d.ReturnType; r != nil {
+		add(r.Variable)
+		if r.VariableType != nil {
+			add(r.VariableType.Variable)
+		}
+	}
+	if n := d.Name; n != nil {
+		add(n.Variable)
+		if n.VariableRegex != nil {
+			add(n.VariableRegex.Variable)
+		}
+		if n.VariableName != nil {
+			add(n.VariableName.Variable)
+		}
+		if n.VariableIndexName != nil {
+			add(n.VariableIndexName.Variable)
+		}
+		if n.VariableIndex != nil {
+			add(n.VariableIndex.Variable)
+		}
+	}
+	if d.Params != nil {
+		for _, param := range d.Params.Params {
+			if param.Name != nil {
+				add(param.Name.Variable)
+			}
+		}
+	}
+	return res
+}
+
 // FunctionScope consists on a function scope type that implements the Capture for the parser on participle.
 type FunctionScope int
 
@@ -208,9 +258,11 @@ type identifierIndex struct {
 }
 
 // FuncDefinitionReturn is the return definition for the func method.
+// multi-value results are defined as a list of types, ex. (i32, i64).
 type FuncDefinitionReturn struct {
 	Any          AnyTermBoolean    `@( "*" )`
 	Type         string            `| ( "void" | @( WasmType ) )`
+	Types        []string          `| ( "(" @( WasmType ) ( "," @( WasmType ) )* ")" )`
 	Variable     string            `| ( "%" @Identifier "%" )`
 	VariableType *funcVariableType `| ( "%" @@ "%" )`
 }
@@ -266,8 +318,9 @@ type templateMethodInput struct {
 
 // returnsMethodInput represents the input value passed to the returns method
 type returnsMethodInput struct {
-	Any   AnyTermBoolean `@( "*" )`
-	Value string         `| ( "void" | @( WasmType ) )`
+	Any    AnyTermBoolean `@( "*" )`
+	Value  string         `| ( "void" | @( WasmType ) )`
+	Values []string       `| ( "(" @( WasmType ) ( "," @( WasmType ) )* ")" )`
 }
 
 var (
@@ -276,7 +329,7 @@ var (
 		{"Number", `(?:\d*\.)?\d+`, nil},
 		{"Index", `\$[a-zA-Z][\w\d_]*`, nil},
 		{"Regex", `/(\\/|[^/])*/`, nil},
-		{"WasmType", `(i32|i64|f32|f64)`, nil},
+		{"WasmType", `(i32|i64|f32|f64|v128|funcref|externref)`, nil},
 		{"WasmLocalType", `(param|local)`, nil},
 
 		{"Identifier", `[a-zA-Z][\w\d_]*`, nil},
@@ -12,13 +12,23 @@ import (
 )
 
 // Expr is the model for the variable expression.
+// the type blocks are filled from the parsed type parts.
 type Expr struct {
-	Type  []string `@(SimpleType | MapType | ArrayType)+`
-	Value *Value   `("=" @@)?`
+	Parts []*TypePart `@@+`
+	Value *Value      `("=" @@)?`
+	Type  []string
 }
 
 // GetValue returns the variable value as string.
+// the fields of struct variables not defined on the value are initialized with their zero value.
 func (expr *Expr) GetValue(def string) string {
+	if len(expr.Parts) == 1 && expr.Parts[0].Struct != nil {
+		var fields []StructValue
+		if expr.Value != nil {
+			fields = expr.Value.StructValues
+		}
+		return expr.Parts[0].Struct.value(fields)
+	}
 	if expr.Value != nil {
 		return expr.Value.GetValue(0, nil)
 	}
@@ -30,6 +40,80 @@ func (expr *Expr) GetType() string {
 	return strings.Join(expr.Type, "")
 }
 
+// TypePart is the model for each block of the variable type, e.g., the array prefix or a struct.
+type TypePart struct {
+	Simple string      `@(SimpleType | MapType | ArrayType)`
+	Struct *StructType `| @@`
+}
+
+// String returns the type block as string.
+func (part *TypePart) String() string {
+	if part.Struct != nil {
+		return part.Struct.String()
+	}
+	return part.Simple
+}
+
+// StructType is the model for a struct type, whose fields may also be structs.
+type StructType struct {
+	Fields []*StructFieldType `"struct" "{" (@@ ("," @@)*)? "}"`
+}
+
+// String returns the struct type as string, without whitespaces.
+func (st *StructType) String() string {
+	var fields []string
+	for _, field := range st.Fields {
+		fields = append(fields, fmt.Sprintf("%s:%s", field.Name, field.String()))
+	}
+	return fmt.Sprintf("struct{%s}", strings.Join(fields, ","))
+}
+
+// value returns the value of the struct with some fields defined.
+// the remaining fields have their zero value: 0 for primitives, empty strings, empty objects for maps, empty arrays
+// and the zero value of the nested structs.
+func (st *StructType) value(values []StructValue) string {
+	defined := make(map[string]Value, len(values))
+	for _, field := range values {
+		defined[strings.Trim(field.Name, `"`)] = field.Value
+	}
+	var res []string
+	for _, field := range st.Fields {
+		var value string
+		v, ok := defined[field.Name]
+		switch typ := field.String(); {
+		case len(field.Parts) == 1 && field.Parts[0].Struct != nil:
+			value = field.Parts[0].Struct.value(v.StructValues)
+		case ok:
+			value = v.GetFieldValue(0)
+		case typ == "string":
+			value = `""`
+		case strings.HasPrefix(typ, "map["):
+			value = "{}"
+		case strings.HasPrefix(typ, "[]"):
+			value = "[]"
+		default:
+			value = "0"
+		}
+		res = append(res, fmt.Sprintf("%q:%s", field.Name, value))
+	}
+	return fmt.Sprintf("{%s}", strings.Join(res, ","))
+}
+
+// StructFieldType is the model for a field of a struct type.
+type StructFieldType struct {
+	Name  string      `@Ident ":"`
+	Parts []*TypePart `@@+`
+}
+
+// String returns the type of the field as string.
+func (field *StructFieldType) String() string {
+	var res string
+	for _, part := range field.Parts {
+		res += part.String()
+	}
+	return res
+}
+
 // Type represents the several type blocks that forms the variable type.
 type Type []string
 
@@ -49,15 +133,16 @@ func (t *Type) Capture(values []string) error {
 
 // isSimpleType returns if a type is simple or not.
 func isSimpleType(t string) bool {
-	return regexp.MustCompile("^(i32|i64|f32|f64|string)$").MatchString(t)
+	return regexp.MustCompile("^(i32|i64|f32|f64|string|funcref|externref|v128)$").MatchString(t)
 }
 
 // Value is the model for the variable value.
 type Value struct {
-	String      *string      `@String`
-	Number      *float64     `| @Number`
-	ArrayValues []ArrayValue `| "[" @@ ("," @@)* "]"`
-	Empty       string       `| @ArrayType?`
+	String       *string       `@String`
+	Number       *float64      `| @Number`
+	ArrayValues  []ArrayValue  `| "[" @@ ("," @@)* "]"`
+	StructValues []StructValue `| "{" (@@ ("," @@)*)? "}"`
+	Empty        string        `| @ArrayType?`
 }
 
 // GetValue returns the variable value as string.
@@ -84,14 +169,39 @@ func (val *Value) GetValue(index int, parent *Value) string {
 		}
 		return fmt.Sprintf("[%s]", strings.Join(res, ","))
 	}
+	if val.StructValues != nil {
+		var res []string
+		for _, field := range val.StructValues {
+			res = append(res, fmt.Sprintf("%s:%s", field.Name, field.Value.GetFieldValue(index)))
+		}
+		return fmt.Sprintf("{%s}", strings.Join(res, ","))
+	}
 	return ""
 }
 
+// GetFieldValue returns the value of a struct field as string.
+// unlike the other values, numbers equal to zero are kept.
+func (val *Value) GetFieldValue(index int) string {
+	if val.Number != nil {
+		return strconv.FormatFloat(*val.Number, 'f', -1, 64)
+	}
+	if res := strings.TrimSpace(val.GetValue(index, val)); res != "" {
+		return res
+	}
+	return "null"
+}
+
 // Value represents a key-value entry.
 type ArrayValue struct {
 	Values []Value `@@ ("," @@)*`
 }
 
+// StructValue represents a struct field entry.
+type StructValue struct {
+	Name  string `@String`
+	Value Value  `":" @@`
+}
+
 // KeyValue represents a key-value entry.
 type KeyValue struct {
 	Key   Value `"[" @@`
@@ -101,8 +211,9 @@ type KeyValue struct {
 var (
 	lexer = stateful.MustSimple([]stateful.Rule{
 		{"String", `"(\\"|[^"])*"`, nil},
-		{"SimpleType", `i32|i64|f32|f64|string`, nil},
+		{"SimpleType", `(i32|i64|f32|f64|string|funcref|externref|v128)\b`, nil},
 		{"MapType", `map\[(i32|i64|f32|f64|string)\]`, nil},
+		{"Ident", `[a-zA-Z_]\w*`, nil},
 		{"ArrayType", `\[\]`, nil},
 		{"Number", `(?:\d*\.)?\d+`, nil},
 		{"Punct", `[-[!@#$%^&*()+_={}\|:;"'<,>.?/]|]`, nil},
@@ -123,5 +234,8 @@ func Parse(expr string) (*Expr, error) {
 	if err != nil {
 		return nil, fmt.Errorf("parsing input expression: %w", err)
 	}
+	for _, part := range ast.Parts {
+		ast.Type = append(ast.Type, part.String())
+	}
 	return ast, nil
 }
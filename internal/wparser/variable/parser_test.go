@@ -0,0 +1,35 @@
+package variable
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		typ   string
+		value string
+	}{
+		{"i32 = 1", "i32", "1"},
+		{`[]string = ["a", "b"]`, "[]string", `["a","b"]`},
+		{`map[string]i32`, "map[string]i32", ""},
+		{`struct{a:i32, s:string} = {"a": 2}`, "struct{a:i32,s:string}", `{"a":2,"s":""}`},
+		{`struct { a: i32, b: struct{c: i32, d: []i32} }`, "struct{a:i32,b:struct{c:i32,d:[]i32}}", `{"a":0,"b":{"c":0,"d":[]}}`},
+		{`struct{a:i32,b:struct{c:i32}} = {"b": {"c": 3}}`, "struct{a:i32,b:struct{c:i32}}", `{"a":0,"b":{"c":3}}`},
+		{`[]struct{i32s:[]i32,stringValue:string}`, "[]struct{i32s:[]i32,stringValue:string}", ""},
+	} {
+		expr, err := Parse(test.input)
+		if err != nil {
+			t.Errorf("%s: %v", test.input, err)
+			continue
+		}
+		if typ := expr.GetType(); typ != test.typ {
+			t.Errorf("%s: expected type %s, got %s", test.input, test.typ, typ)
+		}
+		if value := expr.GetValue(""); value != test.value {
+			t.Errorf("%s: expected value %s, got %s", test.input, test.value, value)
+		}
+	}
+
+	if _, err := Parse("struct{a:i32,b:struct{c:i32}"); err == nil {
+		t.Error("expected error for an unclosed struct")
+	}
+}
@@ -3,6 +3,8 @@ package wpointcut
 import (
 	"fmt"
 	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/shivamMg/ppds/tree"
 	"github.com/sirupsen/logrus"
@@ -16,11 +18,21 @@ import (
 type Node interface {
 	tree.Node
 	Filter(in *PointcutContext) *PointcutContext
+	instance() *NodeInstance
 }
 
 // newPointcutNode is a constructor for Node.
-// the pointcut node is selected accordingly to the block type.
+// the pointcut node is selected accordingly to the block type, keeping the method as it was written.
 func newPointcutNode(pp *ParsedPointcut, blockType NodeType, block *pointcut.Method, joinPointParams map[string]ParsedParam) Node {
+	node := newMethodNode(pp, blockType, block, joinPointParams)
+	if node != nil {
+		node.instance().text = block.String()
+	}
+	return node
+}
+
+// newMethodNode returns the node for some pointcut method.
+func newMethodNode(pp *ParsedPointcut, blockType NodeType, block *pointcut.Method, joinPointParams map[string]ParsedParam) Node {
 	switch {
 	case block.Func != nil:
 		return newFuncNode(block.Func.Name, blockType, block.Func.Input)
@@ -30,8 +42,12 @@ func newPointcutNode(pp *ParsedPointcut, blockType NodeType, block *pointcut.Met
 		return newArgsNode(block.Args.Name, blockType, block.Args.Input, joinPointParams)
 	case block.Returns != nil:
 		var returnType *string
-		if !block.Returns.Input.Any {
-			returnType = &block.Returns.Input.Value
+		if input := block.Returns.Input; !input.Any {
+			returnType = &input.Value
+			if len(input.Values) > 0 {
+				value := strings.Join(input.Values, " ")
+				returnType = &value
+			}
 		}
 		return newReturnsNode(block.Returns.Name, blockType, returnType)
 	case block.Templ != nil:
@@ -135,6 +151,48 @@ func (pp *ParsedPointcut) parseExpression(expr []pointcut.Instruction, stacks *p
 	return popBlock(&stacks.blockStack)
 }
 
+// Describe returns the description of the pointcut after being parsed, with the parameters sorted by name.
+func (pp *ParsedPointcut) Describe() *PointcutDescription {
+	expr := pp.expr
+	if expr == nil {
+		expr = pp.ParseExpression()
+	}
+	params := make([]ParsedParam, 0, len(pp.Params))
+	for _, param := range pp.Params {
+		params = append(params, param)
+	}
+	sort.Slice(params, func(i, j int) bool {
+		return params[i].Name < params[j].Name
+	})
+	return &PointcutDescription{Params: params, Expression: describeNode(expr)}
+}
+
+// PointcutDescription describes a parsed pointcut.
+type PointcutDescription struct {
+	Params     []ParsedParam
+	Expression *ExpressionNode
+}
+
+// ExpressionNode describes a node of a parsed pointcut expression.
+// the operations (and, or) have the operands as children, while the named pointcuts have their expression as the only child.
+type ExpressionNode struct {
+	Type     string
+	Method   string
+	Children []*ExpressionNode
+}
+
+// describeNode returns the description of some node and its children.
+func describeNode(node Node) *ExpressionNode {
+	switch n := node.(type) {
+	case *OperationNode:
+		return &ExpressionNode{Type: n.name, Children: []*ExpressionNode{describeNode(n.left), describeNode(n.right)}}
+	case *otherMethodNode:
+		return &ExpressionNode{Type: "pointcut", Method: n.text, Children: []*ExpressionNode{describeNode(n.Expr)}}
+	default:
+		return &ExpressionNode{Type: node.instance().name, Method: node.instance().text}
+	}
+}
+
 // ParsedParam contains the data for a pointcut parameter already parsed.
 type ParsedParam struct {
 	Name     string
@@ -146,6 +204,7 @@ type ParsedParam struct {
 // NodeInstance is the base model for any node instance.
 type NodeInstance struct {
 	name     string
+	text     string
 	nodeType NodeType
 	left     Node
 	right    Node
@@ -166,6 +225,11 @@ func (n NodeInstance) String() string {
 	return fmt.Sprintf("{Type: %q, Name: %+v}", n.nodeType, n.name)
 }
 
+// instance returns the node instance.
+func (n *NodeInstance) instance() *NodeInstance {
+	return n
+}
+
 // Data returns the node itself.
 func (n NodeInstance) Data() interface{} {
 	return n
@@ -321,6 +385,9 @@ func resolvePointcutFunctionReturn(inReturn *pointcut.FuncDefinitionReturn) *fun
 	switch {
 	case ret.Type != "":
 		res.Type = &ret.Type
+	case len(ret.Types) > 0:
+		value := strings.Join(ret.Types, " ")
+		res.Type = &value
 	case ret.Variable != "":
 		res.Variable = &ret.Variable
 	case ret.VariableType != nil:
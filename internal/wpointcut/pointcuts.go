@@ -300,12 +300,12 @@ func (node *templateNode) Filter(in *PointcutContext) *PointcutContext {
 			for i, b := range jp.blocks {
 				wg.Add(1)
 				go func(i int, b *wcode.JoinPointBlock) {
+					defer wg.Done()
 					if res, ok := node.findResults(in, b); ok {
 						ch <- &templateNodeFilterAux{i, res}
 					} else {
 						ch <- &templateNodeFilterAux{i, []*wcode.JoinPointBlock{}}
 					}
-					wg.Done()
 				}(i, b)
 			}
 			wg.Wait()
@@ -1,21 +1,39 @@
 package wyaml
 
 import (
+	"encoding/json"
 	"fmt"
+	"path/filepath"
+	"strings"
+
 	"gopkg.in/yaml.v2"
 
 	"joao/wasm-manipulator/pkg/wfile"
 )
 
-// Read reads a yaml file and returns the content on a transformation model.
+// Read reads a yaml or json file and returns the content on a transformation model.
+// the format is chosen by the file extension, the json files have the same keys as the yaml ones.
 func Read(filename string) (*BaseYAML, error) {
-	yamlContent, err := wfile.ReadFile(filename)
+	content, err := wfile.ReadFile(filename)
 	if err != nil {
 		return nil, fmt.Errorf("reading yaml input content: %w", err)
 	}
 	var data BaseYAML
-	if err := yaml.Unmarshal([]byte(yamlContent), &data); err != nil {
+	if err := unmarshal(filename, content, &data); err != nil {
 		return nil, fmt.Errorf("unmarshal yaml input content: %w", err)
 	}
 	return &data, nil
 }
+
+// isJSON returns if some transformation file uses the json format.
+func isJSON(filename string) bool {
+	return strings.EqualFold(filepath.Ext(filename), ".json")
+}
+
+// unmarshal decodes the content of some transformation file, according to its format.
+func unmarshal(filename, content string, data *BaseYAML) error {
+	if isJSON(filename) {
+		return json.Unmarshal([]byte(content), data)
+	}
+	return yaml.Unmarshal([]byte(content), data)
+}
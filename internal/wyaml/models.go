@@ -22,6 +22,8 @@ type AdviceYAML struct {
 	Order     *int
 	All       bool
 	Smart     bool
+	Shared    bool
+	Pooled    bool
 }
 
 // ContextYAML contains the context data.
@@ -30,6 +32,13 @@ type AdviceYAML struct {
 type ContextYAML struct {
 	Variables map[string]string
 	Functions map[string]FunctionYAML
+	Memories  map[string]MemoryYAML
+	Tables    map[string]TableYAML
+	Data      map[string]DataYAML
+	Elems     map[string]ElemYAML
+	Exports   map[string]ExportYAML
+	Imports   map[string]ImportYAML
+	Clones    map[string]CloneYAML
 }
 
 // CountImportedFunctions counts the number of imported function in context.
@@ -44,15 +53,28 @@ func (c *ContextYAML) CountImportedFunctions() int {
 }
 
 // FunctionYAML contains the function definition data.
+// the result is a shorthand for functions with a single result.
 type FunctionYAML struct {
 	Variables map[string]string
 	Args      []FunctionArgYAML
-	Result    string
+	Result    string   `yaml:",omitempty"`
+	Results   []string `yaml:",omitempty"`
 	Code      string
 	Imported  *FunctionImportYAML `yaml:",omitempty"`
 	Exported  *string             `yaml:",omitempty"`
 }
 
+// ResultTypes returns the result types of the function.
+func (f *FunctionYAML) ResultTypes() []string {
+	if len(f.Results) > 0 {
+		return f.Results
+	}
+	if f.Result != "" {
+		return []string{f.Result}
+	}
+	return nil
+}
+
 // FunctionArgYAML represents a function argument.
 type FunctionArgYAML struct {
 	Name string
@@ -64,3 +86,59 @@ type FunctionImportYAML struct {
 	Module string
 	Field  string
 }
+
+// MemoryYAML contains the memory definition data.
+type MemoryYAML struct {
+	Min      int
+	Max      *int    `yaml:",omitempty"`
+	Exported *string `yaml:",omitempty"`
+}
+
+// TableYAML contains the table definition data.
+type TableYAML struct {
+	Min      int
+	Max      *int `yaml:",omitempty"`
+	Type     string
+	Exported *string `yaml:",omitempty"`
+}
+
+// DataYAML contains the data segment definition data.
+// the memory is optional, using the first memory of the module by default.
+type DataYAML struct {
+	Memory string
+	Offset int
+	Value  string
+}
+
+// ElemYAML contains the element segment definition data.
+// the table is optional, using the first table of the module by default.
+type ElemYAML struct {
+	Table     string
+	Offset    int
+	Functions []string
+}
+
+// ExportYAML contains the rewriting directives for some exported function.
+// the key of the directive is the export name.
+type ExportYAML struct {
+	Rename string   `yaml:",omitempty"`
+	Remove bool     `yaml:",omitempty"`
+	Alias  []string `yaml:",omitempty"`
+}
+
+// ImportYAML contains the rewriting directives for some imported function.
+// the import can be redirected to another module and field, or implemented by a context function.
+type ImportYAML struct {
+	Module    string
+	Field     string
+	Redirect  *FunctionImportYAML `yaml:",omitempty"`
+	Implement string              `yaml:",omitempty"`
+}
+
+// CloneYAML contains the definition of a function clone.
+// the arguments with a constant value are removed from the clone signature (specialization).
+type CloneYAML struct {
+	Function string
+	Args     map[string]string `yaml:",omitempty"`
+	Exported *string           `yaml:",omitempty"`
+}
@@ -0,0 +1,157 @@
+package wyaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// schemaVersion is the JSON Schema draft used by the transformation schema.
+const schemaVersion = "http://json-schema.org/draft-07/schema#"
+
+const (
+	// primitiveTypes are the types that can be used by the variables and functions of the context.
+	primitiveTypes = "i32|i64|f32|f64|v128|funcref|externref|string"
+	// typePattern matches the primitive, array, map and struct types.
+	// only the start of the composite types is matched, since they can be nested, so their elements are validated by the check command.
+	typePattern = `(` + primitiveTypes + `|\[\]\s*\S[\s\S]*|map\s*\[\s*(i32|i64|f32|f64|string)\s*\]\s*\S[\s\S]*|struct\s*\{[\s\S]*\})`
+	// pointcutPattern matches the pointcut syntax, i.e., the parameters followed by the instructions.
+	pointcutPattern = `^\s*\([^()]*\)\s*=>\s*\S[\s\S]*$`
+	// variablePattern matches the variable declarations, i.e., the type with an optional value.
+	variablePattern = `^\s*` + typePattern + `(\s*=[\s\S]*)?\s*$`
+)
+
+// Schema is a JSON Schema value.
+type Schema map[string]interface{}
+
+// schemaOverrides contains the schemas with the constraints that are not expressed by the model types.
+// the schemas are identified by the model name and the field key.
+var schemaOverrides = map[string]Schema{
+	"BaseYAML.templates": {
+		"description":          "Templates used by the pointcuts, by name.",
+		"type":                 "object",
+		"additionalProperties": Schema{"type": "string"},
+	},
+	"BaseYAML.pointcuts": {
+		"description":          "Pointcuts that can be used by the advices, by name.",
+		"type":                 "object",
+		"additionalProperties": Schema{"type": "string", "pattern": pointcutPattern},
+	},
+	"AdviceYAML.pointcut": {
+		"description": "Join-points where the advice code is applied.",
+		"type":        "string",
+		"pattern":     pointcutPattern,
+	},
+	"AdviceYAML.variables":   variablesSchema(),
+	"ContextYAML.variables":  variablesSchema(),
+	"FunctionYAML.variables": variablesSchema(),
+	"FunctionYAML.result":    valueTypeSchema(),
+	"FunctionYAML.results": {
+		"type":  "array",
+		"items": valueTypeSchema(),
+	},
+	"FunctionArgYAML.type": valueTypeSchema(),
+	"TableYAML.type": {
+		"type": "string",
+		"enum": []string{"funcref", "externref"},
+	},
+}
+
+// schemaRequired contains the required fields of the models.
+var schemaRequired = map[string][]string{
+	"AdviceYAML": {"pointcut"},
+	"CloneYAML":  {"function"},
+}
+
+// GenerateSchema returns the JSON Schema of the transformation file, generated from the models.
+// the nested models are stored as definitions, referred by their name.
+func GenerateSchema() Schema {
+	definitions := make(Schema)
+	root := structSchema(reflect.TypeOf(BaseYAML{}), definitions)
+	root["$schema"] = schemaVersion
+	root["title"] = "WebAssembly module transformation"
+	root["definitions"] = definitions
+	return root
+}
+
+// JSON returns the schema encoded as indented json.
+func (s Schema) JSON() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(s); err != nil {
+		return nil, fmt.Errorf("encoding schema: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// typeSchema returns the schema of some model type.
+// the structs are added to the definitions, being referred by the returned schema.
+func typeSchema(typ reflect.Type, definitions Schema) Schema {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Struct:
+		name := typ.Name()
+		if _, ok := definitions[name]; !ok {
+			// Store first, avoiding cycles.
+			definitions[name] = nil
+			definitions[name] = structSchema(typ, definitions)
+		}
+		return Schema{"$ref": "#/definitions/" + name}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": typeSchema(typ.Elem(), definitions)}
+	case reflect.Slice:
+		return Schema{"type": "array", "items": typeSchema(typ.Elem(), definitions)}
+	case reflect.Int:
+		return Schema{"type": "integer"}
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	default:
+		return Schema{"type": "string"}
+	}
+}
+
+// structSchema returns the schema of some model struct.
+// unknown fields are not allowed.
+func structSchema(typ reflect.Type, definitions Schema) Schema {
+	properties := make(Schema)
+	for key, field := range modelFields(typ) {
+		if override, ok := schemaOverrides[typ.Name()+"."+key]; ok {
+			properties[key] = override
+			continue
+		}
+		properties[key] = typeSchema(field.Type, definitions)
+	}
+	res := Schema{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if required, ok := schemaRequired[typ.Name()]; ok {
+		res["required"] = required
+	}
+	return res
+}
+
+// valueTypeSchema returns the schema of the types of the arguments, results and variables.
+func valueTypeSchema() Schema {
+	return Schema{
+		"anyOf": []Schema{
+			{"type": "string", "enum": strings.Split(primitiveTypes, "|")},
+			{"type": "string", "pattern": "^" + typePattern + "$"},
+		},
+	}
+}
+
+// variablesSchema returns the schema of the variable declarations, by name.
+func variablesSchema() Schema {
+	return Schema{
+		"type":                 "object",
+		"additionalProperties": Schema{"type": "string", "pattern": variablePattern},
+	}
+}
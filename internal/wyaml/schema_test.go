@@ -0,0 +1,100 @@
+package wyaml
+
+import (
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestGenerateSchema(t *testing.T) {
+	data, err := GenerateSchema().JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := ioutil.ReadFile("../../transformation.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(expected) {
+		t.Error("transformation.schema.json is outdated, run: wmr schema transformation.schema.json")
+	}
+
+	pointcut := regexp.MustCompile(pointcutPattern)
+	for value, valid := range map[string]bool{
+		"(i32.param[0] p) => func(* %fname% (..))": true,
+		"() => call(log)":                          true,
+		"func(* %fname% (..))":                     false,
+	} {
+		if pointcut.MatchString(value) != valid {
+			t.Errorf("pointcut %q: expected valid %v", value, valid)
+		}
+	}
+	variable := regexp.MustCompile(variablePattern)
+	for value, valid := range map[string]bool{
+		"i32 = 0":                          true,
+		"map[string]i32":                   true,
+		"struct{a i32} = {}":               true,
+		"int = 0":                          false,
+		"[][]i32":                          true,
+		"[]map[string]i32":                 true,
+		"[]struct{a:i32}":                  true,
+		"struct{a:struct{b:i32}, c:[]i32}": true,
+		"map[i32][]struct{a:i32} = {}":     true,
+		"map[bool]i32":                     false,
+		"i32 i64":                          false,
+	} {
+		if variable.MatchString(value) != valid {
+			t.Errorf("variable %q: expected valid %v", value, valid)
+		}
+	}
+}
+
+func TestReadSourceJSON(t *testing.T) {
+	file, err := ioutil.TempFile("", "transformation-*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(jsonInput); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	input, _, issues, err := ReadSource(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, issue := range issues {
+		got = append(got, issue.String())
+	}
+	expected := `7:5: error: unknown field "Smart" on "aspects.advices.a1", did you mean "smart"?`
+	if strings.Join(got, "\n") != expected {
+		t.Errorf("expected issues:\n%s\ngot:\n%s", expected, strings.Join(got, "\n"))
+	}
+	if advice := input.Aspects.Advices["a1"]; advice.Pointcut != "() => call(log)" || advice.Advice != "nop" {
+		t.Errorf("unexpected advice %+v", advice)
+	}
+
+	if err := ioutil.WriteFile(file.Name(), []byte(`{"aspects": {`+"\n"+`"advices": }}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, issues, _ := ReadSource(file.Name()); len(issues) != 1 || issues[0].Line != 2 {
+		t.Errorf("expected a syntax issue on line 2, got %v", issues)
+	}
+}
+
+const jsonInput = `{
+	"aspects": {
+		"advices": {
+			"a1": {
+				"pointcut": "() => call(log)",
+				"advice": "nop",
+				"Smart": true
+			}
+		}
+	}
+}
+`
@@ -0,0 +1,249 @@
+package wyaml
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	yamlv2 "gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
+
+	"joao/wasm-manipulator/pkg/wfile"
+)
+
+// Severity is the severity of some issue.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// yamlErrorLineRegex matches the line of the yaml syntax errors.
+var yamlErrorLineRegex = regexp.MustCompile(`line (\d+)`)
+
+// Position is the position of some value on the yaml file.
+type Position struct {
+	Line   int
+	Column int
+}
+
+// Issue is a problem found on the transformation yaml.
+type Issue struct {
+	Position
+	Severity Severity
+	Message  string
+}
+
+// String returns the issue in the format line:column: severity: message.
+func (issue *Issue) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s", issue.Line, issue.Column, issue.Severity, issue.Message)
+}
+
+// Source contains the positions of the values on the yaml file.
+// the values are identified by their path, with the keys separated by dots (e.g. aspects.advices.a1.pointcut).
+type Source struct {
+	Filename  string
+	positions map[string]Position
+}
+
+// Position returns the position of the value with some path.
+// the position of the closest parent is returned when the path is not found.
+func (s *Source) Position(path ...string) Position {
+	for i := len(path); i > 0; i-- {
+		if pos, ok := s.positions[strings.Join(path[:i], ".")]; ok {
+			return pos
+		}
+	}
+	return s.positions[""]
+}
+
+// Issue returns an issue on the value with some path.
+func (s *Source) Issue(severity Severity, message string, path ...string) *Issue {
+	return &Issue{Position: s.Position(path...), Severity: severity, Message: message}
+}
+
+// ReadSource reads a yaml or json file and returns the content on a transformation model, with the positions of its values.
+// the structure of the file is validated against the model, the syntax and structural issues are returned.
+func ReadSource(filename string) (*BaseYAML, *Source, []*Issue, error) {
+	content, err := wfile.ReadFile(filename)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("reading yaml input content: %w", err)
+	}
+	return ParseSource(filename, content)
+}
+
+// ParseSource parses the content of a yaml or json file, as ReadSource.
+// the filename is only used to choose the format.
+func ParseSource(filename, content string) (*BaseYAML, *Source, []*Issue, error) {
+	source := &Source{Filename: filename, positions: map[string]Position{"": {Line: 1, Column: 1}}}
+
+	if isJSON(filename) {
+		if issue := jsonSyntaxIssue(content); issue != nil {
+			return nil, source, []*Issue{issue}, nil
+		}
+	}
+	// The json files are also parsed as yaml, keeping the positions of their values.
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &root); err != nil {
+		issue := &Issue{Position: Position{Line: 1, Column: 1}, Severity: SeverityError, Message: err.Error()}
+		if match := yamlErrorLineRegex.FindStringSubmatch(err.Error()); match != nil {
+			issue.Line, _ = strconv.Atoi(match[1])
+		}
+		return nil, source, []*Issue{issue}, nil
+	}
+	var issues []*Issue
+	if len(root.Content) > 0 {
+		issues = validateNode(source, root.Content[0], reflect.TypeOf(BaseYAML{}), nil)
+	}
+
+	// The model is filled as on the execution, ignoring the type errors already found.
+	var data BaseYAML
+	if err := unmarshal(filename, content, &data); err != nil {
+		var yamlErr *yamlv2.TypeError
+		var jsonErr *json.UnmarshalTypeError
+		if !errors.As(err, &yamlErr) && !errors.As(err, &jsonErr) {
+			return nil, nil, nil, fmt.Errorf("unmarshal yaml input content: %w", err)
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Line < issues[j].Line
+	})
+	return &data, source, issues, nil
+}
+
+// jsonSyntaxIssue returns the issue of some json content with invalid syntax, or nil if it is valid.
+func jsonSyntaxIssue(content string) *Issue {
+	var value interface{}
+	err := json.Unmarshal([]byte(content), &value)
+	if err == nil {
+		return nil
+	}
+	issue := &Issue{Position: Position{Line: 1, Column: 1}, Severity: SeverityError, Message: err.Error()}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		issue.Position = offsetPosition(content, syntaxErr.Offset)
+	}
+	return issue
+}
+
+// offsetPosition returns the position of some byte offset on the content.
+func offsetPosition(content string, offset int64) Position {
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+	before := content[:offset]
+	line := strings.Count(before, "\n") + 1
+	return Position{Line: line, Column: int(offset) - strings.LastIndex(before, "\n")}
+}
+
+// validateNode stores the positions of some node and validates it against the model type.
+func validateNode(source *Source, node *yaml.Node, typ reflect.Type, path []string) []*Issue {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Tag == "!!null" {
+		return nil
+	}
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	pathStr := strings.Join(path, ".")
+	if _, ok := source.positions[pathStr]; !ok {
+		source.positions[pathStr] = Position{Line: node.Line, Column: node.Column}
+	}
+	newIssue := func(message string) []*Issue {
+		return []*Issue{{Position: Position{Line: node.Line, Column: node.Column}, Severity: SeverityError, Message: message}}
+	}
+
+	var issues []*Issue
+	switch typ.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return newIssue(fmt.Sprintf("%s must be an object", pathDescription(path)))
+		}
+		fields := modelFields(typ)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			keyPath := append(append([]string{}, path...), key.Value)
+			source.positions[strings.Join(keyPath, ".")] = Position{Line: key.Line, Column: key.Column}
+			field, ok := fields[key.Value]
+			if !ok {
+				message := fmt.Sprintf("unknown field %q on %s", key.Value, pathDescription(path))
+				for name := range fields {
+					if strings.EqualFold(name, key.Value) {
+						message = fmt.Sprintf("%s, did you mean %q?", message, name)
+					}
+				}
+				issues = append(issues, &Issue{Position: Position{Line: key.Line, Column: key.Column}, Severity: SeverityError, Message: message})
+				continue
+			}
+			issues = append(issues, validateNode(source, value, field.Type, keyPath)...)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return newIssue(fmt.Sprintf("%s must be a map", pathDescription(path)))
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			keyPath := append(append([]string{}, path...), key.Value)
+			source.positions[strings.Join(keyPath, ".")] = Position{Line: key.Line, Column: key.Column}
+			issues = append(issues, validateNode(source, value, typ.Elem(), keyPath)...)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return newIssue(fmt.Sprintf("%s must be a list", pathDescription(path)))
+		}
+		for i, item := range node.Content {
+			itemPath := append(append([]string{}, path...), strconv.Itoa(i))
+			issues = append(issues, validateNode(source, item, typ.Elem(), itemPath)...)
+		}
+	case reflect.Int:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			return newIssue(fmt.Sprintf("%s must be an integer", pathDescription(path)))
+		}
+	case reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			return newIssue(fmt.Sprintf("%s must be a boolean", pathDescription(path)))
+		}
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode {
+			return newIssue(fmt.Sprintf("%s must be a string", pathDescription(path)))
+		}
+	}
+	return issues
+}
+
+// modelFields returns the fields of some model by their yaml key.
+// the key is the one defined on the yaml tag or, by default, the field name lowercased.
+func modelFields(typ reflect.Type) map[string]reflect.StructField {
+	res := make(map[string]reflect.StructField)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		res[name] = field
+	}
+	return res
+}
+
+// pathDescription returns the description of some value path for the issue messages.
+func pathDescription(path []string) string {
+	if len(path) == 0 {
+		return "the transformation"
+	}
+	return strconv.Quote(strings.Join(path, "."))
+}
@@ -16,7 +16,7 @@ import (
 type CmdFlags int
 
 const (
-	RunCommandSilent CmdFlags = iota >> 1
+	RunCommandSilent CmdFlags = 1 << iota
 	RunCommandNoFoldWatExpr
 )
 
@@ -54,9 +54,15 @@ func ConvertWatToWasmFile(inFilename, outFilename string, flags ...CmdFlags) (st
 	return outFilename, nil
 }
 
-// ConvertWatToWasm converts wasm file into wat file.
+// ConvertWasmToWat converts wasm file into wat file.
 func ConvertWasmToWat(inFilename string, flags ...CmdFlags) (string, error) {
 	outFilename := ReplaceExt(inFilename, ".wat")
+	return ConvertWasmToWatFile(inFilename, outFilename, flags...)
+}
+
+// ConvertWasmToWatFile converts wasm file into wat file.
+// the expressions are folded unless the no fold flag is provided.
+func ConvertWasmToWatFile(inFilename, outFilename string, flags ...CmdFlags) (string, error) {
 	argsFlags := []string{inFilename, "--no-check", "--generate-names"}
 	if !hasFlag(RunCommandNoFoldWatExpr, flags) {
 		argsFlags = append(argsFlags, "--fold-exprs")
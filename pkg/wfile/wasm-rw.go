@@ -39,6 +39,24 @@ func PrintWasmCode(code, outputFilename string) error {
 	return nil
 }
 
+// PrintFlatWatCode prints a module to a flat (non-folded) wat file.
+func PrintFlatWatCode(code, outputFilename string) error {
+	watFilename, err := SaveWat(code)
+	defer func() { logError(DeleteFile(watFilename)) }()
+	if err != nil {
+		return fmt.Errorf("saving web assembly textual code: %w", err)
+	}
+	wasmFilename, err := ConvertWatToWasm(watFilename)
+	defer func() { logError(DeleteFile(wasmFilename)) }()
+	if err != nil {
+		return fmt.Errorf("converting temporary file %q to binary: %w", watFilename, err)
+	}
+	if _, err := ConvertWasmToWatFile(wasmFilename, outputFilename, RunCommandNoFoldWatExpr); err != nil {
+		return err
+	}
+	return nil
+}
+
 // logError logs an error to the output logger.
 func logError(err error) {
 	if err != nil {
@@ -0,0 +1,783 @@
+package whost
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Scope resolves the identifiers used by the runtime expressions.
+type Scope func(name string) (interface{}, error)
+
+// ExpressionEngine is implemented by the engines able to evaluate runtime expressions.
+// values are represented by nil, bool, float64, string, []interface{} and map[string]interface{}.
+type ExpressionEngine interface {
+	Evaluate(expression string, scope Scope) (interface{}, error)
+}
+
+// expressionEngine is the default expression engine.
+// it supports a subset of the javascript expressions (literals, members, unary, binary, logical and conditional operators).
+type expressionEngine struct{}
+
+// NewExpressionEngine is a constructor for the default ExpressionEngine.
+func NewExpressionEngine() ExpressionEngine {
+	return expressionEngine{}
+}
+
+// Evaluate evaluates the expression.
+func (expressionEngine) Evaluate(expression string, scope Scope) (interface{}, error) {
+	tokens, err := tokenizeExpression(expression)
+	if err != nil {
+		return nil, fmt.Errorf("reading expression %q: %w", expression, err)
+	}
+	parser := newExpressionParser(tokens)
+	node, err := parser.parse()
+	if err != nil {
+		return nil, fmt.Errorf("parsing expression %q: %w", expression, err)
+	}
+	res, err := node.eval(scope)
+	if err != nil {
+		return nil, fmt.Errorf("evaluating expression %q: %w", expression, err)
+	}
+	return res, nil
+}
+
+// exprTokenType represents the type of an expression token.
+type exprTokenType int
+
+const (
+	exprTokenNumber exprTokenType = iota
+	exprTokenString
+	exprTokenIdentifier
+	exprTokenPunct
+	exprTokenEnd
+)
+
+// exprToken contains the data of an expression token.
+type exprToken struct {
+	typ   exprTokenType
+	value string
+	pos   int
+}
+
+// exprPuncts contains the punctuators ordered by length (longest first).
+var exprPuncts = []string{
+	"===", "!==",
+	"==", "!=", "<=", ">=", "&&", "||",
+	"(", ")", "[", "]", "{", "}", ",", ":", "?", ".", "!", "+", "-", "*", "/", "%", "<", ">",
+}
+
+// tokenizeExpression splits the expression into tokens.
+func tokenizeExpression(expression string) ([]exprToken, error) {
+	var tokens []exprToken
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i++; i < len(runes); i++ {
+				c := runes[i]
+				if unicode.IsDigit(c) || unicode.IsLetter(c) || c == '.' {
+					continue
+				}
+				isHex := strings.HasPrefix(strings.ToLower(string(runes[start:i])), "0x")
+				if (c == '+' || c == '-') && (runes[i-1] == 'e' || runes[i-1] == 'E') && !isHex {
+					continue
+				}
+				break
+			}
+			tokens = append(tokens, exprToken{exprTokenNumber, string(runes[start:i]), start})
+		case r == '"' || r == '\'':
+			start := i
+			var sb strings.Builder
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					switch runes[i] {
+					case 'n':
+						sb.WriteRune('\n')
+					case 't':
+						sb.WriteRune('\t')
+					case 'r':
+						sb.WriteRune('\r')
+					default:
+						sb.WriteRune(runes[i])
+					}
+					continue
+				}
+				sb.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, exprToken{exprTokenString, sb.String(), start})
+		case isIdentifierRune(r, true) || r == '#':
+			start := i
+			for i++; i < len(runes) && isIdentifierRune(runes[i], false); i++ {
+			}
+			tokens = append(tokens, exprToken{exprTokenIdentifier, string(runes[start:i]), start})
+		default:
+			var found bool
+			for _, punct := range exprPuncts {
+				if strings.HasPrefix(string(runes[i:]), punct) {
+					tokens = append(tokens, exprToken{exprTokenPunct, punct, i})
+					i += len([]rune(punct))
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("unexpected character %q at position %d", r, i)
+			}
+		}
+	}
+	return append(tokens, exprToken{exprTokenEnd, "", len(runes)}), nil
+}
+
+// isIdentifierRune returns if the rune can be used on an identifier.
+func isIdentifierRune(r rune, first bool) bool {
+	if r == '_' || r == '$' || unicode.IsLetter(r) {
+		return true
+	}
+	return !first && unicode.IsDigit(r)
+}
+
+// exprNode is implemented by the nodes of the expression tree.
+type exprNode interface {
+	eval(scope Scope) (interface{}, error)
+}
+
+// expressionParser is responsible for parsing the expression tokens into an expression tree.
+type expressionParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+// newExpressionParser is a constructor for expressionParser.
+func newExpressionParser(tokens []exprToken) *expressionParser {
+	return &expressionParser{tokens: tokens}
+}
+
+// parse parses the complete expression.
+func (p *expressionParser) parse() (exprNode, error) {
+	node, err := p.parseConditional()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.typ != exprTokenEnd {
+		return nil, fmt.Errorf("unexpected token %q at position %d", tok.value, tok.pos)
+	}
+	return node, nil
+}
+
+// peek returns the current token.
+func (p *expressionParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+// next returns the current token and moves to the next one.
+func (p *expressionParser) next() exprToken {
+	tok := p.tokens[p.pos]
+	if tok.typ != exprTokenEnd {
+		p.pos++
+	}
+	return tok
+}
+
+// acceptPunct moves to the next token if the current one is some of the punctuators.
+func (p *expressionParser) acceptPunct(puncts ...string) (string, bool) {
+	tok := p.peek()
+	if tok.typ != exprTokenPunct {
+		return "", false
+	}
+	for _, punct := range puncts {
+		if tok.value == punct {
+			p.pos++
+			return punct, true
+		}
+	}
+	return "", false
+}
+
+// expectPunct requires the current token to be the punctuator.
+func (p *expressionParser) expectPunct(punct string) error {
+	if _, ok := p.acceptPunct(punct); !ok {
+		tok := p.peek()
+		return fmt.Errorf("expected %q at position %d but got %q", punct, tok.pos, tok.value)
+	}
+	return nil
+}
+
+// parseConditional parses the conditional (ternary) operator.
+func (p *expressionParser) parseConditional() (exprNode, error) {
+	test, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.acceptPunct("?"); !ok {
+		return test, nil
+	}
+	consequent, err := p.parseConditional()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectPunct(":"); err != nil {
+		return nil, err
+	}
+	alternate, err := p.parseConditional()
+	if err != nil {
+		return nil, err
+	}
+	return &conditionalNode{test, consequent, alternate}, nil
+}
+
+// binaryOperators contains the binary operators ordered by precedence (lowest first).
+var binaryOperators = [][]string{
+	{"||"},
+	{"&&"},
+	{"===", "!==", "==", "!="},
+	{"<=", ">=", "<", ">"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+// parseBinary parses the binary operators with some precedence level.
+func (p *expressionParser) parseBinary(level int) (exprNode, error) {
+	if level >= len(binaryOperators) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptPunct(binaryOperators[level]...)
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op, left, right}
+	}
+}
+
+// parseUnary parses the unary operators.
+func (p *expressionParser) parseUnary() (exprNode, error) {
+	if op, ok := p.acceptPunct("!", "-", "+"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op, operand}, nil
+	}
+	return p.parseMember()
+}
+
+// parseMember parses the member accesses.
+func (p *expressionParser) parseMember() (exprNode, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch punct, _ := p.acceptPunct(".", "["); punct {
+		case ".":
+			tok := p.next()
+			if tok.typ != exprTokenIdentifier {
+				return nil, fmt.Errorf("expected member name at position %d but got %q", tok.pos, tok.value)
+			}
+			node = &memberNode{node, &literalNode{tok.value}}
+		case "[":
+			property, err := p.parseConditional()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectPunct("]"); err != nil {
+				return nil, err
+			}
+			node = &memberNode{node, property}
+		default:
+			return node, nil
+		}
+	}
+}
+
+// parsePrimary parses literals, identifiers, groups, arrays and objects.
+func (p *expressionParser) parsePrimary() (exprNode, error) {
+	tok := p.next()
+	switch tok.typ {
+	case exprTokenNumber:
+		value, err := parseNumberLiteral(tok.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.value, tok.pos)
+		}
+		return &literalNode{value}, nil
+	case exprTokenString:
+		return &literalNode{tok.value}, nil
+	case exprTokenIdentifier:
+		switch tok.value {
+		case "true":
+			return &literalNode{true}, nil
+		case "false":
+			return &literalNode{false}, nil
+		case "null", "undefined":
+			return &literalNode{nil}, nil
+		case "NaN":
+			return &literalNode{math.NaN()}, nil
+		case "Infinity":
+			return &literalNode{math.Inf(1)}, nil
+		}
+		return &identifierNode{strings.TrimPrefix(tok.value, "#")}, nil
+	case exprTokenPunct:
+		switch tok.value {
+		case "(":
+			node, err := p.parseConditional()
+			if err != nil {
+				return nil, err
+			}
+			return node, p.expectPunct(")")
+		case "[":
+			return p.parseArray()
+		case "{":
+			return p.parseObject()
+		}
+	}
+	return nil, fmt.Errorf("unexpected token %q at position %d", tok.value, tok.pos)
+}
+
+// parseArray parses an array literal.
+func (p *expressionParser) parseArray() (exprNode, error) {
+	node := new(arrayNode)
+	for {
+		if _, ok := p.acceptPunct("]"); ok {
+			return node, nil
+		}
+		elem, err := p.parseConditional()
+		if err != nil {
+			return nil, err
+		}
+		node.elems = append(node.elems, elem)
+		if _, ok := p.acceptPunct(","); !ok {
+			return node, p.expectPunct("]")
+		}
+	}
+}
+
+// parseObject parses an object literal.
+func (p *expressionParser) parseObject() (exprNode, error) {
+	node := new(objectNode)
+	for {
+		if _, ok := p.acceptPunct("}"); ok {
+			return node, nil
+		}
+		tok := p.next()
+		if tok.typ != exprTokenIdentifier && tok.typ != exprTokenString && tok.typ != exprTokenNumber {
+			return nil, fmt.Errorf("invalid object key %q at position %d", tok.value, tok.pos)
+		}
+		if err := p.expectPunct(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.parseConditional()
+		if err != nil {
+			return nil, err
+		}
+		node.keys = append(node.keys, tok.value)
+		node.values = append(node.values, value)
+		if _, ok := p.acceptPunct(","); !ok {
+			return node, p.expectPunct("}")
+		}
+	}
+}
+
+// parseNumberLiteral parses a numeric literal.
+func parseNumberLiteral(value string) (float64, error) {
+	if strings.HasPrefix(strings.ToLower(value), "0x") {
+		n, err := strconv.ParseInt(value[2:], 16, 64)
+		return float64(n), err
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// literalNode represents a literal value.
+type literalNode struct {
+	value interface{}
+}
+
+// eval evaluates the node.
+func (node *literalNode) eval(Scope) (interface{}, error) {
+	return node.value, nil
+}
+
+// identifierNode represents an identifier resolved by the scope.
+type identifierNode struct {
+	name string
+}
+
+// eval evaluates the node.
+func (node *identifierNode) eval(scope Scope) (interface{}, error) {
+	if scope == nil {
+		return nil, fmt.Errorf("value for %s not found", node.name)
+	}
+	return scope(node.name)
+}
+
+// arrayNode represents an array literal.
+type arrayNode struct {
+	elems []exprNode
+}
+
+// eval evaluates the node.
+func (node *arrayNode) eval(scope Scope) (interface{}, error) {
+	res := make([]interface{}, 0, len(node.elems))
+	for _, elem := range node.elems {
+		value, err := elem.eval(scope)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, value)
+	}
+	return res, nil
+}
+
+// objectNode represents an object literal.
+type objectNode struct {
+	keys   []string
+	values []exprNode
+}
+
+// eval evaluates the node.
+func (node *objectNode) eval(scope Scope) (interface{}, error) {
+	res := make(map[string]interface{}, len(node.keys))
+	for i, key := range node.keys {
+		value, err := node.values[i].eval(scope)
+		if err != nil {
+			return nil, err
+		}
+		res[key] = value
+	}
+	return res, nil
+}
+
+// memberNode represents a member access.
+type memberNode struct {
+	object   exprNode
+	property exprNode
+}
+
+// eval evaluates the node.
+func (node *memberNode) eval(scope Scope) (interface{}, error) {
+	object, err := node.object.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	property, err := node.property.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	key := jsToString(property)
+	switch o := object.(type) {
+	case nil:
+		return nil, fmt.Errorf("cannot read property %s of null", key)
+	case string:
+		if key == "length" {
+			return float64(len([]rune(o))), nil
+		}
+		if index, ok := jsIndex(property); ok && index < len([]rune(o)) {
+			return string([]rune(o)[index]), nil
+		}
+	case []interface{}:
+		if key == "length" {
+			return float64(len(o)), nil
+		}
+		if index, ok := jsIndex(property); ok && index < len(o) {
+			return o[index], nil
+		}
+	case map[string]interface{}:
+		return o[key], nil
+	}
+	return nil, nil
+}
+
+// unaryNode represents an unary operation.
+type unaryNode struct {
+	op      string
+	operand exprNode
+}
+
+// eval evaluates the node.
+func (node *unaryNode) eval(scope Scope) (interface{}, error) {
+	value, err := node.operand.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	switch node.op {
+	case "!":
+		return !jsTruthy(value), nil
+	case "-":
+		return -jsToNumber(value), nil
+	default:
+		return jsToNumber(value), nil
+	}
+}
+
+// binaryNode represents a binary operation.
+type binaryNode struct {
+	op    string
+	left  exprNode
+	right exprNode
+}
+
+// eval evaluates the node.
+func (node *binaryNode) eval(scope Scope) (interface{}, error) {
+	left, err := node.left.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	// Short-circuit operators.
+	switch node.op {
+	case "&&":
+		if !jsTruthy(left) {
+			return left, nil
+		}
+		return node.right.eval(scope)
+	case "||":
+		if jsTruthy(left) {
+			return left, nil
+		}
+		return node.right.eval(scope)
+	}
+	right, err := node.right.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	switch node.op {
+	case "+":
+		if isJsStringLike(left) || isJsStringLike(right) {
+			return jsToString(left) + jsToString(right), nil
+		}
+		return jsToNumber(left) + jsToNumber(right), nil
+	case "-":
+		return jsToNumber(left) - jsToNumber(right), nil
+	case "*":
+		return jsToNumber(left) * jsToNumber(right), nil
+	case "/":
+		return jsToNumber(left) / jsToNumber(right), nil
+	case "%":
+		return math.Mod(jsToNumber(left), jsToNumber(right)), nil
+	case "==":
+		return jsLooseEquals(left, right), nil
+	case "!=":
+		return !jsLooseEquals(left, right), nil
+	case "===":
+		return jsStrictEquals(left, right), nil
+	case "!==":
+		return !jsStrictEquals(left, right), nil
+	case "<", "<=", ">", ">=":
+		return jsCompare(node.op, left, right), nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", node.op)
+}
+
+// conditionalNode represents a conditional (ternary) operation.
+type conditionalNode struct {
+	test       exprNode
+	consequent exprNode
+	alternate  exprNode
+}
+
+// eval evaluates the node.
+func (node *conditionalNode) eval(scope Scope) (interface{}, error) {
+	test, err := node.test.eval(scope)
+	if err != nil {
+		return nil, err
+	}
+	if jsTruthy(test) {
+		return node.consequent.eval(scope)
+	}
+	return node.alternate.eval(scope)
+}
+
+// jsTruthy returns if the value is truthy.
+func jsTruthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	case string:
+		return v != ""
+	default:
+		return true
+	}
+}
+
+// jsToNumber converts the value into a number.
+func jsToNumber(value interface{}) float64 {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	case float64:
+		return v
+	case string:
+		v = strings.TrimSpace(v)
+		if v == "" {
+			return 0
+		}
+		n, err := parseNumberLiteral(v)
+		if err != nil {
+			return math.NaN()
+		}
+		return n
+	case []interface{}:
+		if len(v) == 0 {
+			return 0
+		}
+		if len(v) == 1 {
+			return jsToNumber(jsToString(v[0]))
+		}
+	}
+	return math.NaN()
+}
+
+// jsToString converts the value into a string.
+func jsToString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return formatNumber(v)
+	case string:
+		return v
+	case []interface{}:
+		res := make([]string, len(v))
+		for i, elem := range v {
+			if elem != nil {
+				res[i] = jsToString(elem)
+			}
+		}
+		return strings.Join(res, ",")
+	default:
+		return "[object Object]"
+	}
+}
+
+// formatNumber formats a number the same way as javascript.
+func formatNumber(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "Infinity"
+	case math.IsInf(v, -1):
+		return "-Infinity"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// jsIndex returns the value as an index.
+func jsIndex(value interface{}) (int, bool) {
+	n := jsToNumber(value)
+	if math.IsNaN(n) || n < 0 || n != math.Trunc(n) {
+		return 0, false
+	}
+	return int(n), true
+}
+
+// isJsStringLike returns if the value is converted into a string on additions.
+func isJsStringLike(value interface{}) bool {
+	switch value.(type) {
+	case string, []interface{}, map[string]interface{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// jsLooseEquals returns if the values are equal (==).
+func jsLooseEquals(left, right interface{}) bool {
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+	ls, lok := left.(string)
+	rs, rok := right.(string)
+	if lok && rok {
+		return ls == rs
+	}
+	if isJsStringLike(left) && isJsStringLike(right) {
+		return false
+	}
+	if !isJsPrimitive(left) || !isJsPrimitive(right) {
+		return jsToString(left) == jsToString(right)
+	}
+	return jsToNumber(left) == jsToNumber(right)
+}
+
+// jsStrictEquals returns if the values are strictly equal (===).
+func jsStrictEquals(left, right interface{}) bool {
+	switch l := left.(type) {
+	case nil:
+		return right == nil
+	case bool, float64, string:
+		return l == right
+	default:
+		return false
+	}
+}
+
+// isJsPrimitive returns if the value is a primitive value.
+func isJsPrimitive(value interface{}) bool {
+	switch value.(type) {
+	case nil, bool, float64, string:
+		return true
+	default:
+		return false
+	}
+}
+
+// jsCompare compares two values with a relational operator.
+func jsCompare(op string, left, right interface{}) bool {
+	ls, lok := left.(string)
+	rs, rok := right.(string)
+	var cmp int
+	if lok && rok {
+		cmp = strings.Compare(ls, rs)
+	} else {
+		l, r := jsToNumber(left), jsToNumber(right)
+		if math.IsNaN(l) || math.IsNaN(r) {
+			return false
+		}
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	}
+	switch op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
@@ -0,0 +1,467 @@
+package whost
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ValueType represents a web assembly value type.
+type ValueType string
+
+const (
+	ValueTypeI32 ValueType = "i32"
+	ValueTypeI64 ValueType = "i64"
+	ValueTypeF32 ValueType = "f32"
+	ValueTypeF64 ValueType = "f64"
+)
+
+// HostFunction contains the definition of a function implemented by the host and imported by the module.
+// the values are encoded as uint64 (see the Encode* and Decode* functions).
+type HostFunction struct {
+	Module  string
+	Name    string
+	Params  []ValueType
+	Results []ValueType
+	Call    func(params []uint64) []uint64
+}
+
+// Binder is implemented by the web assembly runtimes able to register host functions.
+type Binder interface {
+	Bind(fn *HostFunction) error
+}
+
+// BinderFunc is an adapter to allow the use of ordinary functions as binders.
+type BinderFunc func(fn *HostFunction) error
+
+// Bind registers the host function.
+func (f BinderFunc) Bind(fn *HostFunction) error {
+	return f(fn)
+}
+
+// Argument contains the definition of an argument of a transformed function.
+type Argument struct {
+	Type      ValueType
+	Composite bool
+	Code      int
+}
+
+// PrimitiveArgument is a constructor for Argument.
+// creates an argument of primitive type.
+func PrimitiveArgument(typ ValueType) Argument {
+	return Argument{Type: typ}
+}
+
+// CompositeArgument is a constructor for Argument.
+// creates an argument of composite type with some type code.
+func CompositeArgument(code int) Argument {
+	return Argument{Composite: true, Code: code}
+}
+
+// FunctionDefinition contains the definition of a transformed function with composite arguments or result.
+type FunctionDefinition struct {
+	Name            string
+	Args            []Argument
+	Result          ValueType
+	CompositeResult bool
+}
+
+// params returns the types of the primitive arguments.
+func (def FunctionDefinition) params() []ValueType {
+	var res []ValueType
+	for _, arg := range def.Args {
+		if !arg.Composite {
+			res = append(res, arg.Type)
+		}
+	}
+	return res
+}
+
+// results returns the types of the primitive result.
+func (def FunctionDefinition) results() []ValueType {
+	if def.Result == "" || def.CompositeResult {
+		return nil
+	}
+	return []ValueType{def.Result}
+}
+
+// ImportFunction is the host implementation of an imported function with composite arguments or result.
+// primitive arguments are int32, int64, float32 or float64 and composite arguments are host values.
+type ImportFunction func(args []interface{}) (interface{}, error)
+
+// ExportedFunction calls a function exported by the module.
+type ExportedFunction func(params []uint64) ([]uint64, error)
+
+// ErrorContext contains the provenance data of a runtime expression.
+// the contexts are generated with the transformed module (see the error contexts on the javascript glue).
+type ErrorContext struct {
+	ID            int
+	Advice        string
+	Function      string
+	FunctionIndex int
+	Expression    string
+}
+
+// RuntimeError is an error found while running the import modules.
+// it contains the provenance data of the last runtime expression started by the module.
+type RuntimeError struct {
+	Err           error
+	ExpressionID  int
+	Advice        string
+	Function      string
+	FunctionIndex int
+	Expression    string
+}
+
+// Error returns the error message.
+func (err *RuntimeError) Error() string {
+	if err.ExpressionID == noIndex {
+		return err.Err.Error()
+	}
+	return fmt.Sprintf("%v (advice: %q, function: %s, index: %d, expression: %d)",
+		err.Err, err.Advice, err.Function, err.FunctionIndex, err.ExpressionID)
+}
+
+// Unwrap returns the underlying error.
+func (err *RuntimeError) Unwrap() error {
+	return err.Err
+}
+
+// Environment contains the state of the runtime import modules (operations, args, zone, returns and error).
+type Environment struct {
+	args          *argsModule
+	zone          *zoneModule
+	operations    *operationsModule
+	returns       *returnsModule
+	error         *errorModule
+	engine        ExpressionEngine
+	onError       func(err *RuntimeError)
+	trap          bool
+	errorContexts map[int]ErrorContext
+	imports       []*HostFunction
+}
+
+// NewEnvironment is a constructor for Environment.
+func NewEnvironment() *Environment {
+	env := &Environment{
+		engine: NewExpressionEngine(),
+		onError: func(err *RuntimeError) {
+			logrus.Error(err)
+		},
+		errorContexts: make(map[int]ErrorContext),
+	}
+	env.args = newArgsModule(env, nil)
+	env.zone = newZoneModule(env, nil)
+	env.operations = newOperationsModule(env)
+	env.returns = newReturnsModule(env)
+	env.error = newErrorModule(env)
+	return env
+}
+
+// SetExpressionEngine sets the engine used to evaluate the runtime expressions.
+func (env *Environment) SetExpressionEngine(engine ExpressionEngine) {
+	env.engine = engine
+}
+
+// SetErrorHandler sets the handler of the errors found while running the import modules.
+func (env *Environment) SetErrorHandler(handler func(err *RuntimeError)) {
+	env.onError = handler
+}
+
+// SetErrorContexts sets the provenance data of the runtime expressions of the module.
+func (env *Environment) SetErrorContexts(contexts []ErrorContext) {
+	env.errorContexts = make(map[int]ErrorContext, len(contexts))
+	for _, errCtx := range contexts {
+		env.errorContexts[errCtx.ID] = errCtx
+	}
+}
+
+// SetTrap sets if the module must trap on runtime errors.
+// the trap is done by panicking with the *RuntimeError after the error is handled.
+func (env *Environment) SetTrap(trap bool) {
+	env.trap = trap
+}
+
+// Variable returns the value of a variable visible on the current zone.
+func (env *Environment) Variable(name string) (interface{}, bool) {
+	variable := env.zone.getVar(name)
+	if variable == nil {
+		return nil, false
+	}
+	return variable.Value(), true
+}
+
+// Import registers the host implementation of an imported function with composite arguments or result.
+// the function is imported by the transformed module from the operations module.
+func (env *Environment) Import(def FunctionDefinition, fn ImportFunction) {
+	params := def.params()
+	env.imports = append(env.imports, env.newHostFunction(moduleOperations, def.Name, params, def.results(),
+		func(raw []uint64) ([]uint64, error) {
+			args := make([]interface{}, len(def.Args))
+			var primitiveIndex int
+			for i, arg := range def.Args {
+				if !arg.Composite {
+					args[i] = decodeArgument(arg.Type, raw[primitiveIndex])
+					primitiveIndex++
+					continue
+				}
+				variable, err := env.args.getArg(i)
+				if err != nil {
+					return nil, err
+				}
+				args[i] = variable.Value()
+			}
+			res, err := fn(args)
+			if err != nil {
+				return nil, err
+			}
+			if def.CompositeResult {
+				value, err := normalizeValue(res)
+				if err != nil {
+					return nil, err
+				}
+				env.returns.pushVar(newDynamicVariable(value))
+				return nil, nil
+			}
+			if def.Result == "" {
+				return nil, nil
+			}
+			n, ok := toNumber(res)
+			if !ok {
+				return nil, fmt.Errorf("result %v is not valid for type %s", res, def.Result)
+			}
+			return []uint64{encodeNumber(def.Result, n)}, nil
+		}))
+}
+
+// CallExported calls a function exported by the module with composite arguments or result.
+// composite arguments are passed to the module through the args module.
+func (env *Environment) CallExported(def FunctionDefinition, fn ExportedFunction, args ...interface{}) (interface{}, error) {
+	if len(args) != len(def.Args) {
+		return nil, fmt.Errorf("%s expects %d argument(s) but got %d", def.Name, len(def.Args), len(args))
+	}
+	env.pushArgs()
+	params, err := env.exportedParams(def, args)
+	var results []uint64
+	if err == nil {
+		results, err = fn(params)
+	}
+	if popErr := env.popArgs(); popErr != nil {
+		return nil, fmt.Errorf("calling %s: %w", def.Name, popErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if def.CompositeResult {
+		res, err := env.returns.shiftVar()
+		if err != nil {
+			return nil, err
+		}
+		return res.Value(), nil
+	}
+	if def.Result == "" || len(results) == 0 {
+		return nil, nil
+	}
+	return decodeArgument(def.Result, results[0]), nil
+}
+
+// exportedParams returns the primitive parameters of an exported function call.
+// composite arguments are added to the current arguments scope instead.
+func (env *Environment) exportedParams(def FunctionDefinition, args []interface{}) ([]uint64, error) {
+	var params []uint64
+	for i, arg := range def.Args {
+		if !arg.Composite {
+			n, ok := toNumber(args[i])
+			if !ok {
+				return nil, fmt.Errorf("argument %d of %s: value %v is not valid for type %s", i, def.Name, args[i], arg.Type)
+			}
+			params = append(params, encodeNumber(arg.Type, n))
+			continue
+		}
+		if err := env.args.addArg(arg.Code, args[i], i); err != nil {
+			return nil, fmt.Errorf("argument %d of %s: %w", i, def.Name, err)
+		}
+	}
+	return params, nil
+}
+
+// Bind registers every host function using some binder.
+func (env *Environment) Bind(binder Binder) error {
+	for _, fn := range env.Functions() {
+		if err := binder.Bind(fn); err != nil {
+			return fmt.Errorf("binding %s.%s: %w", fn.Module, fn.Name, err)
+		}
+	}
+	return nil
+}
+
+// pushArgs creates a new arguments scope.
+func (env *Environment) pushArgs() {
+	env.args = newArgsModule(env, env.args)
+}
+
+// popArgs removes the current arguments scope.
+func (env *Environment) popArgs() error {
+	if env.args.parent == nil {
+		return errors.New("removing arguments: arguments stack is empty")
+	}
+	env.args = env.args.parent
+	return nil
+}
+
+// pushZone creates a new zone.
+func (env *Environment) pushZone() {
+	env.zone = newZoneModule(env, env.zone)
+}
+
+// popZone removes the current zone.
+func (env *Environment) popZone() error {
+	if env.zone.parent == nil {
+		return errors.New("removing zone: zones stack is empty")
+	}
+	env.zone = env.zone.parent
+	return nil
+}
+
+// reportError reports an error found while running the import modules.
+// the module traps if it is required.
+func (env *Environment) reportError(err error) {
+	rtErr := &RuntimeError{Err: err, ExpressionID: env.error.expressionID}
+	if errCtx, ok := env.errorContexts[rtErr.ExpressionID]; ok {
+		rtErr.Advice = errCtx.Advice
+		rtErr.Function = errCtx.Function
+		rtErr.FunctionIndex = errCtx.FunctionIndex
+		rtErr.Expression = errCtx.Expression
+	}
+	if env.onError != nil {
+		env.onError(rtErr)
+	}
+	if env.trap {
+		panic(rtErr)
+	}
+}
+
+// newHostFunction is a constructor for HostFunction.
+// the errors returned by the implementation are reported and the results are zeroed.
+func (env *Environment) newHostFunction(module, name string, params, results []ValueType, call func(params []uint64) ([]uint64, error)) *HostFunction {
+	return &HostFunction{
+		Module:  module,
+		Name:    name,
+		Params:  params,
+		Results: results,
+		Call: func(params []uint64) []uint64 {
+			res, err := call(params)
+			if err != nil {
+				env.reportError(fmt.Errorf("%s.%s: %w", module, name, err))
+				res = nil
+			}
+			if len(res) != len(results) {
+				res = make([]uint64, len(results))
+			}
+			return res
+		},
+	}
+}
+
+// normalizeValue converts a host value into the representation used by the expressions.
+func normalizeValue(value interface{}) (interface{}, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("converting value %v: %w", value, err)
+	}
+	var res interface{}
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil, fmt.Errorf("converting value %v: %w", value, err)
+	}
+	return res, nil
+}
+
+// EncodeI32 encodes an i32 value.
+func EncodeI32(v int32) uint64 {
+	return uint64(uint32(v))
+}
+
+// DecodeI32 decodes an i32 value.
+func DecodeI32(v uint64) int32 {
+	return int32(uint32(v))
+}
+
+// EncodeI64 encodes an i64 value.
+func EncodeI64(v int64) uint64 {
+	return uint64(v)
+}
+
+// DecodeI64 decodes an i64 value.
+func DecodeI64(v uint64) int64 {
+	return int64(v)
+}
+
+// EncodeF32 encodes an f32 value.
+func EncodeF32(v float32) uint64 {
+	return uint64(math.Float32bits(v))
+}
+
+// DecodeF32 decodes an f32 value.
+func DecodeF32(v uint64) float32 {
+	return math.Float32frombits(uint32(v))
+}
+
+// EncodeF64 encodes an f64 value.
+func EncodeF64(v float64) uint64 {
+	return math.Float64bits(v)
+}
+
+// DecodeF64 decodes an f64 value.
+func DecodeF64(v uint64) float64 {
+	return math.Float64frombits(v)
+}
+
+// decodeNumber decodes a value as a number.
+func decodeNumber(typ ValueType, v uint64) float64 {
+	switch typ {
+	case ValueTypeI64:
+		return float64(DecodeI64(v))
+	case ValueTypeF32:
+		return float64(DecodeF32(v))
+	case ValueTypeF64:
+		return DecodeF64(v)
+	default:
+		return float64(DecodeI32(v))
+	}
+}
+
+// decodeArgument decodes a value as the go type of the value type.
+func decodeArgument(typ ValueType, v uint64) interface{} {
+	switch typ {
+	case ValueTypeI64:
+		return DecodeI64(v)
+	case ValueTypeF32:
+		return DecodeF32(v)
+	case ValueTypeF64:
+		return DecodeF64(v)
+	default:
+		return DecodeI32(v)
+	}
+}
+
+// encodeNumber encodes a number as some value type.
+// integers are truncated and wrapped the same way as the javascript conversion.
+func encodeNumber(typ ValueType, n float64) uint64 {
+	switch typ {
+	case ValueTypeF32:
+		return EncodeF32(float32(n))
+	case ValueTypeF64:
+		return EncodeF64(n)
+	}
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0
+	}
+	n = math.Trunc(n)
+	if typ == ValueTypeI64 {
+		return EncodeI64(int64(n))
+	}
+	return EncodeI32(int32(uint32(int64(math.Mod(n, 1<<32)))))
+}
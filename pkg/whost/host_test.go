@@ -0,0 +1,286 @@
+package whost
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"joao/wasm-manipulator/internal/wgenerator"
+)
+
+func TestHost_FunctionsSignatures(t *testing.T) {
+	fns := make(map[string]*HostFunction)
+	for _, fn := range NewEnvironment().Functions() {
+		fns[fn.Module+"."+fn.Name] = fn
+	}
+	var defs []*wgenerator.ImportFunctionDef
+	defs = append(defs, wgenerator.OperationFunctions()...)
+	defs = append(defs, wgenerator.ArgsFunctions()...)
+	defs = append(defs, wgenerator.ZoneFunctions()...)
+	defs = append(defs, wgenerator.ReturnsFunctions()...)
+	defs = append(defs, wgenerator.ErrorFunctions()...)
+	for _, def := range defs {
+		name := def.ModuleName + "." + def.ExportedName
+		fn, ok := fns[name]
+		if !ok {
+			t.Errorf("host function %s not implemented", name)
+			continue
+		}
+		var params, results []string
+		for _, param := range fn.Params {
+			params = append(params, string(param))
+		}
+		for _, result := range fn.Results {
+			results = append(results, string(result))
+		}
+		var expectedResults []string
+		if def.Result != "" {
+			expectedResults = []string{def.Result}
+		}
+		if !reflect.DeepEqual(params, def.Params) && (len(params) != 0 || len(def.Params) != 0) {
+			t.Errorf("host function %s: expected params %v, got %v", name, def.Params, params)
+		}
+		if !reflect.DeepEqual(results, expectedResults) {
+			t.Errorf("host function %s: expected results %v, got %v", name, expectedResults, results)
+		}
+	}
+}
+
+func TestHost_ZoneEvaluation(t *testing.T) {
+	env := NewEnvironment()
+	env.SetErrorHandler(func(err *RuntimeError) {
+		t.Error(err)
+	})
+	module := newTestingModule(env)
+
+	// (local i32 $a = 5) and ([]i32 $arr = [1,2,3]) on the zone.
+	module.call("zone.new", EncodeI32(2))
+	module.writeString("zone.write_name", "a")
+	module.call("zone.write_value", EncodeI32(5))
+	module.call("zone.set")
+	module.call("zone.new", EncodeI32(162))
+	module.writeString("zone.write_name", "arr")
+	module.writeString("zone.write_value", "[1,2,3]")
+	module.call("zone.set")
+
+	// Primitive evaluation.
+	module.evaluate("#a * 2 + #arr[1]", 0)
+	if res := DecodeI32(module.call("operations.read", EncodeI32(0))[0]); res != 12 {
+		t.Errorf("expected 12, got %d", res)
+	}
+	module.call("operations.clear")
+
+	// Composite evaluation copied to a member of the array.
+	module.call("zone.new_copy")
+	module.writeString("zone.copy_name", "arr")
+	module.writeString("zone.copy_key", "[#a]")
+	module.evaluate("#arr[0] > 0 ? 'ok'.length : -1", 0)
+	module.call("zone.copy_operation", EncodeI32(0))
+	module.call("operations.clear")
+	if value, _ := env.Variable("arr"); !reflect.DeepEqual(value, []interface{}{1.0, 2.0, 3.0, 0.0, 0.0, 2.0}) {
+		t.Errorf("unexpected array value %v", value)
+	}
+
+	// Composite return through the returns module.
+	module.call("returns.new_copy")
+	module.writeString("returns.copy_name", "arr")
+	module.call("returns.copy_var")
+	module.evaluate("return_.length + ' items'", 1)
+	module.call("returns.copy_operation", EncodeI32(0))
+	module.call("operations.clear")
+	res, err := env.returns.shiftVar()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Value() != "6 items" {
+		t.Errorf("expected \"6 items\", got %v", res.Value())
+	}
+}
+
+func TestHost_StructVariable(t *testing.T) {
+	env := NewEnvironment()
+	env.SetErrorHandler(func(err *RuntimeError) {
+		t.Error(err)
+	})
+	module := newTestingModule(env)
+
+	// (local struct{name:string,count:i32} $rec) on the zone.
+	module.call("zone.new", EncodeI32(11))
+	module.writeString("zone.write_name", "rec")
+	module.writeString("zone.write_value", `{"name":"a","count":1}`)
+	module.call("zone.set")
+
+	// Field copied from an evaluation (#rec.count).
+	module.call("zone.new_copy")
+	module.writeString("zone.copy_name", "rec")
+	module.writeString("zone.copy_key", `"count"`)
+	module.evaluate("#rec.count + 1", 0)
+	module.call("zone.copy_operation", EncodeI32(0))
+	module.call("operations.clear")
+	expected := map[string]interface{}{"name": "a", "count": 2.0}
+	if value, _ := env.Variable("rec"); !reflect.DeepEqual(value, expected) {
+		t.Errorf("expected %v, got %v", expected, value)
+	}
+}
+
+func TestHost_ImportAndExport(t *testing.T) {
+	env := NewEnvironment()
+	env.SetErrorHandler(func(err *RuntimeError) {
+		t.Error(err)
+	})
+	def := FunctionDefinition{
+		Name:            "concat",
+		Args:            []Argument{CompositeArgument(149), PrimitiveArgument(ValueTypeI32)},
+		CompositeResult: true,
+	}
+	env.Import(def, func(args []interface{}) (interface{}, error) {
+		values := args[0].(map[string]interface{})
+		return []interface{}{values["k"], values["other"], args[1]}, nil
+	})
+	module := newTestingModule(env)
+
+	// The exported function sends its composite parameter to the imported function.
+	res, err := env.CallExported(def, func(params []uint64) ([]uint64, error) {
+		module.call("zone.push")
+		module.call("zone.new_copy")
+		module.writeString("zone.copy_name", "p")
+		module.call("zone.copy_arg", EncodeI32(0))
+		module.evaluate("#p", 1)
+		module.call("args.push")
+		module.call("args.new_copy")
+		module.call("args.copy_index", EncodeI32(0))
+		module.call("args.copy_operation", EncodeI32(0))
+		module.call("operations.clear")
+		module.call("operations.concat", params...)
+		module.call("args.pop")
+		module.call("zone.pop")
+		return nil, nil
+	}, map[string]string{"k": "v", "other": "w"}, int32(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res, []interface{}{"v", "w", 7.0}) {
+		t.Errorf("unexpected result %v", res)
+	}
+}
+
+func TestHost_Expressions(t *testing.T) {
+	scope := func(name string) (interface{}, error) {
+		return map[string]interface{}{
+			"x":  3.0,
+			"$s": "abc",
+			"m":  map[string]interface{}{"k": []interface{}{1.0, 2.0}},
+		}[name], nil
+	}
+	tests := []struct {
+		expr     string
+		expected interface{}
+	}{
+		{"1 + 2 * 3", 7.0},
+		{"(1 + 2) * 3 % 4", 1.0},
+		{"#x >= 3 && !false", true},
+		{"#$s + #x", "abc3"},
+		{"#m.k[1] + #m['k'].length", 4.0},
+		{"#x == '3' && #x !== '3'", true},
+		{"null || 'default'", "default"},
+		{"[1, {a: 2}][1].a", 2.0},
+		{"#x > 2 ? 'big' : 'small'", "big"},
+		{"-#x + 0x10", 13.0},
+	}
+	engine := NewExpressionEngine()
+	for _, test := range tests {
+		res, err := engine.Evaluate(test.expr, scope)
+		if err != nil {
+			t.Errorf("%s: %v", test.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(res, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.expr, test.expected, res)
+		}
+	}
+	if _, err := engine.Evaluate("1 +", scope); err == nil {
+		t.Error("expected error for an invalid expression")
+	}
+}
+
+func TestHost_RuntimeError(t *testing.T) {
+	env := NewEnvironment()
+	env.SetErrorContexts([]ErrorContext{
+		{ID: 3, Advice: "log_calls", Function: "$f2", FunctionIndex: 5, Expression: "#missing + 1"},
+	})
+	var reported *RuntimeError
+	env.SetErrorHandler(func(err *RuntimeError) {
+		reported = err
+	})
+	module := newTestingModule(env)
+
+	module.call("error.context", EncodeI32(3))
+	module.evaluate("#missing + 1", evaluateResultPrimitive)
+	if reported == nil {
+		t.Fatal("expected runtime error to be reported")
+	}
+	if reported.ExpressionID != 3 || reported.Advice != "log_calls" || reported.Function != "$f2" || reported.FunctionIndex != 5 {
+		t.Errorf("unexpected runtime error context: %+v", reported)
+	}
+
+	env.SetTrap(true)
+	defer func() {
+		if _, ok := recover().(*RuntimeError); !ok {
+			t.Error("expected module to trap with a runtime error")
+		}
+	}()
+	module.call("operations.new_code", EncodeI32(0))
+	module.writeString("operations.write", "#missing")
+	module.call("operations.evaluate")
+}
+
+// testingModule simulates the calls of a transformed module to the host functions.
+type testingModule struct {
+	fns []*HostFunction
+}
+
+// newTestingModule is a constructor for testingModule.
+func newTestingModule(env *Environment) *testingModule {
+	return &testingModule{fns: env.Functions()}
+}
+
+// call calls some host function.
+func (m *testingModule) call(name string, params ...uint64) []uint64 {
+	for _, fn := range m.fns {
+		if fn.Module+"."+fn.Name == name {
+			return fn.Call(params)
+		}
+	}
+	panic("host function " + name + " not found")
+}
+
+// writeString writes a string word by word.
+func (m *testingModule) writeString(name, value string) {
+	for _, word := range StringToWords(value) {
+		m.call(name, EncodeI32(word))
+	}
+}
+
+// evaluate evaluates an expression validating the result code.
+func (m *testingModule) evaluate(expr string, expectedCode int32) {
+	m.call("operations.new_code", EncodeI32(0))
+	m.writeString("operations.write", expr)
+	if code := DecodeI32(m.call("operations.evaluate")[0]); code != expectedCode {
+		panic("unexpected evaluation code for " + expr)
+	}
+}
+
+func TestHost_CallExportedArgsStack(t *testing.T) {
+	env := NewEnvironment()
+	module := newTestingModule(env)
+	def := FunctionDefinition{Name: "f", Args: []Argument{PrimitiveArgument(ValueTypeI32)}}
+
+	// The module removing the arguments scope of the call leaves the stack corrupted.
+	_, err := env.CallExported(def, func(params []uint64) ([]uint64, error) {
+		module.call("args.pop")
+		return nil, nil
+	}, int32(1))
+	if err == nil || !strings.Contains(err.Error(), "arguments stack is empty") {
+		t.Errorf("expected the arguments stack error, got %v", err)
+	}
+}
@@ -0,0 +1,753 @@
+package whost
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	moduleOperations = "operations"
+	moduleArgs       = "args"
+	moduleZone       = "zone"
+	moduleReturns    = "returns"
+	moduleError      = "error"
+)
+
+const (
+	// evaluateResultPrimitive is the code returned by the evaluation of primitive (or empty) results.
+	evaluateResultPrimitive int32 = 0
+	// evaluateResultComposite is the code returned by the evaluation of composite results.
+	evaluateResultComposite int32 = 1
+)
+
+// returnKeyword is the identifier used on expressions to reference the return value of a function with a composite type.
+const returnKeyword = "return_"
+
+// noIndex represents an index not initialized.
+const noIndex = -1
+
+// Functions returns every host function of the import modules.
+// the imported functions registered with Import are also included.
+func (env *Environment) Functions() []*HostFunction {
+	i32, f32, f64 := []ValueType{ValueTypeI32}, []ValueType{ValueTypeF32}, []ValueType{ValueTypeF64}
+	none := func(fn func() error) func([]uint64) ([]uint64, error) {
+		return func([]uint64) ([]uint64, error) {
+			return nil, fn()
+		}
+	}
+	withI32 := func(fn func(v int32) error) func([]uint64) ([]uint64, error) {
+		return func(params []uint64) ([]uint64, error) {
+			return nil, fn(DecodeI32(params[0]))
+		}
+	}
+	withNumber := func(typ ValueType, fn func(v float64) error) func([]uint64) ([]uint64, error) {
+		return func(params []uint64) ([]uint64, error) {
+			return nil, fn(decodeNumber(typ, params[0]))
+		}
+	}
+	read := func(typ ValueType) func([]uint64) ([]uint64, error) {
+		return func(params []uint64) ([]uint64, error) {
+			n, err := env.operations.read(int(DecodeI32(params[0])))
+			if err != nil {
+				return nil, err
+			}
+			return []uint64{encodeNumber(typ, n)}, nil
+		}
+	}
+
+	fns := []*HostFunction{
+		// Operations.
+		env.newHostFunction(moduleOperations, "clear", nil, nil, none(func() error {
+			env.operations.clear()
+			return nil
+		})),
+		env.newHostFunction(moduleOperations, "new_code", i32, nil, withI32(func(v int32) error {
+			return env.operations.newVar(int(v), &VariableType{kind: kindSource})
+		})),
+		env.newHostFunction(moduleOperations, "write", i32, nil, withNumber(ValueTypeI32, env.operations.write)),
+		env.newHostFunction(moduleOperations, "write_f32", f32, nil, withNumber(ValueTypeF32, env.operations.write)),
+		env.newHostFunction(moduleOperations, "write_f64", f64, nil, withNumber(ValueTypeF64, env.operations.write)),
+		env.newHostFunction(moduleOperations, "read", i32, i32, read(ValueTypeI32)),
+		env.newHostFunction(moduleOperations, "read_f32", i32, f32, read(ValueTypeF32)),
+		env.newHostFunction(moduleOperations, "read_f64", i32, f64, read(ValueTypeF64)),
+		env.newHostFunction(moduleOperations, "evaluate", nil, i32, func([]uint64) ([]uint64, error) {
+			code, err := env.operations.evaluate()
+			if err != nil {
+				return nil, err
+			}
+			return []uint64{EncodeI32(code)}, nil
+		}),
+
+		// Args.
+		env.newHostFunction(moduleArgs, "push", nil, nil, none(func() error {
+			env.pushArgs()
+			return nil
+		})),
+		env.newHostFunction(moduleArgs, "pop", nil, nil, none(env.popArgs)),
+		env.newHostFunction(moduleArgs, "new", i32, nil, withI32(func(v int32) error {
+			return env.args.newVar(int(v))
+		})),
+		env.newHostFunction(moduleArgs, "write", i32, nil, withNumber(ValueTypeI32, func(v float64) error {
+			return env.args.write(v)
+		})),
+		env.newHostFunction(moduleArgs, "write_f32", f32, nil, withNumber(ValueTypeF32, func(v float64) error {
+			return env.args.write(v)
+		})),
+		env.newHostFunction(moduleArgs, "write_f64", f64, nil, withNumber(ValueTypeF64, func(v float64) error {
+			return env.args.write(v)
+		})),
+		env.newHostFunction(moduleArgs, "new_copy", nil, nil, none(func() error {
+			env.args.newCopy()
+			return nil
+		})),
+		env.newHostFunction(moduleArgs, "copy_index", i32, nil, withI32(func(v int32) error {
+			return env.args.copyIndex(int(v))
+		})),
+		env.newHostFunction(moduleArgs, "copy_operation", i32, nil, withI32(func(v int32) error {
+			return env.args.copyOperation(int(v))
+		})),
+
+		// Zone.
+		env.newHostFunction(moduleZone, "push", nil, nil, none(func() error {
+			env.pushZone()
+			return nil
+		})),
+		env.newHostFunction(moduleZone, "pop", nil, nil, none(env.popZone)),
+		env.newHostFunction(moduleZone, "new", i32, nil, withI32(func(v int32) error {
+			env.zone.newVar(int(v))
+			return nil
+		})),
+		env.newHostFunction(moduleZone, "write_name", i32, nil, withI32(func(v int32) error {
+			return env.zone.writeName(v)
+		})),
+		env.newHostFunction(moduleZone, "write_key", i32, nil, withI32(func(v int32) error {
+			return env.zone.writeKey(v)
+		})),
+		env.newHostFunction(moduleZone, "write_value", i32, nil, withNumber(ValueTypeI32, func(v float64) error {
+			return env.zone.writeValue(v)
+		})),
+		env.newHostFunction(moduleZone, "write_value_f32", f32, nil, withNumber(ValueTypeF32, func(v float64) error {
+			return env.zone.writeValue(v)
+		})),
+		env.newHostFunction(moduleZone, "write_value_f64", f64, nil, withNumber(ValueTypeF64, func(v float64) error {
+			return env.zone.writeValue(v)
+		})),
+		env.newHostFunction(moduleZone, "new_copy", nil, nil, none(func() error {
+			env.zone.newCopy()
+			return nil
+		})),
+		env.newHostFunction(moduleZone, "copy_name", i32, nil, withI32(func(v int32) error {
+			return env.zone.copyName(v)
+		})),
+		env.newHostFunction(moduleZone, "copy_key", i32, nil, withI32(func(v int32) error {
+			return env.zone.copyKey(v)
+		})),
+		env.newHostFunction(moduleZone, "copy_arg", i32, nil, withI32(func(v int32) error {
+			return env.zone.copyArg(int(v))
+		})),
+		env.newHostFunction(moduleZone, "copy_operation", i32, nil, withI32(func(v int32) error {
+			return env.zone.copyOperation(int(v), false)
+		})),
+		env.newHostFunction(moduleZone, "copy_operation_global", i32, nil, withI32(func(v int32) error {
+			return env.zone.copyOperation(int(v), true)
+		})),
+		env.newHostFunction(moduleZone, "set", nil, nil, none(func() error {
+			return env.zone.set(false)
+		})),
+		env.newHostFunction(moduleZone, "set_global", nil, nil, none(func() error {
+			return env.zone.set(true)
+		})),
+
+		// Returns.
+		env.newHostFunction(moduleReturns, "new_copy", nil, nil, none(func() error {
+			env.returns.newCopy()
+			return nil
+		})),
+		env.newHostFunction(moduleReturns, "copy_name", i32, nil, withI32(func(v int32) error {
+			return env.returns.copyName(v)
+		})),
+		env.newHostFunction(moduleReturns, "copy_key", i32, nil, withI32(func(v int32) error {
+			return env.returns.copyKey(v)
+		})),
+		env.newHostFunction(moduleReturns, "copy_var", nil, nil, none(env.returns.copyVar)),
+		env.newHostFunction(moduleReturns, "copy_operation", i32, nil, withI32(func(v int32) error {
+			return env.returns.copyOperation(int(v))
+		})),
+
+		// Error.
+		env.newHostFunction(moduleError, "new", nil, nil, none(func() error {
+			env.error.clear()
+			return nil
+		})),
+		env.newHostFunction(moduleError, "set", i32, nil, withI32(func(v int32) error {
+			env.error.set(v)
+			return nil
+		})),
+		env.newHostFunction(moduleError, "print", nil, nil, none(func() error {
+			env.error.print()
+			return nil
+		})),
+		env.newHostFunction(moduleError, "context", i32, nil, withI32(func(v int32) error {
+			env.error.context(int(v))
+			return nil
+		})),
+	}
+	return append(fns, env.imports...)
+}
+
+// processingVar contains the data written by the module for a variable not yet set.
+type processingVar struct {
+	typeCode int
+	name     []float64
+	key      []float64
+	value    []float64
+}
+
+// newProcessingVar is a constructor for processingVar.
+func newProcessingVar(typeCode int) *processingVar {
+	return &processingVar{typeCode: typeCode}
+}
+
+// operationsModule contains the state of the operations import module.
+type operationsModule struct {
+	env        *Environment
+	values     []*Variable
+	results    []*Variable
+	processing int
+}
+
+// newOperationsModule is a constructor for operationsModule.
+func newOperationsModule(env *Environment) *operationsModule {
+	return &operationsModule{env: env, processing: noIndex}
+}
+
+// newVar creates a new input value for the operations.
+func (ops *operationsModule) newVar(index int, typ *VariableType) error {
+	if index < 0 {
+		return fmt.Errorf("creating new input value: invalid value index %d", index)
+	}
+	for len(ops.values) <= index {
+		ops.values = append(ops.values, nil)
+	}
+	ops.values[index] = newVariable(typ)
+	ops.processing = index
+	ops.results = nil
+	return nil
+}
+
+// write writes a word on the processing input value.
+func (ops *operationsModule) write(value float64) error {
+	if ops.processing == noIndex || ops.values[ops.processing] == nil {
+		return errors.New("setting value: processing value not initialized: initialize value first")
+	}
+	ops.values[ops.processing].addWord(value)
+	return nil
+}
+
+// read reads a primitive result.
+func (ops *operationsModule) read(index int) (float64, error) {
+	res, err := ops.getResult(index)
+	if err != nil {
+		return 0, err
+	}
+	switch v := res.Value().(type) {
+	case float64:
+		return v, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case nil:
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("reading result: result type not valid for value %v", v)
+	}
+}
+
+// getResult returns the result of some operation.
+func (ops *operationsModule) getResult(index int) (*Variable, error) {
+	if index < 0 || index >= len(ops.results) {
+		return nil, fmt.Errorf("index out of range: index %d on operations result with length %d", index, len(ops.results))
+	}
+	return ops.results[index], nil
+}
+
+// clear removes the input values and the results.
+func (ops *operationsModule) clear() {
+	ops.values = nil
+	ops.results = nil
+	ops.processing = noIndex
+}
+
+// evaluate evaluates the expression written on the first input value.
+// returns a code identifying if the result is primitive or composite.
+func (ops *operationsModule) evaluate() (int32, error) {
+	if len(ops.values) != 1 || ops.values[0] == nil {
+		return evaluateResultPrimitive, errors.New("evaluate operation expects 1 parameter")
+	}
+	expression := jsToString(ops.values[0].Value())
+
+	// Identifiers are resolved only once for each evaluation.
+	resolved := make(map[string]interface{})
+	scope := func(name string) (interface{}, error) {
+		if value, ok := resolved[name]; ok {
+			return value, nil
+		}
+		var value interface{}
+		if name == returnKeyword {
+			variable, err := ops.env.returns.shiftVar()
+			if err != nil {
+				return nil, fmt.Errorf("invalid return on evaluate: %w", err)
+			}
+			value = variable.Value()
+		} else {
+			variable := ops.env.zone.getVar(name)
+			if variable == nil {
+				return nil, fmt.Errorf("invalid expression on evaluate: value for %s not found", name)
+			}
+			value = variable.Value()
+		}
+		resolved[name] = value
+		return value, nil
+	}
+
+	res, err := ops.env.engine.Evaluate(expression, scope)
+	if err != nil {
+		ops.results = []*Variable{newDynamicVariable(nil)}
+		return evaluateResultPrimitive, fmt.Errorf("evaluating expression: %w", err)
+	}
+	switch v := res.(type) {
+	case nil, float64:
+		ops.results = []*Variable{newDynamicVariable(v)}
+		return evaluateResultPrimitive, nil
+	case bool:
+		var n float64
+		if v {
+			n = 1
+		}
+		ops.results = []*Variable{newDynamicVariable(n)}
+		return evaluateResultPrimitive, nil
+	default:
+		value, err := normalizeValue(v)
+		if err != nil {
+			return evaluateResultComposite, err
+		}
+		ops.results = []*Variable{newDynamicVariable(value)}
+		return evaluateResultComposite, nil
+	}
+}
+
+// argsModule contains the state of the args import module.
+// a new state is created for each arguments scope.
+type argsModule struct {
+	env        *Environment
+	parent     *argsModule
+	args       []*Variable
+	processing int
+	copying    bool
+	copyIdx    int
+}
+
+// newArgsModule is a constructor for argsModule.
+func newArgsModule(env *Environment, parent *argsModule) *argsModule {
+	return &argsModule{env: env, parent: parent, processing: noIndex, copyIdx: noIndex}
+}
+
+// setArg sets the argument on some index.
+func (args *argsModule) setArg(index int, variable *Variable) {
+	for len(args.args) <= index {
+		args.args = append(args.args, nil)
+	}
+	args.args[index] = variable
+}
+
+// newVar creates a new argument with some type code.
+func (args *argsModule) newVar(typeCode int) error {
+	variable, err := newVariableFromCode(typeCode)
+	if err != nil {
+		return fmt.Errorf("creating new argument: %w", err)
+	}
+	args.processing = len(args.args)
+	args.setArg(args.processing, variable)
+	return nil
+}
+
+// write writes a word on the processing argument.
+func (args *argsModule) write(value float64) error {
+	if args.processing == noIndex {
+		return errors.New("setting value: processing argument not initialized: initialize argument first")
+	}
+	args.args[args.processing].addWord(value)
+	return nil
+}
+
+// getArg returns the argument on some index.
+func (args *argsModule) getArg(index int) (*Variable, error) {
+	if index < 0 || index >= len(args.args) || args.args[index] == nil {
+		return nil, fmt.Errorf("getting argument: invalid argument index %d", index)
+	}
+	return args.args[index], nil
+}
+
+// addArg adds an argument with some type code and host value.
+func (args *argsModule) addArg(typeCode int, value interface{}, index int) error {
+	variable, err := newVariableFromCode(typeCode)
+	if err != nil {
+		return err
+	}
+	if err := variable.setParsed(value); err != nil {
+		return err
+	}
+	args.setArg(index, variable)
+	return nil
+}
+
+// newCopy starts a copy action.
+func (args *argsModule) newCopy() {
+	args.copying = true
+	args.copyIdx = noIndex
+}
+
+// copyIndex sets the index of the argument to copy into.
+func (args *argsModule) copyIndex(index int) error {
+	if !args.copying {
+		return errors.New("setting copy index: processing copy not initialized: initialize copy action first")
+	}
+	if index < 0 {
+		return fmt.Errorf("setting argument index to copy: invalid argument index %d", index)
+	}
+	args.copyIdx = index
+	return nil
+}
+
+// copyOperation copies the result of some operation into the argument.
+func (args *argsModule) copyOperation(opIndex int) error {
+	if !args.copying || args.copyIdx == noIndex {
+		return errors.New("copying operation: processing copy not initialized: initialize copy action first")
+	}
+	res, err := args.env.operations.getResult(opIndex)
+	if err != nil {
+		return fmt.Errorf("copying operation result at index %d to argument at index %d: %w", opIndex, args.copyIdx, err)
+	}
+	args.setArg(args.copyIdx, res)
+	args.copying = false
+	args.copyIdx = noIndex
+	return nil
+}
+
+// zoneModule contains the state of the zone import module.
+// a new state is created for each zone and variables are searched from the innermost zone.
+type zoneModule struct {
+	env        *Environment
+	parent     *zoneModule
+	vars       map[string]*Variable
+	processing *processingVar
+	copying    *processingVar
+}
+
+// newZoneModule is a constructor for zoneModule.
+func newZoneModule(env *Environment, parent *zoneModule) *zoneModule {
+	return &zoneModule{env: env, parent: parent, vars: make(map[string]*Variable)}
+}
+
+// root returns the outermost zone.
+func (zone *zoneModule) root() *zoneModule {
+	z := zone
+	for z.parent != nil {
+		z = z.parent
+	}
+	return z
+}
+
+// getVar returns the variable with some name.
+func (zone *zoneModule) getVar(name string) *Variable {
+	for z := zone; z != nil; z = z.parent {
+		if variable, ok := z.vars[name]; ok {
+			return variable
+		}
+	}
+	return nil
+}
+
+// resolve resolves the identifiers used on variable keys.
+func (zone *zoneModule) resolve(name string) (interface{}, error) {
+	variable := zone.getVar(name)
+	if variable == nil {
+		return nil, fmt.Errorf("identifier %s not found", name)
+	}
+	return variable.Value(), nil
+}
+
+// newVar starts a new variable with some type code.
+func (zone *zoneModule) newVar(typeCode int) {
+	zone.processing = newProcessingVar(typeCode)
+}
+
+// writeName writes a word of the processing variable name.
+func (zone *zoneModule) writeName(value int32) error {
+	if zone.processing == nil {
+		return errors.New("setting name: processing variable not initialized: initialize variable first")
+	}
+	zone.processing.name = append(zone.processing.name, float64(value))
+	return nil
+}
+
+// writeKey writes a word of the processing variable key.
+func (zone *zoneModule) writeKey(value int32) error {
+	if zone.processing == nil {
+		return errors.New("setting key: processing variable not initialized: initialize variable first")
+	}
+	zone.processing.key = append(zone.processing.key, float64(value))
+	return nil
+}
+
+// writeValue writes a word of the processing variable value.
+func (zone *zoneModule) writeValue(value float64) error {
+	if zone.processing == nil {
+		return errors.New("setting value: processing variable not initialized: initialize variable first")
+	}
+	zone.processing.value = append(zone.processing.value, value)
+	return nil
+}
+
+// set sets the processing variable on the current zone (or on the global zone).
+func (zone *zoneModule) set(global bool) error {
+	if zone.processing == nil {
+		return errors.New("setting variable: processing variable not initialized: initialize variable first")
+	}
+	processing := zone.processing
+	zone.processing = nil
+	target := zone
+	if global {
+		target = zone.root()
+	}
+
+	name := wordsToString(processing.name)
+	variable, ok := target.vars[name]
+	if !ok {
+		var err error
+		if variable, err = newVariableFromCode(processing.typeCode); err != nil {
+			return fmt.Errorf("setting variable %s: %w", name, err)
+		}
+		target.vars[name] = variable
+	}
+	if len(processing.value) == 0 {
+		return nil
+	}
+	if len(processing.key) == 0 {
+		return variable.setWords(processing.value)
+	}
+
+	// Set the value of a member.
+	key := wordsToString(processing.key)
+	memberType, err := variable.memberType(key)
+	if err != nil {
+		return fmt.Errorf("setting variable %s: %w", name, err)
+	}
+	member := &Variable{typ: memberType}
+	if err := member.setWords(processing.value); err != nil {
+		return fmt.Errorf("setting variable %s: %w", name, err)
+	}
+	return variable.setByKey(key, member.Value(), zone.resolve)
+}
+
+// newCopy starts a copy action.
+func (zone *zoneModule) newCopy() {
+	zone.copying = newProcessingVar(0)
+}
+
+// copyName writes a word of the name of the variable to copy into.
+func (zone *zoneModule) copyName(value int32) error {
+	if zone.copying == nil {
+		return errors.New("setting copy name: processing copy not initialized: initialize copy action first")
+	}
+	zone.copying.name = append(zone.copying.name, float64(value))
+	return nil
+}
+
+// copyKey writes a word of the key of the variable to copy into.
+func (zone *zoneModule) copyKey(value int32) error {
+	if err := zone.assertValidCopy("setting copy key"); err != nil {
+		return err
+	}
+	zone.copying.key = append(zone.copying.key, float64(value))
+	return nil
+}
+
+// copyArg copies some argument into the variable.
+func (zone *zoneModule) copyArg(argIndex int) error {
+	if err := zone.assertValidCopy("copying argument variable"); err != nil {
+		return err
+	}
+	copying := zone.copying
+	zone.copying = nil
+	arg, err := zone.env.args.getArg(argIndex)
+	if err != nil {
+		return fmt.Errorf("copying argument at index %d to zone variable named %s: %w", argIndex, wordsToString(copying.name), err)
+	}
+	return zone.copyVar(copying, arg)
+}
+
+// copyOperation copies the result of some operation into the variable of the current zone (or of the global zone).
+func (zone *zoneModule) copyOperation(opIndex int, global bool) error {
+	if err := zone.assertValidCopy("copying operation variable"); err != nil {
+		return err
+	}
+	copying := zone.copying
+	zone.copying = nil
+	res, err := zone.env.operations.getResult(opIndex)
+	if err != nil {
+		return fmt.Errorf("copying operation at index %d to zone variable named %s: %w", opIndex, wordsToString(copying.name), err)
+	}
+	target := zone
+	if global {
+		target = zone.root()
+	}
+	return target.copyVar(copying, res)
+}
+
+// copyVar copies some variable into the variable (or member) described by the copy action.
+func (zone *zoneModule) copyVar(copying *processingVar, source *Variable) error {
+	name := wordsToString(copying.name)
+	current, ok := zone.vars[name]
+	if !ok {
+		zone.vars[name] = source
+		return nil
+	}
+	if len(copying.key) == 0 {
+		return current.setParsed(source.Value())
+	}
+	return current.setByKey(wordsToString(copying.key), source.Value(), zone.resolve)
+}
+
+// assertValidCopy validates if the copy action has a name.
+func (zone *zoneModule) assertValidCopy(source string) error {
+	if zone.copying == nil {
+		return fmt.Errorf("%s: processing copy not initialized: initialize copy action first", source)
+	}
+	if len(zone.copying.name) == 0 {
+		return fmt.Errorf("%s: processing copy name is empty", source)
+	}
+	return nil
+}
+
+// returnsModule contains the state of the returns import module.
+type returnsModule struct {
+	env     *Environment
+	vars    []*Variable
+	copying *processingVar
+}
+
+// newReturnsModule is a constructor for returnsModule.
+func newReturnsModule(env *Environment) *returnsModule {
+	return &returnsModule{env: env}
+}
+
+// newCopy starts a copy action.
+func (returns *returnsModule) newCopy() {
+	returns.copying = newProcessingVar(0)
+}
+
+// copyName writes a word of the name of the zone variable to return.
+func (returns *returnsModule) copyName(value int32) error {
+	if returns.copying == nil {
+		return errors.New("setting copy name: processing copy not initialized: initialize copy action first")
+	}
+	returns.copying.name = append(returns.copying.name, float64(value))
+	return nil
+}
+
+// copyKey writes a word of the key of the zone variable to return.
+func (returns *returnsModule) copyKey(value int32) error {
+	if returns.copying == nil {
+		return errors.New("setting copy key: processing copy not initialized: initialize copy action first")
+	}
+	returns.copying.key = append(returns.copying.key, float64(value))
+	return nil
+}
+
+// copyVar pushes the zone variable (or member) described by the copy action into the returns queue.
+func (returns *returnsModule) copyVar() error {
+	copying := returns.copying
+	returns.copying = nil
+	if copying == nil || len(copying.name) == 0 {
+		return errors.New("copying zone variable to return value: processing copy name is empty")
+	}
+	name := wordsToString(copying.name)
+	zone := returns.env.zone
+	variable := zone.getVar(name)
+	if variable == nil {
+		return fmt.Errorf("copying zone variable to return value: zone variable %s not found", name)
+	}
+	if len(copying.key) > 0 {
+		var err error
+		key := wordsToString(copying.key)
+		if variable, err = variable.getByKey(key, zone.resolve); err != nil {
+			return fmt.Errorf("copying zone variable to return value: variable member %s: %w", key, err)
+		}
+	}
+	returns.pushVar(variable)
+	return nil
+}
+
+// copyOperation pushes the result of some operation into the returns queue.
+func (returns *returnsModule) copyOperation(opIndex int) error {
+	res, err := returns.env.operations.getResult(opIndex)
+	if err != nil {
+		return fmt.Errorf("copying operation variable to return value: %w", err)
+	}
+	returns.pushVar(res)
+	return nil
+}
+
+// pushVar pushes a variable into the returns queue.
+func (returns *returnsModule) pushVar(variable *Variable) {
+	returns.vars = append(returns.vars, variable)
+}
+
+// shiftVar removes the first variable of the returns queue.
+func (returns *returnsModule) shiftVar() (*Variable, error) {
+	if len(returns.vars) == 0 {
+		return nil, errors.New("popping a return value: returns queue is empty")
+	}
+	variable := returns.vars[0]
+	returns.vars = returns.vars[1:]
+	return variable, nil
+}
+
+// errorModule contains the state of the error import module.
+type errorModule struct {
+	env          *Environment
+	words        []float64
+	expressionID int
+}
+
+// newErrorModule is a constructor for errorModule.
+func newErrorModule(env *Environment) *errorModule {
+	return &errorModule{env: env, expressionID: noIndex}
+}
+
+// clear starts a new error message.
+func (e *errorModule) clear() {
+	e.words = nil
+}
+
+// set writes a word of the error message.
+func (e *errorModule) set(value int32) {
+	e.words = append(e.words, float64(value))
+}
+
+// context sets the identifier of the runtime expression being executed.
+func (e *errorModule) context(id int) {
+	e.expressionID = id
+}
+
+// print reports the error message.
+func (e *errorModule) print() {
+	if len(e.words) == 0 {
+		e.env.reportError(errors.New("unknown error"))
+		return
+	}
+	e.env.reportError(errors.New(wordsToString(e.words)))
+}
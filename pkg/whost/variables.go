@@ -0,0 +1,659 @@
+package whost
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/bits"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// keyRegex matches the members of a variable key (e.g. [1]["k"]).
+var keyRegex = regexp.MustCompile(`\[([^\]]+)\]`)
+
+// variableKind represents the kind of runtime variable.
+// the values must match the codes used by the module transformation.
+type variableKind int
+
+const (
+	kindSource variableKind = iota + 1
+	kindI32
+	kindF32
+	kindF64
+	kindString
+	kindMapI32
+	kindMapF32
+	kindMapF64
+	kindMapString
+	kindArray
+	kindStruct
+)
+
+// kindShiftSize is the number of bits used by each kind on a type code.
+var kindShiftSize = bits.Len(uint(kindStruct))
+
+// VariableType contains the type definition for runtime variables.
+type VariableType struct {
+	kind  variableKind
+	value *VariableType
+}
+
+// ParseTypeCode returns the variable type for some type code.
+func ParseTypeCode(code int) (*VariableType, error) {
+	bitSize := bits.Len(uint(code))
+	if bitSize%kindShiftSize != 0 {
+		bitSize += kindShiftSize - bitSize%kindShiftSize
+	}
+	return parseTypeCode(code, bitSize-kindShiftSize)
+}
+
+// parseTypeCode returns the variable type for some type code starting on some shift.
+func parseTypeCode(code, shift int) (*VariableType, error) {
+	if shift < 0 {
+		return nil, fmt.Errorf("invalid type code %d: value type not defined", code)
+	}
+	kind := variableKind((code >> shift) & (1<<kindShiftSize - 1))
+	switch kind {
+	case kindSource, kindI32, kindF32, kindF64, kindString, kindStruct:
+		return &VariableType{kind: kind}, nil
+	case kindMapI32, kindMapF32, kindMapF64, kindMapString, kindArray:
+		value, err := parseTypeCode(code, shift-kindShiftSize)
+		if err != nil {
+			return nil, err
+		}
+		return &VariableType{kind: kind, value: value}, nil
+	default:
+		return nil, fmt.Errorf("invalid type code %d", code)
+	}
+}
+
+// inferVariableType returns the variable type for some value.
+// returns nil if the type cannot be inferred.
+func inferVariableType(value interface{}) *VariableType {
+	switch v := value.(type) {
+	case bool:
+		return &VariableType{kind: kindI32}
+	case float64:
+		if v != math.Trunc(v) || math.Abs(v) > math.MaxInt32 {
+			return &VariableType{kind: kindF64}
+		}
+		return &VariableType{kind: kindI32}
+	case string:
+		return &VariableType{kind: kindString}
+	case []interface{}:
+		for _, elem := range v {
+			if elemType := inferVariableType(elem); elemType != nil {
+				return &VariableType{kind: kindArray, value: elemType}
+			}
+		}
+		return &VariableType{kind: kindArray, value: &VariableType{kind: kindF64}}
+	case map[string]interface{}:
+		for _, elem := range v {
+			if elemType := inferVariableType(elem); elemType != nil {
+				return &VariableType{kind: kindMapString, value: elemType}
+			}
+		}
+		return &VariableType{kind: kindMapString, value: &VariableType{kind: kindF64}}
+	default:
+		return nil
+	}
+}
+
+// IsPrimitive returns if the type is primitive.
+func (t *VariableType) IsPrimitive() bool {
+	switch t.kind {
+	case kindI32, kindF32, kindF64:
+		return true
+	default:
+		return false
+	}
+}
+
+// String returns the type textual representation.
+func (t *VariableType) String() string {
+	switch t.kind {
+	case kindSource:
+		return "src"
+	case kindI32:
+		return "i32"
+	case kindF32:
+		return "f32"
+	case kindF64:
+		return "f64"
+	case kindString:
+		return "string"
+	case kindMapI32:
+		return fmt.Sprintf("map[i32]%s", t.value)
+	case kindMapF32:
+		return fmt.Sprintf("map[f32]%s", t.value)
+	case kindMapF64:
+		return fmt.Sprintf("map[f64]%s", t.value)
+	case kindMapString:
+		return fmt.Sprintf("map[string]%s", t.value)
+	case kindArray:
+		return fmt.Sprintf("[]%s", t.value)
+	case kindStruct:
+		return "struct"
+	default:
+		return "unknown"
+	}
+}
+
+// member returns the type of the members of the type.
+// the struct fields are dynamic, so their type is nil.
+func (t *VariableType) member() (*VariableType, error) {
+	if t.kind == kindStruct {
+		return nil, nil
+	}
+	if t.value == nil {
+		return nil, fmt.Errorf("type %s does not have members", t)
+	}
+	return t.value, nil
+}
+
+// zero returns the zero value for the type.
+func (t *VariableType) zero() interface{} {
+	switch {
+	case t.IsPrimitive():
+		return float64(0)
+	case t.kind == kindString, t.kind == kindSource:
+		return ""
+	case t.kind == kindArray:
+		return []interface{}{}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// coerce converts a value into the representation used by the type.
+func (t *VariableType) coerce(value interface{}) (interface{}, error) {
+	switch {
+	case t.IsPrimitive():
+		n, ok := toNumber(value)
+		if !ok {
+			return nil, fmt.Errorf("value %v is not valid for type %s", value, t)
+		}
+		if t.kind == kindF32 {
+			n = float64(float32(n))
+		}
+		return n, nil
+	case t.kind == kindString:
+		switch v := value.(type) {
+		case nil:
+			return "", nil
+		case string:
+			return v, nil
+		}
+		if n, ok := toNumber(value); ok {
+			return formatNumber(n), nil
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("value %v is not valid for type %s: %w", value, t, err)
+		}
+		return string(b), nil
+	case t.kind == kindSource:
+		return value, nil
+	case t.kind == kindArray:
+		return t.coerceArray(value)
+	case t.kind == kindStruct:
+		return t.coerceStruct(value)
+	default:
+		return t.coerceMap(value)
+	}
+}
+
+// coerceStruct converts a value into a struct.
+// the value must be an object, and the fields keep their values.
+func (t *VariableType) coerceStruct(value interface{}) (interface{}, error) {
+	res := map[string]interface{}{}
+	if value == nil {
+		return res, nil
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Map {
+		return nil, fmt.Errorf("value %v is not valid for type %s: value must be an object", value, t)
+	}
+	iter := rv.MapRange()
+	for iter.Next() {
+		res[jsToString(iter.Key().Interface())] = iter.Value().Interface()
+	}
+	return res, nil
+}
+
+// coerceArray converts a value into an array.
+func (t *VariableType) coerceArray(value interface{}) (interface{}, error) {
+	res := []interface{}{}
+	if value == nil {
+		return res, nil
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("value %v is not valid for type %s: value must be an array", value, t)
+	}
+	for i := 0; i < rv.Len(); i++ {
+		elem, err := t.coerceElem(rv.Index(i).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("array element %d: %w", i, err)
+		}
+		res = append(res, elem)
+	}
+	return res, nil
+}
+
+// coerceMap converts a value into a map.
+// the value may be a map or a list of key-value pairs.
+func (t *VariableType) coerceMap(value interface{}) (interface{}, error) {
+	res := map[string]interface{}{}
+	if value == nil {
+		return res, nil
+	}
+	set := func(k, v interface{}) error {
+		key, err := t.mapKey(k)
+		if err != nil {
+			return err
+		}
+		elem, err := t.coerceElem(v)
+		if err != nil {
+			return fmt.Errorf("map value for key %s: %w", key, err)
+		}
+		res[key] = elem
+		return nil
+	}
+	switch rv := reflect.ValueOf(value); rv.Kind() {
+	case reflect.Map:
+		iter := rv.MapRange()
+		for iter.Next() {
+			if err := set(iter.Key().Interface(), iter.Value().Interface()); err != nil {
+				return nil, err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			entry := reflect.ValueOf(rv.Index(i).Interface())
+			if (entry.Kind() != reflect.Slice && entry.Kind() != reflect.Array) || entry.Len() != 2 {
+				return nil, fmt.Errorf("map entry %v must be an array of size 2", rv.Index(i).Interface())
+			}
+			if err := set(entry.Index(0).Interface(), entry.Index(1).Interface()); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("value %v is not valid for type %s: value must be a map or an array of entries", value, t)
+	}
+	return res, nil
+}
+
+// coerceElem converts an element of an array or map.
+// null elements keep the zero value of the element type.
+func (t *VariableType) coerceElem(value interface{}) (interface{}, error) {
+	if value == nil {
+		return t.value.zero(), nil
+	}
+	return t.value.coerce(value)
+}
+
+// mapKey returns the textual key used by a map.
+func (t *VariableType) mapKey(key interface{}) (string, error) {
+	if t.kind == kindMapString || t.kind == kindStruct {
+		if s, ok := key.(string); ok {
+			return s, nil
+		}
+		return jsToString(key), nil
+	}
+	n, ok := toNumber(key)
+	if !ok {
+		return "", fmt.Errorf("key %v is not valid for type %s", key, t)
+	}
+	if t.kind == kindMapF32 {
+		n = float64(float32(n))
+	}
+	return formatNumber(n), nil
+}
+
+// Variable is a value shared between the module and the host.
+type Variable struct {
+	typ   *VariableType
+	value interface{}
+	words []float64
+}
+
+// newVariable is a constructor for Variable.
+func newVariable(typ *VariableType) *Variable {
+	return &Variable{typ: typ, value: typ.zero()}
+}
+
+// newVariableFromCode is a constructor for Variable.
+// creates a variable for some type code.
+func newVariableFromCode(code int) (*Variable, error) {
+	typ, err := ParseTypeCode(code)
+	if err != nil {
+		return nil, err
+	}
+	return newVariable(typ), nil
+}
+
+// newDynamicVariable is a constructor for Variable.
+// creates a variable with the type inferred from the value.
+func newDynamicVariable(value interface{}) *Variable {
+	return &Variable{typ: inferVariableType(value), value: value}
+}
+
+// Type returns the variable type (nil if unknown).
+func (v *Variable) Type() *VariableType {
+	return v.typ
+}
+
+// Value returns the variable value.
+func (v *Variable) Value() interface{} {
+	v.flush()
+	return v.value
+}
+
+// addWord adds a word written by the module.
+// primitive variables are replaced by the word, while strings append the word bytes.
+func (v *Variable) addWord(word float64) {
+	if v.typ != nil && v.typ.IsPrimitive() {
+		v.value = word
+		return
+	}
+	v.words = append(v.words, word)
+}
+
+// flush converts the pending words into the variable value.
+func (v *Variable) flush() {
+	if v.words == nil {
+		return
+	}
+	words := v.words
+	v.words = nil
+	if err := v.setWords(words); err != nil {
+		v.value = v.typ.zero()
+	}
+}
+
+// setWords sets the value from the words written by the module.
+func (v *Variable) setWords(words []float64) error {
+	if v.typ == nil {
+		// Values without type (ex. struct fields) are json values or strings.
+		str := wordsToString(words)
+		if err := json.Unmarshal([]byte(str), &v.value); err != nil {
+			v.value = str
+		}
+		return nil
+	}
+	switch {
+	case v.typ.IsPrimitive():
+		if len(words) == 0 {
+			v.value = float64(0)
+			return nil
+		}
+		return v.setParsed(words[len(words)-1])
+	case v.typ.kind == kindString:
+		v.value = unquote(wordsToString(words))
+		return nil
+	case v.typ.kind == kindSource:
+		v.value = wordsToString(words)
+		return nil
+	}
+	str := wordsToString(words)
+	var value interface{}
+	if err := json.Unmarshal([]byte(str), &value); err != nil {
+		return fmt.Errorf("setting %s value %s: invalid value: %w", v.typ, str, err)
+	}
+	return v.setParsed(value)
+}
+
+// setParsed sets the value from a host value.
+func (v *Variable) setParsed(value interface{}) error {
+	v.words = nil
+	if v.typ == nil {
+		v.value = value
+		return nil
+	}
+	res, err := v.typ.coerce(value)
+	if err != nil {
+		return err
+	}
+	v.value = res
+	return nil
+}
+
+// getByKey returns the member of the variable for some key (e.g. [1]["k"]).
+func (v *Variable) getByKey(key string, resolve Scope) (*Variable, error) {
+	keys, err := parseKeys(key, resolve)
+	if err != nil {
+		return nil, err
+	}
+	typ, value := v.typ, v.Value()
+	for _, k := range keys {
+		var memberType *VariableType
+		if typ != nil {
+			if memberType, err = typ.member(); err != nil {
+				return nil, err
+			}
+		}
+		var ok bool
+		switch container := value.(type) {
+		case []interface{}:
+			var index int
+			if index, ok = jsIndex(k); ok && index < len(container) {
+				value = container[index]
+			} else {
+				ok = false
+			}
+		case map[string]interface{}:
+			mapKey := jsToString(k)
+			if typ != nil {
+				if mapKey, err = typ.mapKey(k); err != nil {
+					return nil, err
+				}
+			}
+			value, ok = container[mapKey]
+		}
+		if !ok {
+			return nil, fmt.Errorf("member %s not found", jsToString(k))
+		}
+		typ = memberType
+	}
+	return &Variable{typ: typ, value: value}, nil
+}
+
+// setByKey sets the member of the variable for some key (e.g. [1]["k"]).
+// missing members are created.
+func (v *Variable) setByKey(key string, value interface{}, resolve Scope) error {
+	keys, err := parseKeys(key, resolve)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("invalid key %q", key)
+	}
+	res, err := setMember(v.typ, v.Value(), keys, value)
+	if err != nil {
+		return err
+	}
+	v.value = res
+	return nil
+}
+
+// memberType returns the type of the member of the variable for some key.
+func (v *Variable) memberType(key string) (*VariableType, error) {
+	typ := v.typ
+	for range keyRegex.FindAllString(bracketKey(key), -1) {
+		if typ == nil {
+			return nil, nil
+		}
+		memberType, err := typ.member()
+		if err != nil {
+			return nil, fmt.Errorf("variable does not have members for key %q: %w", key, err)
+		}
+		typ = memberType
+	}
+	return typ, nil
+}
+
+// setMember sets the member of some container value, returning the updated container.
+func setMember(typ *VariableType, container interface{}, keys []interface{}, value interface{}) (interface{}, error) {
+	if len(keys) == 0 {
+		if typ == nil {
+			return value, nil
+		}
+		return typ.coerce(value)
+	}
+	var elemType *VariableType
+	if typ != nil {
+		var err error
+		if elemType, err = typ.member(); err != nil {
+			return nil, err
+		}
+	}
+	zero := func() interface{} {
+		if elemType == nil {
+			return nil
+		}
+		return elemType.zero()
+	}
+	switch c := container.(type) {
+	case []interface{}:
+		index, ok := jsIndex(keys[0])
+		if !ok {
+			return nil, fmt.Errorf("invalid index %v on array: index is not an integer", keys[0])
+		}
+		for len(c) <= index {
+			c = append(c, zero())
+		}
+		elem, err := setMember(elemType, c[index], keys[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		c[index] = elem
+		return c, nil
+	case map[string]interface{}:
+		mapKey := jsToString(keys[0])
+		if typ != nil {
+			var err error
+			if mapKey, err = typ.mapKey(keys[0]); err != nil {
+				return nil, err
+			}
+		}
+		current, ok := c[mapKey]
+		if !ok {
+			current = zero()
+		}
+		elem, err := setMember(elemType, current, keys[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		c[mapKey] = elem
+		return c, nil
+	default:
+		return nil, fmt.Errorf("value %v does not have members", container)
+	}
+}
+
+// parseKeys parses the members of a key.
+// members that are not valid json values are resolved as identifiers.
+func parseKeys(key string, resolve Scope) ([]interface{}, error) {
+	var keys []interface{}
+	for _, match := range keyRegex.FindAllStringSubmatch(bracketKey(key), -1) {
+		var k interface{}
+		if err := json.Unmarshal([]byte(match[1]), &k); err == nil {
+			keys = append(keys, k)
+			continue
+		}
+		if resolve == nil {
+			return nil, fmt.Errorf("invalid key member %s", match[1])
+		}
+		k, err := resolve(strings.TrimPrefix(match[1], "#"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid key member %s: %w", match[1], err)
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// bracketKey wraps the key with brackets when needed (e.g. 1 → [1]).
+func bracketKey(key string) string {
+	key = strings.TrimSpace(key)
+	if !strings.HasPrefix(key, "[") {
+		key = "[" + key + "]"
+	}
+	return key
+}
+
+// toNumber converts a host value into a number.
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case nil:
+		return 0, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	}
+	switch rv := reflect.ValueOf(value); rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+// unquote removes the quotes from a json string.
+func unquote(s string) string {
+	if !strings.HasPrefix(s, `"`) {
+		return s
+	}
+	var res string
+	if err := json.Unmarshal([]byte(s), &res); err != nil {
+		return s
+	}
+	return res
+}
+
+// wordsToString converts the words written by the module into a string.
+// each word contains 4 bytes (big-endian) and the zero bytes are ignored.
+func wordsToString(words []float64) string {
+	var b []byte
+	for _, word := range words {
+		w := uint32(int64(word))
+		for shift := 24; shift >= 0; shift -= 8 {
+			c := byte(w >> uint(shift))
+			if c == 0 {
+				break
+			}
+			b = append(b, c)
+		}
+	}
+	return string(b)
+}
+
+// StringToWords converts a string into the words used by the module (4 bytes per word, big-endian).
+func StringToWords(s string) []int32 {
+	b := []byte(s)
+	var res []int32
+	for i := 0; i < len(b); i += 4 {
+		var w uint32
+		for j := 0; j < 4; j++ {
+			w <<= 8
+			if i+j < len(b) {
+				w |= uint32(b[i+j])
+			}
+		}
+		res = append(res, int32(w))
+	}
+	return res
+}